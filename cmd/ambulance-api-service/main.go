@@ -4,6 +4,7 @@ import (
     "context"
     "log"
     "os"
+    "strconv"
     "strings"
     "time"
 
@@ -13,7 +14,9 @@ import (
 
     "github.com/wac-project/wac-api/api"
 	"github.com/wac-project/wac-api/internal/ambulance"
+	"github.com/wac-project/wac-api/internal/blob_store"
 	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/idempotency"
 )
 
 func main() {
@@ -35,35 +38,179 @@ func main() {
     corsMiddleware := cors.New(cors.Config{
         AllowOrigins:     []string{"*"},
         AllowMethods:     []string{"GET", "PUT", "POST", "DELETE", "PATCH", "OPTIONS"},
-        AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-        ExposeHeaders:    []string{""},
+        AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", "X-User-Role", "Idempotency-Key"},
+        ExposeHeaders:    []string{"Idempotent-Replayed"},
         AllowCredentials: false,
         MaxAge:           12 * time.Hour,
     })
     engine.Use(corsMiddleware)
 
+    // one client shared by the services, so that writes to several collections can run in a transaction
+   // (transactions need MongoDB running as a replica set or a sharded cluster; on a
+   // standalone server the writes run without them)
+   mongoClient := db_service.NewMongoClient(db_service.MongoServiceConfig{})
+
     // one service per collection/type
-   dbAmbSvc  := db_service.NewMongoService[ambulance.Ambulance](db_service.MongoServiceConfig{Collection: "ambulance"})
-   dbPaySvc  := db_service.NewMongoService[ambulance.Payment](  db_service.MongoServiceConfig{Collection: "payment"})
-   dbProcSvc := db_service.NewMongoService[ambulance.Procedure](db_service.MongoServiceConfig{Collection: "procedure"})
+   dbAmbSvc  := db_service.NewMongoService[ambulance.Ambulance](db_service.MongoServiceConfig{Collection: "ambulance", Client: mongoClient})
+   dbPaySvc  := db_service.NewMongoService[ambulance.Payment](  db_service.MongoServiceConfig{Collection: "payment", Client: mongoClient,
+       RenamedFields: map[string]string{"procedureid": "procedure_id"}})
+   dbProcSvc := db_service.NewMongoService[ambulance.Procedure](db_service.MongoServiceConfig{Collection: "procedure", Client: mongoClient,
+       RenamedFields: map[string]string{"ambulanceid": "ambulance_id", "visittype": "visit_type"}})
+   dbCrewSvc := db_service.NewMongoService[ambulance.CrewMember](db_service.MongoServiceConfig{Collection: "crew_member", Client: mongoClient})
+   dbShiftSvc := db_service.NewMongoService[ambulance.Shift](db_service.MongoServiceConfig{Collection: "shift", Client: mongoClient})
+   dbMaintSvc := db_service.NewMongoService[ambulance.MaintenanceEntry](db_service.MongoServiceConfig{Collection: "maintenance_entry", Client: mongoClient})
+   dbSchedSvc := db_service.NewMongoService[ambulance.ServiceSchedule](db_service.MongoServiceConfig{Collection: "service_schedule", Client: mongoClient})
+   dbDeptSvc := db_service.NewMongoService[ambulance.Department](db_service.MongoServiceConfig{Collection: "department", Client: mongoClient})
+   dbPatientSvc := db_service.NewMongoService[ambulance.Patient](db_service.MongoServiceConfig{Collection: "patient", Client: mongoClient})
+   dbProcTypeSvc := db_service.NewMongoService[ambulance.ProcedureType](db_service.MongoServiceConfig{Collection: "procedure_type", Client: mongoClient})
+   dbPriceListSvc := db_service.NewMongoService[ambulance.PriceList](db_service.MongoServiceConfig{Collection: "price_list", Client: mongoClient})
+   dbClaimSvc := db_service.NewMongoService[ambulance.Claim](db_service.MongoServiceConfig{Collection: "claim", Client: mongoClient})
+   dbPayerSvc := db_service.NewMongoService[ambulance.Payer](db_service.MongoServiceConfig{Collection: "payer", Client: mongoClient})
+   dbInvoiceSvc := db_service.NewMongoService[ambulance.Invoice](db_service.MongoServiceConfig{Collection: "invoice", Client: mongoClient})
+   dbInvoiceCounterSvc := db_service.NewMongoService[ambulance.InvoiceCounter](db_service.MongoServiceConfig{Collection: "invoice_counter", Client: mongoClient})
+   dbBankStatementSvc := db_service.NewMongoService[ambulance.BankStatement](db_service.MongoServiceConfig{Collection: "bank_statement", Client: mongoClient})
+   dbIdempotencySvc := db_service.NewMongoService[idempotency.Record](db_service.MongoServiceConfig{Collection: "idempotency_key", Client: mongoClient})
+
+   // encrypt patient data at rest when a key file is configured
+   if keyFile := os.Getenv("AMBULANCE_API_ENCRYPTION_KEY_FILE"); keyFile != "" {
+       keys, err := db_service.LoadKeyRing(keyFile)
+       if err != nil {
+           log.Fatalf("Cannot load encryption keys: %v", err)
+       }
+       if dbPatientSvc, err = db_service.NewEncryptedService(dbPatientSvc, keys, ambulance.PatientEncryptedFields...); err != nil {
+           log.Fatalf("Cannot encrypt patients: %v", err)
+       }
+       if dbProcSvc, err = db_service.NewEncryptedService(dbProcSvc, keys, ambulance.ProcedureEncryptedFields...); err != nil {
+           log.Fatalf("Cannot encrypt procedures: %v", err)
+       }
+   } else {
+       log.Printf("AMBULANCE_API_ENCRYPTION_KEY_FILE not set, patient data is stored unencrypted")
+   }
 
-   // tear down all three on exit
+   // attachment content goes to the local filesystem unless GridFS is selected
+   var blobStore blob_store.BlobStore
+   switch storeKind := os.Getenv("AMBULANCE_API_BLOB_STORE"); storeKind {
+   case "", "filesystem":
+       dir := os.Getenv("AMBULANCE_API_BLOB_DIR")
+       if dir == "" {
+           dir = "attachments"
+       }
+       store, err := blob_store.NewFileStore(dir)
+       if err != nil {
+           log.Fatalf("Cannot open attachment store: %v", err)
+       }
+       blobStore = store
+   case "gridfs":
+       blobStore = blob_store.NewGridFSStore(blob_store.GridFSConfig{Bucket: "attachments"})
+   default:
+       log.Fatalf("Unknown blob store %v", storeKind)
+   }
+   if value := os.Getenv("AMBULANCE_API_ATTACHMENT_MAX_SIZE"); value != "" {
+       if size, err := strconv.ParseInt(value, 10, 64); err == nil && size > 0 {
+           ambulance.MaxAttachmentSize = size
+       } else {
+           log.Printf("Invalid attachment size limit: %v", value)
+       }
+   }
+   if value := os.Getenv("AMBULANCE_API_INVOICE_TAX_RATE"); value != "" {
+       if rate, err := strconv.ParseFloat(value, 64); err == nil && rate >= 0 && rate <= 100 {
+           ambulance.DefaultInvoiceTaxRate = rate
+       } else {
+           log.Printf("Invalid invoice tax rate: %v", value)
+       }
+   }
+   if issuer := os.Getenv("AMBULANCE_API_INVOICE_ISSUER"); issuer != "" {
+       ambulance.InvoiceIssuer = issuer
+   }
+
+   // tear down all services on exit
    defer dbAmbSvc.Disconnect(context.Background())
    defer dbPaySvc.Disconnect(context.Background())
    defer dbProcSvc.Disconnect(context.Background())
+   defer dbCrewSvc.Disconnect(context.Background())
+   defer dbShiftSvc.Disconnect(context.Background())
+   defer dbMaintSvc.Disconnect(context.Background())
+   defer dbSchedSvc.Disconnect(context.Background())
+   defer dbDeptSvc.Disconnect(context.Background())
+   defer dbPatientSvc.Disconnect(context.Background())
+   defer dbProcTypeSvc.Disconnect(context.Background())
+   defer dbPriceListSvc.Disconnect(context.Background())
+   defer dbClaimSvc.Disconnect(context.Background())
+   defer dbPayerSvc.Disconnect(context.Background())
+   defer dbInvoiceSvc.Disconnect(context.Background())
+   defer dbInvoiceCounterSvc.Disconnect(context.Background())
+   defer dbBankStatementSvc.Disconnect(context.Background())
+   defer dbIdempotencySvc.Disconnect(context.Background())
+   defer blobStore.Disconnect(context.Background())
+   defer mongoClient.Disconnect(context.Background())
 
    // inject each under its own key
    engine.Use(func(ctx *gin.Context) {
        ctx.Set("db_service_ambulance", dbAmbSvc)
        ctx.Set("db_service_payment",   dbPaySvc)
        ctx.Set("db_service_procedure",  dbProcSvc)
+       ctx.Set("db_service_crew",       dbCrewSvc)
+       ctx.Set("db_service_shift",      dbShiftSvc)
+       ctx.Set("db_service_maintenance", dbMaintSvc)
+       ctx.Set("db_service_service_schedule", dbSchedSvc)
+       ctx.Set("db_service_department", dbDeptSvc)
+       ctx.Set("db_service_patient",    dbPatientSvc)
+       ctx.Set("db_service_procedure_type", dbProcTypeSvc)
+       ctx.Set("db_service_price_list", dbPriceListSvc)
+       ctx.Set("db_service_claim",      dbClaimSvc)
+       ctx.Set("db_service_payer",      dbPayerSvc)
+       ctx.Set("db_service_invoice",    dbInvoiceSvc)
+       ctx.Set("db_service_invoice_counter", dbInvoiceCounterSvc)
+       ctx.Set("db_service_bank_statement", dbBankStatementSvc)
+       ctx.Set("blob_store",            blobStore)
+       ctx.Set("db_transactor",         mongoClient)
            ctx.Next()
     })
 
+    // replay responses of retried POST requests carrying an Idempotency-Key
+    idempotencyTTL := idempotency.DefaultTTL
+    if value := os.Getenv("AMBULANCE_API_IDEMPOTENCY_TTL"); value != "" {
+        if ttl, err := time.ParseDuration(value); err == nil && ttl > 0 {
+            idempotencyTTL = ttl
+        } else {
+            log.Printf("Invalid idempotency key TTL: %v", value)
+        }
+    }
+    engine.Use(idempotency.Middleware(dbIdempotencySvc, idempotencyTTL))
+
+    // take ambulances with overdue mandatory inspections out of service
+    maintenanceInterval := time.Hour
+    if value := os.Getenv("AMBULANCE_API_MAINTENANCE_CHECK_INTERVAL"); value != "" {
+        if interval, err := time.ParseDuration(value); err == nil && interval > 0 {
+            maintenanceInterval = interval
+        } else {
+            log.Printf("Invalid maintenance check interval: %v", value)
+        }
+    }
+    watcherCtx, stopWatcher := context.WithCancel(context.Background())
+    defer stopWatcher()
+    go ambulance.RunMaintenanceWatcher(watcherCtx, dbAmbSvc, dbSchedSvc, maintenanceInterval)
+
     handleFunctions := &ambulance.ApiHandleFunctions{
         AmbulanceManagementAPI: ambulance.NewAmbulanceAPI(),
+        BankReconciliationAPI:  ambulance.NewBankReconciliationAPI(),
+        BatchAPI:               ambulance.NewBatchAPI(),
+        ClaimsAPI:              ambulance.NewClaimsAPI(),
+        ClinicalRecordsAPI:     ambulance.NewClinicalRecordsAPI(),
+        CrewManagementAPI:      ambulance.NewCrewAPI(),
+        DepartmentManagementAPI: ambulance.NewDepartmentAPI(),
+        InvoicesAPI:            ambulance.NewInvoicesAPI(),
+        MaintenanceManagementAPI: ambulance.NewMaintenanceAPI(),
+        MigrationsAPI:          ambulance.NewMigrationsAPI(),
+        PatientManagementAPI:   ambulance.NewPatientAPI(),
         PaymentManagementAPI:   ambulance.NewPaymentAPI(),
+        PayerManagementAPI:     ambulance.NewPayerAPI(),
+        PricingAPI:             ambulance.NewPricingAPI(),
+        ProcedureCatalogAPI:    ambulance.NewProcedureCatalogAPI(),
         ProcedureManagementAPI: ambulance.NewProcedureAPI(),
+        ReportsAPI:             ambulance.NewReportsAPI(),
+        SchedulingAPI:          ambulance.NewSchedulingAPI(),
+        ShiftManagementAPI:     ambulance.NewShiftAPI(),
     }
 
     ambulance.NewRouterWithGinEngine(engine, *handleFunctions)
@@ -16,6 +16,10 @@ import (
 
 type AmbulanceManagementAPI interface {
 
+	// AssignAmbulanceCrew Put /api/ambulances/:ambulanceId/crew
+	// Assign crew members to an ambulance for a shift
+	AssignAmbulanceCrew(c *gin.Context)
+
 	// CreateAmbulance Post /api/ambulances
 	// Create a new ambulance
 	CreateAmbulance(c *gin.Context)
@@ -40,6 +44,10 @@ type AmbulanceManagementAPI interface {
 	// Get procedures for an ambulance
 	GetProceduresByAmbulance(c *gin.Context)
 
+	// ImportAmbulances Post /api/ambulances:import
+	// Import ambulances from NDJSON or CSV
+	ImportAmbulances(c *gin.Context)
+
 	// UpdateAmbulance Put /api/ambulances/:ambulanceId
 	// Update ambulance details
 	UpdateAmbulance(c *gin.Context)
@@ -0,0 +1,46 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type BankReconciliationAPI interface {
+
+	// ConfirmBankStatement Post /api/bank-statements/:statementId/confirmation
+	// Book every matched line of a bank statement as a payment
+	ConfirmBankStatement(c *gin.Context)
+
+	// ConfirmBankStatementLine Post /api/bank-statements/:statementId/lines/:lineNumber/confirmation
+	// Book a bank statement line as a payment
+	ConfirmBankStatementLine(c *gin.Context)
+
+	// DismissBankStatementLine Post /api/bank-statements/:statementId/lines/:lineNumber/dismissal
+	// Mark a bank statement line as not a payment
+	DismissBankStatementLine(c *gin.Context)
+
+	// GetBankStatementById Get /api/bank-statements/:statementId
+	// Get an imported bank statement
+	GetBankStatementById(c *gin.Context)
+
+	// GetBankStatements Get /api/bank-statements
+	// Get list of imported bank statements
+	GetBankStatements(c *gin.Context)
+
+	// GetReconciliationQueue Get /api/reconciliation/queue
+	// Get bank statement lines awaiting review
+	GetReconciliationQueue(c *gin.Context)
+
+	// ImportBankStatement Post /api/bank-statements
+	// Import a CSV or camt.053 bank statement
+	ImportBankStatement(c *gin.Context)
+}
@@ -0,0 +1,22 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type BatchAPI interface {
+
+	// ExecuteBatch Post /api/batch
+	// Execute several API requests in one round trip
+	ExecuteBatch(c *gin.Context)
+}
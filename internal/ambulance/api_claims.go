@@ -0,0 +1,58 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type ClaimsAPI interface {
+
+	// CreateClaim Post /api/claims
+	// Create a draft claim for an insurer
+	CreateClaim(c *gin.Context)
+
+	// DecideClaim Post /api/claims/:claimId/decision
+	// Record the insurer's decision on a submitted claim
+	DecideClaim(c *gin.Context)
+
+	// DeleteClaim Delete /api/claims/:claimId
+	// Delete a draft claim
+	DeleteClaim(c *gin.Context)
+
+	// GetClaimAgingReport Get /api/reports/claims/aging
+	// Get unpaid claims by insurer and age
+	GetClaimAgingReport(c *gin.Context)
+
+	// GetClaimById Get /api/claims/:claimId
+	// Get claim details
+	GetClaimById(c *gin.Context)
+
+	// GetClaimRejectionReport Get /api/reports/claims/rejections
+	// Get rejection rates and reasons by insurer
+	GetClaimRejectionReport(c *gin.Context)
+
+	// GetClaims Get /api/claims
+	// Get list of claims
+	GetClaims(c *gin.Context)
+
+	// SettleClaim Post /api/claims/:claimId/settlement
+	// Record the insurer's payment of an accepted claim
+	SettleClaim(c *gin.Context)
+
+	// SubmitClaim Post /api/claims/:claimId/submission
+	// Submit a draft claim to the insurer
+	SubmitClaim(c *gin.Context)
+
+	// UpdateClaim Put /api/claims/:claimId
+	// Update a draft claim
+	UpdateClaim(c *gin.Context)
+}
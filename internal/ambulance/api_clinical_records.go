@@ -0,0 +1,50 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type ClinicalRecordsAPI interface {
+
+	// CreateProcedureNote Post /api/procedures/:procedureId/notes
+	// Add a clinical note to a procedure
+	CreateProcedureNote(c *gin.Context)
+
+	// DeleteAttachment Delete /api/procedures/:procedureId/attachments/:attachmentId
+	// Delete an attachment
+	DeleteAttachment(c *gin.Context)
+
+	// DeleteProcedureNote Delete /api/procedures/:procedureId/notes/:noteId
+	// Delete a clinical note
+	DeleteProcedureNote(c *gin.Context)
+
+	// DownloadAttachment Get /api/procedures/:procedureId/attachments/:attachmentId
+	// Download the content of an attachment
+	DownloadAttachment(c *gin.Context)
+
+	// GetAttachments Get /api/procedures/:procedureId/attachments
+	// Get attachments of a procedure
+	GetAttachments(c *gin.Context)
+
+	// GetProcedureNotes Get /api/procedures/:procedureId/notes
+	// Get clinical notes of a procedure
+	GetProcedureNotes(c *gin.Context)
+
+	// UploadAttachment Post /api/procedures/:procedureId/attachments
+	// Upload an attachment to a procedure
+	UploadAttachment(c *gin.Context)
+
+	// VerifyAttachment Get /api/procedures/:procedureId/attachments/:attachmentId/checksum
+	// Verify the stored content of an attachment against its checksum
+	VerifyAttachment(c *gin.Context)
+}
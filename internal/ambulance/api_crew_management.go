@@ -0,0 +1,42 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type CrewManagementAPI interface {
+
+	// CreateCrewMember Post /api/crew
+	// Create a new crew member
+	CreateCrewMember(c *gin.Context)
+
+	// DeleteCrewMember Delete /api/crew/:crewMemberId
+	// Delete a crew member
+	DeleteCrewMember(c *gin.Context)
+
+	// GetCrewMemberById Get /api/crew/:crewMemberId
+	// Get crew member details
+	GetCrewMemberById(c *gin.Context)
+
+	// GetCrewMembers Get /api/crew
+	// Get list of crew members
+	GetCrewMembers(c *gin.Context)
+
+	// GetExpiringCertifications Get /api/certifications/expiring
+	// Get crew certifications expiring soon
+	GetExpiringCertifications(c *gin.Context)
+
+	// UpdateCrewMember Put /api/crew/:crewMemberId
+	// Update crew member details
+	UpdateCrewMember(c *gin.Context)
+}
@@ -0,0 +1,46 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type DepartmentManagementAPI interface {
+
+	// CreateDepartment Post /api/departments
+	// Create a new department
+	CreateDepartment(c *gin.Context)
+
+	// DeleteDepartment Delete /api/departments/:departmentId
+	// Delete a department
+	DeleteDepartment(c *gin.Context)
+
+	// GetDepartmentById Get /api/departments/:departmentId
+	// Get department details
+	GetDepartmentById(c *gin.Context)
+
+	// GetDepartmentReport Get /api/reports/departments
+	// Get roll-up totals for every department
+	GetDepartmentReport(c *gin.Context)
+
+	// GetDepartmentRollup Get /api/departments/:departmentId/rollup
+	// Get roll-up totals for a department including sub-departments
+	GetDepartmentRollup(c *gin.Context)
+
+	// GetDepartments Get /api/departments
+	// Get list of departments
+	GetDepartments(c *gin.Context)
+
+	// UpdateDepartment Put /api/departments/:departmentId
+	// Update department details
+	UpdateDepartment(c *gin.Context)
+}
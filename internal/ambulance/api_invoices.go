@@ -0,0 +1,50 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type InvoicesAPI interface {
+
+	// AllocatePayment Post /api/invoices/:invoiceId/allocations
+	// Allocate a payment against an invoice
+	AllocatePayment(c *gin.Context)
+
+	// CancelInvoice Post /api/invoices/:invoiceId/cancellation
+	// Cancel an issued invoice
+	CancelInvoice(c *gin.Context)
+
+	// CreateInvoice Post /api/invoices
+	// Create a draft invoice from procedures
+	CreateInvoice(c *gin.Context)
+
+	// DeleteInvoice Delete /api/invoices/:invoiceId
+	// Delete a draft invoice
+	DeleteInvoice(c *gin.Context)
+
+	// GetInvoiceById Get /api/invoices/:invoiceId
+	// Get an invoice as JSON, HTML or PDF
+	GetInvoiceById(c *gin.Context)
+
+	// GetInvoices Get /api/invoices
+	// Get list of invoices
+	GetInvoices(c *gin.Context)
+
+	// IssueInvoice Post /api/invoices/:invoiceId/issuance
+	// Issue a draft invoice and assign its number
+	IssueInvoice(c *gin.Context)
+
+	// UpdateInvoice Put /api/invoices/:invoiceId
+	// Update a draft invoice
+	UpdateInvoice(c *gin.Context)
+}
@@ -0,0 +1,54 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type MaintenanceManagementAPI interface {
+
+	// CreateMaintenanceEntry Post /api/ambulances/:ambulanceId/maintenance
+	// Log a maintenance entry for an ambulance
+	CreateMaintenanceEntry(c *gin.Context)
+
+	// CreateServiceSchedule Post /api/ambulances/:ambulanceId/service-schedules
+	// Create a recurring service schedule for an ambulance
+	CreateServiceSchedule(c *gin.Context)
+
+	// DeleteMaintenanceEntry Delete /api/maintenance/:entryId
+	// Delete a maintenance log entry
+	DeleteMaintenanceEntry(c *gin.Context)
+
+	// DeleteServiceSchedule Delete /api/service-schedules/:scheduleId
+	// Delete a service schedule
+	DeleteServiceSchedule(c *gin.Context)
+
+	// GetMaintenanceCostReport Get /api/reports/maintenance-costs
+	// Get maintenance costs per department
+	GetMaintenanceCostReport(c *gin.Context)
+
+	// GetMaintenanceDue Get /api/maintenance/due
+	// Get overdue services
+	GetMaintenanceDue(c *gin.Context)
+
+	// GetMaintenanceEntries Get /api/ambulances/:ambulanceId/maintenance
+	// Get the maintenance log of an ambulance
+	GetMaintenanceEntries(c *gin.Context)
+
+	// GetServiceSchedules Get /api/ambulances/:ambulanceId/service-schedules
+	// Get the service schedules of an ambulance
+	GetServiceSchedules(c *gin.Context)
+
+	// UpdateServiceSchedule Put /api/service-schedules/:scheduleId
+	// Update a service schedule
+	UpdateServiceSchedule(c *gin.Context)
+}
@@ -0,0 +1,46 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type MigrationsAPI interface {
+
+	// MigrateDepartments Post /api/migrations/departments
+	// Link ambulances with free-text departments to department records
+	MigrateDepartments(c *gin.Context)
+
+	// MigrateEncryption Post /api/migrations/encryption
+	// Re-encrypt patient data with the active encryption key
+	MigrateEncryption(c *gin.Context)
+
+	// MigrateFieldNames Post /api/migrations/field-names
+	// Rename fields stored under the names of earlier versions
+	MigrateFieldNames(c *gin.Context)
+
+	// MigrateMoney Post /api/migrations/money
+	// Convert floating point prices and payment amounts to exact decimal amounts
+	MigrateMoney(c *gin.Context)
+
+	// MigrateProcedureCodes Post /api/migrations/procedure-codes
+	// Assign catalog codes to procedures by their name
+	MigrateProcedureCodes(c *gin.Context)
+
+	// MigratePatients Post /api/migrations/patients
+	// Link procedures with free-text patients to patient records
+	MigratePatients(c *gin.Context)
+
+	// MigratePayers Post /api/migrations/payers
+	// Link procedures and payments with free-text payers to payer records
+	MigratePayers(c *gin.Context)
+}
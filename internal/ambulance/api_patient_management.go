@@ -0,0 +1,42 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type PatientManagementAPI interface {
+
+	// CreatePatient Post /api/patients
+	// Register a new patient
+	CreatePatient(c *gin.Context)
+
+	// DeletePatient Delete /api/patients/:patientId
+	// Delete a patient
+	DeletePatient(c *gin.Context)
+
+	// GetPatientById Get /api/patients/:patientId
+	// Get patient details
+	GetPatientById(c *gin.Context)
+
+	// GetPatientProcedures Get /api/patients/:patientId/procedures
+	// Get the procedure timeline of a patient
+	GetPatientProcedures(c *gin.Context)
+
+	// GetPatients Get /api/patients
+	// Get list of patients
+	GetPatients(c *gin.Context)
+
+	// UpdatePatient Put /api/patients/:patientId
+	// Update patient details
+	UpdatePatient(c *gin.Context)
+}
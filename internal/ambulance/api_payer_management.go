@@ -0,0 +1,42 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type PayerManagementAPI interface {
+
+	// CreatePayer Post /api/payers
+	// Register a payer
+	CreatePayer(c *gin.Context)
+
+	// DeletePayer Delete /api/payers/:payerId
+	// Delete a payer
+	DeletePayer(c *gin.Context)
+
+	// GetPayerById Get /api/payers/:payerId
+	// Get payer details
+	GetPayerById(c *gin.Context)
+
+	// GetPayers Get /api/payers
+	// Get list of payers
+	GetPayers(c *gin.Context)
+
+	// GetProcedureCoverage Get /api/procedures/:procedureId/coverage
+	// Split the price of a procedure into the payer's share and the patient's co-payment
+	GetProcedureCoverage(c *gin.Context)
+
+	// UpdatePayer Put /api/payers/:payerId
+	// Update payer details
+	UpdatePayer(c *gin.Context)
+}
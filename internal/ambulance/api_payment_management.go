@@ -33,6 +33,14 @@ type PaymentManagementAPI interface {
     // Get list of payment records 
      GetPayments(c *gin.Context)
 
+    // ImportPayments Post /api/payments:import
+    // Import payment records from NDJSON or CSV 
+     ImportPayments(c *gin.Context)
+
+    // RefundPayment Post /api/payments/:paymentId/refunds
+    // Refund a settled payment in full or in part 
+     RefundPayment(c *gin.Context)
+
     // UpdatePayment Put /api/payments/:paymentId
     // Update payment record details 
      UpdatePayment(c *gin.Context)
@@ -0,0 +1,42 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type PricingAPI interface {
+
+	// CreatePriceList Post /api/price-lists
+	// Create a new price list version
+	CreatePriceList(c *gin.Context)
+
+	// DeletePriceList Delete /api/price-lists/:priceListId
+	// Delete a price list that is not yet effective
+	DeletePriceList(c *gin.Context)
+
+	// GetPriceListById Get /api/price-lists/:priceListId
+	// Get price list details
+	GetPriceListById(c *gin.Context)
+
+	// GetPriceLists Get /api/price-lists
+	// Get list of price lists
+	GetPriceLists(c *gin.Context)
+
+	// QuoteProcedure Post /api/procedures:quote
+	// Compute the price of a procedure without saving it
+	QuoteProcedure(c *gin.Context)
+
+	// UpdatePriceList Put /api/price-lists/:priceListId
+	// Update a price list that is not yet effective
+	UpdatePriceList(c *gin.Context)
+}
@@ -0,0 +1,46 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type ProcedureCatalogAPI interface {
+
+	// CreateProcedureType Post /api/procedure-types
+	// Add a procedure type to the catalog
+	CreateProcedureType(c *gin.Context)
+
+	// DeleteProcedureType Delete /api/procedure-types/:code
+	// Remove a procedure type from the catalog
+	DeleteProcedureType(c *gin.Context)
+
+	// GetProcedureReport Get /api/reports/procedures
+	// Get procedure counts and revenue per catalog code
+	GetProcedureReport(c *gin.Context)
+
+	// GetProcedureType Get /api/procedure-types/:code
+	// Get procedure type details
+	GetProcedureType(c *gin.Context)
+
+	// GetProcedureTypes Get /api/procedure-types
+	// Get the procedure catalog
+	GetProcedureTypes(c *gin.Context)
+
+	// ImportProcedureTypes Post /api/procedure-types/import
+	// Import procedure types from CSV
+	ImportProcedureTypes(c *gin.Context)
+
+	// UpdateProcedureType Put /api/procedure-types/:code
+	// Update a procedure type
+	UpdateProcedureType(c *gin.Context)
+}
@@ -17,6 +17,10 @@ import (
 type ProcedureManagementAPI interface {
 
 
+    // ChangeProcedureStatus Post /api/procedures/:procedureId/status
+    // Move a procedure to another lifecycle status 
+     ChangeProcedureStatus(c *gin.Context)
+
     // CreateProcedure Post /api/procedures
     // Create a new procedure 
      CreateProcedure(c *gin.Context)
@@ -33,6 +37,10 @@ type ProcedureManagementAPI interface {
     // Get list of procedures 
      GetProcedures(c *gin.Context)
 
+    // ImportProcedures Post /api/procedures:import
+    // Import procedures from NDJSON or CSV 
+     ImportProcedures(c *gin.Context)
+
     // UpdateProcedure Put /api/procedures/:procedureId
     // Update procedure details 
      UpdateProcedure(c *gin.Context)
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type ReportsAPI interface {
+
+	// GetAgingReport Get /api/reports/aging
+	// Get outstanding balances by payer, department and age
+	GetAgingReport(c *gin.Context)
+
+	// GetRevenueReport Get /api/reports/revenue
+	// Get billed and collected amounts per period or group
+	GetRevenueReport(c *gin.Context)
+
+	// GetVolumeReport Get /api/reports/volume
+	// Get procedure counts per period or group
+	GetVolumeReport(c *gin.Context)
+}
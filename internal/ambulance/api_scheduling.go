@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type SchedulingAPI interface {
+
+	// BookAppointment Post /api/ambulances/:ambulanceId/appointments
+	// Book a procedure into a free slot
+	BookAppointment(c *gin.Context)
+
+	// GetAmbulanceSlots Get /api/ambulances/:ambulanceId/slots
+	// Get free appointment slots of an ambulance
+	GetAmbulanceSlots(c *gin.Context)
+
+	// RescheduleAppointment Post /api/procedures/:procedureId/reschedule
+	// Move a scheduled procedure to another time
+	RescheduleAppointment(c *gin.Context)
+}
@@ -0,0 +1,46 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+type ShiftManagementAPI interface {
+
+	// CreateShift Post /api/shifts
+	// Create a new shift
+	CreateShift(c *gin.Context)
+
+	// DeleteShift Delete /api/shifts/:shiftId
+	// Delete a shift
+	DeleteShift(c *gin.Context)
+
+	// GetCrewMemberCalendar Get /api/crew/:crewMemberId/calendar
+	// Export the shifts of a crew member as iCalendar
+	GetCrewMemberCalendar(c *gin.Context)
+
+	// GetRoster Get /api/roster
+	// Get the duty roster for a day
+	GetRoster(c *gin.Context)
+
+	// GetShiftById Get /api/shifts/:shiftId
+	// Get shift details
+	GetShiftById(c *gin.Context)
+
+	// GetShifts Get /api/shifts
+	// Get list of shifts
+	GetShifts(c *gin.Context)
+
+	// UpdateShift Put /api/shifts/:shiftId
+	// Update shift details
+	UpdateShift(c *gin.Context)
+}
@@ -14,6 +14,8 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -40,31 +42,39 @@ func withAmbulanceByID(c *gin.Context, fn func(c *gin.Context, ambulance *Ambula
 		return
 	}
 
-	db := getDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
 
-	ambulance, err := db.FindDocument(ctx, ambulanceId)
-	if err != nil {
-		if err == db_service.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"status": http.StatusNotFound, "message": "Ambulance not found"})
-		} else {
+		ambulance, err := db.FindDocument(ctx, ambulanceId)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"status": http.StatusNotFound, "message": "Ambulance not found"}, http.StatusNotFound
+			}
 			log.Println("FindDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": "Internal error"})
+			return gin.H{"status": http.StatusInternalServerError, "message": "Internal error"}, http.StatusInternalServerError
 		}
-		return
-	}
 
-	updatedAmbulance, result, statusCode := fn(c, ambulance)
-	if updatedAmbulance != nil {
-		err := db.UpdateDocument(ctx, ambulanceId, updatedAmbulance)
-		if err != nil {
-			log.Println("UpdateDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"status": http.StatusInternalServerError, "message": "Failed to update ambulance"})
-			return
+		updatedAmbulance, result, statusCode := fn(c, ambulance)
+		if updatedAmbulance != nil {
+			err := db.UpdateDocument(ctx, ambulanceId, updatedAmbulance)
+			if err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"status": http.StatusInternalServerError, "message": "Failed to update ambulance"}, http.StatusInternalServerError
+			}
 		}
+		return result, statusCode
+	})
+}
+
+// checkDispatch validates that the crew on duty allows the ambulance to be dispatched.
+func checkDispatch(ctx context.Context, c *gin.Context, ambulance *Ambulance, at time.Time) ([]string, error) {
+	crew, err := crewOnDuty(ctx, c, ambulance, at)
+	if err != nil {
+		return nil, err
 	}
-	c.JSON(statusCode, result)
+	return validateDispatchCrew(ctx, getCrewDB(c), crew, at)
 }
 
 func (o *implAmbulanceAPI) CreateAmbulance(c *gin.Context) {
@@ -76,11 +86,37 @@ func (o *implAmbulanceAPI) CreateAmbulance(c *gin.Context) {
 	if ambulance.Id == "" {
 		ambulance.Id = uuid.NewString()
 	}
+	if problems := validateWorkingHours(ambulance.WorkingHours); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invalid working hours", "errors": problems})
+		return
+	}
 
 	db := getDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
+	if problem, err := resolveAmbulanceDepartment(ctx, getDepartmentDB(c), &ambulance); err != nil {
+		log.Println("resolveAmbulanceDepartment error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create ambulance"})
+		return
+	} else if problem != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": problem})
+		return
+	}
+
+	if strings.EqualFold(ambulance.Status, AmbulanceStatusDispatched) {
+		problems, err := checkDispatch(ctx, c, &ambulance, time.Now())
+		if err != nil {
+			log.Println("validateDispatchCrew error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to validate crew"})
+			return
+		}
+		if len(problems) > 0 {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Ambulance cannot be dispatched", "errors": problems})
+			return
+		}
+	}
+
 	err := db.CreateDocument(ctx, ambulance.Id, &ambulance)
 	if err != nil {
 		if err == db_service.ErrConflict {
@@ -98,7 +134,7 @@ func (o *implAmbulanceAPI) CreateAmbulance(c *gin.Context) {
 func (o *implAmbulanceAPI) DeleteAmbulance(c *gin.Context) {
 	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
 		db := getDB(c)
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 		defer cancel()
 
 		err := db.DeleteDocument(ctx, ambulance.Id)
@@ -117,8 +153,13 @@ func (o *implAmbulanceAPI) GetAmbulanceById(c *gin.Context) {
 }
 
 func (o *implAmbulanceAPI) GetAmbulances(c *gin.Context) {
+	export, response, code := newListExport(c, "ambulances", ambulanceColumns)
+	if export == nil {
+		c.JSON(code, response)
+		return
+	}
 	db := getDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
 	list, err := db.ListDocuments(ctx)
@@ -127,7 +168,37 @@ func (o *implAmbulanceAPI) GetAmbulances(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to list ambulances"})
 		return
 	}
-	c.JSON(http.StatusOK, list)
+
+	// on_duty=true keeps only the ambulances with a shift in progress or a crew
+	// assignment, e.g. with status=Available to list the units that can be sent out
+	status := c.Query("status")
+	onDutyOnly := c.Query("on_duty") == "true"
+	if status == "" && !onDutyOnly {
+		export.write(c, list)
+		return
+	}
+
+	var onDuty map[string]bool
+	if onDutyOnly {
+		onDuty, err = onDutyAmbulances(ctx, getShiftDB(c), list, time.Now())
+		if err != nil {
+			log.Println("onDutyAmbulances error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to list ambulances"})
+			return
+		}
+	}
+
+	filtered := make([]Ambulance, 0, len(list))
+	for _, a := range list {
+		if status != "" && !strings.EqualFold(a.Status, status) {
+			continue
+		}
+		if onDutyOnly && !onDuty[a.Id] {
+			continue
+		}
+		filtered = append(filtered, a)
+	}
+	export.write(c, filtered)
 }
 
 func (o *implAmbulanceAPI) UpdateAmbulance(c *gin.Context) {
@@ -143,8 +214,14 @@ func (o *implAmbulanceAPI) UpdateAmbulance(c *gin.Context) {
 		if updated.Location != "" {
 			ambulance.Location = updated.Location
 		}
-		if updated.Department != "" {
+		departmentChanged := false
+		if updated.DepartmentId != "" && updated.DepartmentId != ambulance.DepartmentId {
+			ambulance.DepartmentId = updated.DepartmentId
+			departmentChanged = true
+		} else if updated.DepartmentId == "" && updated.Department != "" && updated.Department != ambulance.Department {
+			ambulance.DepartmentId = ""
 			ambulance.Department = updated.Department
+			departmentChanged = true
 		}
 		if updated.Capacity != 0 {
 			ambulance.Capacity = updated.Capacity
@@ -152,16 +229,146 @@ func (o *implAmbulanceAPI) UpdateAmbulance(c *gin.Context) {
 		if updated.Status != "" {
 			ambulance.Status = updated.Status
 		}
+		if updated.Odometer != 0 {
+			ambulance.Odometer = updated.Odometer
+		}
+		if updated.WorkingHours != nil {
+			if problems := validateWorkingHours(updated.WorkingHours); len(problems) > 0 {
+				return nil, gin.H{"message": "Invalid working hours", "errors": problems}, http.StatusUnprocessableEntity
+			}
+			ambulance.WorkingHours = updated.WorkingHours
+		}
 
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		// the crew is checked as PUT /api/ambulances/:ambulanceId/crew checks it
+		if updated.Crew != nil {
+			if result, status := checkCrewAssignment(ctx, c, ambulance.Id, updated.Crew); result != nil {
+				return nil, result, status
+			}
+			ambulance.Crew = updated.Crew
+		}
+
+		if departmentChanged {
+			problem, err := resolveAmbulanceDepartment(ctx, getDepartmentDB(c), ambulance)
+			if err != nil {
+				log.Println("resolveAmbulanceDepartment error:", err)
+				return nil, gin.H{"message": "Failed to update ambulance"}, http.StatusInternalServerError
+			}
+			if problem != "" {
+				return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+			}
+		}
+
+		if strings.EqualFold(ambulance.Status, AmbulanceStatusDispatched) {
+			problems, err := checkDispatch(ctx, c, ambulance, time.Now())
+			if err != nil {
+				log.Println("validateDispatchCrew error:", err)
+				return nil, gin.H{"message": "Failed to validate crew"}, http.StatusInternalServerError
+			}
+			if len(problems) > 0 {
+				return nil, gin.H{"message": "Ambulance cannot be dispatched", "errors": problems}, http.StatusUnprocessableEntity
+			}
+		}
+
+		return ambulance, ambulance, http.StatusOK
+	})
+}
+
+// checkCrewAssignment validates crew assignments of the ambulance: their roles, shift
+// times and crew members, and that no member is booked elsewhere at the same time. It
+// returns the response to give when the crew cannot be assigned, nil otherwise.
+func checkCrewAssignment(ctx context.Context, c *gin.Context, ambulanceId string, crew []CrewAssignment) (interface{}, int) {
+	crewDb := getCrewDB(c)
+	var problems []string
+	for i, a := range crew {
+		prefix := "crew[" + strconv.Itoa(i) + "]"
+		if !isCrewRole(a.Role) {
+			problems = append(problems, prefix+".role must be one of driver, paramedic, doctor, nurse")
+		}
+		if !a.ShiftEnd.After(a.ShiftStart) {
+			problems = append(problems, prefix+".shift_end must be after shift_start")
+		}
+		if _, err := crewDb.FindDocument(ctx, a.CrewMemberId); err != nil {
+			if err != db_service.ErrNotFound {
+				log.Println("FindDocument error:", err)
+				return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+			}
+			problems = append(problems, prefix+".crew_member_id does not reference an existing crew member")
+		}
+	}
+	if len(problems) > 0 {
+		return gin.H{"message": "Invalid crew assignment", "errors": problems}, http.StatusUnprocessableEntity
+	}
+
+	shifts, err := getShiftDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+	}
+	ambulances, err := getDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+	}
+	if conflicts := findAssignmentConflicts(ambulanceId, crew, shifts, ambulances); len(conflicts) > 0 {
+		return gin.H{"message": "Crew assignment conflicts with existing shifts", "conflicts": conflicts}, http.StatusConflict
+	}
+	return nil, 0
+}
+
+// AssignAmbulanceCrew replaces the crew assignments of an ambulance.
+// Every referenced crew member must exist and each shift must end after it starts.
+func (o *implAmbulanceAPI) AssignAmbulanceCrew(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		var crew []CrewAssignment
+		if err := c.ShouldBindJSON(&crew); err != nil {
+			return nil, gin.H{"message": "Invalid request body", "error": err.Error()}, http.StatusBadRequest
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if result, status := checkCrewAssignment(ctx, c, ambulance.Id, crew); result != nil {
+			return nil, result, status
+		}
+		ambulance.Crew = crew
 		return ambulance, ambulance, http.StatusOK
 	})
 }
 
+// GetAmbulanceSummary implements GET /api/ambulances/:ambulanceId/summary
+//
+// Procedure costs are totalled per catalog code so that differently typed names of the
+// same procedure are counted together.
 func (o *implAmbulanceAPI) GetAmbulanceSummary(c *gin.Context) {
 	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
-		summary := gin.H{
-			"ambulance_id": ambulance.Id,
-			"total_cost":   0.0, // Placeholder value
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		found, err := getProcedureDB(c).FindDocumentsByField(ctx, "ambulance_id", ambulance.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to compute summary"}, http.StatusInternalServerError
+		}
+		types, err := getProcedureTypeDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to compute summary"}, http.StatusInternalServerError
+		}
+
+		procedures := make([]Procedure, 0, len(found))
+		for _, p := range found {
+			procedures = append(procedures, *p)
+		}
+		byCode, err := summarizeByCode(procedures, types)
+		summary := GetAmbulanceSummary200Response{AmbulanceId: ambulance.Id, ByCode: byCode}
+		for i := 0; err == nil && i < len(byCode); i++ {
+			summary.TotalCost, err = summary.TotalCost.Add(byCode[i].Total)
+		}
+		if err != nil {
+			return nil, gin.H{"message": "Failed to compute summary", "error": err.Error()}, http.StatusConflict
 		}
 		return nil, summary, http.StatusOK
 	})
@@ -35,33 +35,35 @@ func withPaymentByID(
 		return
 	}
 
-	db := getPaymentDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getPaymentDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
 
-	p, err := db.FindDocument(ctx, id)
-	if err != nil {
-		if err == db_service.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Payment not found"})
-		} else {
+		p, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Payment not found"}, http.StatusNotFound
+			}
 			log.Println("FindDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
 		}
-		return
-	}
 
-	updated, result, status := fn(c, p)
-	if updated != nil {
-		if err := db.UpdateDocument(ctx, id, updated); err != nil {
-			log.Println("UpdateDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update payment"})
-			return
+		updated, result, status := fn(c, p)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update payment"}, http.StatusInternalServerError
+			}
 		}
-	}
-	c.JSON(status, result)
+		return result, status
+	})
 }
 
 // CreatePayment implements POST /api/payments
+//
+// A procedure may be paid by several partial payments; only settled ones count
+// towards its balance. Payments without a status are settled.
 func (o *implPaymentAPI) CreatePayment(c *gin.Context) {
 	var p Payment
 	if err := c.ShouldBindJSON(&p); err != nil {
@@ -71,22 +73,35 @@ func (o *implPaymentAPI) CreatePayment(c *gin.Context) {
 	if p.Id == "" {
 		p.Id = uuid.NewString()
 	}
+	if problem := startPaymentLifecycle(&p); problem != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": problem})
+		return
+	}
 
-	db := getPaymentDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// the procedure and payer the payment references are read from the snapshot it is written to
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getPaymentDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
 
-	if err := db.CreateDocument(ctx, p.Id, &p); err != nil {
-		switch err {
-		case db_service.ErrConflict:
-			c.JSON(http.StatusConflict, gin.H{"message": "Payment already exists"})
-		default:
-			log.Println("CreateDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create payment"})
+		if problem, err := validatePayment(ctx, c, &p); err != nil {
+			log.Println("validatePayment error:", err)
+			return gin.H{"message": "Failed to create payment"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return gin.H{"message": problem}, http.StatusUnprocessableEntity
 		}
-		return
-	}
-	c.JSON(http.StatusCreated, p)
+
+		if err := db.CreateDocument(ctx, p.Id, &p); err != nil {
+			switch err {
+			case db_service.ErrConflict:
+				return gin.H{"message": "Payment already exists"}, http.StatusConflict
+			default:
+				log.Println("CreateDocument error:", err)
+				return gin.H{"message": "Failed to create payment"}, http.StatusInternalServerError
+			}
+		}
+		return p, http.StatusCreated
+	})
 }
 
 // GetPaymentById implements GET /api/payments/:paymentId
@@ -97,8 +112,13 @@ func (o *implPaymentAPI) GetPaymentById(c *gin.Context) {
 }
 
 func (o *implPaymentAPI) GetPayments(c *gin.Context) {
+	export, response, code := newListExport(c, "payments", paymentColumns)
+	if export == nil {
+		c.JSON(code, response)
+		return
+	}
 	db := getPaymentDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
 	procedureID := c.Query("procedure_id")
@@ -118,7 +138,7 @@ func (o *implPaymentAPI) GetPayments(c *gin.Context) {
 			return
 		}
 
-		c.JSON(http.StatusOK, result)
+		export.write(c, result)
 		return
 	}
 
@@ -134,33 +154,61 @@ func (o *implPaymentAPI) GetPayments(c *gin.Context) {
 		result = append(result, *p)
 	}
 
-	c.JSON(http.StatusOK, result)
+	export.write(c, result)
 }
 
 // UpdatePayment implements PUT /api/payments/:paymentId
+//
+// A status change must follow the payment lifecycle: pending payments are authorized,
+// settled or fail, authorized ones are settled or fail and settled ones are refunded.
 func (o *implPaymentAPI) UpdatePayment(c *gin.Context) {
 	withPaymentByID(c, func(_ *gin.Context, existing *Payment) (*Payment, interface{}, int) {
+		if existing.RefundOf != "" {
+			return nil, gin.H{"message": "Refunds cannot be changed"}, http.StatusConflict
+		}
 		var upd Payment
 		if err := c.ShouldBindJSON(&upd); err != nil {
 			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
 		}
-		if upd.Insurance != "" {
+		if upd.PayerId != "" {
+			existing.PayerId = upd.PayerId
+		} else if upd.Insurance != "" && existing.PayerId == "" {
 			existing.Insurance = upd.Insurance
 		}
-		if upd.Amount != 0 {
+		if !upd.Amount.IsZero() {
 			existing.Amount = upd.Amount
 		}
+		if upd.Status != "" {
+			if result, status := transitionPayment(existing, upd.Status); result != nil {
+				return nil, result, status
+			}
+		}
 		existing.Timestamp = upd.Timestamp
 
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if problem, err := validatePayment(ctx, c, existing); err != nil {
+			log.Println("validatePayment error:", err)
+			return nil, gin.H{"message": "Failed to update payment"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+
 		return existing, existing, http.StatusOK
 	})
 }
 
 // DeletePayment implements DELETE /api/payments/:paymentId
+//
+// Only payments that never settled can be deleted.
 func (o *implPaymentAPI) DeletePayment(c *gin.Context) {
 	withPaymentByID(c, func(_ *gin.Context, p *Payment) (*Payment, interface{}, int) {
+		if result, status := checkPaymentDeletable(p); result != nil {
+			return nil, result, status
+		}
 		db := getPaymentDB(c)
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 		defer cancel()
 
 		if err := db.DeleteDocument(ctx, p.Id); err != nil {
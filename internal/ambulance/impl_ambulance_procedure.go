@@ -9,8 +9,15 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
 )
 
+// ProcedureEncryptedFields are the procedure details encrypted at rest.
+var ProcedureEncryptedFields = []db_service.EncryptedField{
+	{Path: "patient"},
+	{Path: "description"},
+}
+
 // implProcedureAPI implements the ProcedureManagementAPI interface.
 type implProcedureAPI struct{}
 
@@ -35,30 +42,50 @@ func withProcedureByID(
 		return
 	}
 
-	db := getProcedureDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getProcedureDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
 
-	proc, err := db.FindDocument(ctx, id)
-	if err != nil {
-		if err == db_service.ErrNotFound {
-			c.JSON(http.StatusNotFound, gin.H{"message": "Procedure not found"})
-		} else {
+		proc, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Procedure not found"}, http.StatusNotFound
+			}
 			log.Println("FindDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
 		}
-		return
-	}
 
-	updated, result, status := fn(c, proc)
-	if updated != nil {
-		if err := db.UpdateDocument(ctx, id, updated); err != nil {
-			log.Println("UpdateDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to update procedure"})
-			return
+		updated, result, status := fn(c, proc)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update procedure"}, http.StatusInternalServerError
+			}
 		}
+		return result, status
+	})
+}
+
+// prepareProcedure resolves the patient, the payer and the procedure type of a new
+// procedure and prices it. It returns a validation problem, if any. Attachments are dropped, as their
+// content can only be uploaded to an existing procedure.
+func prepareProcedure(ctx context.Context, c *gin.Context, p *Procedure) (string, error) {
+	p.Attachments = nil
+	if problem, err := resolveProcedurePatient(ctx, getPatientDB(c), p); err != nil || problem != "" {
+		return problem, err
+	}
+	if problem, err := resolveProcedurePayer(ctx, getPayerDB(c), p); err != nil || problem != "" {
+		return problem, err
 	}
-	c.JSON(status, result)
+	if p.Price.IsNegative() {
+		return "price must not be negative", nil
+	}
+	requested := p.Price
+	if problem, err := resolveProcedureType(ctx, getProcedureTypeDB(c), p); err != nil || problem != "" {
+		return problem, err
+	}
+	return priceProcedure(ctx, c, p, requested, p.PriceOverrideReason)
 }
 
 // CreateProcedure implements POST /api/procedures
@@ -72,44 +99,74 @@ func (o *implProcedureAPI) CreateProcedure(c *gin.Context) {
 		p.Id = uuid.NewString()
 	}
 
-	db := getProcedureDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// the lookups of the patient, payer and prices read the same snapshot the procedure is written to
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getProcedureDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
 
-	if err := db.CreateDocument(ctx, p.Id, &p); err != nil {
-		switch err {
-		case db_service.ErrConflict:
-			c.JSON(http.StatusConflict, gin.H{"message": "Procedure already exists"})
-		default:
-			log.Println("CreateDocument error:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create procedure"})
+		if problem, err := prepareProcedure(ctx, c, &p); err != nil {
+			log.Println("prepareProcedure error:", err)
+			return gin.H{"message": "Failed to create procedure"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return gin.H{"message": problem}, http.StatusUnprocessableEntity
 		}
-		return
-	}
-	c.JSON(http.StatusCreated, p)
+		if problem := startProcedureLifecycle(&p, userRole(c), time.Now()); problem != "" {
+			return gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+
+		if err := db.CreateDocument(ctx, p.Id, &p); err != nil {
+			switch err {
+			case db_service.ErrConflict:
+				return gin.H{"message": "Procedure already exists"}, http.StatusConflict
+			default:
+				log.Println("CreateDocument error:", err)
+				return gin.H{"message": "Failed to create procedure"}, http.StatusInternalServerError
+			}
+		}
+		return p, http.StatusCreated
+	})
 }
 
 // GetProcedureById implements GET /api/procedures/:procedureId
 func (o *implProcedureAPI) GetProcedureById(c *gin.Context) {
 	withProcedureByID(c, func(_ *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
-		return nil, p, http.StatusOK
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		procedures := []Procedure{*p}
+		if err := applyPaymentSummaries(ctx, c, procedures); err != nil {
+			log.Println("applyPaymentSummaries error:", err)
+			return nil, gin.H{"message": "Failed to compute the balance"}, http.StatusInternalServerError
+		}
+		return nil, procedures[0], http.StatusOK
 	})
 }
 
 func (o *implProcedureAPI) GetProcedures(c *gin.Context) {
+	export, response, code := newListExport(c, "procedures", procedureColumns)
+	if export == nil {
+		c.JSON(code, response)
+		return
+	}
 	db := getProcedureDB(c)
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 	defer cancel()
 
 	ambulanceID := c.Query("ambulance_id")
 
 	var (
-		procedures any
+		procedures []Procedure
 		err        error
 	)
 
 	if ambulanceID != "" {
-		procedures, err = db.FindDocumentsByField(ctx, "ambulance_id", ambulanceID)
+		var found []*Procedure
+		found, err = db.FindDocumentsByField(ctx, "ambulance_id", ambulanceID)
+		procedures = make([]Procedure, 0, len(found))
+		for _, p := range found {
+			procedures = append(procedures, *p)
+		}
 	} else {
 		procedures, err = db.ListDocuments(ctx)
 	}
@@ -120,47 +177,139 @@ func (o *implProcedureAPI) GetProcedures(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, procedures)
+	// ?status=scheduled,in_progress lists the procedures still to be done
+	procedures, problem := filterProceduresByStatus(procedures, c.Query("status"))
+	if problem != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": problem})
+		return
+	}
+
+	if err := applyPaymentSummaries(ctx, c, procedures); err != nil {
+		log.Println("applyPaymentSummaries error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve procedures"})
+		return
+	}
+	// ?payment_status=unpaid,partially_paid lists the procedures to collect
+	procedures, problem = filterProceduresByPaymentStatus(procedures, c.Query("payment_status"))
+	if problem != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": problem})
+		return
+	}
+	export.write(c, procedures)
 }
 
 // UpdateProcedure implements PUT /api/procedures/:procedureId
 func (o *implProcedureAPI) UpdateProcedure(c *gin.Context) {
 	withProcedureByID(c, func(_ *gin.Context, existing *Procedure) (*Procedure, interface{}, int) {
+		if result, status := checkProcedureEditable(c, existing); result != nil {
+			return nil, result, status
+		}
 		var upd Procedure
 		if err := c.ShouldBindJSON(&upd); err != nil {
 			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
 		}
-		if upd.Patient != "" {
+		if upd.Status != "" && upd.Status != procedureStatus(existing) {
+			return nil, gin.H{"message": "Change the status through /api/procedures/{procedureId}/status"}, http.StatusUnprocessableEntity
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		before := *existing
+		if upd.Code != "" && normalizeProcedureCode(upd.Code) != existing.Code {
+			// a different procedure type takes its name, description and defaults from the catalog
+			existing.Code = upd.Code
+			existing.Name, existing.Description, existing.Price, existing.Duration = "", "", money.Money{}, 0
+		}
+		if upd.PatientId != "" && upd.PatientId != existing.PatientId {
+			existing.PatientId = upd.PatientId
+			problem, err := resolveProcedurePatient(ctx, getPatientDB(c), existing)
+			if err != nil {
+				log.Println("resolveProcedurePatient error:", err)
+				return nil, gin.H{"message": "Failed to update procedure"}, http.StatusInternalServerError
+			}
+			if problem != "" {
+				return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+			}
+		} else if upd.Patient != "" && existing.PatientId == "" {
 			existing.Patient = upd.Patient
 		}
 		if upd.VisitType != "" {
 			existing.VisitType = upd.VisitType
 		}
-		if upd.Price != 0 {
+		if upd.Price.IsNegative() {
+			return nil, gin.H{"message": "price must not be negative"}, http.StatusUnprocessableEntity
+		}
+		if !upd.Price.IsZero() {
 			existing.Price = upd.Price
 		}
-		if upd.Payer != "" {
+		if upd.PayerId != "" && upd.PayerId != existing.PayerId {
+			existing.PayerId = upd.PayerId
+			problem, err := resolveProcedurePayer(ctx, getPayerDB(c), existing)
+			if err != nil {
+				log.Println("resolveProcedurePayer error:", err)
+				return nil, gin.H{"message": "Failed to update procedure"}, http.StatusInternalServerError
+			}
+			if problem != "" {
+				return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+			}
+		} else if upd.Payer != "" && existing.PayerId == "" {
 			existing.Payer = upd.Payer
 		}
 		if upd.AmbulanceId != "" {
 			existing.AmbulanceId = upd.AmbulanceId
 		}
+		if upd.Duration != 0 {
+			existing.Duration = upd.Duration
+		}
 		existing.Timestamp = upd.Timestamp
+
+		problem, err := resolveProcedureType(ctx, getProcedureTypeDB(c), existing)
+		if err != nil {
+			log.Println("resolveProcedureType error:", err)
+			return nil, gin.H{"message": "Failed to update procedure"}, http.StatusInternalServerError
+		}
+		if problem != "" {
+			return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+
+		// reprice when anything the price depends on changed or a price is requested
+		if existing.Code != before.Code || existing.Payer != before.Payer || existing.VisitType != before.VisitType ||
+			!existing.Timestamp.Equal(before.Timestamp) || !upd.Price.IsZero() || upd.PriceOverrideReason != "" {
+			problem, err := priceProcedure(ctx, c, existing, upd.Price, upd.PriceOverrideReason)
+			if err != nil {
+				log.Println("priceProcedure error:", err)
+				return nil, gin.H{"message": "Failed to update procedure"}, http.StatusInternalServerError
+			}
+			if problem != "" {
+				return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+			}
+		}
 		return existing, existing, http.StatusOK
 	})
 }
 
 // DeleteProcedure implements DELETE /api/procedures/:procedureId
+//
+// The attachments of the procedure are removed with it.
 func (o *implProcedureAPI) DeleteProcedure(c *gin.Context) {
 	withProcedureByID(c, func(_ *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if result, status := checkProcedureEditable(c, p); result != nil {
+			return nil, result, status
+		}
 		db := getProcedureDB(c)
-		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
 		defer cancel()
 
 		if err := db.DeleteDocument(ctx, p.Id); err != nil {
 			log.Println("DeleteDocument error:", err)
 			return nil, gin.H{"message": "Failed to delete procedure"}, http.StatusInternalServerError
 		}
+		// the content goes only once the deletion is committed
+		afterCommit(c, func() {
+			ctx, cancel := context.WithTimeout(outsideTransaction(c), 10*time.Second)
+			defer cancel()
+			removeAttachments(ctx, getBlobStore(c), p)
+		})
 		return nil, nil, http.StatusNoContent
 	})
 }
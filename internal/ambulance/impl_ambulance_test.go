@@ -2,11 +2,13 @@ package ambulance
 
 import (
 	"context"
+	"encoding/json"
 	"github.com/wac-project/wac-api/internal/db_service"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/mock"
@@ -23,6 +25,11 @@ func (m *DbServiceMock[DocType]) CreateDocument(ctx context.Context, id string,
 	return args.Error(0)
 }
 
+func (m *DbServiceMock[DocType]) CreateDocuments(ctx context.Context, ids []string, documents []*DocType) ([]error, error) {
+	args := m.Called(ctx, ids, documents)
+	return args.Get(0).([]error), args.Error(1)
+}
+
 func (m *DbServiceMock[DocType]) FindDocument(ctx context.Context, id string) (*DocType, error) {
 	args := m.Called(ctx, id)
 	return args.Get(0).(*DocType), args.Error(1)
@@ -38,6 +45,11 @@ func (m *DbServiceMock[DocType]) UpdateDocument(ctx context.Context, id string,
 	return args.Error(0)
 }
 
+func (m *DbServiceMock[DocType]) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *DocType) error {
+	args := m.Called(ctx, id, fields, document)
+	return args.Error(0)
+}
+
 func (m *DbServiceMock[DocType]) DeleteDocument(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
@@ -56,7 +68,8 @@ func (m *DbServiceMock[DocType]) FindDocumentsByField(ctx context.Context, field
 // AmbulanceSuite defines the suite for ambulance handler tests
 type AmbulanceSuite struct {
 	suite.Suite
-	dbServiceMock *DbServiceMock[Ambulance]
+	dbServiceMock           *DbServiceMock[Ambulance]
+	departmentDbServiceMock *DbServiceMock[Department]
 }
 
 func TestAmbulanceSuite(t *testing.T) {
@@ -65,6 +78,10 @@ func TestAmbulanceSuite(t *testing.T) {
 
 func (suite *AmbulanceSuite) SetupTest() {
 	suite.dbServiceMock = &DbServiceMock[Ambulance]{}
+	suite.departmentDbServiceMock = &DbServiceMock[Department]{}
+	suite.departmentDbServiceMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Department{{Id: "test-dept", Name: "TestDept"}}, nil)
 	// Ensure mock implements the DbService interface
 	var _ db_service.DbService[Ambulance] = (*DbServiceMock[Ambulance])(nil)
 	// Stub FindDocument to return a sample Ambulance
@@ -92,22 +109,41 @@ func (suite *AmbulanceSuite) Test_CreateAmbulance_CallsCreateDocument() {
 	gin.SetMode(gin.TestMode)
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
-	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Set("db_service_ambulance", suite.dbServiceMock)
+	ctx.Set("db_service_department", suite.departmentDbServiceMock)
 	ctx.Request = httptest.NewRequest("POST", "/api/ambulances", strings.NewReader(payload))
 	ctx.Request.Header.Set("Content-Type", "application/json")
 
 	sut := implAmbulanceAPI{}
 	sut.CreateAmbulance(ctx)
 
-	suite.dbServiceMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+	suite.dbServiceMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything,
+		mock.MatchedBy(func(a *Ambulance) bool { return a.DepartmentId == "test-dept" }))
 	suite.Equal(http.StatusCreated, recorder.Code)
 }
 
+func (suite *AmbulanceSuite) Test_CreateAmbulance_UnknownDepartment_ReturnsUnprocessable() {
+	payload := `{"name":"TestName","location":"TestLoc","department":"Unknown","capacity":5,"status":"active"}`
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_ambulance", suite.dbServiceMock)
+	ctx.Set("db_service_department", suite.departmentDbServiceMock)
+	ctx.Request = httptest.NewRequest("POST", "/api/ambulances", strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	sut := implAmbulanceAPI{}
+	sut.CreateAmbulance(ctx)
+
+	suite.dbServiceMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+}
+
 func (suite *AmbulanceSuite) Test_GetAmbulanceById_ReturnsOK() {
 	gin.SetMode(gin.TestMode)
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
-	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Set("db_service_ambulance", suite.dbServiceMock)
 	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
 	ctx.Request = httptest.NewRequest("GET", "/api/ambulances/test-ambulance", nil)
 
@@ -117,6 +153,47 @@ func (suite *AmbulanceSuite) Test_GetAmbulanceById_ReturnsOK() {
 	suite.Equal(http.StatusOK, recorder.Code)
 }
 
+func (suite *AmbulanceSuite) Test_GetAmbulances_OnDutyListsOnlyUnitsOnDuty() {
+	now := time.Now()
+	suite.dbServiceMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Ambulance{
+			{Id: "on-shift", Status: "Available"},
+			{Id: "assigned", Status: "Available", Crew: []CrewAssignment{{CrewMemberId: "crew001", Role: CrewRoleDriver, ShiftStart: now.Add(-time.Hour), ShiftEnd: now.Add(time.Hour)}}},
+			{Id: "off-duty", Status: "Available"},
+			{Id: "busy", Status: "Occupied"},
+		}, nil)
+	shiftDbMock := &DbServiceMock[Shift]{}
+	shiftDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Shift{{Id: "shift", AmbulanceId: "on-shift", Start: now.Add(-time.Hour), End: now.Add(time.Hour)}}, nil)
+
+	list := func(query string) []string {
+		gin.SetMode(gin.TestMode)
+		recorder := httptest.NewRecorder()
+		ctx, _ := gin.CreateTestContext(recorder)
+		ctx.Set("db_service_ambulance", suite.dbServiceMock)
+		ctx.Set("db_service_shift", shiftDbMock)
+		ctx.Request = httptest.NewRequest("GET", "/api/ambulances?"+query, nil)
+
+		(&implAmbulanceAPI{}).GetAmbulances(ctx)
+
+		suite.Equal(http.StatusOK, recorder.Code)
+		var ambulances []Ambulance
+		suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &ambulances))
+		ids := []string{}
+		for _, a := range ambulances {
+			ids = append(ids, a.Id)
+		}
+		return ids
+	}
+
+	suite.Equal([]string{"on-shift", "assigned", "off-duty"}, list("status=Available"))
+	suite.Equal([]string{"on-shift", "assigned"}, list("status=Available&on_duty=true"))
+	suite.Equal([]string{"on-shift", "assigned", "off-duty"}, list("status=Available&on_duty=false"))
+	suite.Equal([]string{"on-shift", "assigned"}, list("on_duty=true"))
+}
+
 func (suite *AmbulanceSuite) Test_DeleteAmbulance_CallsDeleteDocument() {
 	suite.dbServiceMock.
 		On("DeleteDocument", mock.Anything, "test-ambulance").
@@ -125,7 +202,7 @@ func (suite *AmbulanceSuite) Test_DeleteAmbulance_CallsDeleteDocument() {
 	gin.SetMode(gin.TestMode)
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
-	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Set("db_service_ambulance", suite.dbServiceMock)
 	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
 	ctx.Request = httptest.NewRequest("DELETE", "/api/ambulances/test-ambulance", nil)
 
@@ -137,10 +214,25 @@ func (suite *AmbulanceSuite) Test_DeleteAmbulance_CallsDeleteDocument() {
 }
 
 func (suite *AmbulanceSuite) Test_GetAmbulanceSummary_ReturnsSummary() {
+	procedureDbMock := &DbServiceMock[Procedure]{}
+	procedureDbMock.
+		On("FindDocumentsByField", mock.Anything, "ambulance_id", "test-ambulance").
+		Return([]*Procedure{
+			{Id: "p1", Code: "RTG-CHEST", Name: "Röntgen hrudníka", Price: eur("100")},
+			{Id: "p2", Code: "RTG-CHEST", Name: "RTG hrudnika", Price: eur("120")},
+			{Id: "p3", Name: "Konzultácia", Price: eur("30")},
+		}, nil)
+	procedureTypeDbMock := &DbServiceMock[ProcedureType]{}
+	procedureTypeDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]ProcedureType{{Id: "RTG-CHEST", Code: "RTG-CHEST", Name: "Röntgen hrudníka"}}, nil)
+
 	gin.SetMode(gin.TestMode)
 	recorder := httptest.NewRecorder()
 	ctx, _ := gin.CreateTestContext(recorder)
-	ctx.Set("db_service", suite.dbServiceMock)
+	ctx.Set("db_service_ambulance", suite.dbServiceMock)
+	ctx.Set("db_service_procedure", procedureDbMock)
+	ctx.Set("db_service_procedure_type", procedureTypeDbMock)
 	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
 	ctx.Request = httptest.NewRequest("GET", "/api/ambulances/test-ambulance/summary", nil)
 
@@ -148,4 +240,12 @@ func (suite *AmbulanceSuite) Test_GetAmbulanceSummary_ReturnsSummary() {
 	sut.GetAmbulanceSummary(ctx)
 
 	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`{
+		"ambulance_id": "test-ambulance",
+		"totalCost": {"amount": "250.00", "currency": "EUR"},
+		"by_code": [
+			{"code": "", "name": "Uncatalogued", "count": 1, "total": {"amount": "30.00", "currency": "EUR"}},
+			{"code": "RTG-CHEST", "name": "Röntgen hrudníka", "count": 2, "total": {"amount": "220.00", "currency": "EUR"}}
+		]
+	}`, recorder.Body.String())
 }
@@ -0,0 +1,582 @@
+package ambulance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// Reconciliation statuses of bank statement lines.
+const (
+	BankLineMatched   = "matched"
+	BankLineAmbiguous = "ambiguous"
+	BankLineUnmatched = "unmatched"
+	BankLineConfirmed = "confirmed"
+	BankLineDismissed = "dismissed"
+	BankLineDuplicate = "duplicate"
+)
+
+// MaxBankStatementSize is the largest bank statement accepted for import, in bytes.
+const MaxBankStatementSize = 10 << 20
+
+// implBankReconciliationAPI implements the BankReconciliationAPI interface.
+type implBankReconciliationAPI struct{}
+
+// NewBankReconciliationAPI returns an implementation of BankReconciliationAPI.
+func NewBankReconciliationAPI() BankReconciliationAPI {
+	return &implBankReconciliationAPI{}
+}
+
+// getBankStatementDB extracts the DbService[BankStatement] from the context.
+func getBankStatementDB(c *gin.Context) db_service.DbService[BankStatement] {
+	return c.MustGet("db_service_bank_statement").(db_service.DbService[BankStatement])
+}
+
+// withBankStatementByID loads a BankStatement and calls fn; fn may return an updated doc.
+func withBankStatementByID(
+	c *gin.Context,
+	fn func(*gin.Context, *BankStatement) (*BankStatement, interface{}, int),
+) {
+	id := c.Param("statementId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "statementId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getBankStatementDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		statement, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Bank statement not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, statement)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update bank statement"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// statementLine returns the line of the statement with the given number, or nil.
+func statementLine(statement *BankStatement, number string) *BankStatementLine {
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return nil
+	}
+	for i := range statement.Lines {
+		if int(statement.Lines[i].Line) == n {
+			return &statement.Lines[i]
+		}
+	}
+	return nil
+}
+
+// reconciliationItem is an open procedure or invoice a bank line may pay, with the
+// references and payer names a payment for it may carry.
+type reconciliationItem struct {
+	candidate  ReconciliationCandidate
+	references []string
+	names      []string
+}
+
+// loadReconciliationItems returns the issued invoices and the procedures on no invoice
+// that still have a balance to pay.
+func loadReconciliationItems(ctx context.Context, c *gin.Context) ([]reconciliationItem, error) {
+	payers, err := getPayerDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payerNames := map[string]string{}
+	for _, payer := range payers {
+		payerNames[payer.Id] = payer.Name
+	}
+
+	invoices, err := getInvoiceDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]reconciliationItem, 0)
+	invoiced := map[string]bool{}
+	for _, invoice := range invoices {
+		if invoice.Status == InvoiceStatusCancelled {
+			continue
+		}
+		for _, line := range invoice.Lines {
+			invoiced[line.ProcedureId] = true
+		}
+		if (invoice.Status == InvoiceStatusIssued || invoice.Status == InvoiceStatusPartiallyPaid) && !invoice.Balance.IsZero() {
+			items = append(items, reconciliationItem{
+				candidate:  ReconciliationCandidate{InvoiceId: invoice.Id, PayerId: invoice.PayerId, Balance: invoice.Balance},
+				references: []string{invoice.Number},
+				names:      []string{invoice.BillTo},
+			})
+		}
+	}
+
+	procedures, err := getProcedureDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPaymentSummaries(ctx, c, procedures); err != nil {
+		return nil, err
+	}
+	for _, p := range procedures {
+		if invoiced[p.Id] || procedureStatus(&p) == ProcedureStatusCancelled || p.Balance == nil ||
+			p.Balance.IsZero() || p.Balance.IsNegative() {
+			continue
+		}
+		name := p.Payer
+		if p.PayerId != "" {
+			name = payerNames[p.PayerId]
+		}
+		items = append(items, reconciliationItem{
+			candidate:  ReconciliationCandidate{ProcedureId: p.Id, PayerId: p.PayerId, Balance: *p.Balance},
+			references: []string{p.Id},
+			names:      []string{name},
+		})
+	}
+	return items, nil
+}
+
+// normalizeReference keeps the letters and digits of a payment reference in upper case,
+// so that "2025-000042" and "2025 000042" compare equal.
+func normalizeReference(reference string) string {
+	var b strings.Builder
+	for _, r := range reference {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+// matchBankLine proposes the open items an incoming bank line pays. An item matches by
+// reference when the line's reference or description contains the invoice number or the
+// procedure identifier, or by amount and payer when the line pays exactly the balance
+// and comes from the payer. Reference matches win; a single proposal makes the line
+// matched, several ambiguous, none unmatched. Outgoing lines are dismissed.
+func matchBankLine(line *BankStatementLine, items []reconciliationItem) {
+	line.Candidates = nil
+	if line.Amount.IsNegative() || line.Amount.IsZero() {
+		line.Status, line.Note = BankLineDismissed, "Not an incoming payment"
+		return
+	}
+
+	reference := normalizeReference(line.Reference)
+	description := normalizeReference(line.Description)
+	counterparty := normalizeName(line.Counterparty)
+	var byReference, byAmount []ReconciliationCandidate
+	for _, item := range items {
+		if item.candidate.Balance.Currency != line.Amount.Currency {
+			continue
+		}
+		candidate := item.candidate
+		for _, ref := range item.references {
+			if ref = normalizeReference(ref); len(ref) >= 4 && (strings.Contains(reference, ref) || strings.Contains(description, ref)) {
+				candidate.Reasons = append(candidate.Reasons, "reference")
+				break
+			}
+		}
+		if line.Amount.Equal(candidate.Balance) {
+			candidate.Reasons = append(candidate.Reasons, "amount")
+		}
+		for _, name := range item.names {
+			if name = normalizeName(name); name != "" && counterparty != "" &&
+				(strings.Contains(counterparty, name) || strings.Contains(name, counterparty)) {
+				candidate.Reasons = append(candidate.Reasons, "payer")
+				break
+			}
+		}
+
+		switch {
+		case len(candidate.Reasons) > 0 && candidate.Reasons[0] == "reference":
+			byReference = append(byReference, candidate)
+		case len(candidate.Reasons) == 2:
+			byAmount = append(byAmount, candidate)
+		}
+	}
+
+	line.Candidates = byReference
+	if len(byReference) == 0 {
+		line.Candidates = byAmount
+	} else if len(byReference) > 1 {
+		// several references in the text; the one paid in full is the likely one
+		var exact []ReconciliationCandidate
+		for _, candidate := range byReference {
+			if line.Amount.Equal(candidate.Balance) {
+				exact = append(exact, candidate)
+			}
+		}
+		if len(exact) > 0 {
+			line.Candidates = exact
+		}
+	}
+	switch len(line.Candidates) {
+	case 0:
+		line.Status, line.Note = BankLineUnmatched, "No open procedure or invoice matches the reference, or the amount and payer"
+	case 1:
+		line.Status, line.Note = BankLineMatched, ""
+	default:
+		line.Status, line.Note = BankLineAmbiguous, "Several open procedures or invoices match"
+	}
+}
+
+// bookBankLine confirms a bank line as payment of a procedure or an invoice: a settled
+// payment is created for the line's amount and, for an invoice, allocated against it as
+// far as the balance allows. Without a target the line's single candidate is booked. It
+// returns a problem and its status when the line cannot be booked.
+func bookBankLine(ctx context.Context, c *gin.Context, line *BankStatementLine, target BankLineConfirmation, now time.Time) (string, int, error) {
+	switch line.Status {
+	case BankLineMatched, BankLineAmbiguous, BankLineUnmatched:
+	default:
+		return "The line is " + line.Status, http.StatusConflict, nil
+	}
+	if target.ProcedureId == "" && target.InvoiceId == "" {
+		if len(line.Candidates) != 1 {
+			return "procedure_id or invoice_id is required unless the line has a single candidate", http.StatusUnprocessableEntity, nil
+		}
+		target.ProcedureId, target.InvoiceId = line.Candidates[0].ProcedureId, line.Candidates[0].InvoiceId
+	}
+	if target.ProcedureId != "" && target.InvoiceId != "" {
+		return "give either procedure_id or invoice_id", http.StatusUnprocessableEntity, nil
+	}
+
+	payment := Payment{
+		Id:          uuid.NewString(),
+		Name:        "Bank transfer",
+		Description: strings.TrimSpace(line.Reference + " " + line.Description),
+		Amount:      line.Amount,
+		Status:      PaymentStatusSettled,
+		Timestamp:   now,
+	}
+	if booked, err := time.Parse(time.DateOnly, line.BookingDate); err == nil {
+		payment.Timestamp = booked
+	}
+
+	var invoice *Invoice
+	if target.InvoiceId != "" {
+		var err error
+		invoice, err = getInvoiceDB(c).FindDocument(ctx, target.InvoiceId)
+		if err == db_service.ErrNotFound {
+			return "invoice_id does not reference an existing invoice", http.StatusUnprocessableEntity, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if invoice.Status != InvoiceStatusIssued && invoice.Status != InvoiceStatusPartiallyPaid {
+			return "Payments can only be allocated against issued invoices; the invoice is " + invoice.Status, http.StatusConflict, nil
+		}
+		payment.PayerId = invoice.PayerId
+		if len(invoice.Lines) == 1 {
+			payment.ProcedureId = invoice.Lines[0].ProcedureId
+		}
+		if _, err := invoice.Balance.Sub(line.Amount); err != nil {
+			return "the line must be in the currency of the invoice, " + invoice.Total.Currency, http.StatusUnprocessableEntity, nil
+		}
+		if result, status := allocatePayment(invoice, payment.Id, line.Amount, money.Money{}, now); result != nil {
+			return "the line cannot be allocated against invoice " + invoice.Id, status, nil
+		}
+	} else {
+		procedure, err := getProcedureDB(c).FindDocument(ctx, target.ProcedureId)
+		if err == db_service.ErrNotFound {
+			return "procedure_id does not reference an existing procedure", http.StatusUnprocessableEntity, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if procedureStatus(procedure) == ProcedureStatusCancelled {
+			return "procedure " + procedure.Id + " is cancelled", http.StatusConflict, nil
+		}
+		payment.ProcedureId, payment.PayerId = procedure.Id, procedure.PayerId
+	}
+	if payment.PayerId == "" {
+		payment.Insurance = line.Counterparty
+	}
+	if problem, err := validatePayment(ctx, c, &payment); err != nil || problem != "" {
+		return problem, http.StatusUnprocessableEntity, err
+	}
+
+	if err := getPaymentDB(c).CreateDocument(ctx, payment.Id, &payment); err != nil {
+		return "", 0, err
+	}
+	if invoice != nil {
+		if err := getInvoiceDB(c).UpdateDocument(ctx, invoice.Id, invoice); err != nil {
+			return "", 0, err
+		}
+	}
+	line.Status, line.Note = BankLineConfirmed, ""
+	line.ProcedureId, line.InvoiceId, line.PaymentId = target.ProcedureId, target.InvoiceId, payment.Id
+	line.ResolvedAt = &now
+	return "", 0, nil
+}
+
+// ImportBankStatement implements POST /api/bank-statements
+//
+// The body is a CSV statement as read by parseBankStatementCSV or a camt.053 XML
+// statement. Every incoming line is matched against the open procedures and invoices.
+// Importing the same file again returns the statement imported first; entries already
+// imported with another statement are marked duplicate.
+func (o *implBankReconciliationAPI) ImportBankStatement(c *gin.Context) {
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxBankStatementSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "The statement exceeds the size limit"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		}
+		return
+	}
+	checksum := sha256.Sum256(body)
+	statement := BankStatement{
+		Id:         hex.EncodeToString(checksum[:]),
+		Format:     detectBankStatementFormat(c.ContentType(), body),
+		ImportedAt: time.Now(),
+	}
+
+	db := getBankStatementDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	if existing, err := db.FindDocument(ctx, statement.Id); err == nil {
+		c.JSON(http.StatusOK, existing)
+		return
+	} else if err != db_service.ErrNotFound {
+		log.Println("FindDocument error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import bank statement"})
+		return
+	}
+
+	var problems []ImportError
+	if statement.Format == BankStatementCamt053 {
+		statement.Account, statement.Reference, statement.Lines, problems = parseBankStatementCamt053(bytes.NewReader(body))
+	} else {
+		statement.Lines, problems = parseBankStatementCSV(bytes.NewReader(body))
+	}
+	if len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invalid bank statement", "errors": problems})
+		return
+	}
+
+	statements, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import bank statement"})
+		return
+	}
+	imported := map[string]string{}
+	for _, other := range statements {
+		for _, line := range other.Lines {
+			if line.BankReference != "" {
+				imported[line.BankReference] = other.Id
+			}
+		}
+	}
+	items, err := loadReconciliationItems(ctx, c)
+	if err != nil {
+		log.Println("loadReconciliationItems error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import bank statement"})
+		return
+	}
+	for i := range statement.Lines {
+		line := &statement.Lines[i]
+		line.StatementId = statement.Id
+		if other, ok := imported[line.BankReference]; ok {
+			line.Status, line.Note = BankLineDuplicate, "Already imported with statement "+other
+			continue
+		}
+		matchBankLine(line, items)
+	}
+
+	if err := db.CreateDocument(ctx, statement.Id, &statement); err != nil {
+		if err == db_service.ErrConflict {
+			// the same file was imported concurrently
+			if existing, err := db.FindDocument(ctx, statement.Id); err == nil {
+				c.JSON(http.StatusOK, existing)
+				return
+			}
+		}
+		log.Println("CreateDocument error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import bank statement"})
+		return
+	}
+	c.JSON(http.StatusCreated, statement)
+}
+
+// GetBankStatements implements GET /api/bank-statements
+//
+// Statements are returned newest import first.
+func (o *implBankReconciliationAPI) GetBankStatements(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	statements, err := getBankStatementDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving bank statements:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve bank statements"})
+		return
+	}
+	sort.Slice(statements, func(i, j int) bool {
+		return statements[i].ImportedAt.After(statements[j].ImportedAt)
+	})
+	c.JSON(http.StatusOK, statements)
+}
+
+// GetBankStatementById implements GET /api/bank-statements/:statementId
+func (o *implBankReconciliationAPI) GetBankStatementById(c *gin.Context) {
+	withBankStatementByID(c, func(c *gin.Context, statement *BankStatement) (*BankStatement, interface{}, int) {
+		return nil, statement, http.StatusOK
+	})
+}
+
+// GetReconciliationQueue implements GET /api/reconciliation/queue
+//
+// The queue holds the lines of all statements in one of the comma-separated ?status=,
+// by default the unmatched and ambiguous ones, oldest booking first.
+func (o *implBankReconciliationAPI) GetReconciliationQueue(c *gin.Context) {
+	statuses := map[string]bool{}
+	for _, status := range strings.Split(c.DefaultQuery("status", BankLineUnmatched+","+BankLineAmbiguous), ",") {
+		switch status = strings.ToLower(strings.TrimSpace(status)); status {
+		case BankLineMatched, BankLineAmbiguous, BankLineUnmatched, BankLineConfirmed, BankLineDismissed, BankLineDuplicate:
+			statuses[status] = true
+		default:
+			c.JSON(http.StatusBadRequest, gin.H{"message": "unknown status " + status})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	statements, err := getBankStatementDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving bank statements:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve reconciliation queue"})
+		return
+	}
+	queue := make([]BankStatementLine, 0)
+	for _, statement := range statements {
+		for _, line := range statement.Lines {
+			if statuses[line.Status] {
+				queue = append(queue, line)
+			}
+		}
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		if queue[i].BookingDate != queue[j].BookingDate {
+			return queue[i].BookingDate < queue[j].BookingDate
+		}
+		if queue[i].StatementId != queue[j].StatementId {
+			return queue[i].StatementId < queue[j].StatementId
+		}
+		return queue[i].Line < queue[j].Line
+	})
+	c.JSON(http.StatusOK, queue)
+}
+
+// ConfirmBankStatementLine implements POST /api/bank-statements/:statementId/lines/:lineNumber/confirmation
+//
+// The body names the procedure or invoice the line pays; without one the line's single
+// candidate is booked.
+func (o *implBankReconciliationAPI) ConfirmBankStatementLine(c *gin.Context) {
+	withBankStatementByID(c, func(c *gin.Context, statement *BankStatement) (*BankStatement, interface{}, int) {
+		line := statementLine(statement, c.Param("lineNumber"))
+		if line == nil {
+			return nil, gin.H{"message": "Line not found"}, http.StatusNotFound
+		}
+		var target BankLineConfirmation
+		if err := c.ShouldBindJSON(&target); err != nil && err != io.EOF {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if problem, status, err := bookBankLine(ctx, c, line, target, time.Now()); err != nil {
+			log.Println("bookBankLine error:", err)
+			return nil, gin.H{"message": "Failed to confirm bank statement line"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, status
+		}
+		return statement, line, http.StatusOK
+	})
+}
+
+// ConfirmBankStatement implements POST /api/bank-statements/:statementId/confirmation
+//
+// Every matched line is booked to its candidate; lines that cannot be booked stay
+// matched with the reason in their note.
+func (o *implBankReconciliationAPI) ConfirmBankStatement(c *gin.Context) {
+	withBankStatementByID(c, func(c *gin.Context, statement *BankStatement) (*BankStatement, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		for i := range statement.Lines {
+			line := &statement.Lines[i]
+			if line.Status != BankLineMatched {
+				continue
+			}
+			problem, _, err := bookBankLine(ctx, c, line, BankLineConfirmation{}, now)
+			if err != nil {
+				// keep the lines booked so far
+				log.Println("bookBankLine error:", err)
+				return statement, gin.H{"message": "Failed to confirm bank statement"}, http.StatusInternalServerError
+			}
+			line.Note = problem
+		}
+		return statement, statement, http.StatusOK
+	})
+}
+
+// DismissBankStatementLine implements POST /api/bank-statements/:statementId/lines/:lineNumber/dismissal
+//
+// Dismissed lines, such as bank fees or transfers between own accounts, leave the queue
+// without creating a payment.
+func (o *implBankReconciliationAPI) DismissBankStatementLine(c *gin.Context) {
+	withBankStatementByID(c, func(c *gin.Context, statement *BankStatement) (*BankStatement, interface{}, int) {
+		line := statementLine(statement, c.Param("lineNumber"))
+		if line == nil {
+			return nil, gin.H{"message": "Line not found"}, http.StatusNotFound
+		}
+		var request BankLineDismissal
+		if err := c.ShouldBindJSON(&request); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			return nil, gin.H{"message": "reason is required"}, http.StatusUnprocessableEntity
+		}
+		if line.Status == BankLineConfirmed {
+			return nil, gin.H{"message": "The line is booked as payment " + line.PaymentId}, http.StatusConflict
+		}
+		now := time.Now()
+		line.Status, line.Note, line.ResolvedAt = BankLineDismissed, strings.TrimSpace(request.Reason), &now
+		return statement, line, http.StatusOK
+	})
+}
@@ -0,0 +1,244 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+const camt053Statement = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.02">
+  <BkToCstmrStmt>
+    <Stmt>
+      <Id>STMT-2025-06-02</Id>
+      <Acct><Id><IBAN>SK3112000000198742637541</IBAN></Id></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">132.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-06-02</Dt></BookgDt>
+        <AcctSvcrRef>BANK-0001</AcctSvcrRef>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs>
+          <RltdPties><Dbtr><Nm>POISTOVNA XYZ A.S.</Nm></Dbtr></RltdPties>
+          <RmtInf><Strd><CdtrRefInf><Ref>2025-000042</Ref></CdtrRefInf></Strd></RmtInf>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">4.50</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <Sts>BOOK</Sts>
+        <BookgDt><Dt>2025-06-02</Dt></BookgDt>
+        <AddtlNtryInf>Account fee</AddtlNtryInf>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">10.00</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <Sts>PDNG</Sts>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>`
+
+// BankReconciliationSuite defines the suite for bank statement import and reconciliation tests
+type BankReconciliationSuite struct {
+	suite.Suite
+	statementDbMock *DbServiceMock[BankStatement]
+	invoiceDbMock   *DbServiceMock[Invoice]
+	paymentDbMock   *DbServiceMock[Payment]
+	procedureDbMock *DbServiceMock[Procedure]
+	statement       BankStatement
+	invoice         Invoice
+	items           []reconciliationItem
+}
+
+func TestBankReconciliationSuite(t *testing.T) {
+	suite.Run(t, new(BankReconciliationSuite))
+}
+
+func (suite *BankReconciliationSuite) SetupTest() {
+	suite.items = []reconciliationItem{
+		{
+			candidate:  ReconciliationCandidate{InvoiceId: "inv1", PayerId: "payer1", Balance: eur("132")},
+			references: []string{"2025-000042"},
+			names:      []string{"Poisťovňa XYZ"},
+		},
+		{
+			candidate:  ReconciliationCandidate{ProcedureId: "proc002", Balance: eur("60")},
+			references: []string{"proc002"},
+			names:      []string{"Ján Novák"},
+		},
+		{
+			candidate:  ReconciliationCandidate{ProcedureId: "proc003", Balance: eur("60")},
+			references: []string{"proc003"},
+			names:      []string{"Ján Novák"},
+		},
+	}
+	suite.invoice = Invoice{
+		Id:      "inv1",
+		Number:  "2025-000042",
+		Status:  InvoiceStatusIssued,
+		Lines:   []InvoiceLine{{ProcedureId: "proc001"}},
+		Total:   eur("132"),
+		Paid:    eur("0"),
+		Balance: eur("132"),
+	}
+	suite.statement = BankStatement{
+		Id:     "stmt1",
+		Format: BankStatementCSV,
+		Lines: []BankStatementLine{{
+			StatementId: "stmt1",
+			Line:        1,
+			BookingDate: "2025-06-02",
+			Amount:      eur("200"),
+			Reference:   "2025-000042",
+			Status:      BankLineMatched,
+			Candidates:  []ReconciliationCandidate{{InvoiceId: "inv1", Balance: eur("132"), Reasons: []string{"reference"}}},
+		}},
+	}
+
+	suite.statementDbMock = &DbServiceMock[BankStatement]{}
+	suite.statementDbMock.
+		On("FindDocument", mock.Anything, "stmt1").
+		Return(&suite.statement, nil)
+	suite.statementDbMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return((*BankStatement)(nil), db_service.ErrNotFound)
+	suite.statementDbMock.
+		On("UpdateDocument", mock.Anything, "stmt1", mock.Anything).
+		Return(nil)
+
+	suite.invoiceDbMock = &DbServiceMock[Invoice]{}
+	suite.invoiceDbMock.
+		On("FindDocument", mock.Anything, "inv1").
+		Return(&suite.invoice, nil)
+	suite.invoiceDbMock.
+		On("UpdateDocument", mock.Anything, "inv1", mock.Anything).
+		Return(nil)
+
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&Procedure{Id: "proc001", Price: eur("120")}, nil)
+}
+
+func (suite *BankReconciliationSuite) request(method, path, payload string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_bank_statement", suite.statementDbMock)
+	ctx.Set("db_service_invoice", suite.invoiceDbMock)
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Params = []gin.Param{{Key: "statementId", Value: "stmt1"}, {Key: "lineNumber", Value: "1"}}
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	return ctx, recorder
+}
+
+func (suite *BankReconciliationSuite) Test_ParseBankStatementCSV_ReadsDecimalCommaAndDates() {
+	lines, problems := parseBankStatementCSV(strings.NewReader(
+		"booking_date,amount,currency,reference,counterparty\n" +
+			"02.06.2025,\"1 234,50\",EUR,2025-000042,Poisťovňa XYZ\n" +
+			"2025-06-03,abc,EUR,,\n"))
+
+	suite.Equal([]ImportError{{Line: 3, Message: "amount must be a number"}}, problems)
+	suite.Require().Len(lines, 2)
+	suite.Equal("2025-06-02", lines[0].BookingDate)
+	suite.Equal(eur("1234.50"), lines[0].Amount)
+	suite.Equal("2025-000042", lines[0].Reference)
+}
+
+func (suite *BankReconciliationSuite) Test_ParseBankStatementCamt053_ReadsBookedEntries() {
+	account, reference, lines, problems := parseBankStatementCamt053(strings.NewReader(camt053Statement))
+
+	suite.Empty(problems)
+	suite.Equal("SK3112000000198742637541", account)
+	suite.Equal("STMT-2025-06-02", reference)
+	suite.Require().Len(lines, 2)
+	suite.Equal(eur("132"), lines[0].Amount)
+	suite.Equal("2025-000042", lines[0].Reference)
+	suite.Equal("POISTOVNA XYZ A.S.", lines[0].Counterparty)
+	suite.Equal("BANK-0001", lines[0].BankReference)
+	suite.Equal(eur("-4.50"), lines[1].Amount)
+}
+
+func (suite *BankReconciliationSuite) Test_MatchBankLine_ByReference() {
+	line := BankStatementLine{Amount: eur("100"), Reference: "2025 000042"}
+
+	matchBankLine(&line, suite.items)
+
+	suite.Equal(BankLineMatched, line.Status)
+	suite.Require().Len(line.Candidates, 1)
+	suite.Equal("inv1", line.Candidates[0].InvoiceId)
+}
+
+func (suite *BankReconciliationSuite) Test_MatchBankLine_ByAmountAndPayer() {
+	line := BankStatementLine{Amount: eur("132"), Counterparty: "POISTOVNA XYZ A.S."}
+
+	matchBankLine(&line, suite.items)
+
+	suite.Equal(BankLineMatched, line.Status)
+	suite.Equal([]string{"amount", "payer"}, line.Candidates[0].Reasons)
+}
+
+func (suite *BankReconciliationSuite) Test_MatchBankLine_SeveralCandidatesAreAmbiguous() {
+	line := BankStatementLine{Amount: eur("60"), Counterparty: "Jan Novak"}
+
+	matchBankLine(&line, suite.items)
+
+	suite.Equal(BankLineAmbiguous, line.Status)
+	suite.Len(line.Candidates, 2)
+}
+
+func (suite *BankReconciliationSuite) Test_ImportBankStatement_SameFileReturnsFirstImport() {
+	existing := BankStatement{Id: "stmt0"}
+	suite.statementDbMock.ExpectedCalls = nil
+	suite.statementDbMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return(&existing, nil)
+	ctx, recorder := suite.request("POST", "/api/bank-statements", camt053Statement)
+	ctx.Request.Header.Set("Content-Type", "application/xml")
+
+	(&implBankReconciliationAPI{}).ImportBankStatement(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.statementDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *BankReconciliationSuite) Test_ConfirmBankStatementLine_CreatesPaymentAndAllocatesInvoice() {
+	ctx, recorder := suite.request("POST", "/api/bank-statements/stmt1/lines/1/confirmation", "")
+
+	(&implBankReconciliationAPI{}).ConfirmBankStatementLine(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.paymentDbMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.MatchedBy(func(p *Payment) bool {
+		return p.ProcedureId == "proc001" && p.Status == PaymentStatusSettled && p.Amount.Equal(eur("200")) &&
+			p.Timestamp.Equal(time.Date(2025, 6, 2, 0, 0, 0, 0, time.UTC))
+	}))
+	suite.Equal(InvoiceStatusPaid, suite.invoice.Status)
+	suite.Equal(eur("132"), suite.invoice.Allocations[0].Amount)
+	suite.Equal(BankLineConfirmed, suite.statement.Lines[0].Status)
+	suite.NotEmpty(suite.statement.Lines[0].PaymentId)
+}
+
+func (suite *BankReconciliationSuite) Test_DismissBankStatementLine_RequiresReason() {
+	ctx, recorder := suite.request("POST", "/api/bank-statements/stmt1/lines/1/dismissal", `{}`)
+
+	(&implBankReconciliationAPI{}).DismissBankStatementLine(ctx)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.Equal(BankLineMatched, suite.statement.Lines[0].Status)
+}
@@ -0,0 +1,244 @@
+package ambulance
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// Bank statement formats.
+const (
+	BankStatementCSV     = "csv"
+	BankStatementCamt053 = "camt053"
+)
+
+// detectBankStatementFormat picks the format from the content type, or from the content
+// when the type says neither CSV nor XML.
+func detectBankStatementFormat(contentType string, body []byte) string {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return BankStatementCSV
+	case "application/xml", "text/xml":
+		return BankStatementCamt053
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(bytes.TrimPrefix(body, []byte("\ufeff"))), []byte("<")) {
+		return BankStatementCamt053
+	}
+	return BankStatementCSV
+}
+
+// parseBankDate reads a booking date as YYYY-MM-DD or DD.MM.YYYY.
+func parseBankDate(value string) (string, error) {
+	for _, layout := range []string{time.DateOnly, "2.1.2006"} {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(time.DateOnly), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
+
+// parseBankAmount reads an amount that may use a decimal comma, as bank exports often do.
+func parseBankAmount(value, currency string) (money.Money, error) {
+	value = strings.ReplaceAll(strings.TrimSpace(value), " ", "")
+	if strings.Contains(value, ",") && !strings.Contains(value, ".") {
+		value = strings.Replace(value, ",", ".", 1)
+	}
+	return money.Parse(value, currency)
+}
+
+// parseBankStatementCSV reads bank statement lines from CSV with a header row. The
+// booking_date and amount columns are required; currency (EUR by default), reference,
+// counterparty, counterparty_account, description and bank_reference are optional.
+// Outgoing payments have a negative amount.
+func parseBankStatementCSV(r io.Reader) ([]BankStatementLine, []ImportError) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []ImportError{{Line: 1, Message: "missing header row"}}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"booking_date", "amount"} {
+		if _, ok := columns[required]; !ok {
+			return nil, []ImportError{{Line: 1, Message: "missing column " + required}}
+		}
+	}
+
+	lines := make([]BankStatementLine, 0)
+	errors := make([]ImportError, 0)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				line = parseErr.Line
+			}
+			errors = append(errors, ImportError{Line: int32(line), Message: err.Error()})
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		entry := BankStatementLine{
+			Line:                int32(len(lines) + 1),
+			BankReference:       value("bank_reference"),
+			Reference:           value("reference"),
+			Counterparty:        value("counterparty"),
+			CounterpartyAccount: value("counterparty_account"),
+			Description:         value("description"),
+		}
+		if date, err := parseBankDate(value("booking_date")); err != nil {
+			errors = append(errors, ImportError{Line: int32(line), Message: "booking_date must be a date"})
+		} else {
+			entry.BookingDate = date
+		}
+		if amount, err := parseBankAmount(value("amount"), strings.ToUpper(value("currency"))); err != nil {
+			errors = append(errors, ImportError{Line: int32(line), Message: "amount must be a number"})
+		} else {
+			entry.Amount = amount
+		}
+		lines = append(lines, entry)
+	}
+	return lines, errors
+}
+
+// camtDocument is the part of an ISO 20022 camt.053 bank-to-customer statement read on import.
+type camtDocument struct {
+	Statements []struct {
+		Id      string `xml:"Id"`
+		IBAN    string `xml:"Acct>Id>IBAN"`
+		Other   string `xml:"Acct>Id>Othr>Id"`
+		Entries []struct {
+			Amount struct {
+				Value    string `xml:",chardata"`
+				Currency string `xml:"Ccy,attr"`
+			} `xml:"Amt"`
+			CreditDebit string `xml:"CdtDbtInd"`
+			Status      struct {
+				Value string `xml:",chardata"`
+				Code  string `xml:"Cd"`
+			} `xml:"Sts"`
+			BookingDate     string `xml:"BookgDt>Dt"`
+			BookingDateTime string `xml:"BookgDt>DtTm"`
+			ServicerRef     string `xml:"AcctSvcrRef"`
+			AdditionalInfo  string `xml:"AddtlNtryInf"`
+			Transactions    []struct {
+				EndToEndId        string   `xml:"Refs>EndToEndId"`
+				DebtorName        string   `xml:"RltdPties>Dbtr>Nm"`
+				DebtorPartyName   string   `xml:"RltdPties>Dbtr>Pty>Nm"`
+				DebtorIBAN        string   `xml:"RltdPties>DbtrAcct>Id>IBAN"`
+				CreditorName      string   `xml:"RltdPties>Cdtr>Nm"`
+				CreditorPartyName string   `xml:"RltdPties>Cdtr>Pty>Nm"`
+				CreditorIBAN      string   `xml:"RltdPties>CdtrAcct>Id>IBAN"`
+				CreditorReference string   `xml:"RmtInf>Strd>CdtrRefInf>Ref"`
+				Unstructured      []string `xml:"RmtInf>Ustrd"`
+			} `xml:"NtryDtls>TxDtls"`
+		} `xml:"Ntry"`
+	} `xml:"BkToCstmrStmt>Stmt"`
+}
+
+// parseBankStatementCamt053 reads the booked entries of camt.053 statements, one line
+// per entry described by its first transaction. It returns the account and the bank's
+// statement identifier with the lines; errors refer to entries by position.
+func parseBankStatementCamt053(r io.Reader) (string, string, []BankStatementLine, []ImportError) {
+	var document camtDocument
+	if err := xml.NewDecoder(r).Decode(&document); err != nil {
+		line := 0
+		if syntaxErr, ok := err.(*xml.SyntaxError); ok {
+			line = syntaxErr.Line
+		}
+		return "", "", nil, []ImportError{{Line: int32(line), Message: "invalid camt.053 document: " + err.Error()}}
+	}
+	if len(document.Statements) == 0 {
+		return "", "", nil, []ImportError{{Line: 1, Message: "the document holds no BkToCstmrStmt statement"}}
+	}
+
+	account := document.Statements[0].IBAN
+	if account == "" {
+		account = document.Statements[0].Other
+	}
+	lines := make([]BankStatementLine, 0)
+	errors := make([]ImportError, 0)
+	position := int32(0)
+	for _, statement := range document.Statements {
+		for _, entry := range statement.Entries {
+			position++
+			status := strings.TrimSpace(entry.Status.Value)
+			if entry.Status.Code != "" {
+				status = entry.Status.Code
+			}
+			if status != "" && status != "BOOK" {
+				continue
+			}
+
+			line := BankStatementLine{Line: int32(len(lines) + 1), BankReference: entry.ServicerRef, Description: entry.AdditionalInfo}
+			date := entry.BookingDate
+			if date == "" && len(entry.BookingDateTime) >= 10 {
+				date = entry.BookingDateTime[:10]
+			}
+			if parsed, err := parseBankDate(date); err != nil {
+				errors = append(errors, ImportError{Line: position, Message: fmt.Sprintf("entry %d: BookgDt must be a date", position)})
+			} else {
+				line.BookingDate = parsed
+			}
+			if amount, err := money.Parse(strings.TrimSpace(entry.Amount.Value), entry.Amount.Currency); err != nil {
+				errors = append(errors, ImportError{Line: position, Message: fmt.Sprintf("entry %d: Amt must be an amount with a currency", position)})
+			} else if entry.CreditDebit == "DBIT" {
+				line.Amount = amount.Neg()
+			} else {
+				line.Amount = amount
+			}
+
+			if len(entry.Transactions) > 0 {
+				tx := entry.Transactions[0]
+				line.Reference = tx.CreditorReference
+				if line.Reference == "" && tx.EndToEndId != "NOTPROVIDED" {
+					line.Reference = tx.EndToEndId
+				}
+				if entry.CreditDebit == "DBIT" {
+					line.Counterparty = firstNonEmpty(tx.CreditorName, tx.CreditorPartyName)
+					line.CounterpartyAccount = tx.CreditorIBAN
+				} else {
+					line.Counterparty = firstNonEmpty(tx.DebtorName, tx.DebtorPartyName)
+					line.CounterpartyAccount = tx.DebtorIBAN
+				}
+				if len(tx.Unstructured) > 0 {
+					line.Description = strings.Join(tx.Unstructured, " ")
+				}
+			}
+			lines = append(lines, line)
+		}
+	}
+	return account, document.Statements[0].Id, lines, errors
+}
+
+// firstNonEmpty returns the first of values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
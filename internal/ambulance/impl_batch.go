@@ -0,0 +1,340 @@
+package ambulance
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// MaxBatchOperations is the largest number of operations in a batch.
+const MaxBatchOperations = 100
+
+// batchRouterKey is the context key of the router that executes the operations of a batch.
+const batchRouterKey = "batch_router"
+
+// batchReference matches ${label.field.field} references to the response of an earlier
+// operation of a batch. Numeric fields index arrays.
+var batchReference = regexp.MustCompile(`\$\{([A-Za-z0-9_-]+)((?:\.[A-Za-z0-9_-]+)*)\}`)
+
+// batchMethods are the methods of operations a batch may hold.
+var batchMethods = map[string]bool{
+	http.MethodGet: true, http.MethodPost: true, http.MethodPut: true, http.MethodPatch: true, http.MethodDelete: true,
+}
+
+// batchHeaders are headers of the batch request not passed on to its operations.
+var batchHeaders = []string{"Content-Length", "Content-Type", "Accept", "Idempotency-Key"}
+
+// implBatchAPI implements the BatchAPI interface.
+type implBatchAPI struct{}
+
+// NewBatchAPI returns an implementation of BatchAPI executing operations on the routes of
+// the router built by NewRouterWithGinEngine.
+func NewBatchAPI() BatchAPI {
+	return &implBatchAPI{}
+}
+
+// batchRecorder is the response writer of an operation of a batch.
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) Write(data []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(data)
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+// Flush lets handlers streaming their response flush it; the whole response is kept.
+func (r *batchRecorder) Flush() {}
+
+// validateBatch checks the operations of a batch before any of them is executed: methods,
+// paths, unique labels and references to labels of earlier operations only.
+func validateBatch(batch *BatchRequest) []string {
+	var problems []string
+	if len(batch.Operations) == 0 {
+		problems = append(problems, "operations must not be empty")
+	}
+	if len(batch.Operations) > MaxBatchOperations {
+		problems = append(problems, fmt.Sprintf("a batch holds at most %d operations", MaxBatchOperations))
+	}
+	labels := map[string]bool{}
+	for i, op := range batch.Operations {
+		name := fmt.Sprintf("operation %d", i+1)
+		if op.Id != "" {
+			name = "operation " + op.Id
+		}
+		if !batchMethods[strings.ToUpper(op.Method)] {
+			problems = append(problems, name+": method must be GET, POST, PUT, PATCH or DELETE")
+		}
+		path := strings.SplitN(op.Path, "?", 2)[0]
+		if !strings.HasPrefix(path, "/api/") {
+			problems = append(problems, name+": path must start with /api/")
+		} else if path == "/api/batch" {
+			problems = append(problems, name+": batches cannot be nested")
+		}
+		if len(op.Body) > 0 && !json.Valid(op.Body) {
+			problems = append(problems, name+": body must be JSON")
+		}
+		for _, ref := range batchReference.FindAllStringSubmatch(op.Path+string(op.Body), -1) {
+			if !labels[ref[1]] {
+				problems = append(problems, fmt.Sprintf("%s: %s does not refer to an earlier operation", name, ref[0]))
+			}
+		}
+		if op.Id != "" {
+			if labels[op.Id] {
+				problems = append(problems, name+": id is used by an earlier operation")
+			}
+			labels[op.Id] = true
+		}
+	}
+	return problems
+}
+
+// batchContext holds the responses of the operations executed so far.
+type batchContext struct {
+	bodies map[string]any
+	failed map[string]bool
+}
+
+// lookup returns the value a reference stands for, or a problem.
+func (b *batchContext) lookup(ref []string) (any, string) {
+	if b.failed[ref[1]] {
+		return nil, fmt.Sprintf("%s refers to operation %s, which failed", ref[0], ref[1])
+	}
+	value := b.bodies[ref[1]]
+	for _, field := range strings.Split(strings.TrimPrefix(ref[2], "."), ".") {
+		if field == "" {
+			continue
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[field]
+		case []any:
+			if i, err := strconv.Atoi(field); err == nil && i >= 0 && i < len(v) {
+				value = v[i]
+			} else {
+				value = nil
+			}
+		default:
+			value = nil
+		}
+		if value == nil {
+			return nil, fmt.Sprintf("%s is not in the response of operation %s", ref[0], ref[1])
+		}
+	}
+	return value, ""
+}
+
+// text returns the text of a referenced value for use within a string.
+func (b *batchContext) text(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	data, _ := json.Marshal(value)
+	return string(data)
+}
+
+// resolve replaces the references in a string. A string that is a single reference is
+// replaced by the referenced value itself, which need not be a string.
+func (b *batchContext) resolve(s string, escape func(string) string) (any, string) {
+	if ref := batchReference.FindStringSubmatch(s); ref != nil && ref[0] == s {
+		value, problem := b.lookup(ref)
+		if escape != nil && problem == "" {
+			return escape(b.text(value)), ""
+		}
+		return value, problem
+	}
+	problem := ""
+	resolved := batchReference.ReplaceAllStringFunc(s, func(match string) string {
+		value, p := b.lookup(batchReference.FindStringSubmatch(match))
+		if p != "" {
+			problem = p
+			return match
+		}
+		if escape != nil {
+			return escape(b.text(value))
+		}
+		return b.text(value)
+	})
+	return resolved, problem
+}
+
+// resolveBody replaces the references in the string values of a JSON body.
+func (b *batchContext) resolveBody(value any) (any, string) {
+	switch v := value.(type) {
+	case string:
+		return b.resolve(v, nil)
+	case map[string]any:
+		for key, item := range v {
+			resolved, problem := b.resolveBody(item)
+			if problem != "" {
+				return nil, problem
+			}
+			v[key] = resolved
+		}
+	case []any:
+		for i, item := range v {
+			resolved, problem := b.resolveBody(item)
+			if problem != "" {
+				return nil, problem
+			}
+			v[i] = resolved
+		}
+	}
+	return value, ""
+}
+
+// decodeJSON decodes JSON keeping numbers as they are written.
+func decodeJSON(data []byte) (any, error) {
+	var value any
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	err := decoder.Decode(&value)
+	return value, err
+}
+
+// execute runs an operation on the router and returns its result. Operations referring
+// to a failed operation fail with 424 without being executed.
+func (b *batchContext) execute(c *gin.Context, router http.Handler, op BatchOperation) BatchResult {
+	result := BatchResult{Id: op.Id}
+	fail := func(status int, problem string) BatchResult {
+		result.Status = int32(status)
+		result.Body, _ = json.Marshal(gin.H{"message": problem})
+		b.failed[op.Id] = true
+		return result
+	}
+
+	path, problem := b.resolve(op.Path, url.PathEscape)
+	if problem != "" {
+		return fail(http.StatusFailedDependency, problem)
+	}
+	var body []byte
+	if len(op.Body) > 0 {
+		value, err := decodeJSON(op.Body)
+		if err != nil {
+			return fail(http.StatusBadRequest, "body must be JSON")
+		}
+		if value, problem = b.resolveBody(value); problem != "" {
+			return fail(http.StatusFailedDependency, problem)
+		}
+		body, _ = json.Marshal(value)
+	}
+
+	request, err := http.NewRequestWithContext(c.Request.Context(), strings.ToUpper(op.Method), path.(string), bytes.NewReader(body))
+	if err != nil {
+		return fail(http.StatusBadRequest, "Invalid path: "+err.Error())
+	}
+	request.Header = c.Request.Header.Clone()
+	for _, name := range batchHeaders {
+		request.Header.Del(name)
+	}
+	request.Header.Set("Accept", gin.MIMEJSON)
+	if body != nil {
+		request.Header.Set("Content-Type", gin.MIMEJSON)
+	}
+	request.RemoteAddr = c.Request.RemoteAddr
+
+	response := &batchRecorder{header: http.Header{}}
+	router.ServeHTTP(response, request)
+	if response.status == 0 {
+		response.status = http.StatusOK
+	}
+	result.Status = int32(response.status)
+
+	data := response.body.Bytes()
+	if value, err := decodeJSON(data); err == nil {
+		result.Body = data
+		if op.Id != "" {
+			b.bodies[op.Id] = value
+		}
+	} else if len(data) > 0 {
+		result.Body, _ = json.Marshal(string(data))
+	}
+	if response.status >= http.StatusBadRequest && op.Id != "" {
+		b.failed[op.Id] = true
+	}
+	return result
+}
+
+// ExecuteBatch implements POST /api/batch
+//
+// The operations run one after another through the routes of the API, with the headers
+// of the batch request. Later operations refer to the responses of earlier ones with
+// ${label.field} in their path or in string values of their body. Every operation is
+// executed; those referring to a failed operation fail with 424. The operations of a
+// transactional batch run in one transaction, which stops and is rolled back at the
+// first failed operation; without a database running transactions they are refused with 501.
+func (o *implBatchAPI) ExecuteBatch(c *gin.Context) {
+	var batch BatchRequest
+	if err := c.ShouldBindJSON(&batch); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if problems := validateBatch(&batch); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invalid batch", "errors": problems})
+		return
+	}
+	router, ok := c.Get(batchRouterKey)
+	if !ok {
+		c.JSON(http.StatusNotImplemented, gin.H{"message": "Batches are not available"})
+		return
+	}
+
+	b := &batchContext{bodies: map[string]any{}, failed: map[string]bool{}}
+	response := BatchResponse{Transactional: batch.Transactional, Results: make([]BatchResult, 0, len(batch.Operations))}
+	if !batch.Transactional {
+		for _, op := range batch.Operations {
+			response.Results = append(response.Results, b.execute(c, router.(http.Handler), op))
+		}
+		c.JSON(http.StatusOK, response)
+		return
+	}
+
+	err := requireTransaction(c, func() error {
+		for _, op := range batch.Operations {
+			result := b.execute(c, router.(http.Handler), op)
+			response.Results = append(response.Results, result)
+			if result.Status >= http.StatusBadRequest {
+				return errResponseFailed
+			}
+		}
+		return nil
+	})
+	switch {
+	case err == errResponseFailed:
+		response.RolledBack = true
+		c.JSON(http.StatusConflict, response)
+	case err == db_service.ErrNoTransactions:
+		c.JSON(http.StatusNotImplemented, gin.H{"message": "Transactional batches need MongoDB running as a replica set or a sharded cluster"})
+	case err != nil:
+		log.Println("transaction error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to execute batch"})
+	default:
+		c.JSON(http.StatusOK, response)
+	}
+}
@@ -0,0 +1,174 @@
+package ambulance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// BatchSuite defines the suite for batch operation tests
+type BatchSuite struct {
+	suite.Suite
+	ambulanceDbMock *DbServiceMock[Ambulance]
+	procedureDbMock *DbServiceMock[Procedure]
+	engine          *gin.Engine
+}
+
+func TestBatchSuite(t *testing.T) {
+	suite.Run(t, new(BatchSuite))
+}
+
+func (suite *BatchSuite) SetupTest() {
+	suite.ambulanceDbMock = &DbServiceMock[Ambulance]{}
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.On("FindDocument", mock.Anything, "missing").Return((*Procedure)(nil), db_service.ErrNotFound)
+
+	gin.SetMode(gin.TestMode)
+	suite.engine = gin.New()
+	suite.engine.Use(func(c *gin.Context) {
+		c.Set("db_service_ambulance", suite.ambulanceDbMock)
+		c.Set("db_service_department", &DbServiceMock[Department]{})
+		c.Set("db_service_payment", &DbServiceMock[Payment]{})
+		c.Set("db_service_procedure", suite.procedureDbMock)
+	})
+	NewRouterWithGinEngine(suite.engine, testHandleFunctions())
+}
+
+func (suite *BatchSuite) post(payload string) *httptest.ResponseRecorder {
+	recorder := httptest.NewRecorder()
+	suite.engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/batch", strings.NewReader(payload)))
+	return recorder
+}
+
+func (suite *BatchSuite) Test_ExecuteBatch_ResolvesReferencesToEarlierOperations() {
+	var created *Ambulance
+	find := suite.ambulanceDbMock.On("FindDocument", mock.Anything, mock.Anything).Return((*Ambulance)(nil), db_service.ErrNotFound)
+	suite.ambulanceDbMock.On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			created = args.Get(2).(*Ambulance)
+			find.Return(created, nil)
+		}).
+		Return(nil)
+
+	recorder := suite.post(`{"operations":[
+		{"id":"amb","method":"POST","path":"/api/ambulances","body":{"name":"Ambulancia 1","capacity":4}},
+		{"method":"GET","path":"/api/ambulances/${amb.id}"},
+		{"id":"pay","method":"post","path":"/api/payments","body":{"procedure_id":"missing","amount":{"amount":"10.00","currency":"EUR"},"description":"for ${amb.name}"}},
+		{"method":"GET","path":"/api/payments/${pay.id}"}
+	]}`)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	var response BatchResponse
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Require().Len(response.Results, 4)
+	suite.Equal(int32(http.StatusCreated), response.Results[0].Status)
+	suite.Equal("amb", response.Results[0].Id)
+	suite.Equal(int32(http.StatusOK), response.Results[1].Status)
+	suite.Require().NotNil(created)
+	suite.NotEmpty(created.Id)
+	suite.ambulanceDbMock.AssertCalled(suite.T(), "FindDocument", mock.Anything, created.Id)
+	suite.Equal(int32(http.StatusUnprocessableEntity), response.Results[2].Status)
+	suite.Equal(int32(http.StatusFailedDependency), response.Results[3].Status)
+	suite.Contains(string(response.Results[3].Body), "operation pay, which failed")
+}
+
+func (suite *BatchSuite) Test_ExecuteBatch_RejectsInvalidBatch() {
+	recorder := suite.post(`{"operations":[
+		{"method":"GET","path":"/api/ambulances/${later.id}"},
+		{"id":"later","method":"OPTIONS","path":"/api/ambulances"},
+		{"method":"POST","path":"/api/batch"},
+		{"method":"GET","path":"https://example.com/api/ambulances"}
+	]}`)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	var response struct{ Errors []string }
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.Len(response.Errors, 4)
+
+	recorder = suite.post(`{"transactional":true,"operations":[{"method":"GET","path":"/api/ambulances"}]}`)
+	suite.Equal(http.StatusNotImplemented, recorder.Code)
+
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "ListDocuments", mock.Anything)
+}
+
+func (suite *BatchSuite) Test_ExecuteBatch_TransactionalRollsBackOnFailure() {
+	ambulanceDb := db_service.NewMemoryService[Ambulance]()
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("db_service_ambulance", ambulanceDb)
+		c.Set("db_service_department", &DbServiceMock[Department]{})
+		c.Set("db_transactor", db_service.NewMemoryTransactor())
+	})
+	NewRouterWithGinEngine(engine, testHandleFunctions())
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/batch", strings.NewReader(`{"transactional":true,"operations":[
+		{"id":"amb","method":"POST","path":"/api/ambulances","body":{"name":"Ambulancia 1","capacity":4}},
+		{"method":"GET","path":"/api/ambulances/missing"},
+		{"method":"GET","path":"/api/ambulances/${amb.id}"}
+	]}`)))
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	var response BatchResponse
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.True(response.Transactional)
+	suite.True(response.RolledBack)
+	suite.Require().Len(response.Results, 2)
+	suite.Equal(int32(http.StatusCreated), response.Results[0].Status)
+	suite.Equal(int32(http.StatusNotFound), response.Results[1].Status)
+	ambulances, err := ambulanceDb.ListDocuments(context.Background())
+	suite.Require().NoError(err)
+	suite.Empty(ambulances)
+}
+
+func (suite *BatchSuite) Test_ExecuteBatch_TransactionalCommitsAllOperations() {
+	ambulanceDb := db_service.NewMemoryService[Ambulance]()
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("db_service_ambulance", ambulanceDb)
+		c.Set("db_service_department", &DbServiceMock[Department]{})
+		c.Set("db_transactor", db_service.NewMemoryTransactor())
+	})
+	NewRouterWithGinEngine(engine, testHandleFunctions())
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/batch", strings.NewReader(`{"transactional":true,"operations":[
+		{"id":"amb","method":"POST","path":"/api/ambulances","body":{"name":"Ambulancia 1","capacity":4}},
+		{"method":"GET","path":"/api/ambulances/${amb.id}"}
+	]}`)))
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	var response BatchResponse
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &response))
+	suite.False(response.RolledBack)
+	suite.Require().Len(response.Results, 2)
+	suite.Equal(int32(http.StatusOK), response.Results[1].Status)
+	ambulances, err := ambulanceDb.ListDocuments(context.Background())
+	suite.Require().NoError(err)
+	suite.Len(ambulances, 1)
+}
+
+func (suite *BatchSuite) Test_ExecuteBatch_TransactionalNeedsTransactions() {
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("db_service_ambulance", suite.ambulanceDbMock)
+		c.Set("db_transactor", standaloneTransactor{})
+	})
+	NewRouterWithGinEngine(engine, testHandleFunctions())
+
+	recorder := httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/batch", strings.NewReader(`{"transactional":true,"operations":[
+		{"method":"POST","path":"/api/ambulances","body":{"name":"Ambulancia 1","capacity":4}}
+	]}`)))
+
+	suite.Equal(http.StatusNotImplemented, recorder.Code)
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,446 @@
+package ambulance
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// MIMENDJSON is the content type of newline-delimited JSON, one record per line.
+const MIMENDJSON = "application/x-ndjson"
+
+// MaxImportSize is the largest bulk import accepted, in bytes.
+const MaxImportSize = 32 << 20
+
+// importBatchSize is the number of records stored with one database write.
+const importBatchSize = 500
+
+// Outcomes of the records of a bulk import
+const (
+	ImportLineImported = "imported"
+	ImportLineValid    = "valid"
+	ImportLineFailed   = "failed"
+)
+
+// importLayouts are the time layouts read besides the date and time of the locale.
+var importLayouts = []string{time.RFC3339, "2006-01-02 15:04", time.DateOnly}
+
+// bulkImport describes how the records of a resource are read, validated and stored.
+type bulkImport[T any] struct {
+	name    string
+	columns []listColumn[T]
+	id      func(*T) *string
+	prepare func(ctx context.Context, c *gin.Context, record *T) ([]string, error)
+	db      db_service.DbService[T]
+}
+
+// importRecord is a record read from a line of the import, with its validation problems.
+type importRecord[T any] struct {
+	line   int32
+	value  T
+	errors []string
+	stored bool
+}
+
+// errImportFailed rolls back an all-or-nothing import of which a record could not be stored.
+var errImportFailed = errors.New("a record of the import could not be stored")
+
+// problemList returns a validation problem as a list, which is empty without a problem.
+func problemList(problem string) []string {
+	if problem == "" {
+		return nil
+	}
+	return []string{problem}
+}
+
+// readNDJSON reads a record from every non-blank line.
+func readNDJSON[T any](body []byte) []importRecord[T] {
+	var records []importRecord[T]
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, MaxImportSize)
+	for line := int32(1); scanner.Scan(); line++ {
+		text := bytes.TrimSpace(scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+		record := importRecord[T]{line: line}
+		if err := json.Unmarshal(text, &record.value); err != nil {
+			record.errors = []string{"invalid JSON: " + err.Error()}
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// readCSV reads a record from every row after the header, which names the columns of
+// the rows. Computed columns are ignored; a currency column gives the currency of the
+// amounts of its row. It returns a problem when the header names an unknown column.
+func readCSV[T any](body []byte, columns []listColumn[T], locale exportLocale) ([]importRecord[T], string) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(body, []byte("\ufeff"))))
+	r.Comma = locale.comma
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, ""
+	}
+	if err != nil {
+		return nil, "Invalid CSV header: " + err.Error()
+	}
+
+	byName := map[string]listColumn[T]{}
+	names := make([]string, 0, len(columns))
+	for _, column := range columns {
+		byName[column.name] = column
+		names = append(names, column.name)
+	}
+	fields := make([]*listColumn[T], len(header))
+	currency := -1
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		column, ok := byName[name]
+		switch {
+		case !ok:
+			return nil, fmt.Sprintf("Unknown column %q; the columns are %s", name, strings.Join(names, ", "))
+		case name == "currency":
+			currency = i
+		case column.field != nil:
+			fields[i] = &column
+		}
+	}
+
+	zone := locale.location()
+	var records []importRecord[T]
+	for {
+		row, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// a malformed row has no fields to take the line from
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) {
+				return nil, "Invalid CSV: " + err.Error()
+			}
+			records = append(records, importRecord[T]{line: int32(parseErr.StartLine), errors: []string{err.Error()}})
+			continue
+		}
+		line, _ := r.FieldPos(0)
+		record := importRecord[T]{line: int32(line)}
+		unit := ""
+		if currency >= 0 {
+			unit = strings.TrimSpace(row[currency])
+		}
+		for i, value := range row {
+			if fields[i] == nil || strings.TrimSpace(value) == "" {
+				continue
+			}
+			if problem := setImportField(fields[i].field(&record.value), value, unit, locale, zone); problem != "" {
+				record.errors = append(record.errors, fields[i].name+": "+problem)
+			}
+		}
+		records = append(records, record)
+	}
+	return records, ""
+}
+
+// setImportField parses a CSV value into a field as written by listExport.text.
+func setImportField(field any, value string, currency string, locale exportLocale, zone *time.Location) string {
+	value = strings.TrimSpace(value)
+	switch f := field.(type) {
+	case *string:
+		if len(value) > 1 && value[0] == '\'' && strings.ContainsRune("=+-@\t\r", rune(value[1])) {
+			value = value[1:]
+		}
+		*f = value
+	case *int32:
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil {
+			return fmt.Sprintf("%q is not a whole number", value)
+		}
+		*f = int32(n)
+	case *money.Money:
+		amount, err := money.Parse(strings.Replace(value, locale.decimal, ".", 1), currency)
+		if err != nil {
+			return err.Error()
+		}
+		*f = amount
+	case *time.Time:
+		for _, layout := range append([]string{locale.dateTime}, importLayouts...) {
+			if t, err := time.ParseInLocation(layout, value, zone); err == nil {
+				*f = t
+				return ""
+			}
+		}
+		return fmt.Sprintf("%q is not a date and time like %s", value, locale.dateTime)
+	}
+	return ""
+}
+
+// runImport reads the records of a bulk import from an NDJSON or CSV body, validates
+// them and stores the valid ones, responding with the outcome of every record. With
+// ?dry_run=true nothing is stored, and ids already stored are not detected. Otherwise the
+// valid records are written as they are, each stored or reported failed on its own. With
+// ?all_or_nothing=true nothing is written unless every record is valid, and the writes
+// run in one transaction rolled back when a record cannot be stored. Without transactions
+// the stored records are deleted again, and the import fails with 500 when one of them
+// cannot be.
+func runImport[T any](c *gin.Context, imp bulkImport[T]) {
+	report := ImportReport{
+		DryRun:       c.Query("dry_run") == "true",
+		AllOrNothing: c.Query("all_or_nothing") == "true",
+		Lines:        []ImportLine{},
+	}
+	contentType := c.ContentType()
+	if contentType != MIMENDJSON && contentType != MIMECSV {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"message": "The import must be " + MIMENDJSON + " or " + MIMECSV})
+		return
+	}
+	locale, problem := requestLocale(c)
+	if problem != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": problem})
+		return
+	}
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, MaxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"message": "The import exceeds the size limit"})
+		} else {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		}
+		return
+	}
+
+	var records []importRecord[T]
+	if contentType == MIMECSV {
+		if records, problem = readCSV(body, imp.columns, locale); problem != "" {
+			c.JSON(http.StatusBadRequest, gin.H{"message": problem})
+			return
+		}
+	} else {
+		records = readNDJSON[T](body)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	// ids already stored are reported by the writes
+	taken := map[string]int32{}
+
+	var valid []*importRecord[T]
+	for i := range records {
+		record := &records[i]
+		if len(record.errors) > 0 {
+			continue
+		}
+		id := imp.id(&record.value)
+		if *id == "" {
+			*id = uuid.NewString()
+		}
+		if line, ok := taken[*id]; ok {
+			record.errors = []string{fmt.Sprintf("id %s already appears on line %d", *id, line)}
+			continue
+		}
+		taken[*id] = record.line
+
+		problems, err := imp.prepare(ctx, c, &record.value)
+		if err != nil {
+			log.Println("import validation error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import " + imp.name})
+			return
+		}
+		if record.errors = problems; len(problems) == 0 {
+			valid = append(valid, record)
+		}
+	}
+
+	failed := len(valid) < len(records)
+	switch {
+	case report.DryRun:
+	case !report.AllOrNothing:
+		// a best-effort import may exceed what a transaction holds, and its records
+		// succeed or fail on their own
+		storeImport(ctx, imp, valid, false)
+	case !failed:
+		write := func() error {
+			ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+			defer cancel()
+			if failed = storeImport(ctx, imp, valid, true); failed {
+				return errImportFailed
+			}
+			return nil
+		}
+		err := requireTransaction(c, write)
+		if err == db_service.ErrNoTransactions {
+			// without a transaction the stored records are deleted again, and the import
+			// is visible in between
+			if err = write(); err == errImportFailed && !undoImport(ctx, imp, valid) {
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import " + imp.name + "; some records could not be deleted again"})
+				return
+			}
+		}
+		switch {
+		case err == errImportFailed:
+			for _, record := range valid {
+				record.stored = false
+			}
+		case err != nil:
+			log.Println("import transaction error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import " + imp.name})
+			return
+		}
+	}
+
+	for i := range records {
+		record := &records[i]
+		line := ImportLine{Line: record.line, Id: *imp.id(&record.value), Status: ImportLineValid, Errors: record.errors}
+		switch {
+		case len(record.errors) > 0:
+			line.Status = ImportLineFailed
+			report.Failed++
+		case record.stored:
+			line.Status = ImportLineImported
+			report.Imported++
+		}
+		report.Lines = append(report.Lines, line)
+	}
+	report.Total = int32(len(records))
+
+	if report.AllOrNothing && failed {
+		c.JSON(http.StatusUnprocessableEntity, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// storeImport writes the valid records in batches and marks those stored. With stopOnFailure
+// it writes no further batch once a record failed. It reports whether a record failed.
+// A transaction retrying the writes calls it again, so it starts from a clean slate.
+func storeImport[T any](ctx context.Context, imp bulkImport[T], valid []*importRecord[T], stopOnFailure bool) bool {
+	for _, record := range valid {
+		record.stored, record.errors = false, nil
+	}
+	failed := false
+	for start := 0; start < len(valid) && !(stopOnFailure && failed); start += importBatchSize {
+		batch := valid[start:min(start+importBatchSize, len(valid))]
+		ids := make([]string, len(batch))
+		documents := make([]*T, len(batch))
+		for i, record := range batch {
+			ids[i], documents[i] = *imp.id(&record.value), &record.value
+		}
+		errs, err := imp.db.CreateDocuments(ctx, ids, documents)
+		if err != nil {
+			log.Println("CreateDocuments error:", err)
+			errs = make([]error, len(batch))
+			for i := range errs {
+				errs[i] = err
+			}
+		}
+		for i, err := range errs {
+			switch {
+			case err == nil:
+				batch[i].stored = true
+				continue
+			case errors.Is(err, db_service.ErrConflict):
+				batch[i].errors = []string{fmt.Sprintf("a record with id %s already exists", ids[i])}
+			default:
+				batch[i].errors = []string{"the record could not be stored"}
+			}
+			failed = true
+		}
+	}
+	return failed
+}
+
+// undoImport deletes the stored records again. It reports whether all of them are gone.
+func undoImport[T any](ctx context.Context, imp bulkImport[T], valid []*importRecord[T]) bool {
+	undone := true
+	for _, record := range valid {
+		if !record.stored {
+			continue
+		}
+		if err := imp.db.DeleteDocument(ctx, *imp.id(&record.value)); err != nil && err != db_service.ErrNotFound {
+			log.Println("DeleteDocument error:", err)
+			undone = false
+			continue
+		}
+		record.stored = false
+	}
+	return undone
+}
+
+// ImportAmbulances implements POST /api/ambulances:import
+//
+// Records are validated like the bodies of POST /api/ambulances.
+func (o *implAmbulanceAPI) ImportAmbulances(c *gin.Context) {
+	runImport(c, bulkImport[Ambulance]{
+		name:    "ambulances",
+		columns: ambulanceColumns,
+		id:      func(a *Ambulance) *string { return &a.Id },
+		prepare: func(ctx context.Context, c *gin.Context, a *Ambulance) ([]string, error) {
+			if problems := validateWorkingHours(a.WorkingHours); len(problems) > 0 {
+				return problems, nil
+			}
+			if problem, err := resolveAmbulanceDepartment(ctx, getDepartmentDB(c), a); err != nil || problem != "" {
+				return problemList(problem), err
+			}
+			if strings.EqualFold(a.Status, AmbulanceStatusDispatched) {
+				return checkDispatch(ctx, c, a, time.Now())
+			}
+			return nil, nil
+		},
+		db: getDB(c),
+	})
+}
+
+// ImportProcedures implements POST /api/procedures:import
+//
+// Records are validated and priced like the bodies of POST /api/procedures.
+func (o *implProcedureAPI) ImportProcedures(c *gin.Context) {
+	runImport(c, bulkImport[Procedure]{
+		name:    "procedures",
+		columns: procedureColumns,
+		id:      func(p *Procedure) *string { return &p.Id },
+		prepare: func(ctx context.Context, c *gin.Context, p *Procedure) ([]string, error) {
+			if problem, err := prepareProcedure(ctx, c, p); err != nil || problem != "" {
+				return problemList(problem), err
+			}
+			return problemList(startProcedureLifecycle(p, userRole(c), time.Now())), nil
+		},
+		db: getProcedureDB(c),
+	})
+}
+
+// ImportPayments implements POST /api/payments:import
+//
+// Records are validated like the bodies of POST /api/payments.
+func (o *implPaymentAPI) ImportPayments(c *gin.Context) {
+	runImport(c, bulkImport[Payment]{
+		name:    "payments",
+		columns: paymentColumns,
+		id:      func(p *Payment) *string { return &p.Id },
+		prepare: func(ctx context.Context, c *gin.Context, p *Payment) ([]string, error) {
+			if problem := startPaymentLifecycle(p); problem != "" {
+				return []string{problem}, nil
+			}
+			problem, err := validatePayment(ctx, c, p)
+			return problemList(problem), err
+		},
+		db: getPaymentDB(c),
+	})
+}
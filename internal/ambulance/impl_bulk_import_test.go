@@ -0,0 +1,238 @@
+package ambulance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// BulkImportSuite defines the suite for NDJSON and CSV import tests
+type BulkImportSuite struct {
+	suite.Suite
+	ambulanceDbMock *DbServiceMock[Ambulance]
+	paymentDbMock   *DbServiceMock[Payment]
+	procedureDbMock *DbServiceMock[Procedure]
+}
+
+func TestBulkImportSuite(t *testing.T) {
+	suite.Run(t, new(BulkImportSuite))
+}
+
+func (suite *BulkImportSuite) SetupTest() {
+	suite.ambulanceDbMock = &DbServiceMock[Ambulance]{}
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.On("FindDocument", mock.Anything, "proc001").Return(&Procedure{Id: "proc001", Price: eur("100")}, nil)
+}
+
+func (suite *BulkImportSuite) request(path string, contentType string, body string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_ambulance", suite.ambulanceDbMock)
+	ctx.Set("db_service_department", &DbServiceMock[Department]{})
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Request = httptest.NewRequest("POST", path, strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", contentType)
+	return ctx, recorder
+}
+
+func (suite *BulkImportSuite) report(recorder *httptest.ResponseRecorder) ImportReport {
+	var report ImportReport
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &report))
+	return report
+}
+
+func (suite *BulkImportSuite) Test_ImportPayments_ReadsCSVInSlovakLocale() {
+	var stored []*Payment
+	suite.paymentDbMock.
+		On("CreateDocuments", mock.Anything, []string{"pay1"}, mock.Anything).
+		Run(func(args mock.Arguments) { stored = args.Get(2).([]*Payment) }).
+		Return([]error{nil}, nil)
+	ctx, recorder := suite.request("/api/payments:import?locale=sk", "text/csv",
+		"\ufeffid;procedure_id;amount;currency;timestamp;description\n"+
+			"pay1;proc001;12,50;EUR;2.6.2025 10:30;\"'=HYPERLINK(\"\"x\"\")\"\n"+
+			"pay2;proc001;12.50.00;EUR;yesterday;\n")
+
+	(&implPaymentAPI{}).ImportPayments(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	report := suite.report(recorder)
+	suite.Equal(int32(2), report.Total)
+	suite.Equal(int32(1), report.Imported)
+	suite.Equal(ImportLine{Line: 2, Id: "pay1", Status: ImportLineImported}, report.Lines[0])
+	suite.Equal(ImportLineFailed, report.Lines[1].Status)
+	suite.Len(report.Lines[1].Errors, 2)
+
+	suite.Require().Len(stored, 1)
+	suite.Equal(eur("12.50"), stored[0].Amount)
+	suite.Equal(time.Date(2025, 6, 2, 8, 30, 0, 0, time.UTC), stored[0].Timestamp.UTC())
+	suite.Equal(`=HYPERLINK("x")`, stored[0].Description)
+	suite.Equal(PaymentStatusSettled, stored[0].Status)
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_DryRunStoresNothing() {
+	ctx, recorder := suite.request("/api/ambulances:import?dry_run=true", MIMENDJSON,
+		`{"id":"amb2","name":"Ambulancia 2"}`+"\n\n"+`{"name":"Ambulancia 3"}`+"\n"+`{"id":"amb2"}`+"\n")
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	report := suite.report(recorder)
+	suite.True(report.DryRun)
+	suite.Equal(int32(3), report.Total)
+	suite.Equal(int32(0), report.Imported)
+	suite.Equal(int32(1), report.Failed)
+	suite.Equal(ImportLineValid, report.Lines[0].Status)
+	suite.Equal(int32(3), report.Lines[1].Line)
+	suite.NotEmpty(report.Lines[1].Id)
+	suite.Equal([]string{"id amb2 already appears on line 1"}, report.Lines[2].Errors)
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "CreateDocuments", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// refusingTransactor fails the test when a transaction is started.
+type refusingTransactor struct {
+	suite *BulkImportSuite
+}
+
+func (t refusingTransactor) RunInTransaction(context.Context, func(context.Context) error) error {
+	t.suite.Fail("a best-effort import must not run in a transaction")
+	return db_service.ErrNoTransactions
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_BestEffortWritesWithoutTransaction() {
+	suite.ambulanceDbMock.
+		On("CreateDocuments", mock.Anything, []string{"amb1", "amb2"}, mock.Anything).
+		Return([]error{db_service.ErrConflict, nil}, nil)
+	ctx, recorder := suite.request("/api/ambulances:import", MIMENDJSON,
+		`{"id":"amb1"}`+"\n"+`{"id":"amb2"}`+"\n")
+	ctx.Set("db_transactor", refusingTransactor{suite})
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	report := suite.report(recorder)
+	suite.Equal(int32(1), report.Imported)
+	suite.Equal([]string{"a record with id amb1 already exists"}, report.Lines[0].Errors)
+	suite.Equal(ImportLineImported, report.Lines[1].Status)
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "ListDocuments", mock.Anything)
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_AllOrNothingRejectsDuplicateIds() {
+	ctx, recorder := suite.request("/api/ambulances:import?all_or_nothing=true", MIMENDJSON,
+		`{"id":"amb2"}`+"\n"+`{"id":"amb2"}`+"\n"+`{"id":`+"\n")
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	report := suite.report(recorder)
+	suite.Equal(int32(2), report.Failed)
+	suite.Equal(ImportLineValid, report.Lines[0].Status)
+	suite.Equal([]string{"id amb2 already appears on line 1"}, report.Lines[1].Errors)
+	suite.Contains(report.Lines[2].Errors[0], "invalid JSON")
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "CreateDocuments", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_AllOrNothingDeletesStoredOnConflict() {
+	suite.ambulanceDbMock.
+		On("CreateDocuments", mock.Anything, []string{"amb2", "amb3"}, mock.Anything).
+		Return([]error{nil, db_service.ErrConflict}, nil)
+	suite.ambulanceDbMock.On("DeleteDocument", mock.Anything, "amb2").Return(nil)
+	ctx, recorder := suite.request("/api/ambulances:import?all_or_nothing=true", MIMENDJSON,
+		`{"id":"amb2"}`+"\n"+`{"id":"amb3"}`+"\n")
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	report := suite.report(recorder)
+	suite.Equal(int32(0), report.Imported)
+	suite.Equal(ImportLineValid, report.Lines[0].Status)
+	suite.Equal(ImportLineFailed, report.Lines[1].Status)
+	suite.ambulanceDbMock.AssertCalled(suite.T(), "DeleteDocument", mock.Anything, "amb2")
+}
+
+// concurrentImport stores amb3 just before the records of an import are written.
+type concurrentImport struct {
+	db_service.DbService[Ambulance]
+}
+
+func (s concurrentImport) CreateDocuments(ctx context.Context, ids []string, documents []*Ambulance) ([]error, error) {
+	if err := s.DbService.CreateDocument(context.Background(), "amb3", &Ambulance{Id: "amb3"}); err != nil {
+		return nil, err
+	}
+	return s.DbService.CreateDocuments(ctx, ids, documents)
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_AllOrNothingRollsBackTransaction() {
+	ambulanceDb := db_service.NewMemoryService[Ambulance]()
+	ctx, recorder := suite.request("/api/ambulances:import?all_or_nothing=true", MIMENDJSON,
+		`{"id":"amb2"}`+"\n"+`{"id":"amb3"}`+"\n")
+	ctx.Set("db_service_ambulance", concurrentImport{ambulanceDb})
+	ctx.Set("db_transactor", db_service.NewMemoryTransactor())
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	report := suite.report(recorder)
+	suite.Equal(int32(0), report.Imported)
+	suite.Equal(ImportLineValid, report.Lines[0].Status)
+	suite.Equal([]string{"a record with id amb3 already exists"}, report.Lines[1].Errors)
+	_, err := ambulanceDb.FindDocument(context.Background(), "amb2")
+	suite.Equal(db_service.ErrNotFound, err)
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_AllOrNothingFailsWhenNotUndone() {
+	suite.ambulanceDbMock.
+		On("CreateDocuments", mock.Anything, []string{"amb2", "amb3"}, mock.Anything).
+		Return([]error{nil, db_service.ErrConflict}, nil)
+	suite.ambulanceDbMock.On("DeleteDocument", mock.Anything, "amb2").Return(errors.New("connection lost"))
+	ctx, recorder := suite.request("/api/ambulances:import?all_or_nothing=true", MIMENDJSON,
+		`{"id":"amb2"}`+"\n"+`{"id":"amb3"}`+"\n")
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusInternalServerError, recorder.Code)
+	suite.Contains(recorder.Body.String(), "could not be deleted again")
+}
+
+func (suite *BulkImportSuite) Test_ImportAmbulances_ReportsMalformedCSVRow() {
+	suite.ambulanceDbMock.
+		On("CreateDocuments", mock.Anything, []string{"amb2", "amb3"}, mock.Anything).
+		Return([]error{nil, nil}, nil)
+	ctx, recorder := suite.request("/api/ambulances:import", "text/csv",
+		"id,name\namb2,Ambulancia 2\na\"b,c\namb3,Ambulancia 3\n")
+
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	report := suite.report(recorder)
+	suite.Require().Len(report.Lines, 3)
+	suite.Equal(int32(2), report.Imported)
+	suite.Equal(int32(3), report.Lines[1].Line)
+	suite.Equal(ImportLineFailed, report.Lines[1].Status)
+	suite.Contains(report.Lines[1].Errors[0], "bare \"")
+	suite.Equal(int32(4), report.Lines[2].Line)
+}
+
+func (suite *BulkImportSuite) Test_Import_RejectsUnknownColumnAndContentType() {
+	ctx, recorder := suite.request("/api/ambulances:import", "text/csv", "id,iban\namb2,SK00\n")
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+
+	ctx, recorder = suite.request("/api/ambulances:import", "application/json", "[]")
+	(&implAmbulanceAPI{}).ImportAmbulances(ctx)
+	suite.Equal(http.StatusUnsupportedMediaType, recorder.Code)
+
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "ListDocuments", mock.Anything)
+}
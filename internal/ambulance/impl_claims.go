@@ -0,0 +1,611 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// Claim statuses.
+const (
+	ClaimStatusDraft             = "draft"
+	ClaimStatusSubmitted         = "submitted"
+	ClaimStatusAccepted          = "accepted"
+	ClaimStatusPartiallyAccepted = "partially_accepted"
+	ClaimStatusRejected          = "rejected"
+	ClaimStatusPaid              = "paid"
+)
+
+// Claim line decisions.
+const (
+	ClaimLinePending  = "pending"
+	ClaimLineAccepted = "accepted"
+	ClaimLineRejected = "rejected"
+)
+
+// agingBuckets are the upper bounds in days of the aging buckets; anything older falls into 90+.
+var agingBuckets = []struct {
+	name    string
+	maxDays int
+}{
+	{"0-30", 30},
+	{"31-60", 60},
+	{"61-90", 90},
+}
+
+// implClaimsAPI implements the ClaimsAPI interface.
+type implClaimsAPI struct{}
+
+// NewClaimsAPI returns an implementation of ClaimsAPI.
+func NewClaimsAPI() ClaimsAPI {
+	return &implClaimsAPI{}
+}
+
+// getClaimDB extracts the DbService[Claim] from the context.
+func getClaimDB(c *gin.Context) db_service.DbService[Claim] {
+	return c.MustGet("db_service_claim").(db_service.DbService[Claim])
+}
+
+// withClaimByID loads a Claim and calls fn; fn may return an updated doc.
+func withClaimByID(
+	c *gin.Context,
+	fn func(*gin.Context, *Claim) (*Claim, interface{}, int),
+) {
+	id := c.Param("claimId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "claimId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getClaimDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		claim, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Claim not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, claim)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update claim"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// claimsProcedure reports whether the claim still claims the procedure: the claim was
+// not rejected and the procedure's line was not rejected.
+func claimsProcedure(claim *Claim, procedureId string) bool {
+	if claim.Status == ClaimStatusRejected {
+		return false
+	}
+	for _, line := range claim.Lines {
+		if line.ProcedureId == procedureId && line.Status != ClaimLineRejected {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareClaim checks the claimed procedures and fills in the claimed amounts. Every
+// procedure must have the claim's insurer as payer, must not be cancelled and must not
+// be claimed by another claim. It returns a validation problem and its status, if any.
+func prepareClaim(ctx context.Context, c *gin.Context, claim *Claim) (string, int, error) {
+	claim.Insurer = strings.TrimSpace(claim.Insurer)
+	if claim.Insurer == "" {
+		return "insurer is required", http.StatusUnprocessableEntity, nil
+	}
+	if len(claim.Lines) == 0 {
+		return "lines must list at least one procedure", http.StatusUnprocessableEntity, nil
+	}
+
+	claims, err := getClaimDB(c).ListDocuments(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	seen := map[string]bool{}
+	amounts := make([]money.Money, 0, len(claim.Lines))
+	for i := range claim.Lines {
+		line := &claim.Lines[i]
+		if seen[line.ProcedureId] {
+			return "procedure " + line.ProcedureId + " is listed twice", http.StatusUnprocessableEntity, nil
+		}
+		seen[line.ProcedureId] = true
+
+		procedure, err := getProcedureDB(c).FindDocument(ctx, line.ProcedureId)
+		if err == db_service.ErrNotFound {
+			return "procedure " + line.ProcedureId + " does not exist", http.StatusUnprocessableEntity, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if normalizeName(procedure.Payer) != normalizeName(claim.Insurer) {
+			return "procedure " + line.ProcedureId + " is not paid by " + claim.Insurer, http.StatusUnprocessableEntity, nil
+		}
+		if status := procedureStatus(procedure); status == ProcedureStatusCancelled || status == ProcedureStatusScheduled {
+			return "procedure " + line.ProcedureId + " is " + status + " and cannot be claimed", http.StatusUnprocessableEntity, nil
+		}
+		for j := range claims {
+			if claims[j].Id != claim.Id && claimsProcedure(&claims[j], line.ProcedureId) {
+				return "procedure " + line.ProcedureId + " is already claimed by claim " + claims[j].Id, http.StatusConflict, nil
+			}
+		}
+
+		*line = ClaimLine{ProcedureId: line.ProcedureId, Amount: procedure.Price, Status: ClaimLinePending}
+		amounts = append(amounts, procedure.Price)
+	}
+
+	total, err := money.Sum(amounts...)
+	if err != nil {
+		return "the procedures are priced in different currencies", http.StatusUnprocessableEntity, nil
+	}
+	claim.Total = total
+	return "", 0, nil
+}
+
+// decideClaimLines applies the insurer's decision to the lines of a submitted claim and
+// sets the claim status. Every line needs a decision; rejected lines need a reason and
+// accepted amounts must not exceed the claimed ones. It returns a validation problem, if any.
+func decideClaimLines(claim *Claim, decision *ClaimDecision, now time.Time) string {
+	decisions := map[string]ClaimLineDecision{}
+	for _, d := range decision.Lines {
+		decisions[d.ProcedureId] = d
+	}
+	if len(decisions) != len(claim.Lines) || len(decision.Lines) != len(claim.Lines) {
+		return "lines must hold exactly one decision for every line of the claim"
+	}
+
+	accepted, rejected, reduced := 0, 0, false
+	acceptedTotal := money.Money{Currency: claim.Total.Currency}
+	for i := range claim.Lines {
+		line := &claim.Lines[i]
+		d, ok := decisions[line.ProcedureId]
+		if !ok {
+			return "no decision for procedure " + line.ProcedureId
+		}
+		if !d.Accepted {
+			if strings.TrimSpace(d.RejectionReason) == "" {
+				return "rejection_reason is required for procedure " + line.ProcedureId
+			}
+			line.Status = ClaimLineRejected
+			line.RejectionReason = strings.TrimSpace(d.RejectionReason)
+			rejected++
+			continue
+		}
+
+		amount := line.Amount
+		if d.AcceptedAmount != nil {
+			amount = *d.AcceptedAmount
+		}
+		remainder, err := line.Amount.Sub(amount)
+		if err != nil {
+			return "accepted_amount must be in the currency of the claim for procedure " + line.ProcedureId
+		}
+		if amount.IsNegative() || remainder.IsNegative() {
+			return "accepted_amount must be between zero and the claimed amount for procedure " + line.ProcedureId
+		}
+		reduced = reduced || !remainder.IsZero()
+		line.Status = ClaimLineAccepted
+		line.AcceptedAmount = &amount
+		acceptedTotal, _ = acceptedTotal.Add(amount)
+		accepted++
+	}
+
+	switch {
+	case accepted == 0:
+		claim.Status = ClaimStatusRejected
+	case rejected > 0 || reduced:
+		claim.Status = ClaimStatusPartiallyAccepted
+	default:
+		claim.Status = ClaimStatusAccepted
+	}
+	claim.AcceptedTotal = &acceptedTotal
+	claim.DecidedAt = &now
+	return ""
+}
+
+// agingBucket returns the aging bucket of an amount outstanding for the given number of days.
+func agingBucket(days int) string {
+	for _, bucket := range agingBuckets {
+		if days <= bucket.maxDays {
+			return bucket.name
+		}
+	}
+	return "90+"
+}
+
+// computeClaimAging totals the submitted claims not paid or rejected yet per insurer
+// and age since submission.
+func computeClaimAging(claims []Claim, now time.Time) ([]ClaimAgingRow, error) {
+	rows := map[[2]string]*ClaimAgingRow{}
+	for _, claim := range claims {
+		switch claim.Status {
+		case ClaimStatusSubmitted, ClaimStatusAccepted, ClaimStatusPartiallyAccepted:
+		default:
+			continue
+		}
+		if claim.SubmittedAt == nil {
+			continue
+		}
+		bucket := agingBucket(int(now.Sub(*claim.SubmittedAt).Hours() / 24))
+		key := [2]string{claim.Insurer, bucket}
+		row, ok := rows[key]
+		if !ok {
+			row = &ClaimAgingRow{Insurer: claim.Insurer, Bucket: bucket}
+			rows[key] = row
+		}
+
+		outstanding := claim.Total
+		if claim.AcceptedTotal != nil {
+			outstanding = *claim.AcceptedTotal
+		}
+		amount, err := row.Amount.Add(outstanding)
+		if err != nil {
+			return nil, err
+		}
+		row.Amount = amount
+		row.Claims++
+	}
+
+	order := map[string]int{"90+": len(agingBuckets)}
+	for i, bucket := range agingBuckets {
+		order[bucket.name] = i
+	}
+	result := make([]ClaimAgingRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Insurer != result[j].Insurer {
+			return result[i].Insurer < result[j].Insurer
+		}
+		return order[result[i].Bucket] < order[result[j].Bucket]
+	})
+	return result, nil
+}
+
+// computeClaimRejections counts decided and rejected claim lines and rejection reasons per insurer.
+func computeClaimRejections(claims []Claim) []ClaimRejectionStats {
+	stats := map[string]*ClaimRejectionStats{}
+	reasons := map[string]map[string]int32{}
+	for _, claim := range claims {
+		if claim.DecidedAt == nil {
+			continue
+		}
+		s, ok := stats[claim.Insurer]
+		if !ok {
+			s = &ClaimRejectionStats{Insurer: claim.Insurer}
+			stats[claim.Insurer] = s
+			reasons[claim.Insurer] = map[string]int32{}
+		}
+		for _, line := range claim.Lines {
+			s.LinesDecided++
+			if line.Status == ClaimLineRejected {
+				s.LinesRejected++
+				reasons[claim.Insurer][line.RejectionReason]++
+			}
+		}
+	}
+
+	result := make([]ClaimRejectionStats, 0, len(stats))
+	for insurer, s := range stats {
+		if s.LinesDecided > 0 {
+			s.RejectionRate = float64(s.LinesRejected) / float64(s.LinesDecided)
+		}
+		s.Reasons = make([]ClaimRejectionReason, 0, len(reasons[insurer]))
+		for reason, count := range reasons[insurer] {
+			s.Reasons = append(s.Reasons, ClaimRejectionReason{Reason: reason, Count: count})
+		}
+		sort.Slice(s.Reasons, func(i, j int) bool {
+			if s.Reasons[i].Count != s.Reasons[j].Count {
+				return s.Reasons[i].Count > s.Reasons[j].Count
+			}
+			return s.Reasons[i].Reason < s.Reasons[j].Reason
+		})
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Insurer < result[j].Insurer
+	})
+	return result
+}
+
+// CreateClaim implements POST /api/claims
+//
+// The claim starts as a draft; the claimed amounts are the procedure prices.
+func (o *implClaimsAPI) CreateClaim(c *gin.Context) {
+	var claim Claim
+	if err := c.ShouldBindJSON(&claim); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if claim.Id == "" {
+		claim.Id = uuid.NewString()
+	}
+	claim.Status = ClaimStatusDraft
+	claim.CreatedAt = time.Now()
+	claim.AcceptedTotal, claim.SubmittedAt, claim.DecidedAt, claim.PaidAt = nil, nil, nil, nil
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if problem, status, err := prepareClaim(ctx, c, &claim); err != nil {
+		log.Println("prepareClaim error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create claim"})
+		return
+	} else if problem != "" {
+		c.JSON(status, gin.H{"message": problem})
+		return
+	}
+
+	if err := getClaimDB(c).CreateDocument(ctx, claim.Id, &claim); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Claim already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create claim"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, claim)
+}
+
+// GetClaimById implements GET /api/claims/:claimId
+func (o *implClaimsAPI) GetClaimById(c *gin.Context) {
+	withClaimByID(c, func(_ *gin.Context, claim *Claim) (*Claim, interface{}, int) {
+		return nil, claim, http.StatusOK
+	})
+}
+
+// GetClaims implements GET /api/claims
+//
+// Claims are returned newest first, optionally only those of ?insurer= in ?status=.
+func (o *implClaimsAPI) GetClaims(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	claims, err := getClaimDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving claims:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve claims"})
+		return
+	}
+
+	status, insurer := strings.ToLower(c.Query("status")), normalizeName(c.Query("insurer"))
+	selected := make([]Claim, 0, len(claims))
+	for _, claim := range claims {
+		if (status == "" || claim.Status == status) && (insurer == "" || normalizeName(claim.Insurer) == insurer) {
+			selected = append(selected, claim)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].CreatedAt.After(selected[j].CreatedAt)
+	})
+	c.JSON(http.StatusOK, selected)
+}
+
+// UpdateClaim implements PUT /api/claims/:claimId
+//
+// Only draft claims can change.
+func (o *implClaimsAPI) UpdateClaim(c *gin.Context) {
+	withClaimByID(c, func(c *gin.Context, existing *Claim) (*Claim, interface{}, int) {
+		if existing.Status != ClaimStatusDraft {
+			return nil, gin.H{"message": "Only draft claims can be changed; the claim is " + existing.Status}, http.StatusConflict
+		}
+		var upd Claim
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.Insurer != "" {
+			existing.Insurer = upd.Insurer
+		}
+		if upd.Lines != nil {
+			existing.Lines = upd.Lines
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if problem, status, err := prepareClaim(ctx, c, existing); err != nil {
+			log.Println("prepareClaim error:", err)
+			return nil, gin.H{"message": "Failed to update claim"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, status
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteClaim implements DELETE /api/claims/:claimId
+//
+// Only draft claims can be deleted.
+func (o *implClaimsAPI) DeleteClaim(c *gin.Context) {
+	withClaimByID(c, func(c *gin.Context, claim *Claim) (*Claim, interface{}, int) {
+		if claim.Status != ClaimStatusDraft {
+			return nil, gin.H{"message": "Only draft claims can be deleted; the claim is " + claim.Status}, http.StatusConflict
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := getClaimDB(c).DeleteDocument(ctx, claim.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete claim"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// SubmitClaim implements POST /api/claims/:claimId/submission
+func (o *implClaimsAPI) SubmitClaim(c *gin.Context) {
+	withClaimByID(c, func(c *gin.Context, claim *Claim) (*Claim, interface{}, int) {
+		if claim.Status != ClaimStatusDraft {
+			return nil, gin.H{"message": "Only draft claims can be submitted; the claim is " + claim.Status}, http.StatusConflict
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		// the procedures may have changed since the draft was saved
+		if problem, status, err := prepareClaim(ctx, c, claim); err != nil {
+			log.Println("prepareClaim error:", err)
+			return nil, gin.H{"message": "Failed to submit claim"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, status
+		}
+		now := time.Now()
+		claim.Status = ClaimStatusSubmitted
+		claim.SubmittedAt = &now
+		return claim, claim, http.StatusOK
+	})
+}
+
+// DecideClaim implements POST /api/claims/:claimId/decision
+//
+// A pending payment from the insurer is created for every accepted line; it settles
+// when the claim is paid.
+func (o *implClaimsAPI) DecideClaim(c *gin.Context) {
+	withClaimByID(c, func(c *gin.Context, claim *Claim) (*Claim, interface{}, int) {
+		if claim.Status != ClaimStatusSubmitted {
+			return nil, gin.H{"message": "Only submitted claims can be decided; the claim is " + claim.Status}, http.StatusConflict
+		}
+		var decision ClaimDecision
+		if err := c.ShouldBindJSON(&decision); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		now := time.Now()
+		if problem := decideClaimLines(claim, &decision, now); problem != "" {
+			return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		for i := range claim.Lines {
+			line := &claim.Lines[i]
+			if line.Status != ClaimLineAccepted || line.AcceptedAmount.IsZero() {
+				continue
+			}
+			payment := Payment{
+				Id:          uuid.NewString(),
+				Name:        "Claim " + claim.Id,
+				ProcedureId: line.ProcedureId,
+				Insurance:   claim.Insurer,
+				Amount:      *line.AcceptedAmount,
+				Status:      PaymentStatusPending,
+				ClaimId:     claim.Id,
+				Timestamp:   now,
+			}
+			if err := getPaymentDB(c).CreateDocument(ctx, payment.Id, &payment); err != nil {
+				log.Println("CreateDocument error:", err)
+				return nil, gin.H{"message": "Failed to record claim payments"}, http.StatusInternalServerError
+			}
+			line.PaymentId = payment.Id
+		}
+		return claim, claim, http.StatusOK
+	})
+}
+
+// SettleClaim implements POST /api/claims/:claimId/settlement
+//
+// The payments created on acceptance become settled.
+func (o *implClaimsAPI) SettleClaim(c *gin.Context) {
+	withClaimByID(c, func(c *gin.Context, claim *Claim) (*Claim, interface{}, int) {
+		if claim.Status != ClaimStatusAccepted && claim.Status != ClaimStatusPartiallyAccepted {
+			return nil, gin.H{"message": "Only accepted claims can be paid; the claim is " + claim.Status}, http.StatusConflict
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		paymentDb := getPaymentDB(c)
+		for _, line := range claim.Lines {
+			if line.PaymentId == "" {
+				continue
+			}
+			payment, err := paymentDb.FindDocument(ctx, line.PaymentId)
+			if err != nil {
+				log.Println("FindDocument error:", err)
+				return nil, gin.H{"message": fmt.Sprintf("Failed to settle payment %v", line.PaymentId)}, http.StatusInternalServerError
+			}
+			if paymentStatus(payment) == PaymentStatusSettled {
+				continue
+			}
+			payment.Status = PaymentStatusSettled
+			payment.Timestamp = now
+			if err := paymentDb.UpdateDocument(ctx, payment.Id, payment); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return nil, gin.H{"message": fmt.Sprintf("Failed to settle payment %v", line.PaymentId)}, http.StatusInternalServerError
+			}
+		}
+		claim.Status = ClaimStatusPaid
+		claim.PaidAt = &now
+		return claim, claim, http.StatusOK
+	})
+}
+
+// GetClaimAgingReport implements GET /api/reports/claims/aging
+//
+// Claims submitted but not yet paid or rejected are grouped by insurer and days since
+// submission, as of ?as_of= (default today).
+func (o *implClaimsAPI) GetClaimAgingReport(c *gin.Context) {
+	asOf := time.Now()
+	if value := c.Query("as_of"); value != "" {
+		date, err := time.ParseInLocation(time.DateOnly, value, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "as_of must be in YYYY-MM-DD format"})
+			return
+		}
+		asOf = date.AddDate(0, 0, 1)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	claims, err := getClaimDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute claims aging"})
+		return
+	}
+	rows, err := computeClaimAging(claims, asOf)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "Failed to compute claims aging", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// GetClaimRejectionReport implements GET /api/reports/claims/rejections
+func (o *implClaimsAPI) GetClaimRejectionReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	claims, err := getClaimDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute claim rejections"})
+		return
+	}
+	c.JSON(http.StatusOK, computeClaimRejections(claims))
+}
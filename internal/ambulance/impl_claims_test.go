@@ -0,0 +1,168 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// ClaimsSuite defines the suite for insurance claim tests
+type ClaimsSuite struct {
+	suite.Suite
+	claimDbMock     *DbServiceMock[Claim]
+	procedureDbMock *DbServiceMock[Procedure]
+	paymentDbMock   *DbServiceMock[Payment]
+	claim           Claim
+}
+
+func TestClaimsSuite(t *testing.T) {
+	suite.Run(t, new(ClaimsSuite))
+}
+
+func (suite *ClaimsSuite) SetupTest() {
+	suite.claim = Claim{
+		Id:      "claim1",
+		Insurer: "Poisťovňa XYZ",
+		Status:  ClaimStatusSubmitted,
+		Lines: []ClaimLine{
+			{ProcedureId: "proc001", Amount: eur("120"), Status: ClaimLinePending},
+			{ProcedureId: "proc002", Amount: eur("80"), Status: ClaimLinePending},
+		},
+		Total: eur("200"),
+	}
+	suite.claimDbMock = &DbServiceMock[Claim]{}
+	suite.claimDbMock.
+		On("FindDocument", mock.Anything, "claim1").
+		Return(&suite.claim, nil)
+	suite.claimDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Claim{suite.claim}, nil)
+	suite.claimDbMock.
+		On("UpdateDocument", mock.Anything, "claim1", mock.Anything).
+		Return(nil)
+	suite.claimDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&Procedure{Id: "proc001", Payer: "poistovna xyz", Price: eur("120")}, nil)
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc003").
+		Return(&Procedure{Id: "proc003", Payer: "Union", Price: eur("60")}, nil)
+
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+}
+
+func (suite *ClaimsSuite) request(method, path, payload string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_claim", suite.claimDbMock)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Params = []gin.Param{{Key: "claimId", Value: "claim1"}}
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	return ctx, recorder
+}
+
+func (suite *ClaimsSuite) Test_CreateClaim_RejectsProcedureOfOtherPayer() {
+	ctx, recorder := suite.request("POST", "/api/claims", `{"insurer": "Poisťovňa XYZ", "lines": [{"procedure_id": "proc003"}]}`)
+
+	(&implClaimsAPI{}).CreateClaim(ctx)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.claimDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ClaimsSuite) Test_CreateClaim_RejectsProcedureClaimedElsewhere() {
+	ctx, recorder := suite.request("POST", "/api/claims", `{"insurer": "Poisťovňa XYZ", "lines": [{"procedure_id": "proc001"}]}`)
+
+	(&implClaimsAPI{}).CreateClaim(ctx)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+}
+
+func (suite *ClaimsSuite) Test_DecideClaim_CreatesPendingPaymentsForAcceptedLines() {
+	ctx, recorder := suite.request("POST", "/api/claims/claim1/decision", `{"lines": [
+		{"procedure_id": "proc001", "accepted": true, "accepted_amount": "100"},
+		{"procedure_id": "proc002", "accepted": false, "rejection_reason": "Missing referral"}
+	]}`)
+
+	(&implClaimsAPI{}).DecideClaim(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(ClaimStatusPartiallyAccepted, suite.claim.Status)
+	suite.Equal(eur("100"), *suite.claim.AcceptedTotal)
+	suite.paymentDbMock.AssertNumberOfCalls(suite.T(), "CreateDocument", 1)
+	suite.paymentDbMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.MatchedBy(func(p *Payment) bool {
+		return p.ProcedureId == "proc001" && p.ClaimId == "claim1" && p.Status == PaymentStatusPending && p.Amount.Equal(eur("100"))
+	}))
+	suite.NotEmpty(suite.claim.Lines[0].PaymentId)
+}
+
+func (suite *ClaimsSuite) Test_DecideClaimLines_RequiresReasonForRejection() {
+	problem := decideClaimLines(&suite.claim, &ClaimDecision{Lines: []ClaimLineDecision{
+		{ProcedureId: "proc001", Accepted: true},
+		{ProcedureId: "proc002"},
+	}}, time.Now())
+
+	suite.Contains(problem, "rejection_reason")
+}
+
+func (suite *ClaimsSuite) Test_ComputeClaimAging_BucketsByDaysSinceSubmission() {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	submitted := func(days int) *time.Time {
+		at := now.AddDate(0, 0, -days)
+		return &at
+	}
+	accepted := eur("50")
+	claims := []Claim{
+		{Insurer: "Union", Status: ClaimStatusSubmitted, SubmittedAt: submitted(10), Total: eur("100")},
+		{Insurer: "Union", Status: ClaimStatusPartiallyAccepted, SubmittedAt: submitted(20), Total: eur("80"), AcceptedTotal: &accepted},
+		{Insurer: "Union", Status: ClaimStatusSubmitted, SubmittedAt: submitted(95), Total: eur("30")},
+		{Insurer: "Union", Status: ClaimStatusPaid, SubmittedAt: submitted(40), Total: eur("999")},
+	}
+
+	rows, err := computeClaimAging(claims, now)
+
+	suite.NoError(err)
+	suite.Equal([]ClaimAgingRow{
+		{Insurer: "Union", Bucket: "0-30", Claims: 2, Amount: eur("150")},
+		{Insurer: "Union", Bucket: "90+", Claims: 1, Amount: eur("30")},
+	}, rows)
+}
+
+func (suite *ClaimsSuite) Test_ComputeClaimRejections_CountsReasons() {
+	now := time.Now()
+	claims := []Claim{
+		{Insurer: "Union", DecidedAt: &now, Lines: []ClaimLine{
+			{Status: ClaimLineRejected, RejectionReason: "Missing referral"},
+			{Status: ClaimLineAccepted},
+		}},
+		{Insurer: "Union", DecidedAt: &now, Lines: []ClaimLine{
+			{Status: ClaimLineRejected, RejectionReason: "Missing referral"},
+			{Status: ClaimLineRejected, RejectionReason: "Not covered"},
+		}},
+		{Insurer: "Union", Status: ClaimStatusSubmitted, Lines: []ClaimLine{{Status: ClaimLinePending}}},
+	}
+
+	stats := computeClaimRejections(claims)
+
+	suite.Require().Len(stats, 1)
+	suite.Equal(int32(4), stats[0].LinesDecided)
+	suite.Equal(int32(3), stats[0].LinesRejected)
+	suite.InDelta(0.75, stats[0].RejectionRate, 0.0001)
+	suite.Equal([]ClaimRejectionReason{{Reason: "Missing referral", Count: 2}, {Reason: "Not covered", Count: 1}}, stats[0].Reasons)
+}
@@ -0,0 +1,337 @@
+package ambulance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/blob_store"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// MaxAttachmentSize is the largest attachment accepted, in bytes.
+var MaxAttachmentSize int64 = 20 << 20
+
+// attachmentTypes lists the media types attachments may have.
+var attachmentTypes = map[string]bool{
+	"image/jpeg":        true,
+	"image/png":         true,
+	"application/pdf":   true,
+	"application/dicom": true,
+	"text/plain":        true,
+}
+
+// attachmentTimeout bounds uploads, downloads and checksum verification, which transfer whole files.
+const attachmentTimeout = 5 * time.Minute
+
+// implClinicalRecordsAPI implements the ClinicalRecordsAPI interface.
+type implClinicalRecordsAPI struct{}
+
+// NewClinicalRecordsAPI returns an implementation of ClinicalRecordsAPI.
+func NewClinicalRecordsAPI() ClinicalRecordsAPI {
+	return &implClinicalRecordsAPI{}
+}
+
+// getBlobStore extracts the BlobStore keeping attachment content from the context.
+func getBlobStore(c *gin.Context) blob_store.BlobStore {
+	return c.MustGet("blob_store").(blob_store.BlobStore)
+}
+
+// attachmentKey is the blob store key of an attachment's content.
+func attachmentKey(procedureId, attachmentId string) string {
+	return "procedures/" + procedureId + "/" + attachmentId
+}
+
+// detectAttachmentType returns the media type of the content starting with head.
+// DICOM files are recognised by the "DICM" marker after their 128 byte preamble.
+func detectAttachmentType(head []byte) string {
+	if len(head) >= 132 && string(head[128:132]) == "DICM" {
+		return "application/dicom"
+	}
+	mediaType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	return mediaType
+}
+
+func findAttachment(p *Procedure, id string) int {
+	for i := range p.Attachments {
+		if p.Attachments[i].Id == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// removeAttachments deletes the stored content of all attachments of the procedure.
+// Content that cannot be removed is logged and left behind.
+func removeAttachments(ctx context.Context, store blob_store.BlobStore, p *Procedure) {
+	for _, a := range p.Attachments {
+		err := store.Delete(ctx, attachmentKey(p.Id, a.Id))
+		if err != nil && err != blob_store.ErrNotFound {
+			log.Println("Delete attachment error:", err)
+		}
+	}
+}
+
+// loadAttachment finds the procedure and the attachment named by the path and opens its
+// content. It writes the error response itself and returns false on failure.
+func loadAttachment(ctx context.Context, c *gin.Context) (*Attachment, blob_store.Blob, bool) {
+	p, err := getProcedureDB(c).FindDocument(ctx, c.Param("procedureId"))
+	if err == db_service.ErrNotFound {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Procedure not found"})
+		return nil, nil, false
+	} else if err != nil {
+		log.Println("FindDocument error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+		return nil, nil, false
+	}
+	i := findAttachment(p, c.Param("attachmentId"))
+	if i < 0 {
+		c.JSON(http.StatusNotFound, gin.H{"message": "Attachment not found"})
+		return nil, nil, false
+	}
+
+	blob, err := getBlobStore(c).Open(ctx, attachmentKey(p.Id, p.Attachments[i].Id))
+	if err != nil {
+		log.Println("Open attachment error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Attachment content is unavailable"})
+		return nil, nil, false
+	}
+	return &p.Attachments[i], blob, true
+}
+
+// UploadAttachment implements POST /api/procedures/:procedureId/attachments
+//
+// The multipart form carries the file in "file", and optionally a "description" and the
+// hex SHA-256 checksum of the file in "sha256" to verify the upload against.
+func (o *implClinicalRecordsAPI) UploadAttachment(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if result, status := checkProcedureEditable(c, p); result != nil {
+			return nil, result, status
+		}
+
+		// leave room for the multipart framing and the other form fields
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxAttachmentSize+1<<20)
+		header, err := c.FormFile("file")
+		if err != nil {
+			var tooLarge *http.MaxBytesError
+			if errors.As(err, &tooLarge) {
+				return nil, gin.H{"message": "The attachment is too large"}, http.StatusRequestEntityTooLarge
+			}
+			return nil, gin.H{"message": "file is required", "error": err.Error()}, http.StatusBadRequest
+		}
+		if header.Size > MaxAttachmentSize {
+			return nil, gin.H{"message": "The attachment is too large"}, http.StatusRequestEntityTooLarge
+		}
+		file, err := header.Open()
+		if err != nil {
+			log.Println("Open upload error:", err)
+			return nil, gin.H{"message": "Failed to upload attachment"}, http.StatusInternalServerError
+		}
+		defer file.Close()
+
+		head := make([]byte, 512)
+		n, err := io.ReadFull(file, head)
+		if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+			log.Println("Read upload error:", err)
+			return nil, gin.H{"message": "Failed to upload attachment"}, http.StatusInternalServerError
+		}
+		head = head[:n]
+		contentType := detectAttachmentType(head)
+		if !attachmentTypes[contentType] {
+			return nil, gin.H{"message": "Attachments must be JPEG or PNG images, PDF, DICOM or plain text files, got " + contentType}, http.StatusUnsupportedMediaType
+		}
+
+		attachment := Attachment{
+			Id:          uuid.NewString(),
+			FileName:    path.Base(strings.ReplaceAll(header.Filename, "\\", "/")),
+			ContentType: contentType,
+			Description: c.PostForm("description"),
+			UploadedAt:  time.Now(),
+			UploadedBy:  userRole(c),
+		}
+
+		ctx, cancel := context.WithTimeout(outsideTransaction(c), attachmentTimeout)
+		defer cancel()
+
+		store := getBlobStore(c)
+		key := attachmentKey(p.Id, attachment.Id)
+		hash := sha256.New()
+		size, err := store.Put(ctx, key, io.TeeReader(io.MultiReader(bytes.NewReader(head), file), hash))
+		if err != nil {
+			log.Println("Put attachment error:", err)
+			return nil, gin.H{"message": "Failed to store attachment"}, http.StatusInternalServerError
+		}
+		attachment.Size = size
+		attachment.Sha256 = hex.EncodeToString(hash.Sum(nil))
+
+		if expected := strings.TrimSpace(c.PostForm("sha256")); expected != "" && !strings.EqualFold(expected, attachment.Sha256) {
+			if err := store.Delete(ctx, key); err != nil {
+				log.Println("Delete attachment error:", err)
+			}
+			return nil, gin.H{"message": "Checksum mismatch, the upload is corrupted", "sha256": attachment.Sha256}, http.StatusUnprocessableEntity
+		}
+
+		p.Attachments = append(p.Attachments, attachment)
+		dbCtx, dbCancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer dbCancel()
+		if err := getProcedureDB(c).UpdateDocument(dbCtx, p.Id, p); err != nil {
+			log.Println("UpdateDocument error:", err)
+			// the content of an attachment the procedure does not list is never served
+			if err := store.Delete(ctx, key); err != nil {
+				log.Println("Delete attachment error:", err)
+			}
+			return nil, gin.H{"message": "Failed to upload attachment"}, http.StatusInternalServerError
+		}
+		return nil, attachment, http.StatusCreated
+	})
+}
+
+// GetAttachments implements GET /api/procedures/:procedureId/attachments
+func (o *implClinicalRecordsAPI) GetAttachments(c *gin.Context) {
+	withProcedureByID(c, func(_ *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if p.Attachments == nil {
+			return nil, []Attachment{}, http.StatusOK
+		}
+		return nil, p.Attachments, http.StatusOK
+	})
+}
+
+// DownloadAttachment implements GET /api/procedures/:procedureId/attachments/:attachmentId
+//
+// The content is streamed and supports range requests; the ETag is the SHA-256 checksum.
+func (o *implClinicalRecordsAPI) DownloadAttachment(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), attachmentTimeout)
+	defer cancel()
+
+	attachment, blob, ok := loadAttachment(ctx, c)
+	if !ok {
+		return
+	}
+	defer blob.Close()
+
+	if blob.Size() != attachment.Size {
+		log.Printf("Attachment %v has %d bytes stored, expected %d", attachment.Id, blob.Size(), attachment.Size)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Attachment content is corrupted"})
+		return
+	}
+
+	c.Header("Content-Type", attachment.ContentType)
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": attachment.FileName}))
+	c.Header("ETag", `"`+attachment.Sha256+`"`)
+	http.ServeContent(c.Writer, c.Request, attachment.FileName, attachment.UploadedAt, blob)
+}
+
+// VerifyAttachment implements GET /api/procedures/:procedureId/attachments/:attachmentId/checksum
+func (o *implClinicalRecordsAPI) VerifyAttachment(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), attachmentTimeout)
+	defer cancel()
+
+	attachment, blob, ok := loadAttachment(ctx, c)
+	if !ok {
+		return
+	}
+	defer blob.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, blob); err != nil {
+		log.Println("Read attachment error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to verify attachment"})
+		return
+	}
+	actual := hex.EncodeToString(hash.Sum(nil))
+	c.JSON(http.StatusOK, AttachmentChecksum{
+		AttachmentId: attachment.Id,
+		Expected:     attachment.Sha256,
+		Actual:       actual,
+		Valid:        actual == attachment.Sha256,
+	})
+}
+
+// DeleteAttachment implements DELETE /api/procedures/:procedureId/attachments/:attachmentId
+func (o *implClinicalRecordsAPI) DeleteAttachment(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if result, status := checkProcedureEditable(c, p); result != nil {
+			return nil, result, status
+		}
+		i := findAttachment(p, c.Param("attachmentId"))
+		if i < 0 {
+			return nil, gin.H{"message": "Attachment not found"}, http.StatusNotFound
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		key := attachmentKey(p.Id, p.Attachments[i].Id)
+		p.Attachments = append(p.Attachments[:i], p.Attachments[i+1:]...)
+		if err := getProcedureDB(c).UpdateDocument(ctx, p.Id, p); err != nil {
+			log.Println("UpdateDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete attachment"}, http.StatusInternalServerError
+		}
+		// the content goes only once the procedure no longer lists it
+		afterCommit(c, func() {
+			ctx, cancel := context.WithTimeout(outsideTransaction(c), 10*time.Second)
+			defer cancel()
+			if err := getBlobStore(c).Delete(ctx, key); err != nil && err != blob_store.ErrNotFound {
+				log.Println("Delete attachment error:", err)
+			}
+		})
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// CreateProcedureNote implements POST /api/procedures/:procedureId/notes
+func (o *implClinicalRecordsAPI) CreateProcedureNote(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if result, status := checkProcedureEditable(c, p); result != nil {
+			return nil, result, status
+		}
+		var note ClinicalNote
+		if err := c.ShouldBindJSON(&note); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if strings.TrimSpace(note.Text) == "" {
+			return nil, gin.H{"message": "text is required"}, http.StatusUnprocessableEntity
+		}
+		note.Id = uuid.NewString()
+		note.CreatedAt = time.Now()
+		p.Notes = append(p.Notes, note)
+		return p, note, http.StatusCreated
+	})
+}
+
+// GetProcedureNotes implements GET /api/procedures/:procedureId/notes
+func (o *implClinicalRecordsAPI) GetProcedureNotes(c *gin.Context) {
+	withProcedureByID(c, func(_ *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if p.Notes == nil {
+			return nil, []ClinicalNote{}, http.StatusOK
+		}
+		return nil, p.Notes, http.StatusOK
+	})
+}
+
+// DeleteProcedureNote implements DELETE /api/procedures/:procedureId/notes/:noteId
+func (o *implClinicalRecordsAPI) DeleteProcedureNote(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if result, status := checkProcedureEditable(c, p); result != nil {
+			return nil, result, status
+		}
+		for i, note := range p.Notes {
+			if note.Id == c.Param("noteId") {
+				p.Notes = append(p.Notes[:i], p.Notes[i+1:]...)
+				return p, nil, http.StatusNoContent
+			}
+		}
+		return nil, gin.H{"message": "Note not found"}, http.StatusNotFound
+	})
+}
@@ -0,0 +1,228 @@
+package ambulance
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/blob_store"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// ClinicalRecordsSuite defines the suite for procedure notes and attachments tests
+type ClinicalRecordsSuite struct {
+	suite.Suite
+	procedureDbMock *DbServiceMock[Procedure]
+	procedure       Procedure
+	store           blob_store.BlobStore
+	transactor      db_service.Transactor
+	png             []byte
+}
+
+func TestClinicalRecordsSuite(t *testing.T) {
+	suite.Run(t, new(ClinicalRecordsSuite))
+}
+
+func (suite *ClinicalRecordsSuite) SetupTest() {
+	store, err := blob_store.NewFileStore(suite.T().TempDir())
+	suite.Require().NoError(err)
+	suite.store = store
+	suite.transactor = nil
+	suite.png = append([]byte("\x89PNG\r\n\x1a\n"), bytes.Repeat([]byte{1}, 600)...)
+
+	suite.procedure = Procedure{Id: "proc001", Name: "Röntgen hrudníka", Status: ProcedureStatusCompleted}
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&suite.procedure, nil)
+	suite.procedureDbMock.
+		On("UpdateDocument", mock.Anything, "proc001", mock.Anything).
+		Return(nil)
+}
+
+func (suite *ClinicalRecordsSuite) context(req *http.Request, params ...gin.Param) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Set("blob_store", suite.store)
+	if suite.transactor != nil {
+		ctx.Set("db_transactor", suite.transactor)
+	}
+	ctx.Params = append([]gin.Param{{Key: "procedureId", Value: "proc001"}}, params...)
+	ctx.Request = req
+	return ctx, recorder
+}
+
+func (suite *ClinicalRecordsSuite) upload(content []byte, checksum string) *httptest.ResponseRecorder {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	part, _ := form.CreateFormFile("file", "chest.png")
+	part.Write(content)
+	if checksum != "" {
+		form.WriteField("sha256", checksum)
+	}
+	form.Close()
+
+	req := httptest.NewRequest("POST", "/api/procedures/proc001/attachments", body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	ctx, recorder := suite.context(req)
+	(&implClinicalRecordsAPI{}).UploadAttachment(ctx)
+	return recorder
+}
+
+func (suite *ClinicalRecordsSuite) Test_UploadAttachment_StoresContentWithChecksum() {
+	sum := sha256.Sum256(suite.png)
+	checksum := hex.EncodeToString(sum[:])
+
+	recorder := suite.upload(suite.png, checksum)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Require().Len(suite.procedure.Attachments, 1)
+	attachment := suite.procedure.Attachments[0]
+	suite.Equal("image/png", attachment.ContentType)
+	suite.Equal(checksum, attachment.Sha256)
+	blob, err := suite.store.Open(context.Background(), attachmentKey("proc001", attachment.Id))
+	suite.Require().NoError(err)
+	defer blob.Close()
+	suite.Equal(int64(len(suite.png)), blob.Size())
+}
+
+func (suite *ClinicalRecordsSuite) Test_UploadAttachment_ChecksumMismatch_DiscardsContent() {
+	recorder := suite.upload(suite.png, "00")
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.Empty(suite.procedure.Attachments)
+	suite.procedureDbMock.AssertNotCalled(suite.T(), "UpdateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ClinicalRecordsSuite) Test_UploadAttachment_RejectsUnsupportedType() {
+	recorder := suite.upload([]byte("PK\x03\x04 zipped archive"), "")
+
+	suite.Equal(http.StatusUnsupportedMediaType, recorder.Code)
+}
+
+func (suite *ClinicalRecordsSuite) Test_DownloadAttachment_ServesRange() {
+	suite.Require().Equal(http.StatusCreated, suite.upload(suite.png, "").Code)
+	attachmentId := suite.procedure.Attachments[0].Id
+
+	req := httptest.NewRequest("GET", "/api/procedures/proc001/attachments/"+attachmentId, nil)
+	req.Header.Set("Range", "bytes=1-3")
+	ctx, recorder := suite.context(req, gin.Param{Key: "attachmentId", Value: attachmentId})
+	(&implClinicalRecordsAPI{}).DownloadAttachment(ctx)
+
+	suite.Equal(http.StatusPartialContent, recorder.Code)
+	suite.Equal("PNG", recorder.Body.String())
+	suite.Equal("image/png", recorder.Header().Get("Content-Type"))
+}
+
+func (suite *ClinicalRecordsSuite) Test_DeleteProcedure_RemovesAttachments() {
+	suite.Require().Equal(http.StatusCreated, suite.upload(suite.png, "").Code)
+	key := attachmentKey("proc001", suite.procedure.Attachments[0].Id)
+	suite.procedureDbMock.
+		On("DeleteDocument", mock.Anything, "proc001").
+		Return(nil)
+
+	ctx, recorder := suite.context(httptest.NewRequest("DELETE", "/api/procedures/proc001", nil))
+	(&implProcedureAPI{}).DeleteProcedure(ctx)
+
+	suite.Equal(http.StatusNoContent, recorder.Code)
+	_, err := suite.store.Open(context.Background(), key)
+	suite.Equal(blob_store.ErrNotFound, err)
+}
+
+type sessionKey struct{}
+
+// sessionTransactor marks the contexts of its transactions, as the Mongo client puts its
+// session into them.
+type sessionTransactor struct{}
+
+func (sessionTransactor) RunInTransaction(ctx context.Context, fn func(context.Context) error) error {
+	if ctx.Value(sessionKey{}) != nil {
+		return fn(ctx)
+	}
+	return fn(context.WithValue(ctx, sessionKey{}, true))
+}
+
+// ownClientStore refuses contexts carrying a session, as the GridFS store with its own
+// client does.
+type ownClientStore struct {
+	blob_store.BlobStore
+}
+
+func (s ownClientStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if ctx.Value(sessionKey{}) != nil {
+		return 0, errors.New("session was not created by this client")
+	}
+	return s.BlobStore.Put(ctx, key, r)
+}
+
+func (s ownClientStore) Delete(ctx context.Context, key string) error {
+	if ctx.Value(sessionKey{}) != nil {
+		return errors.New("session was not created by this client")
+	}
+	return s.BlobStore.Delete(ctx, key)
+}
+
+func (suite *ClinicalRecordsSuite) Test_Attachments_StoredOutsideTransaction() {
+	suite.store = ownClientStore{suite.store}
+	suite.transactor = sessionTransactor{}
+
+	suite.Require().Equal(http.StatusCreated, suite.upload(suite.png, "").Code)
+	attachmentId := suite.procedure.Attachments[0].Id
+
+	req := httptest.NewRequest("DELETE", "/api/procedures/proc001/attachments/"+attachmentId, nil)
+	ctx, recorder := suite.context(req, gin.Param{Key: "attachmentId", Value: attachmentId})
+	(&implClinicalRecordsAPI{}).DeleteAttachment(ctx)
+
+	suite.Equal(http.StatusNoContent, recorder.Code)
+	suite.Empty(suite.procedure.Attachments)
+	_, err := suite.store.Open(context.Background(), attachmentKey("proc001", attachmentId))
+	suite.Equal(blob_store.ErrNotFound, err)
+}
+
+func (suite *ClinicalRecordsSuite) failUpdates() {
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&suite.procedure, nil)
+	suite.procedureDbMock.
+		On("UpdateDocument", mock.Anything, "proc001", mock.Anything).
+		Return(errors.New("connection lost"))
+}
+
+func (suite *ClinicalRecordsSuite) Test_UploadAttachment_DiscardsContentWhenUpdateFails() {
+	suite.failUpdates()
+
+	recorder := suite.upload(suite.png, "")
+
+	suite.Equal(http.StatusInternalServerError, recorder.Code)
+	suite.Require().Len(suite.procedure.Attachments, 1)
+	_, err := suite.store.Open(context.Background(), attachmentKey("proc001", suite.procedure.Attachments[0].Id))
+	suite.Equal(blob_store.ErrNotFound, err)
+}
+
+func (suite *ClinicalRecordsSuite) Test_DeleteAttachment_KeepsContentWhenUpdateFails() {
+	suite.Require().Equal(http.StatusCreated, suite.upload(suite.png, "").Code)
+	attachmentId := suite.procedure.Attachments[0].Id
+	suite.failUpdates()
+
+	req := httptest.NewRequest("DELETE", "/api/procedures/proc001/attachments/"+attachmentId, nil)
+	ctx, recorder := suite.context(req, gin.Param{Key: "attachmentId", Value: attachmentId})
+	(&implClinicalRecordsAPI{}).DeleteAttachment(ctx)
+
+	suite.Equal(http.StatusInternalServerError, recorder.Code)
+	blob, err := suite.store.Open(context.Background(), attachmentKey("proc001", attachmentId))
+	suite.Require().NoError(err)
+	blob.Close()
+}
@@ -0,0 +1,323 @@
+package ambulance
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// Crew roles recognised by assignments and certifications.
+const (
+	CrewRoleDriver    = "driver"
+	CrewRoleParamedic = "paramedic"
+	CrewRoleDoctor    = "doctor"
+	CrewRoleNurse     = "nurse"
+)
+
+// AmbulanceStatusDispatched is the status an ambulance takes when sent out on a call.
+const AmbulanceStatusDispatched = "Dispatched"
+
+// AmbulanceStatusAvailable is the status of an ambulance that can be sent out when on duty.
+const AmbulanceStatusAvailable = "Available"
+
+// implCrewAPI implements the CrewManagementAPI interface.
+type implCrewAPI struct{}
+
+// NewCrewAPI returns an implementation of CrewManagementAPI.
+func NewCrewAPI() CrewManagementAPI {
+	return &implCrewAPI{}
+}
+
+// getCrewDB extracts the DbService[CrewMember] from the context.
+func getCrewDB(c *gin.Context) db_service.DbService[CrewMember] {
+	return c.MustGet("db_service_crew").(db_service.DbService[CrewMember])
+}
+
+// isCrewRole reports whether role is one of the known crew roles.
+func isCrewRole(role string) bool {
+	switch role {
+	case CrewRoleDriver, CrewRoleParamedic, CrewRoleDoctor, CrewRoleNurse:
+		return true
+	}
+	return false
+}
+
+// validateCrewMember returns the problems found in a crew member record.
+func validateCrewMember(m *CrewMember) []string {
+	var problems []string
+	if strings.TrimSpace(m.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if !isCrewRole(m.Role) {
+		problems = append(problems, "role must be one of driver, paramedic, doctor, nurse")
+	}
+	for i, cert := range m.Certifications {
+		if strings.TrimSpace(cert.Name) == "" {
+			problems = append(problems, "certifications["+strconv.Itoa(i)+"].name is required")
+		}
+		if !isCrewRole(cert.Role) {
+			problems = append(problems, "certifications["+strconv.Itoa(i)+"].role must be one of driver, paramedic, doctor, nurse")
+		}
+		if cert.ExpiresAt.IsZero() {
+			problems = append(problems, "certifications["+strconv.Itoa(i)+"].expires_at is required")
+		}
+	}
+	return problems
+}
+
+// isQualified reports whether the crew member holds a certification for role valid at the given time.
+func (m *CrewMember) isQualified(role string, at time.Time) bool {
+	for _, cert := range m.Certifications {
+		if cert.Role != role {
+			continue
+		}
+		if !cert.IssuedAt.IsZero() && cert.IssuedAt.After(at) {
+			continue
+		}
+		if cert.ExpiresAt.After(at) {
+			return true
+		}
+	}
+	return false
+}
+
+// activeCrew returns the crew assignments of the ambulance whose shift covers the given time.
+func activeCrew(ambulance *Ambulance, at time.Time) []CrewAssignment {
+	var active []CrewAssignment
+	for _, a := range ambulance.Crew {
+		if !a.ShiftStart.After(at) && a.ShiftEnd.After(at) {
+			active = append(active, a)
+		}
+	}
+	return active
+}
+
+// validateDispatchCrew checks that the crew on shift includes a certified driver and paramedic.
+// It returns the list of problems preventing the dispatch; an empty list means the ambulance may go.
+func validateDispatchCrew(ctx context.Context, db db_service.DbService[CrewMember], crew []CrewAssignment, at time.Time) ([]string, error) {
+	qualified := map[string]bool{}
+	for _, a := range crew {
+		member, err := db.FindDocument(ctx, a.CrewMemberId)
+		if err == db_service.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		if member.isQualified(a.Role, at) {
+			qualified[a.Role] = true
+		}
+	}
+
+	var problems []string
+	for _, role := range []string{CrewRoleDriver, CrewRoleParamedic} {
+		if !qualified[role] {
+			problems = append(problems, "no "+role+" with a valid certification is on shift")
+		}
+	}
+	return problems, nil
+}
+
+// withCrewMemberByID loads a CrewMember and calls fn; fn may return an updated doc.
+func withCrewMemberByID(
+	c *gin.Context,
+	fn func(*gin.Context, *CrewMember) (*CrewMember, interface{}, int),
+) {
+	id := c.Param("crewMemberId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "crewMemberId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getCrewDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		member, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Crew member not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, member)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update crew member"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// CreateCrewMember implements POST /api/crew
+func (o *implCrewAPI) CreateCrewMember(c *gin.Context) {
+	var m CrewMember
+	if err := c.ShouldBindJSON(&m); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if problems := validateCrewMember(&m); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invalid crew member", "errors": problems})
+		return
+	}
+	if m.Id == "" {
+		m.Id = uuid.NewString()
+	}
+
+	db := getCrewDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := db.CreateDocument(ctx, m.Id, &m); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Crew member already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create crew member"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, m)
+}
+
+// GetCrewMemberById implements GET /api/crew/:crewMemberId
+func (o *implCrewAPI) GetCrewMemberById(c *gin.Context) {
+	withCrewMemberByID(c, func(_ *gin.Context, m *CrewMember) (*CrewMember, interface{}, int) {
+		return nil, m, http.StatusOK
+	})
+}
+
+// GetCrewMembers implements GET /api/crew
+func (o *implCrewAPI) GetCrewMembers(c *gin.Context) {
+	db := getCrewDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	role := c.Query("role")
+
+	var (
+		members any
+		err     error
+	)
+
+	if role != "" {
+		members, err = db.FindDocumentsByField(ctx, "role", role)
+	} else {
+		members, err = db.ListDocuments(ctx)
+	}
+
+	if err != nil {
+		log.Println("Error retrieving crew members:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve crew members"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// UpdateCrewMember implements PUT /api/crew/:crewMemberId
+func (o *implCrewAPI) UpdateCrewMember(c *gin.Context) {
+	withCrewMemberByID(c, func(_ *gin.Context, existing *CrewMember) (*CrewMember, interface{}, int) {
+		var upd CrewMember
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.Name != "" {
+			existing.Name = upd.Name
+		}
+		if upd.Role != "" {
+			existing.Role = upd.Role
+		}
+		if upd.Certifications != nil {
+			existing.Certifications = upd.Certifications
+		}
+		if problems := validateCrewMember(existing); len(problems) > 0 {
+			return nil, gin.H{"message": "Invalid crew member", "errors": problems}, http.StatusUnprocessableEntity
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteCrewMember implements DELETE /api/crew/:crewMemberId
+func (o *implCrewAPI) DeleteCrewMember(c *gin.Context) {
+	withCrewMemberByID(c, func(_ *gin.Context, m *CrewMember) (*CrewMember, interface{}, int) {
+		db := getCrewDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := db.DeleteDocument(ctx, m.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete crew member"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// GetExpiringCertifications implements GET /api/certifications/expiring
+//
+// Query parameters:
+//   - days: look-ahead window in days (default 30)
+//   - include_expired: also return certifications that have already expired
+func (o *implCrewAPI) GetExpiringCertifications(c *gin.Context) {
+	days := 30
+	if v := c.Query("days"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "days must be a non-negative integer"})
+			return
+		}
+		days = n
+	}
+	includeExpired := c.Query("include_expired") == "true"
+
+	db := getCrewDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	members, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve crew members"})
+		return
+	}
+
+	now := time.Now()
+	horizon := now.AddDate(0, 0, days)
+	result := make([]ExpiringCertification, 0)
+	for _, m := range members {
+		for _, cert := range m.Certifications {
+			expired := !cert.ExpiresAt.After(now)
+			if expired && !includeExpired {
+				continue
+			}
+			if cert.ExpiresAt.After(horizon) {
+				continue
+			}
+			result = append(result, ExpiringCertification{
+				CrewMemberId:   m.Id,
+				CrewMemberName: m.Name,
+				Certification:  cert,
+				Expired:        expired,
+			})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Certification.ExpiresAt.Before(result[j].Certification.ExpiresAt)
+	})
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,166 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// CrewSuite defines the suite for crew handler tests
+type CrewSuite struct {
+	suite.Suite
+	ambulanceDbMock *DbServiceMock[Ambulance]
+	crewDbMock      *DbServiceMock[CrewMember]
+	shiftDbMock     *DbServiceMock[Shift]
+	now             time.Time
+}
+
+func TestCrewSuite(t *testing.T) {
+	suite.Run(t, new(CrewSuite))
+}
+
+func (suite *CrewSuite) SetupTest() {
+	suite.now = time.Now()
+	suite.ambulanceDbMock = &DbServiceMock[Ambulance]{}
+	suite.crewDbMock = &DbServiceMock[CrewMember]{}
+	suite.shiftDbMock = &DbServiceMock[Shift]{}
+	suite.shiftDbMock.
+		On("FindDocumentsByField", mock.Anything, "ambulance_id", mock.Anything).
+		Return([]*Shift{}, nil)
+
+	suite.crewDbMock.
+		On("FindDocument", mock.Anything, "driver-1").
+		Return(&CrewMember{
+			Id:   "driver-1",
+			Name: "Ján Novák",
+			Role: CrewRoleDriver,
+			Certifications: []Certification{
+				{Name: "Driving licence", Role: CrewRoleDriver, ExpiresAt: suite.now.AddDate(1, 0, 0)},
+			},
+		}, nil)
+	suite.crewDbMock.
+		On("FindDocument", mock.Anything, "paramedic-1").
+		Return(&CrewMember{
+			Id:   "paramedic-1",
+			Name: "Eva Kováčová",
+			Role: CrewRoleParamedic,
+			Certifications: []Certification{
+				{Name: "Paramedic licence", Role: CrewRoleParamedic, ExpiresAt: suite.now.AddDate(0, 6, 0)},
+			},
+		}, nil)
+	suite.crewDbMock.
+		On("FindDocument", mock.Anything, "paramedic-expired").
+		Return(&CrewMember{
+			Id:   "paramedic-expired",
+			Name: "Peter Horváth",
+			Role: CrewRoleParamedic,
+			Certifications: []Certification{
+				{Name: "Paramedic licence", Role: CrewRoleParamedic, ExpiresAt: suite.now.AddDate(0, 0, -1)},
+			},
+		}, nil)
+}
+
+func (suite *CrewSuite) shift(memberId, role string) CrewAssignment {
+	return CrewAssignment{
+		CrewMemberId: memberId,
+		Role:         role,
+		ShiftStart:   suite.now.Add(-time.Hour),
+		ShiftEnd:     suite.now.Add(time.Hour),
+	}
+}
+
+func (suite *CrewSuite) Test_ValidateDispatchCrew_AcceptsCertifiedCrew() {
+	crew := []CrewAssignment{suite.shift("driver-1", CrewRoleDriver), suite.shift("paramedic-1", CrewRoleParamedic)}
+
+	problems, err := validateDispatchCrew(context.Background(), suite.crewDbMock, crew, suite.now)
+
+	suite.NoError(err)
+	suite.Empty(problems)
+}
+
+func (suite *CrewSuite) Test_ValidateDispatchCrew_RejectsExpiredCertification() {
+	crew := []CrewAssignment{suite.shift("driver-1", CrewRoleDriver), suite.shift("paramedic-expired", CrewRoleParamedic)}
+
+	problems, err := validateDispatchCrew(context.Background(), suite.crewDbMock, crew, suite.now)
+
+	suite.NoError(err)
+	suite.Equal([]string{"no paramedic with a valid certification is on shift"}, problems)
+}
+
+func (suite *CrewSuite) Test_ValidateDispatchCrew_IgnoresOffShiftCrew() {
+	offShift := suite.shift("paramedic-1", CrewRoleParamedic)
+	offShift.ShiftStart = suite.now.Add(2 * time.Hour)
+	offShift.ShiftEnd = suite.now.Add(10 * time.Hour)
+	ambulance := &Ambulance{
+		Id:   "test-ambulance",
+		Crew: []CrewAssignment{suite.shift("driver-1", CrewRoleDriver), offShift},
+	}
+
+	problems, err := validateDispatchCrew(context.Background(), suite.crewDbMock, activeCrew(ambulance, suite.now), suite.now)
+
+	suite.NoError(err)
+	suite.Len(problems, 1)
+}
+
+func (suite *CrewSuite) Test_UpdateAmbulance_DispatchWithoutCrew_ReturnsUnprocessable() {
+	suite.ambulanceDbMock.
+		On("FindDocument", mock.Anything, "test-ambulance").
+		Return(&Ambulance{Id: "test-ambulance", Name: "TestName", Status: "Available"}, nil)
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_ambulance", suite.ambulanceDbMock)
+	ctx.Set("db_service_crew", suite.crewDbMock)
+	ctx.Set("db_service_shift", suite.shiftDbMock)
+	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
+	ctx.Request = httptest.NewRequest("PUT", "/api/ambulances/test-ambulance", strings.NewReader(`{"status":"Dispatched"}`))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	sut := implAmbulanceAPI{}
+	sut.UpdateAmbulance(ctx)
+
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "UpdateDocument", mock.Anything, mock.Anything, mock.Anything)
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+}
+
+func (suite *CrewSuite) Test_UpdateAmbulance_RejectsDoubleBookedCrew() {
+	ambulance := Ambulance{Id: "test-ambulance", Name: "TestName", Status: "Available"}
+	other := Ambulance{Id: "other-ambulance", Crew: []CrewAssignment{suite.shift("driver-1", CrewRoleDriver)}}
+	suite.ambulanceDbMock.
+		On("FindDocument", mock.Anything, "test-ambulance").
+		Return(&ambulance, nil)
+	suite.ambulanceDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Ambulance{ambulance, other}, nil)
+	suite.shiftDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Shift{}, nil)
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_ambulance", suite.ambulanceDbMock)
+	ctx.Set("db_service_crew", suite.crewDbMock)
+	ctx.Set("db_service_shift", suite.shiftDbMock)
+	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "test-ambulance"}}
+	body := fmt.Sprintf(`{"crew":[{"crew_member_id":"driver-1","role":"driver","shift_start":%q,"shift_end":%q}]}`,
+		suite.now.Format(time.RFC3339), suite.now.Add(2*time.Hour).Format(time.RFC3339))
+	ctx.Request = httptest.NewRequest("PUT", "/api/ambulances/test-ambulance", strings.NewReader(body))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	sut := implAmbulanceAPI{}
+	sut.UpdateAmbulance(ctx)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Contains(recorder.Body.String(), "other-ambulance")
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "UpdateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,447 @@
+package ambulance
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// implDepartmentAPI implements the DepartmentManagementAPI interface.
+type implDepartmentAPI struct{}
+
+// NewDepartmentAPI returns an implementation of DepartmentManagementAPI.
+func NewDepartmentAPI() DepartmentManagementAPI {
+	return &implDepartmentAPI{}
+}
+
+// getDepartmentDB extracts the DbService[Department] from the context.
+func getDepartmentDB(c *gin.Context) db_service.DbService[Department] {
+	return c.MustGet("db_service_department").(db_service.DbService[Department])
+}
+
+// normalizeDepartmentName folds case and whitespace so that "Internal Medicine"
+// and " internal  medicine" name the same department.
+func normalizeDepartmentName(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// findDepartmentByName returns the department with the given name, ignoring case and whitespace.
+func findDepartmentByName(departments []Department, name string) *Department {
+	normalized := normalizeDepartmentName(name)
+	for i := range departments {
+		if normalizeDepartmentName(departments[i].Name) == normalized {
+			return &departments[i]
+		}
+	}
+	return nil
+}
+
+// resolveAmbulanceDepartment links the ambulance to an existing department, either by
+// department_id or, for legacy clients, by the free-text department name. It returns a
+// validation problem when neither matches an existing department.
+func resolveAmbulanceDepartment(ctx context.Context, db db_service.DbService[Department], ambulance *Ambulance) (string, error) {
+	if ambulance.DepartmentId == "" && strings.TrimSpace(ambulance.Department) == "" {
+		return "", nil
+	}
+	if ambulance.DepartmentId != "" {
+		department, err := db.FindDocument(ctx, ambulance.DepartmentId)
+		if err == db_service.ErrNotFound {
+			return "department_id does not reference an existing department", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		ambulance.Department = department.Name
+		return "", nil
+	}
+
+	departments, err := db.ListDocuments(ctx)
+	if err != nil {
+		return "", err
+	}
+	department := findDepartmentByName(departments, ambulance.Department)
+	if department == nil {
+		return "department does not match an existing department", nil
+	}
+	ambulance.DepartmentId = department.Id
+	ambulance.Department = department.Name
+	return "", nil
+}
+
+// departmentSubtree returns the id of the department followed by the ids of all its descendants.
+func departmentSubtree(departments []Department, rootId string) []string {
+	children := map[string][]string{}
+	for _, d := range departments {
+		if d.ParentId != "" {
+			children[d.ParentId] = append(children[d.ParentId], d.Id)
+		}
+	}
+
+	subtree := []string{rootId}
+	visited := map[string]bool{rootId: true}
+	for i := 0; i < len(subtree); i++ {
+		for _, child := range children[subtree[i]] {
+			if !visited[child] {
+				visited[child] = true
+				subtree = append(subtree, child)
+			}
+		}
+	}
+	return subtree
+}
+
+// validateDepartment checks the department's name and its position in the hierarchy.
+func validateDepartment(d *Department, departments []Department) (string, int) {
+	if strings.TrimSpace(d.Name) == "" {
+		return "name is required", http.StatusUnprocessableEntity
+	}
+	if other := findDepartmentByName(departments, d.Name); other != nil && other.Id != d.Id {
+		return "a department with this name already exists", http.StatusConflict
+	}
+	if d.ParentId == "" {
+		return "", 0
+	}
+	parentExists := false
+	for _, other := range departments {
+		if other.Id == d.ParentId {
+			parentExists = true
+		}
+	}
+	if !parentExists {
+		return "parent_id does not reference an existing department", http.StatusUnprocessableEntity
+	}
+	for _, id := range departmentSubtree(departments, d.Id) {
+		if id == d.ParentId {
+			return "parent_id would create a cycle in the department hierarchy", http.StatusUnprocessableEntity
+		}
+	}
+	return "", 0
+}
+
+// computeDepartmentRollups totals ambulances, procedures and revenue of every requested
+// department together with its sub-departments. Revenue fails with money.ErrCurrencyMismatch
+// when the procedures are priced in different currencies.
+func computeDepartmentRollups(departments []Department, ambulances []Ambulance, procedures []Procedure, ids []string) ([]DepartmentRollup, error) {
+	ambulanceDepartment := map[string]string{}
+	ambulancesPerDepartment := map[string]int32{}
+	for _, a := range ambulances {
+		ambulanceDepartment[a.Id] = a.DepartmentId
+		ambulancesPerDepartment[a.DepartmentId]++
+	}
+	proceduresPerDepartment := map[string]int32{}
+	revenuePerDepartment := map[string]money.Money{}
+	for _, p := range procedures {
+		departmentId := ambulanceDepartment[p.AmbulanceId]
+		proceduresPerDepartment[departmentId]++
+		revenue, err := revenuePerDepartment[departmentId].Add(p.Price)
+		if err != nil {
+			return nil, err
+		}
+		revenuePerDepartment[departmentId] = revenue
+	}
+
+	byId := map[string]Department{}
+	for _, d := range departments {
+		byId[d.Id] = d
+	}
+
+	rollups := make([]DepartmentRollup, 0, len(ids))
+	for _, id := range ids {
+		d := byId[id]
+		rollup := DepartmentRollup{
+			DepartmentId:        d.Id,
+			Name:                d.Name,
+			CostCentre:          d.CostCentre,
+			IncludedDepartments: departmentSubtree(departments, d.Id),
+		}
+		for _, included := range rollup.IncludedDepartments {
+			rollup.Ambulances += ambulancesPerDepartment[included]
+			rollup.Procedures += proceduresPerDepartment[included]
+			revenue, err := rollup.Revenue.Add(revenuePerDepartment[included])
+			if err != nil {
+				return nil, err
+			}
+			rollup.Revenue = revenue
+		}
+		rollups = append(rollups, rollup)
+	}
+	return rollups, nil
+}
+
+// loadRollupData lists the departments, ambulances and procedures needed for roll-up totals.
+func loadRollupData(ctx context.Context, c *gin.Context) ([]Department, []Ambulance, []Procedure, error) {
+	departments, err := getDepartmentDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ambulances, err := getDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	procedures, err := getProcedureDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	return departments, ambulances, procedures, nil
+}
+
+// withDepartmentByID loads a Department and calls fn; fn may return an updated doc.
+func withDepartmentByID(
+	c *gin.Context,
+	fn func(*gin.Context, *Department) (*Department, interface{}, int),
+) {
+	id := c.Param("departmentId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "departmentId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getDepartmentDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		department, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Department not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, department)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update department"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// CreateDepartment implements POST /api/departments
+func (o *implDepartmentAPI) CreateDepartment(c *gin.Context) {
+	var d Department
+	if err := c.ShouldBindJSON(&d); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if d.Id == "" {
+		d.Id = uuid.NewString()
+	}
+	d.Name = strings.TrimSpace(d.Name)
+
+	db := getDepartmentDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	departments, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create department"})
+		return
+	}
+	if problem, status := validateDepartment(&d, departments); problem != "" {
+		c.JSON(status, gin.H{"message": problem})
+		return
+	}
+	if problems := validateWorkingHours(d.WorkingHours); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invalid working hours", "errors": problems})
+		return
+	}
+
+	if err := db.CreateDocument(ctx, d.Id, &d); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Department already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create department"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, d)
+}
+
+// GetDepartmentById implements GET /api/departments/:departmentId
+func (o *implDepartmentAPI) GetDepartmentById(c *gin.Context) {
+	withDepartmentByID(c, func(_ *gin.Context, d *Department) (*Department, interface{}, int) {
+		return nil, d, http.StatusOK
+	})
+}
+
+// GetDepartments implements GET /api/departments
+//
+// The optional parent_id query parameter lists the direct sub-departments of a department.
+func (o *implDepartmentAPI) GetDepartments(c *gin.Context) {
+	db := getDepartmentDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	parentID := c.Query("parent_id")
+
+	var (
+		departments any
+		err         error
+	)
+
+	if parentID != "" {
+		departments, err = db.FindDocumentsByField(ctx, "parent_id", parentID)
+	} else {
+		departments, err = db.ListDocuments(ctx)
+	}
+
+	if err != nil {
+		log.Println("Error retrieving departments:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve departments"})
+		return
+	}
+
+	c.JSON(http.StatusOK, departments)
+}
+
+// UpdateDepartment implements PUT /api/departments/:departmentId
+//
+// Renaming a department also renames it on the ambulances that reference it.
+func (o *implDepartmentAPI) UpdateDepartment(c *gin.Context) {
+	withDepartmentByID(c, func(c *gin.Context, existing *Department) (*Department, interface{}, int) {
+		var upd Department
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		renamed := false
+		if upd.Name != "" && strings.TrimSpace(upd.Name) != existing.Name {
+			existing.Name = strings.TrimSpace(upd.Name)
+			renamed = true
+		}
+		existing.ParentId = upd.ParentId
+		if upd.CostCentre != "" {
+			existing.CostCentre = upd.CostCentre
+		}
+		if upd.WorkingHours != nil {
+			if problems := validateWorkingHours(upd.WorkingHours); len(problems) > 0 {
+				return nil, gin.H{"message": "Invalid working hours", "errors": problems}, http.StatusUnprocessableEntity
+			}
+			existing.WorkingHours = upd.WorkingHours
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		departments, err := getDepartmentDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to update department"}, http.StatusInternalServerError
+		}
+		if problem, status := validateDepartment(existing, departments); problem != "" {
+			return nil, gin.H{"message": problem}, status
+		}
+
+		if renamed {
+			ambulanceDb := getDB(c)
+			ambulances, err := ambulanceDb.FindDocumentsByField(ctx, "department_id", existing.Id)
+			if err != nil {
+				log.Println("FindDocumentsByField error:", err)
+				return nil, gin.H{"message": "Failed to update department"}, http.StatusInternalServerError
+			}
+			for _, a := range ambulances {
+				a.Department = existing.Name
+				if err := ambulanceDb.UpdateDocument(ctx, a.Id, a); err != nil {
+					log.Println("UpdateDocument error:", err)
+					return nil, gin.H{"message": "Failed to update department"}, http.StatusInternalServerError
+				}
+			}
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteDepartment implements DELETE /api/departments/:departmentId
+//
+// Departments that still have sub-departments or ambulances cannot be deleted.
+func (o *implDepartmentAPI) DeleteDepartment(c *gin.Context) {
+	withDepartmentByID(c, func(c *gin.Context, d *Department) (*Department, interface{}, int) {
+		db := getDepartmentDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		children, err := db.FindDocumentsByField(ctx, "parent_id", d.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to delete department"}, http.StatusInternalServerError
+		}
+		if len(children) > 0 {
+			return nil, gin.H{"message": "Department has sub-departments"}, http.StatusConflict
+		}
+		ambulances, err := getDB(c).FindDocumentsByField(ctx, "department_id", d.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to delete department"}, http.StatusInternalServerError
+		}
+		if len(ambulances) > 0 {
+			return nil, gin.H{"message": "Department still has ambulances"}, http.StatusConflict
+		}
+
+		if err := db.DeleteDocument(ctx, d.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete department"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// GetDepartmentRollup implements GET /api/departments/:departmentId/rollup
+func (o *implDepartmentAPI) GetDepartmentRollup(c *gin.Context) {
+	withDepartmentByID(c, func(c *gin.Context, d *Department) (*Department, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		departments, ambulances, procedures, err := loadRollupData(ctx, c)
+		if err != nil {
+			log.Println("loadRollupData error:", err)
+			return nil, gin.H{"message": "Failed to compute department totals"}, http.StatusInternalServerError
+		}
+		rollups, err := computeDepartmentRollups(departments, ambulances, procedures, []string{d.Id})
+		if err != nil {
+			return nil, gin.H{"message": "Failed to compute department totals", "error": err.Error()}, http.StatusConflict
+		}
+		return nil, rollups[0], http.StatusOK
+	})
+}
+
+// GetDepartmentReport implements GET /api/reports/departments
+func (o *implDepartmentAPI) GetDepartmentReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	departments, ambulances, procedures, err := loadRollupData(ctx, c)
+	if err != nil {
+		log.Println("loadRollupData error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute department totals"})
+		return
+	}
+
+	sort.Slice(departments, func(i, j int) bool {
+		return departments[i].Name < departments[j].Name
+	})
+	ids := make([]string, 0, len(departments))
+	for _, d := range departments {
+		ids = append(ids, d.Id)
+	}
+	rollups, err := computeDepartmentRollups(departments, ambulances, procedures, ids)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "Failed to compute department totals", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rollups)
+}
@@ -0,0 +1,87 @@
+package ambulance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// DepartmentSuite defines the suite for department hierarchy tests
+type DepartmentSuite struct {
+	suite.Suite
+	departments []Department
+}
+
+func TestDepartmentSuite(t *testing.T) {
+	suite.Run(t, new(DepartmentSuite))
+}
+
+func (suite *DepartmentSuite) SetupTest() {
+	suite.departments = []Department{
+		{Id: "med", Name: "Internal Medicine"},
+		{Id: "cardio", Name: "Cardiology", ParentId: "med"},
+		{Id: "icu", Name: "Cardiac ICU", ParentId: "cardio"},
+		{Id: "surgery", Name: "Surgery"},
+	}
+}
+
+func (suite *DepartmentSuite) Test_FindDepartmentByName_IgnoresCaseAndWhitespace() {
+	d := findDepartmentByName(suite.departments, "  internal   medicine ")
+
+	suite.Require().NotNil(d)
+	suite.Equal("med", d.Id)
+}
+
+func (suite *DepartmentSuite) Test_ValidateDepartment_RejectsCycle() {
+	d := suite.departments[0]
+	d.ParentId = "icu"
+
+	problem, _ := validateDepartment(&d, suite.departments)
+
+	suite.Equal("parent_id would create a cycle in the department hierarchy", problem)
+}
+
+func (suite *DepartmentSuite) Test_ValidateDepartment_RejectsDuplicateName() {
+	d := Department{Id: "new", Name: "SURGERY"}
+
+	problem, _ := validateDepartment(&d, suite.departments)
+
+	suite.Equal("a department with this name already exists", problem)
+}
+
+func (suite *DepartmentSuite) Test_ComputeDepartmentRollups_IncludesSubDepartments() {
+	ambulances := []Ambulance{
+		{Id: "amb1", DepartmentId: "med"},
+		{Id: "amb2", DepartmentId: "icu"},
+		{Id: "amb3", DepartmentId: "surgery"},
+	}
+	procedures := []Procedure{
+		{Id: "p1", AmbulanceId: "amb1", Price: eur("100")},
+		{Id: "p2", AmbulanceId: "amb2", Price: eur("50.50")},
+		{Id: "p3", AmbulanceId: "amb3", Price: eur("999")},
+	}
+
+	rollups, err := computeDepartmentRollups(suite.departments, ambulances, procedures, []string{"med", "cardio"})
+
+	suite.Require().NoError(err)
+
+	suite.Equal([]string{"med", "cardio", "icu"}, rollups[0].IncludedDepartments)
+	suite.Equal(int32(2), rollups[0].Ambulances)
+	suite.Equal(int32(2), rollups[0].Procedures)
+	suite.Equal(eur("150.50"), rollups[0].Revenue)
+	suite.Equal(int32(1), rollups[1].Ambulances)
+	suite.Equal(eur("50.50"), rollups[1].Revenue)
+}
+
+func (suite *DepartmentSuite) Test_ComputeDepartmentRollups_RejectsMixedCurrencies() {
+	ambulances := []Ambulance{{Id: "amb1", DepartmentId: "med"}}
+	procedures := []Procedure{
+		{Id: "p1", AmbulanceId: "amb1", Price: eur("100")},
+		{Id: "p2", AmbulanceId: "amb1", Price: money.New(250000, "CZK")},
+	}
+
+	_, err := computeDepartmentRollups(suite.departments, ambulances, procedures, []string{"med"})
+
+	suite.Equal(money.ErrCurrencyMismatch, err)
+}
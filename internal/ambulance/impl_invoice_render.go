@@ -0,0 +1,168 @@
+package ambulance
+
+import (
+	"bytes"
+	"html/template"
+	"strconv"
+
+	"github.com/wac-project/wac-api/internal/pdf"
+)
+
+// invoiceTitle returns the heading of an invoice; drafts have no number yet.
+func invoiceTitle(invoice *Invoice) string {
+	switch invoice.Status {
+	case InvoiceStatusDraft:
+		return "Draft invoice"
+	case InvoiceStatusCancelled:
+		return "Invoice " + invoice.Number + " (cancelled)"
+	default:
+		return "Invoice " + invoice.Number
+	}
+}
+
+// invoiceFileName returns the name of files holding the invoice, without extension.
+func invoiceFileName(invoice *Invoice) string {
+	if invoice.Number == "" {
+		return "invoice-draft-" + invoice.Id
+	}
+	return "invoice-" + invoice.Number
+}
+
+// formatTaxRate formats the tax rate of a line, such as "20 %".
+func formatTaxRate(rate *float64) string {
+	if rate == nil {
+		return ""
+	}
+	return strconv.FormatFloat(*rate, 'f', -1, 64) + " %"
+}
+
+var invoiceHTMLTemplate = template.Must(template.New("invoice").Funcs(template.FuncMap{
+	"title":   invoiceTitle,
+	"taxRate": formatTaxRate,
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{title .Invoice}}</title>
+<style>
+body { font-family: Helvetica, Arial, sans-serif; margin: 2em; }
+table { border-collapse: collapse; width: 100%; }
+th, td { padding: 0.3em 0.5em; border-bottom: 1px solid #ccc; text-align: left; }
+.amount { text-align: right; }
+</style>
+</head>
+<body>
+<h1>{{title .Invoice}}</h1>
+<p>{{.Issuer}}</p>
+<p><strong>Bill to:</strong> {{.Invoice.BillTo}}{{with .Invoice.BillToAddress}}<br>{{.}}{{end}}</p>
+<p>{{with .Invoice.IssueDate}}<strong>Issue date:</strong> {{.}}<br>{{end}}{{with .Invoice.DueDate}}<strong>Due date:</strong> {{.}}{{end}}</p>
+<table>
+<tr><th>Description</th><th class="amount">Net</th><th class="amount">Tax rate</th><th class="amount">Tax</th><th class="amount">Total</th></tr>
+{{range .Invoice.Lines}}<tr><td>{{.Description}}</td><td class="amount">{{.Net}}</td><td class="amount">{{taxRate .TaxRate}}</td><td class="amount">{{.Tax}}</td><td class="amount">{{.Total}}</td></tr>
+{{end}}</table>
+<p class="amount">Subtotal: {{.Invoice.Subtotal}}<br>Tax: {{.Invoice.TaxTotal}}<br><strong>Total: {{.Invoice.Total}}</strong><br>Paid: {{.Invoice.Paid}}<br><strong>Balance: {{.Invoice.Balance}}</strong></p>
+{{with .Invoice.Notes}}<p>{{.}}</p>{{end}}
+{{with .Invoice.CancellationReason}}<p><strong>Cancelled:</strong> {{.}}</p>{{end}}
+</body>
+</html>
+`))
+
+// renderInvoiceHTML writes the invoice as an HTML page.
+func renderInvoiceHTML(out *bytes.Buffer, invoice *Invoice) error {
+	return invoiceHTMLTemplate.Execute(out, struct {
+		Invoice *Invoice
+		Issuer  string
+	}{invoice, InvoiceIssuer})
+}
+
+// renderInvoicePDF writes the invoice as an A4 PDF document, continuing the lines on
+// further pages when they do not fit on one.
+func renderInvoicePDF(out *bytes.Buffer, invoice *Invoice) error {
+	const (
+		left, right = 50.0, pdf.PageWidth - 50
+		bottom      = 80.0
+		size        = 10.0
+		lineHeight  = 16.0
+	)
+	columns := [4]float64{right - 240, right - 170, right - 80, right}
+
+	document := pdf.New()
+	page := document.AddPage()
+	y := pdf.PageHeight - 60
+	page.Text(left, y, 18, true, invoiceTitle(invoice))
+	y -= 24
+	page.Text(left, y, size, false, InvoiceIssuer)
+	y -= 2 * lineHeight
+	page.Text(left, y, size, true, "Bill to")
+	page.Text(left+60, y, size, false, invoice.BillTo)
+	if invoice.BillToAddress != "" {
+		y -= lineHeight
+		page.Text(left+60, y, size, false, invoice.BillToAddress)
+	}
+	if invoice.IssueDate != "" {
+		y -= lineHeight
+		page.Text(left, y, size, true, "Issue date")
+		page.Text(left+60, y, size, false, invoice.IssueDate)
+	}
+	if invoice.DueDate != "" {
+		y -= lineHeight
+		page.Text(left, y, size, true, "Due date")
+		page.Text(left+60, y, size, false, invoice.DueDate)
+	}
+
+	header := func() {
+		y -= 2 * lineHeight
+		page.Text(left, y, size, true, "Description")
+		for i, heading := range [4]string{"Net", "Tax rate", "Tax", "Total"} {
+			page.TextRight(columns[i], y, size, true, heading)
+		}
+		y -= 6
+		page.Line(left, y, right, y)
+		y -= lineHeight - 6
+	}
+	header()
+	for _, line := range invoice.Lines {
+		if y < bottom {
+			page = document.AddPage()
+			y = pdf.PageHeight - 60
+			header()
+		}
+		page.Text(left, y, size, false, line.Description)
+		for i, value := range [4]string{line.Net.String(), formatTaxRate(line.TaxRate), line.Tax.String(), line.Total.String()} {
+			page.TextRight(columns[i], y, size, false, value)
+		}
+		y -= lineHeight
+	}
+	if y < bottom+5*lineHeight {
+		page = document.AddPage()
+		y = pdf.PageHeight - 60
+	}
+
+	page.Line(left, y+lineHeight-6, right, y+lineHeight-6)
+	y -= 4
+	for _, total := range []struct {
+		label  string
+		amount string
+		bold   bool
+	}{
+		{"Subtotal", invoice.Subtotal.String(), false},
+		{"Tax", invoice.TaxTotal.String(), false},
+		{"Total", invoice.Total.String(), true},
+		{"Paid", invoice.Paid.String(), false},
+		{"Balance", invoice.Balance.String(), true},
+	} {
+		page.Text(columns[1], y, size, total.bold, total.label)
+		page.TextRight(right, y, size, total.bold, total.amount)
+		y -= lineHeight
+	}
+	if invoice.Notes != "" {
+		y -= lineHeight
+		page.Text(left, y, size, false, invoice.Notes)
+	}
+	if invoice.CancellationReason != "" {
+		y -= lineHeight
+		page.Text(left, y, size, true, "Cancelled: "+invoice.CancellationReason)
+	}
+	_, err := document.WriteTo(out)
+	return err
+}
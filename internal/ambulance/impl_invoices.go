@@ -0,0 +1,685 @@
+package ambulance
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// Invoice statuses.
+const (
+	InvoiceStatusDraft         = "draft"
+	InvoiceStatusIssued        = "issued"
+	InvoiceStatusPartiallyPaid = "partially_paid"
+	InvoiceStatusPaid          = "paid"
+	InvoiceStatusCancelled     = "cancelled"
+)
+
+// MIMEPDF is the content type of invoices rendered as PDF.
+const MIMEPDF = "application/pdf"
+
+// DefaultInvoicePaymentTermsDays is the number of days after issue an invoice is due
+// when its payer has no payment terms of its own.
+const DefaultInvoicePaymentTermsDays = 14
+
+// DefaultInvoiceTaxRate is the tax rate in per cent of invoice lines that give none.
+var DefaultInvoiceTaxRate float64 = 0
+
+// InvoiceIssuer is the name of the issuer printed on invoices.
+var InvoiceIssuer = "Hospital Ambulance Service"
+
+// invoiceNumberingAttempts bounds how often issuing retries taking a number that another
+// invoice issued at the same time took first.
+const invoiceNumberingAttempts = 10
+
+// InvoiceCounter keeps the last number of a year taken by an issued invoice. Its id is
+// the year.
+type InvoiceCounter struct {
+	Id       string `json:"id"`
+	Year     int32  `json:"year"`
+	Sequence int32  `json:"sequence"`
+}
+
+// implInvoicesAPI implements the InvoicesAPI interface.
+type implInvoicesAPI struct{}
+
+// NewInvoicesAPI returns an implementation of InvoicesAPI.
+func NewInvoicesAPI() InvoicesAPI {
+	return &implInvoicesAPI{}
+}
+
+// getInvoiceDB extracts the DbService[Invoice] from the context.
+func getInvoiceDB(c *gin.Context) db_service.DbService[Invoice] {
+	return c.MustGet("db_service_invoice").(db_service.DbService[Invoice])
+}
+
+// getInvoiceCounterDB extracts the DbService[InvoiceCounter] from the context.
+func getInvoiceCounterDB(c *gin.Context) db_service.DbService[InvoiceCounter] {
+	return c.MustGet("db_service_invoice_counter").(db_service.DbService[InvoiceCounter])
+}
+
+// withInvoiceByID loads an Invoice and calls fn; fn may return an updated doc.
+func withInvoiceByID(
+	c *gin.Context,
+	fn func(*gin.Context, *Invoice) (*Invoice, interface{}, int),
+) {
+	id := c.Param("invoiceId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "invoiceId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getInvoiceDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		invoice, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Invoice not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, invoice)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update invoice"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// invoicesProcedure reports whether the invoice bills the procedure and was not cancelled.
+func invoicesProcedure(invoice *Invoice, procedureId string) bool {
+	if invoice.Status == InvoiceStatusCancelled {
+		return false
+	}
+	for _, line := range invoice.Lines {
+		if line.ProcedureId == procedureId {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareInvoice checks the invoiced procedures and computes the lines and totals. Only
+// performed procedures can be invoiced, each on one invoice that was not cancelled. It
+// returns a validation problem and its status, if any.
+func prepareInvoice(ctx context.Context, c *gin.Context, invoice *Invoice) (string, int, error) {
+	invoice.BillTo = strings.TrimSpace(invoice.BillTo)
+	if invoice.PayerId != "" {
+		payer, problem, err := resolvePayer(ctx, getPayerDB(c), invoice.PayerId)
+		if err != nil || problem != "" {
+			return problem, http.StatusUnprocessableEntity, err
+		}
+		invoice.BillTo = payer.Name
+	}
+	if invoice.BillTo == "" {
+		return "bill_to or payer_id is required", http.StatusUnprocessableEntity, nil
+	}
+	if len(invoice.Lines) == 0 {
+		return "lines must list at least one procedure", http.StatusUnprocessableEntity, nil
+	}
+	if invoice.DueDate != "" {
+		if _, err := time.Parse(time.DateOnly, invoice.DueDate); err != nil {
+			return "due_date must be in YYYY-MM-DD format", http.StatusUnprocessableEntity, nil
+		}
+	}
+
+	invoices, err := getInvoiceDB(c).ListDocuments(ctx)
+	if err != nil {
+		return "", 0, err
+	}
+	seen := map[string]bool{}
+	var nets, taxes []money.Money
+	for i := range invoice.Lines {
+		line := &invoice.Lines[i]
+		if seen[line.ProcedureId] {
+			return "procedure " + line.ProcedureId + " is listed twice", http.StatusUnprocessableEntity, nil
+		}
+		seen[line.ProcedureId] = true
+
+		procedure, err := getProcedureDB(c).FindDocument(ctx, line.ProcedureId)
+		if err == db_service.ErrNotFound {
+			return "procedure " + line.ProcedureId + " does not exist", http.StatusUnprocessableEntity, nil
+		}
+		if err != nil {
+			return "", 0, err
+		}
+		if status := procedureStatus(procedure); status != ProcedureStatusCompleted && status != ProcedureStatusBilled {
+			return "procedure " + line.ProcedureId + " is " + status + " and cannot be invoiced", http.StatusUnprocessableEntity, nil
+		}
+		for j := range invoices {
+			if invoices[j].Id != invoice.Id && invoicesProcedure(&invoices[j], line.ProcedureId) {
+				return "procedure " + line.ProcedureId + " is already invoiced by invoice " + invoices[j].Id, http.StatusConflict, nil
+			}
+		}
+
+		rate := DefaultInvoiceTaxRate
+		if line.TaxRate != nil {
+			rate = *line.TaxRate
+		}
+		if rate < 0 || rate > 100 {
+			return "tax_rate of procedure " + line.ProcedureId + " must be between 0 and 100", http.StatusUnprocessableEntity, nil
+		}
+		description := procedure.Name
+		if procedure.Code != "" {
+			description = procedure.Code + " " + description
+		}
+		tax := procedure.Price.Percent(rate)
+		total, _ := procedure.Price.Add(tax)
+		*line = InvoiceLine{
+			ProcedureId: line.ProcedureId,
+			Description: description,
+			TaxRate:     &rate,
+			Net:         procedure.Price,
+			Tax:         tax,
+			Total:       total,
+		}
+		nets = append(nets, line.Net)
+		taxes = append(taxes, line.Tax)
+	}
+
+	subtotal, err := money.Sum(nets...)
+	if err != nil {
+		return "the procedures are priced in different currencies", http.StatusUnprocessableEntity, nil
+	}
+	taxTotal, _ := money.Sum(taxes...)
+	invoice.Subtotal = subtotal
+	invoice.TaxTotal = taxTotal
+	invoice.Total, _ = subtotal.Add(taxTotal)
+	invoice.Paid = money.Money{Currency: subtotal.Currency}
+	invoice.Balance = invoice.Total
+	invoice.Allocations = nil
+	return "", 0, nil
+}
+
+// nextInvoiceSequence returns the next position in the numbering of a year: one after
+// the highest position taken by the year's invoices.
+func nextInvoiceSequence(invoices []*Invoice) int32 {
+	var highest int32
+	for _, invoice := range invoices {
+		highest = max(highest, invoice.Sequence)
+	}
+	return highest + 1
+}
+
+// invoiceDueDate returns the due date of an invoice issued on issueDate: the payer's
+// payment terms after it, or DefaultInvoicePaymentTermsDays.
+func invoiceDueDate(ctx context.Context, c *gin.Context, invoice *Invoice, issueDate time.Time) (string, error) {
+	days := DefaultInvoicePaymentTermsDays
+	if invoice.PayerId != "" {
+		payer, err := getPayerDB(c).FindDocument(ctx, invoice.PayerId)
+		if err != nil {
+			return "", err
+		}
+		if payer.ContractTerms != nil && payer.ContractTerms.PaymentTermsDays > 0 {
+			days = int(payer.ContractTerms.PaymentTermsDays)
+		}
+	}
+	return issueDate.AddDate(0, 0, days).Format(time.DateOnly), nil
+}
+
+// issueInvoiceNumber assigns the next number of the year to the invoice and stores it.
+// The number is taken from the counter of the year, which is advanced only if no other
+// invoice took the number first; a year without a counter continues after its invoices.
+// Run in the transaction of the issuance, a number is given back when the issuance fails,
+// so that the numbering of a year has no gaps; without transactions a failed issuance
+// leaves a gap. Within a transaction a concurrent issuance makes the write of the counter
+// conflict instead, and the issuance runs again.
+func issueInvoiceNumber(ctx context.Context, c *gin.Context, invoice *Invoice, now time.Time) error {
+	year := int32(now.Year())
+	sequence, err := takeInvoiceSequence(ctx, c, year)
+	if err != nil {
+		return err
+	}
+	invoice.Year = year
+	invoice.Sequence = sequence
+	invoice.Number = fmt.Sprintf("%d-%06d", year, invoice.Sequence)
+	invoice.Status = InvoiceStatusIssued
+	invoice.IssueDate = now.Format(time.DateOnly)
+	return getInvoiceDB(c).UpdateDocument(ctx, invoice.Id, invoice)
+}
+
+// takeInvoiceSequence advances the counter of the year and returns the number taken.
+func takeInvoiceSequence(ctx context.Context, c *gin.Context, year int32) (int32, error) {
+	counters := getInvoiceCounterDB(c)
+	id := strconv.Itoa(int(year))
+	for attempt := 1; ; attempt++ {
+		counter, err := counters.FindDocument(ctx, id)
+		switch {
+		case err == db_service.ErrNotFound:
+			var invoices []*Invoice
+			if invoices, err = getInvoiceDB(c).FindDocumentsByField(ctx, "year", year); err != nil {
+				return 0, err
+			}
+			counter = &InvoiceCounter{Id: id, Year: year, Sequence: nextInvoiceSequence(invoices)}
+			err = counters.CreateDocument(ctx, id, counter)
+		case err != nil:
+			return 0, err
+		default:
+			taken := counter.Sequence
+			counter.Sequence++
+			err = counters.UpdateDocumentIf(ctx, id, map[string]any{"sequence": taken}, counter)
+		}
+		if err == nil {
+			return counter.Sequence, nil
+		}
+		if err != db_service.ErrConflict || attempt == invoiceNumberingAttempts {
+			return 0, err
+		}
+	}
+}
+
+// billInvoicedProcedures moves the completed procedures of an issued invoice to billed.
+func billInvoicedProcedures(ctx context.Context, c *gin.Context, invoice *Invoice, role string, now time.Time) error {
+	procedureDb := getProcedureDB(c)
+	for _, line := range invoice.Lines {
+		procedure, err := procedureDb.FindDocument(ctx, line.ProcedureId)
+		if err != nil {
+			return err
+		}
+		if procedureStatus(procedure) != ProcedureStatusCompleted {
+			continue
+		}
+		recordProcedureStatus(procedure, ProcedureStatusBilled, role, "Invoice "+invoice.Number, now)
+		if err := procedureDb.UpdateDocument(ctx, procedure.Id, procedure); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// allocatePayment allocates amount of a payment against the invoice; available is the
+// part of the payment not refunded or allocated elsewhere. Without an amount as much is
+// allocated as the payment and the invoice balance allow. The invoice becomes partially
+// paid or paid.
+func allocatePayment(invoice *Invoice, paymentId string, available, amount money.Money, now time.Time) (interface{}, int) {
+	if amount.IsNegative() {
+		return gin.H{"message": "amount must not be negative"}, http.StatusUnprocessableEntity
+	}
+	if _, err := invoice.Balance.Add(available); err != nil {
+		return gin.H{"message": "the payment must be in the currency of the invoice, " + invoice.Total.Currency}, http.StatusUnprocessableEntity
+	}
+	if amount.IsZero() {
+		amount = available
+		if rest, _ := invoice.Balance.Sub(available); rest.IsNegative() {
+			amount = invoice.Balance
+		}
+	}
+	remaining, err := available.Sub(amount)
+	if err != nil {
+		return gin.H{"message": "amount must be in the currency of the invoice, " + invoice.Total.Currency}, http.StatusUnprocessableEntity
+	}
+	if remaining.IsNegative() {
+		return gin.H{"message": "amount exceeds the part of the payment not refunded or allocated yet", "available": available}, http.StatusConflict
+	}
+	balance, _ := invoice.Balance.Sub(amount)
+	if balance.IsNegative() {
+		return gin.H{"message": "amount exceeds the balance of the invoice", "balance": invoice.Balance}, http.StatusConflict
+	}
+	if amount.IsZero() {
+		return gin.H{"message": "Nothing to allocate"}, http.StatusConflict
+	}
+
+	invoice.Allocations = append(invoice.Allocations, InvoiceAllocation{PaymentId: paymentId, Amount: amount, AllocatedAt: now})
+	invoice.Paid, _ = invoice.Paid.Add(amount)
+	invoice.Balance = balance
+	invoice.Status = InvoiceStatusPartiallyPaid
+	if balance.IsZero() {
+		invoice.Status = InvoiceStatusPaid
+	}
+	return nil, 0
+}
+
+// CreateInvoice implements POST /api/invoices
+//
+// The invoice starts as a draft without a number; the line amounts are the procedure prices.
+func (o *implInvoicesAPI) CreateInvoice(c *gin.Context) {
+	var invoice Invoice
+	if err := c.ShouldBindJSON(&invoice); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if invoice.Id == "" {
+		invoice.Id = uuid.NewString()
+	}
+	invoice.Status = InvoiceStatusDraft
+	invoice.CreatedAt = time.Now()
+	invoice.Number, invoice.Year, invoice.Sequence, invoice.IssueDate, invoice.CancellationReason = "", 0, 0, "", ""
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if problem, status, err := prepareInvoice(ctx, c, &invoice); err != nil {
+		log.Println("prepareInvoice error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create invoice"})
+		return
+	} else if problem != "" {
+		c.JSON(status, gin.H{"message": problem})
+		return
+	}
+
+	if err := getInvoiceDB(c).CreateDocument(ctx, invoice.Id, &invoice); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Invoice already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create invoice"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, invoice)
+}
+
+// GetInvoiceById implements GET /api/invoices/:invoiceId
+//
+// The Accept header selects the representation: JSON (the default), HTML or PDF.
+func (o *implInvoicesAPI) GetInvoiceById(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	invoice, err := getInvoiceDB(c).FindDocument(ctx, c.Param("invoiceId"))
+	if err != nil {
+		if err == db_service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Invoice not found"})
+		} else {
+			log.Println("FindDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+		}
+		return
+	}
+
+	var (
+		body   bytes.Buffer
+		render func(*bytes.Buffer, *Invoice) error
+	)
+	format := c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML, MIMEPDF)
+	switch format {
+	case gin.MIMEJSON:
+		c.JSON(http.StatusOK, invoice)
+		return
+	case gin.MIMEHTML:
+		render = renderInvoiceHTML
+		format += "; charset=utf-8"
+	case MIMEPDF:
+		render = renderInvoicePDF
+		c.Header("Content-Disposition", `inline; filename="`+invoiceFileName(invoice)+`.pdf"`)
+	default:
+		c.JSON(http.StatusNotAcceptable, gin.H{"message": "Invoices are available as application/json, text/html and application/pdf"})
+		return
+	}
+	if err := render(&body, invoice); err != nil {
+		log.Println("render invoice error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to render invoice"})
+		return
+	}
+	c.Data(http.StatusOK, format, body.Bytes())
+}
+
+// GetInvoices implements GET /api/invoices
+//
+// Invoices are returned newest first, optionally only those in ?status=, of ?year= or
+// billed to ?payer_id=.
+func (o *implInvoicesAPI) GetInvoices(c *gin.Context) {
+	var year int64
+	if value := c.Query("year"); value != "" {
+		var err error
+		if year, err = strconv.ParseInt(value, 10, 32); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "year must be a number"})
+			return
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	invoices, err := getInvoiceDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving invoices:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve invoices"})
+		return
+	}
+
+	status, payerID := strings.ToLower(c.Query("status")), c.Query("payer_id")
+	selected := make([]Invoice, 0, len(invoices))
+	for _, invoice := range invoices {
+		if (status == "" || invoice.Status == status) && (year == 0 || int64(invoice.Year) == year) &&
+			(payerID == "" || invoice.PayerId == payerID) {
+			selected = append(selected, invoice)
+		}
+	}
+	sort.Slice(selected, func(i, j int) bool {
+		return selected[i].CreatedAt.After(selected[j].CreatedAt)
+	})
+	c.JSON(http.StatusOK, selected)
+}
+
+// UpdateInvoice implements PUT /api/invoices/:invoiceId
+//
+// Only draft invoices can change.
+func (o *implInvoicesAPI) UpdateInvoice(c *gin.Context) {
+	withInvoiceByID(c, func(c *gin.Context, existing *Invoice) (*Invoice, interface{}, int) {
+		if existing.Status != InvoiceStatusDraft {
+			return nil, gin.H{"message": "Only draft invoices can be changed; the invoice is " + existing.Status}, http.StatusConflict
+		}
+		var upd Invoice
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.PayerId != "" || upd.BillTo != "" {
+			existing.PayerId, existing.BillTo = upd.PayerId, upd.BillTo
+		}
+		if upd.BillToAddress != "" {
+			existing.BillToAddress = upd.BillToAddress
+		}
+		if upd.Lines != nil {
+			existing.Lines = upd.Lines
+		}
+		if upd.DueDate != "" {
+			existing.DueDate = upd.DueDate
+		}
+		if upd.Notes != "" {
+			existing.Notes = upd.Notes
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if problem, status, err := prepareInvoice(ctx, c, existing); err != nil {
+			log.Println("prepareInvoice error:", err)
+			return nil, gin.H{"message": "Failed to update invoice"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, status
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteInvoice implements DELETE /api/invoices/:invoiceId
+//
+// Only draft invoices can be deleted; issued ones keep their number and are cancelled instead.
+func (o *implInvoicesAPI) DeleteInvoice(c *gin.Context) {
+	withInvoiceByID(c, func(c *gin.Context, invoice *Invoice) (*Invoice, interface{}, int) {
+		if invoice.Status != InvoiceStatusDraft {
+			return nil, gin.H{"message": "Only draft invoices can be deleted; cancel issued invoices instead"}, http.StatusConflict
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := getInvoiceDB(c).DeleteDocument(ctx, invoice.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete invoice"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// IssueInvoice implements POST /api/invoices/:invoiceId/issuance
+//
+// Issuing is billing: only billing may issue invoices, and the invoiced procedures that
+// are completed become billed. The invoice is dated today and takes the next number of
+// the year.
+func (o *implInvoicesAPI) IssueInvoice(c *gin.Context) {
+	// issuances at once conflict on the counter of the year; issuing reads no body and
+	// loads the invoice again, so the issuance can simply run again
+	c.Request = c.Request.WithContext(db_service.RetryOnWriteConflict(c.Request.Context()))
+	withInvoiceByID(c, func(c *gin.Context, invoice *Invoice) (*Invoice, interface{}, int) {
+		role := userRole(c)
+		if role != RoleBilling {
+			return nil, gin.H{"message": "Only billing can issue invoices"}, http.StatusForbidden
+		}
+		if invoice.Status != InvoiceStatusDraft {
+			return nil, gin.H{"message": "Only draft invoices can be issued; the invoice is " + invoice.Status}, http.StatusConflict
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		// the procedures may have changed since the draft was saved
+		if problem, status, err := prepareInvoice(ctx, c, invoice); err != nil {
+			log.Println("prepareInvoice error:", err)
+			return nil, gin.H{"message": "Failed to issue invoice"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, status
+		}
+		now := time.Now()
+		if invoice.DueDate == "" {
+			dueDate, err := invoiceDueDate(ctx, c, invoice, now)
+			if err != nil {
+				log.Println("invoiceDueDate error:", err)
+				return nil, gin.H{"message": "Failed to issue invoice"}, http.StatusInternalServerError
+			}
+			invoice.DueDate = dueDate
+		} else if invoice.DueDate < now.Format(time.DateOnly) {
+			return nil, gin.H{"message": "due_date must not be before the issue date"}, http.StatusUnprocessableEntity
+		}
+
+		if err := issueInvoiceNumber(ctx, c, invoice, now); err != nil {
+			log.Println("issueInvoiceNumber error:", err)
+			return nil, gin.H{"message": "Failed to issue invoice"}, http.StatusInternalServerError
+		}
+		// the failed issuance is rolled back with its number
+		if err := billInvoicedProcedures(ctx, c, invoice, role, now); err != nil {
+			log.Println("billInvoicedProcedures error:", err)
+			return nil, gin.H{"message": "Failed to issue invoice"}, http.StatusInternalServerError
+		}
+		return nil, invoice, http.StatusOK
+	})
+}
+
+// CancelInvoice implements POST /api/invoices/:invoiceId/cancellation
+//
+// Only billing may cancel an issued invoice, and only before payments are allocated
+// against it. The invoice keeps its number; its procedures can be invoiced again.
+func (o *implInvoicesAPI) CancelInvoice(c *gin.Context) {
+	withInvoiceByID(c, func(c *gin.Context, invoice *Invoice) (*Invoice, interface{}, int) {
+		if userRole(c) != RoleBilling {
+			return nil, gin.H{"message": "Only billing can cancel invoices"}, http.StatusForbidden
+		}
+		var request InvoiceCancellation
+		if err := c.ShouldBindJSON(&request); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			return nil, gin.H{"message": "reason is required"}, http.StatusUnprocessableEntity
+		}
+		switch {
+		case invoice.Status == InvoiceStatusDraft:
+			return nil, gin.H{"message": "Draft invoices are deleted, not cancelled"}, http.StatusConflict
+		case invoice.Status != InvoiceStatusIssued || len(invoice.Allocations) > 0:
+			return nil, gin.H{"message": "Only issued invoices without allocated payments can be cancelled; the invoice is " + invoice.Status}, http.StatusConflict
+		}
+		invoice.Status = InvoiceStatusCancelled
+		invoice.CancellationReason = strings.TrimSpace(request.Reason)
+		return invoice, invoice, http.StatusOK
+	})
+}
+
+// AllocatePayment implements POST /api/invoices/:invoiceId/allocations
+//
+// A settled payment can be split across invoices; the allocations of a payment never
+// exceed its amount less refunds.
+func (o *implInvoicesAPI) AllocatePayment(c *gin.Context) {
+	withInvoiceByID(c, func(c *gin.Context, invoice *Invoice) (*Invoice, interface{}, int) {
+		if invoice.Status != InvoiceStatusIssued && invoice.Status != InvoiceStatusPartiallyPaid {
+			return nil, gin.H{"message": "Payments can only be allocated against issued invoices; the invoice is " + invoice.Status}, http.StatusConflict
+		}
+		var request InvoiceAllocation
+		if err := c.ShouldBindJSON(&request); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		paymentDb := getPaymentDB(c)
+		payment, err := paymentDb.FindDocument(ctx, request.PaymentId)
+		if err == db_service.ErrNotFound {
+			return nil, gin.H{"message": "payment_id does not reference an existing payment"}, http.StatusUnprocessableEntity
+		}
+		if err != nil {
+			log.Println("FindDocument error:", err)
+			return nil, gin.H{"message": "Failed to allocate payment"}, http.StatusInternalServerError
+		}
+		if payment.RefundOf != "" {
+			return nil, gin.H{"message": "Refunds cannot be allocated"}, http.StatusUnprocessableEntity
+		}
+		if paymentStatus(payment) != PaymentStatusSettled {
+			return nil, gin.H{"message": "Only settled payments can be allocated; the payment is " + paymentStatus(payment)}, http.StatusConflict
+		}
+
+		refunds, err := paymentDb.FindDocumentsByField(ctx, "refund_of", payment.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to allocate payment"}, http.StatusInternalServerError
+		}
+		available, err := refundableAmount(payment, refunds)
+		if err != nil {
+			log.Println("refundableAmount error:", err)
+			return nil, gin.H{"message": "Failed to allocate payment"}, http.StatusInternalServerError
+		}
+		others, err := getInvoiceDB(c).FindDocumentsByField(ctx, "allocations.payment_id", payment.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to allocate payment"}, http.StatusInternalServerError
+		}
+		allocations := invoice.Allocations
+		for _, other := range others {
+			if other.Id != invoice.Id {
+				allocations = append(allocations, other.Allocations...)
+			}
+		}
+		for _, allocation := range allocations {
+			if allocation.PaymentId == payment.Id {
+				if available, err = available.Sub(allocation.Amount); err != nil {
+					log.Println("allocation error:", err)
+					return nil, gin.H{"message": "Failed to allocate payment"}, http.StatusInternalServerError
+				}
+			}
+		}
+
+		if result, status := allocatePayment(invoice, payment.Id, available, request.Amount, time.Now()); result != nil {
+			return nil, result, status
+		}
+		return invoice, invoice, http.StatusCreated
+	})
+}
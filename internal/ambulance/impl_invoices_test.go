@@ -0,0 +1,303 @@
+package ambulance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// InvoicesSuite defines the suite for invoice tests
+type InvoicesSuite struct {
+	suite.Suite
+	invoiceDbMock   *DbServiceMock[Invoice]
+	counterDb       db_service.DbService[InvoiceCounter]
+	procedureDbMock *DbServiceMock[Procedure]
+	invoice         Invoice
+	procedure       Procedure
+}
+
+func TestInvoicesSuite(t *testing.T) {
+	suite.Run(t, new(InvoicesSuite))
+}
+
+func (suite *InvoicesSuite) SetupTest() {
+	rate := 20.0
+	suite.invoice = Invoice{
+		Id:        "inv1",
+		Status:    InvoiceStatusDraft,
+		BillTo:    "Ján Novák",
+		Lines:     []InvoiceLine{{ProcedureId: "proc001", TaxRate: &rate}},
+		CreatedAt: time.Now(),
+	}
+	suite.procedure = Procedure{
+		Id:     "proc001",
+		Name:   "Chest X-ray",
+		Code:   "RTG-CHEST",
+		Price:  eur("120"),
+		Status: ProcedureStatusCompleted,
+	}
+
+	suite.invoiceDbMock = &DbServiceMock[Invoice]{}
+	suite.invoiceDbMock.
+		On("FindDocument", mock.Anything, "inv1").
+		Return(&suite.invoice, nil)
+	suite.invoiceDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Invoice{suite.invoice}, nil)
+	suite.invoiceDbMock.
+		On("UpdateDocument", mock.Anything, "inv1", mock.Anything).
+		Return(nil)
+	suite.invoiceDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	suite.counterDb = db_service.NewMemoryService[InvoiceCounter]()
+
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&suite.procedure, nil)
+	suite.procedureDbMock.
+		On("UpdateDocument", mock.Anything, "proc001", mock.Anything).
+		Return(nil)
+}
+
+func (suite *InvoicesSuite) request(method, path, payload, role string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_invoice", suite.invoiceDbMock)
+	ctx.Set("db_service_invoice_counter", suite.counterDb)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Params = []gin.Param{{Key: "invoiceId", Value: "inv1"}}
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	ctx.Request.Header.Set("X-User-Role", role)
+	return ctx, recorder
+}
+
+func (suite *InvoicesSuite) Test_CreateInvoice_RejectsProcedureInvoicedElsewhere() {
+	ctx, recorder := suite.request("POST", "/api/invoices", `{"bill_to": "Ján Novák", "lines": [{"procedure_id": "proc001"}]}`, "")
+
+	(&implInvoicesAPI{}).CreateInvoice(ctx)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.invoiceDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *InvoicesSuite) Test_UpdateInvoice_ComputesTaxAndTotals() {
+	ctx, recorder := suite.request("PUT", "/api/invoices/inv1", `{"lines": [{"procedure_id": "proc001", "tax_rate": 10}]}`, "")
+
+	(&implInvoicesAPI{}).UpdateInvoice(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("RTG-CHEST Chest X-ray", suite.invoice.Lines[0].Description)
+	suite.Equal(eur("12"), suite.invoice.TaxTotal)
+	suite.Equal(eur("132"), suite.invoice.Total)
+	suite.Equal(eur("132"), suite.invoice.Balance)
+}
+
+func (suite *InvoicesSuite) Test_IssueInvoice_TakesNextNumberOfYearAndBillsProcedures() {
+	year := int32(time.Now().Year())
+	suite.invoiceDbMock.
+		On("FindDocumentsByField", mock.Anything, "year", year).
+		Return([]*Invoice{{Id: "inv0", Year: year, Sequence: 41}, {Id: "inv9", Year: year, Sequence: 7}}, nil)
+	ctx, recorder := suite.request("POST", "/api/invoices/inv1/issuance", "", RoleBilling)
+
+	(&implInvoicesAPI{}).IssueInvoice(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(fmt.Sprintf("%d-000042", year), suite.invoice.Number)
+	suite.Equal(InvoiceStatusIssued, suite.invoice.Status)
+	suite.Equal(time.Now().AddDate(0, 0, DefaultInvoicePaymentTermsDays).Format(time.DateOnly), suite.invoice.DueDate)
+	suite.Equal(ProcedureStatusBilled, suite.procedure.Status)
+	counter, err := suite.counterDb.FindDocument(context.Background(), strconv.Itoa(int(year)))
+	suite.Require().NoError(err)
+	suite.Equal(int32(42), counter.Sequence)
+}
+
+func (suite *InvoicesSuite) Test_IssueInvoice_AdvancesCounterOfYear() {
+	year := int32(time.Now().Year())
+	id := strconv.Itoa(int(year))
+	suite.Require().NoError(suite.counterDb.CreateDocument(context.Background(), id, &InvoiceCounter{Id: id, Year: year, Sequence: 42}))
+	ctx, recorder := suite.request("POST", "/api/invoices/inv1/issuance", "", RoleBilling)
+
+	(&implInvoicesAPI{}).IssueInvoice(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(fmt.Sprintf("%d-000043", year), suite.invoice.Number)
+	counter, err := suite.counterDb.FindDocument(context.Background(), id)
+	suite.Require().NoError(err)
+	suite.Equal(int32(43), counter.Sequence)
+	suite.invoiceDbMock.AssertNotCalled(suite.T(), "FindDocumentsByField", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *InvoicesSuite) Test_IssueInvoice_GivesNumberBackWhenBillingFails() {
+	year := int32(time.Now().Year())
+	id := strconv.Itoa(int(year))
+	suite.Require().NoError(suite.counterDb.CreateDocument(context.Background(), id, &InvoiceCounter{Id: id, Year: year, Sequence: 42}))
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&suite.procedure, nil)
+	suite.procedureDbMock.
+		On("UpdateDocument", mock.Anything, "proc001", mock.Anything).
+		Return(errors.New("connection lost"))
+	ctx, recorder := suite.request("POST", "/api/invoices/inv1/issuance", "", RoleBilling)
+	ctx.Set("db_transactor", db_service.NewMemoryTransactor())
+
+	(&implInvoicesAPI{}).IssueInvoice(ctx)
+
+	suite.Equal(http.StatusInternalServerError, recorder.Code)
+	counter, err := suite.counterDb.FindDocument(context.Background(), id)
+	suite.Require().NoError(err)
+	suite.Equal(int32(42), counter.Sequence)
+}
+
+func (suite *InvoicesSuite) Test_IssueInvoice_RequiresBillingRole() {
+	ctx, recorder := suite.request("POST", "/api/invoices/inv1/issuance", "", "doctor")
+
+	(&implInvoicesAPI{}).IssueInvoice(ctx)
+
+	suite.Equal(http.StatusForbidden, recorder.Code)
+	suite.Empty(suite.invoice.Number)
+}
+
+func (suite *InvoicesSuite) Test_AllocatePayment_LimitedByPaymentAndBalance() {
+	invoice := Invoice{Status: InvoiceStatusIssued, Total: eur("120"), Paid: eur("0"), Balance: eur("120")}
+
+	_, status := allocatePayment(&invoice, "pay1", eur("50"), eur("60"), time.Now())
+	suite.Equal(http.StatusConflict, status)
+
+	result, _ := allocatePayment(&invoice, "pay1", eur("50"), eur("0"), time.Now())
+	suite.Nil(result)
+	suite.Equal(eur("50"), invoice.Paid)
+	suite.Equal(eur("70"), invoice.Balance)
+	suite.Equal(InvoiceStatusPartiallyPaid, invoice.Status)
+
+	result, _ = allocatePayment(&invoice, "pay2", eur("200"), eur("0"), time.Now())
+	suite.Nil(result)
+	suite.Equal(eur("70"), invoice.Allocations[1].Amount)
+	suite.Equal(InvoiceStatusPaid, invoice.Status)
+}
+
+func (suite *InvoicesSuite) Test_GetInvoiceById_NegotiatesFormat() {
+	suite.invoice.Status, suite.invoice.Number = InvoiceStatusIssued, "2025-000042"
+	for accept, expected := range map[string]string{
+		"application/json": `"number":"2025-000042"`,
+		"text/html":        "Invoice 2025-000042",
+		"application/pdf":  "%PDF-1.4",
+	} {
+		ctx, recorder := suite.request("GET", "/api/invoices/inv1", "", "")
+		ctx.Request.Header.Set("Accept", accept)
+
+		(&implInvoicesAPI{}).GetInvoiceById(ctx)
+
+		suite.Equal(http.StatusOK, recorder.Code, accept)
+		suite.Contains(recorder.Header().Get("Content-Type"), accept)
+		suite.Contains(recorder.Body.String(), expected)
+	}
+
+	ctx, recorder := suite.request("GET", "/api/invoices/inv1", "", "")
+	ctx.Request.Header.Set("Accept", "image/png")
+	(&implInvoicesAPI{}).GetInvoiceById(ctx)
+	suite.Equal(http.StatusNotAcceptable, recorder.Code)
+}
+
+// racingCounters advances the counter once, as an invoice issued at the same time would,
+// before the first conditional update.
+type racingCounters struct {
+	db_service.DbService[InvoiceCounter]
+	raced bool
+}
+
+func (r *racingCounters) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *InvoiceCounter) error {
+	if !r.raced {
+		r.raced = true
+		counter, err := r.DbService.FindDocument(ctx, id)
+		if err != nil {
+			return err
+		}
+		counter.Sequence++
+		if err := r.DbService.UpdateDocument(ctx, id, counter); err != nil {
+			return err
+		}
+	}
+	return r.DbService.UpdateDocumentIf(ctx, id, fields, document)
+}
+
+func (suite *InvoicesSuite) Test_IssueInvoice_RetriesNumberTakenMeanwhile() {
+	year := int32(time.Now().Year())
+	id := strconv.Itoa(int(year))
+	suite.Require().NoError(suite.counterDb.CreateDocument(context.Background(), id, &InvoiceCounter{Id: id, Year: year, Sequence: 42}))
+	suite.counterDb = &racingCounters{DbService: suite.counterDb}
+	ctx, recorder := suite.request("POST", "/api/invoices/inv1/issuance", "", RoleBilling)
+
+	(&implInvoicesAPI{}).IssueInvoice(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(fmt.Sprintf("%d-000044", year), suite.invoice.Number)
+}
+
+// conflictingTransactor aborts the first transaction, as the database does when another
+// transaction wrote the same documents, and runs fn again as RetryOnWriteConflict asks.
+type conflictingTransactor struct {
+	*db_service.MemoryTransactor
+	attempts int
+}
+
+func (t *conflictingTransactor) RunInTransaction(ctx context.Context, fn func(context.Context) error) error {
+	t.attempts++
+	err := t.MemoryTransactor.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if err := fn(txCtx); err != nil {
+			return err
+		}
+		return errors.New("write conflict")
+	})
+	if err == nil {
+		return nil
+	}
+	t.attempts++
+	return t.MemoryTransactor.RunInTransaction(ctx, fn)
+}
+
+func (suite *InvoicesSuite) Test_IssueInvoice_RunsAgainAfterWriteConflict() {
+	year := int32(time.Now().Year())
+	id := strconv.Itoa(int(year))
+	suite.Require().NoError(suite.counterDb.CreateDocument(context.Background(), id, &InvoiceCounter{Id: id, Year: year, Sequence: 42}))
+	transactor := &conflictingTransactor{MemoryTransactor: db_service.NewMemoryTransactor()}
+	// the first attempt is rolled back only in services taking part in transactions
+	invoiceDb := db_service.NewMemoryService[Invoice]()
+	suite.Require().NoError(invoiceDb.CreateDocument(context.Background(), "inv1", &suite.invoice))
+	procedureDb := db_service.NewMemoryService[Procedure]()
+	suite.Require().NoError(procedureDb.CreateDocument(context.Background(), "proc001", &suite.procedure))
+	ctx, recorder := suite.request("POST", "/api/invoices/inv1/issuance", "", RoleBilling)
+	ctx.Set("db_service_invoice", invoiceDb)
+	ctx.Set("db_service_procedure", procedureDb)
+	ctx.Set("db_transactor", transactor)
+
+	(&implInvoicesAPI{}).IssueInvoice(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(2, transactor.attempts)
+	invoice, err := invoiceDb.FindDocument(context.Background(), "inv1")
+	suite.Require().NoError(err)
+	suite.Equal(fmt.Sprintf("%d-000043", year), invoice.Number)
+	counter, err := suite.counterDb.FindDocument(context.Background(), id)
+	suite.Require().NoError(err)
+	suite.Equal(int32(43), counter.Sequence)
+	procedure, err := procedureDb.FindDocument(context.Background(), "proc001")
+	suite.Require().NoError(err)
+	suite.Equal(ProcedureStatusBilled, procedure.Status)
+}
@@ -0,0 +1,351 @@
+package ambulance
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	// locale time zones must resolve in the scratch container image, which has no zoneinfo
+	_ "time/tzdata"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/money"
+	"github.com/wac-project/wac-api/internal/xlsx"
+	"golang.org/x/text/language"
+)
+
+// MIMEXLSX is the content type of Office Open XML spreadsheets.
+const MIMEXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+
+// exportFlushRows is the number of rows written to the client between flushes of an export.
+const exportFlushRows = 200
+
+// formatNames are the values of ?format= and the content types they stand for.
+var formatNames = map[string]string{"json": gin.MIMEJSON, "csv": MIMECSV, "xlsx": MIMEXLSX}
+
+// negotiateFormat returns the offered content type named by ?format= when given, the one
+// the Accept header prefers otherwise. It returns "" when neither is offered.
+func negotiateFormat(c *gin.Context, offered ...string) string {
+	if name := c.Query("format"); name != "" {
+		for _, offer := range offered {
+			if formatNames[name] == offer {
+				return offer
+			}
+		}
+		return ""
+	}
+	return c.NegotiateFormat(offered...)
+}
+
+// exportLocale is how CSV exports write, and CSV imports read, numbers and times.
+// Spreadsheets store numbers and dates as such and leave their display to the application.
+type exportLocale struct {
+	decimal  string
+	comma    rune
+	dateTime string
+	zone     string
+}
+
+// exportLocales are keyed by language. Slovak and Czech spreadsheet applications expect
+// a decimal comma and therefore semicolon-separated fields.
+var exportLocales = map[string]exportLocale{
+	"en": {decimal: ".", comma: ',', dateTime: "2006-01-02 15:04", zone: "UTC"},
+	"sk": {decimal: ",", comma: ';', dateTime: "2.1.2006 15:04", zone: "Europe/Bratislava"},
+	"cs": {decimal: ",", comma: ';', dateTime: "2.1.2006 15:04", zone: "Europe/Prague"},
+}
+
+var exportLanguages = language.NewMatcher([]language.Tag{language.English, language.Slovak, language.Czech})
+
+// requestLocale returns the locale named by ?locale=, or the one best matching the
+// Accept-Language header, English by default. It returns a problem for unknown locales.
+func requestLocale(c *gin.Context) (exportLocale, string) {
+	if value := c.Query("locale"); value != "" {
+		tag, err := language.Parse(value)
+		base, _ := tag.Base()
+		locale, ok := exportLocales[base.String()]
+		if err != nil || !ok {
+			return exportLocale{}, "locale must be one of en, sk, cs"
+		}
+		return locale, ""
+	}
+	if tags, _, err := language.ParseAcceptLanguage(c.GetHeader("Accept-Language")); err == nil && len(tags) > 0 {
+		if tag, _, confidence := exportLanguages.Match(tags...); confidence != language.No {
+			base, _ := tag.Base()
+			if locale, ok := exportLocales[base.String()]; ok {
+				return locale, ""
+			}
+		}
+	}
+	return exportLocales["en"], ""
+}
+
+// location returns the time zone of the locale, UTC when it is not available.
+func (l exportLocale) location() *time.Location {
+	if zone, err := time.LoadLocation(l.zone); err == nil {
+		return zone
+	}
+	return time.UTC
+}
+
+// listColumn is a column of list exports and CSV imports. field returns a pointer to
+// the field of a row the column reads and, on import, writes: a *string, *int32,
+// *money.Money or *time.Time. Columns computed by value instead are only exported.
+type listColumn[T any] struct {
+	name  string
+	value func(*T) any
+	field func(*T) any
+}
+
+// get returns the value of the column in a row: a string, int32, money.Money,
+// *money.Money or time.Time.
+func (column listColumn[T]) get(row *T) any {
+	if column.value != nil {
+		return column.value(row)
+	}
+	switch field := column.field(row).(type) {
+	case *string:
+		return *field
+	case *int32:
+		return *field
+	case *money.Money:
+		return *field
+	case *time.Time:
+		return *field
+	}
+	return nil
+}
+
+// listExport writes a list as JSON, or as CSV or XLSX rows streamed to the client.
+type listExport[T any] struct {
+	format  string
+	name    string
+	columns []listColumn[T]
+	locale  exportLocale
+	zone    *time.Location
+}
+
+// newListExport negotiates the format of a list response, reading ?format= or the
+// Accept header. Exports take the columns named in ?columns= (all by default) and the
+// locale of ?locale= or the Accept-Language header (English by default).
+func newListExport[T any](c *gin.Context, name string, columns []listColumn[T]) (*listExport[T], interface{}, int) {
+	e := &listExport[T]{format: negotiateFormat(c, gin.MIMEJSON, MIMECSV, MIMEXLSX), name: name, columns: columns}
+	if e.format == "" {
+		return nil, gin.H{"message": "The list is available as application/json, text/csv and " + MIMEXLSX}, http.StatusNotAcceptable
+	}
+	if e.format == gin.MIMEJSON {
+		return e, nil, http.StatusOK
+	}
+
+	if value := c.Query("columns"); value != "" {
+		byName := map[string]listColumn[T]{}
+		names := make([]string, 0, len(columns))
+		for _, column := range columns {
+			byName[column.name] = column
+			names = append(names, column.name)
+		}
+		e.columns = nil
+		for _, name := range strings.Split(value, ",") {
+			column, ok := byName[strings.TrimSpace(name)]
+			if !ok {
+				return nil, gin.H{"message": fmt.Sprintf("Unknown column %q; the columns are %s", name, strings.Join(names, ", "))}, http.StatusBadRequest
+			}
+			e.columns = append(e.columns, column)
+		}
+	}
+
+	locale, problem := requestLocale(c)
+	if problem != "" {
+		return nil, gin.H{"message": problem}, http.StatusBadRequest
+	}
+	e.locale, e.zone = locale, locale.location()
+	return e, nil, http.StatusOK
+}
+
+// text formats a value for CSV. Text that a spreadsheet would take for a formula is
+// prefixed with an apostrophe.
+func (e *listExport[T]) text(value any) string {
+	switch v := value.(type) {
+	case string:
+		if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+			return "'" + v
+		}
+		return v
+	case int32:
+		return strconv.Itoa(int(v))
+	case *money.Money:
+		if v == nil {
+			return ""
+		}
+		return e.text(*v)
+	case money.Money:
+		return strings.Replace(v.Decimal(), ".", e.locale.decimal, 1)
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.In(e.zone).Format(e.locale.dateTime)
+	}
+	return fmt.Sprint(value)
+}
+
+// cell converts a value to a spreadsheet cell.
+func (e *listExport[T]) cell(value any) xlsx.Cell {
+	switch v := value.(type) {
+	case string:
+		return xlsx.String(v)
+	case int32:
+		return xlsx.Number(strconv.Itoa(int(v)))
+	case *money.Money:
+		if v == nil {
+			return xlsx.Cell{}
+		}
+		return xlsx.Amount(v.Decimal())
+	case money.Money:
+		return xlsx.Amount(v.Decimal())
+	case time.Time:
+		if v.IsZero() {
+			return xlsx.Cell{}
+		}
+		return xlsx.DateTime(v.In(e.zone))
+	}
+	return xlsx.String(fmt.Sprint(value))
+}
+
+// write responds with the list in the negotiated format. CSV and XLSX rows are written
+// and flushed to the client as they are formatted.
+func (e *listExport[T]) write(c *gin.Context, list []T) {
+	if e.format == gin.MIMEJSON {
+		c.JSON(http.StatusOK, list)
+		return
+	}
+
+	extension := "csv"
+	if e.format == MIMEXLSX {
+		extension = "xlsx"
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.%s"`, e.name, time.Now().In(e.zone).Format(time.DateOnly), extension))
+	c.Status(http.StatusOK)
+
+	var err error
+	if e.format == MIMEXLSX {
+		c.Header("Content-Type", MIMEXLSX)
+		err = e.writeXLSX(c, list)
+	} else {
+		c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+		err = e.writeCSV(c, list)
+	}
+	if err != nil {
+		log.Println("export error:", err)
+	}
+}
+
+// writeCSV writes a header row and the rows. The byte order mark makes spreadsheet
+// applications read the file as UTF-8.
+func (e *listExport[T]) writeCSV(c *gin.Context, list []T) error {
+	c.Writer.WriteString("\ufeff")
+	w := csv.NewWriter(c.Writer)
+	w.Comma = e.locale.comma
+
+	record := make([]string, len(e.columns))
+	for i, column := range e.columns {
+		record[i] = column.name
+	}
+	w.Write(record)
+	for n := range list {
+		for i, column := range e.columns {
+			record[i] = e.text(column.get(&list[n]))
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		if (n+1)%exportFlushRows == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func (e *listExport[T]) writeXLSX(c *gin.Context, list []T) error {
+	w, err := xlsx.NewWriter(c.Writer, e.name)
+	if err != nil {
+		return err
+	}
+	cells := make([]xlsx.Cell, len(e.columns))
+	for i, column := range e.columns {
+		cells[i] = xlsx.Header(column.name)
+	}
+	w.WriteRow(cells...)
+	for n := range list {
+		for i, column := range e.columns {
+			cells[i] = e.cell(column.get(&list[n]))
+		}
+		if err := w.WriteRow(cells...); err != nil {
+			return err
+		}
+		if (n+1)%exportFlushRows == 0 {
+			if err := w.Flush(); err != nil {
+				return err
+			}
+			c.Writer.Flush()
+		}
+	}
+	return w.Close()
+}
+
+// exportCurrency returns the currency of an amount, which older records leave empty.
+func exportCurrency(m money.Money) string {
+	if m.Currency == "" {
+		return money.DefaultCurrency
+	}
+	return m.Currency
+}
+
+var ambulanceColumns = []listColumn[Ambulance]{
+	{"id", nil, func(a *Ambulance) any { return &a.Id }},
+	{"name", nil, func(a *Ambulance) any { return &a.Name }},
+	{"location", nil, func(a *Ambulance) any { return &a.Location }},
+	{"department_id", nil, func(a *Ambulance) any { return &a.DepartmentId }},
+	{"department", nil, func(a *Ambulance) any { return &a.Department }},
+	{"capacity", nil, func(a *Ambulance) any { return &a.Capacity }},
+	{"status", nil, func(a *Ambulance) any { return &a.Status }},
+	{"odometer", nil, func(a *Ambulance) any { return &a.Odometer }},
+	{"crew", func(a *Ambulance) any { return int32(len(a.Crew)) }, nil},
+}
+
+var procedureColumns = []listColumn[Procedure]{
+	{"id", nil, func(p *Procedure) any { return &p.Id }},
+	{"code", nil, func(p *Procedure) any { return &p.Code }},
+	{"name", nil, func(p *Procedure) any { return &p.Name }},
+	{"patient_id", nil, func(p *Procedure) any { return &p.PatientId }},
+	{"patient", nil, func(p *Procedure) any { return &p.Patient }},
+	{"visit_type", nil, func(p *Procedure) any { return &p.VisitType }},
+	{"ambulance_id", nil, func(p *Procedure) any { return &p.AmbulanceId }},
+	{"timestamp", nil, func(p *Procedure) any { return &p.Timestamp }},
+	{"duration", nil, func(p *Procedure) any { return &p.Duration }},
+	{"status", func(p *Procedure) any { return procedureStatus(p) }, func(p *Procedure) any { return &p.Status }},
+	{"payer_id", nil, func(p *Procedure) any { return &p.PayerId }},
+	{"payer", nil, func(p *Procedure) any { return &p.Payer }},
+	{"price", nil, func(p *Procedure) any { return &p.Price }},
+	{"balance", func(p *Procedure) any { return p.Balance }, nil},
+	{"currency", func(p *Procedure) any { return exportCurrency(p.Price) }, nil},
+	{"payment_status", func(p *Procedure) any { return p.PaymentStatus }, nil},
+}
+
+var paymentColumns = []listColumn[Payment]{
+	{"id", nil, func(p *Payment) any { return &p.Id }},
+	{"procedure_id", nil, func(p *Payment) any { return &p.ProcedureId }},
+	{"payer_id", nil, func(p *Payment) any { return &p.PayerId }},
+	{"insurance", nil, func(p *Payment) any { return &p.Insurance }},
+	{"amount", nil, func(p *Payment) any { return &p.Amount }},
+	{"currency", func(p *Payment) any { return exportCurrency(p.Amount) }, nil},
+	{"status", func(p *Payment) any { return paymentStatus(p) }, func(p *Payment) any { return &p.Status }},
+	{"refund_of", nil, func(p *Payment) any { return &p.RefundOf }},
+	{"claim_id", nil, func(p *Payment) any { return &p.ClaimId }},
+	{"timestamp", nil, func(p *Payment) any { return &p.Timestamp }},
+	{"description", nil, func(p *Payment) any { return &p.Description }},
+}
@@ -0,0 +1,88 @@
+package ambulance
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// ListExportSuite defines the suite for CSV and XLSX list export tests
+type ListExportSuite struct {
+	suite.Suite
+	paymentDbMock *DbServiceMock[Payment]
+}
+
+func TestListExportSuite(t *testing.T) {
+	suite.Run(t, new(ListExportSuite))
+}
+
+func (suite *ListExportSuite) SetupTest() {
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Payment{
+			{Id: "pay1", ProcedureId: "proc001", Amount: eur("1234.5"), Description: "Röntgen hrudníka", Timestamp: time.Date(2025, 6, 2, 8, 30, 0, 0, time.UTC)},
+			{Id: "pay2", ProcedureId: "proc002", Amount: eur("-20"), Status: PaymentStatusRefunded, Description: "=HYPERLINK(\"x\")"},
+		}, nil)
+}
+
+func (suite *ListExportSuite) request(path string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Request = httptest.NewRequest("GET", path, nil)
+	return ctx, recorder
+}
+
+func (suite *ListExportSuite) Test_GetPayments_ExportsCSVInSlovakLocale() {
+	ctx, recorder := suite.request("/api/payments?columns=id,amount,timestamp,description")
+	ctx.Request.Header.Set("Accept", "text/csv")
+	ctx.Request.Header.Set("Accept-Language", "sk-SK,sk;q=0.9,en;q=0.5")
+
+	(&implPaymentAPI{}).GetPayments(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal("text/csv; charset=utf-8", recorder.Header().Get("Content-Type"))
+	suite.Contains(recorder.Header().Get("Content-Disposition"), `filename="payments-`)
+	suite.Equal("\ufeffid;amount;timestamp;description\n"+
+		"pay1;1234,50;2.6.2025 10:30;Röntgen hrudníka\n"+
+		"pay2;-20,00;;\"'=HYPERLINK(\"\"x\"\")\"\n", recorder.Body.String())
+}
+
+func (suite *ListExportSuite) Test_GetPayments_ExportsXLSX() {
+	ctx, recorder := suite.request("/api/payments?format=xlsx&columns=id,amount")
+
+	(&implPaymentAPI{}).GetPayments(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Equal(MIMEXLSX, recorder.Header().Get("Content-Type"))
+	data := recorder.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	suite.Require().NoError(err)
+	f, err := archive.Open("xl/worksheets/sheet1.xml")
+	suite.Require().NoError(err)
+	sheet, _ := io.ReadAll(f)
+	suite.Contains(string(sheet), `<c r="B2" s="2"><v>1234.50</v></c>`)
+}
+
+func (suite *ListExportSuite) Test_GetPayments_RejectsUnknownColumnAndFormat() {
+	ctx, recorder := suite.request("/api/payments?format=csv&columns=id,iban")
+	(&implPaymentAPI{}).GetPayments(ctx)
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+
+	ctx, recorder = suite.request("/api/payments")
+	ctx.Request.Header.Set("Accept", "application/pdf")
+	(&implPaymentAPI{}).GetPayments(ctx)
+	suite.Equal(http.StatusNotAcceptable, recorder.Code)
+
+	suite.paymentDbMock.AssertNotCalled(suite.T(), "ListDocuments", mock.Anything)
+}
@@ -0,0 +1,518 @@
+package ambulance
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// Ambulance statuses driven by the maintenance subsystem.
+const (
+	AmbulanceStatusMaintenance  = "Maintenance"
+	AmbulanceStatusOutOfService = "OutOfService"
+)
+
+// implMaintenanceAPI implements the MaintenanceManagementAPI interface.
+type implMaintenanceAPI struct{}
+
+// NewMaintenanceAPI returns an implementation of MaintenanceManagementAPI.
+func NewMaintenanceAPI() MaintenanceManagementAPI {
+	return &implMaintenanceAPI{}
+}
+
+// getMaintenanceDB extracts the DbService[MaintenanceEntry] from the context.
+func getMaintenanceDB(c *gin.Context) db_service.DbService[MaintenanceEntry] {
+	return c.MustGet("db_service_maintenance").(db_service.DbService[MaintenanceEntry])
+}
+
+// getServiceScheduleDB extracts the DbService[ServiceSchedule] from the context.
+func getServiceScheduleDB(c *gin.Context) db_service.DbService[ServiceSchedule] {
+	return c.MustGet("db_service_service_schedule").(db_service.DbService[ServiceSchedule])
+}
+
+// evaluateSchedule reports whether the schedule is overdue for an ambulance with the given odometer reading.
+func evaluateSchedule(s *ServiceSchedule, odometer int32, now time.Time) (MaintenanceDue, bool) {
+	due := MaintenanceDue{
+		ScheduleId:   s.Id,
+		ScheduleName: s.Name,
+		Mandatory:    s.Mandatory,
+		AmbulanceId:  s.AmbulanceId,
+		Odometer:     odometer,
+	}
+	overdue := false
+	if s.IntervalDays > 0 {
+		dueDate := s.LastServiceDate.AddDate(0, 0, int(s.IntervalDays))
+		due.DueDate = &dueDate
+		if !now.Before(dueDate) {
+			overdue = true
+		}
+	}
+	if s.IntervalKm > 0 {
+		dueOdometer := s.LastServiceOdometer + s.IntervalKm
+		due.DueOdometer = &dueOdometer
+		if odometer >= dueOdometer {
+			overdue = true
+		}
+	}
+	return due, overdue
+}
+
+// applyMaintenanceDue evaluates every service schedule, takes ambulances with an overdue
+// mandatory inspection out of service and returns the overdue services.
+func applyMaintenanceDue(
+	ctx context.Context,
+	ambulanceDb db_service.DbService[Ambulance],
+	scheduleDb db_service.DbService[ServiceSchedule],
+	now time.Time,
+) ([]MaintenanceDue, error) {
+	ambulances, err := ambulanceDb.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	schedules, err := scheduleDb.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byId := map[string]*Ambulance{}
+	for i := range ambulances {
+		byId[ambulances[i].Id] = &ambulances[i]
+	}
+
+	result := make([]MaintenanceDue, 0)
+	for i := range schedules {
+		ambulance, ok := byId[schedules[i].AmbulanceId]
+		if !ok {
+			continue
+		}
+		due, overdue := evaluateSchedule(&schedules[i], ambulance.Odometer, now)
+		if !overdue {
+			continue
+		}
+		due.AmbulanceName = ambulance.Name
+		result = append(result, due)
+
+		if due.Mandatory && ambulance.Status != AmbulanceStatusOutOfService {
+			log.Printf("Ambulance %v is out of service: mandatory %q is overdue", ambulance.Id, due.ScheduleName)
+			ambulance.Status = AmbulanceStatusOutOfService
+			switch err := takeOutOfService(ctx, ambulanceDb, ambulance.Id); err {
+			case nil:
+			case db_service.ErrNotFound, db_service.ErrConflict:
+				// deleted or changed meanwhile; the next evaluation sees the change
+				log.Printf("Ambulance %v was not taken out of service: %v", ambulance.Id, err)
+			default:
+				return nil, err
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Mandatory != result[j].Mandatory {
+			return result[i].Mandatory
+		}
+		return result[i].AmbulanceName < result[j].AmbulanceName
+	})
+	return result, nil
+}
+
+// takeOutOfService marks the ambulance out of service. The ambulance is read again and
+// written only while its status is still the one read, so that edits made since the
+// ambulances were listed are not overwritten.
+func takeOutOfService(ctx context.Context, ambulanceDb db_service.DbService[Ambulance], ambulanceId string) error {
+	ambulance, err := ambulanceDb.FindDocument(ctx, ambulanceId)
+	if err != nil {
+		return err
+	}
+	if ambulance.Status == AmbulanceStatusOutOfService {
+		return nil
+	}
+	previous := ambulance.Status
+	ambulance.Status = AmbulanceStatusOutOfService
+	return ambulanceDb.UpdateDocumentIf(ctx, ambulanceId, map[string]any{"status": previous}, ambulance)
+}
+
+// RunMaintenanceWatcher periodically applies overdue mandatory inspections until ctx is cancelled,
+// so that ambulances are taken out of service even when nobody queries the due list.
+func RunMaintenanceWatcher(
+	ctx context.Context,
+	ambulanceDb db_service.DbService[Ambulance],
+	scheduleDb db_service.DbService[ServiceSchedule],
+	interval time.Duration,
+) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		if _, err := applyMaintenanceDue(checkCtx, ambulanceDb, scheduleDb, time.Now()); err != nil {
+			log.Println("Maintenance check error:", err)
+		}
+		cancel()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CreateMaintenanceEntry implements POST /api/ambulances/:ambulanceId/maintenance
+//
+// Logging an entry advances the ambulance odometer and, when the entry names a
+// service schedule, restarts that schedule's interval.
+func (o *implMaintenanceAPI) CreateMaintenanceEntry(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		var e MaintenanceEntry
+		if err := c.ShouldBindJSON(&e); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if strings.TrimSpace(e.Type) == "" {
+			return nil, gin.H{"message": "type is required"}, http.StatusUnprocessableEntity
+		}
+		if e.Odometer < 0 || e.Cost < 0 {
+			return nil, gin.H{"message": "odometer and cost must not be negative"}, http.StatusUnprocessableEntity
+		}
+		if e.Id == "" {
+			e.Id = uuid.NewString()
+		}
+		e.AmbulanceId = ambulance.Id
+		if e.Timestamp.IsZero() {
+			e.Timestamp = time.Now()
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if e.ScheduleId != "" {
+			scheduleDb := getServiceScheduleDB(c)
+			schedule, err := scheduleDb.FindDocument(ctx, e.ScheduleId)
+			if err != nil {
+				if err == db_service.ErrNotFound {
+					return nil, gin.H{"message": "schedule_id does not reference an existing service schedule"}, http.StatusUnprocessableEntity
+				}
+				log.Println("FindDocument error:", err)
+				return nil, gin.H{"message": "Internal error"}, http.StatusInternalServerError
+			}
+			if schedule.AmbulanceId != ambulance.Id {
+				return nil, gin.H{"message": "schedule_id belongs to a different ambulance"}, http.StatusUnprocessableEntity
+			}
+			schedule.LastServiceDate = e.Timestamp
+			schedule.LastServiceOdometer = e.Odometer
+			if err := scheduleDb.UpdateDocument(ctx, schedule.Id, schedule); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return nil, gin.H{"message": "Failed to update service schedule"}, http.StatusInternalServerError
+			}
+		}
+
+		if err := getMaintenanceDB(c).CreateDocument(ctx, e.Id, &e); err != nil {
+			switch err {
+			case db_service.ErrConflict:
+				return nil, gin.H{"message": "Maintenance entry already exists"}, http.StatusConflict
+			default:
+				log.Println("CreateDocument error:", err)
+				return nil, gin.H{"message": "Failed to create maintenance entry"}, http.StatusInternalServerError
+			}
+		}
+
+		if e.Odometer > ambulance.Odometer {
+			ambulance.Odometer = e.Odometer
+			return ambulance, e, http.StatusCreated
+		}
+		return nil, e, http.StatusCreated
+	})
+}
+
+// GetMaintenanceEntries implements GET /api/ambulances/:ambulanceId/maintenance
+func (o *implMaintenanceAPI) GetMaintenanceEntries(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		entries, err := getMaintenanceDB(c).FindDocumentsByField(ctx, "ambulance_id", ambulance.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to retrieve maintenance entries"}, http.StatusInternalServerError
+		}
+		sort.Slice(entries, func(i, j int) bool {
+			return entries[i].Timestamp.After(entries[j].Timestamp)
+		})
+		return nil, entries, http.StatusOK
+	})
+}
+
+// DeleteMaintenanceEntry implements DELETE /api/maintenance/:entryId
+func (o *implMaintenanceAPI) DeleteMaintenanceEntry(c *gin.Context) {
+	id := c.Param("entryId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "entryId is required"})
+		return
+	}
+
+	db := getMaintenanceDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := db.DeleteDocument(ctx, id); err != nil {
+		if err == db_service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Maintenance entry not found"})
+		} else {
+			log.Println("DeleteDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to delete maintenance entry"})
+		}
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// validateServiceSchedule returns the problems found in a service schedule.
+func validateServiceSchedule(s *ServiceSchedule) []string {
+	var problems []string
+	if strings.TrimSpace(s.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if s.IntervalKm < 0 || s.IntervalDays < 0 {
+		problems = append(problems, "interval_km and interval_days must not be negative")
+	}
+	if s.IntervalKm == 0 && s.IntervalDays == 0 {
+		problems = append(problems, "either interval_km or interval_days is required")
+	}
+	return problems
+}
+
+// CreateServiceSchedule implements POST /api/ambulances/:ambulanceId/service-schedules
+//
+// When the last service is not given, the interval starts now at the current odometer reading.
+func (o *implMaintenanceAPI) CreateServiceSchedule(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		var s ServiceSchedule
+		if err := c.ShouldBindJSON(&s); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if problems := validateServiceSchedule(&s); len(problems) > 0 {
+			return nil, gin.H{"message": "Invalid service schedule", "errors": problems}, http.StatusUnprocessableEntity
+		}
+		if s.Id == "" {
+			s.Id = uuid.NewString()
+		}
+		s.AmbulanceId = ambulance.Id
+		if s.LastServiceDate.IsZero() {
+			s.LastServiceDate = time.Now()
+		}
+		if s.LastServiceOdometer == 0 {
+			s.LastServiceOdometer = ambulance.Odometer
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := getServiceScheduleDB(c).CreateDocument(ctx, s.Id, &s); err != nil {
+			switch err {
+			case db_service.ErrConflict:
+				return nil, gin.H{"message": "Service schedule already exists"}, http.StatusConflict
+			default:
+				log.Println("CreateDocument error:", err)
+				return nil, gin.H{"message": "Failed to create service schedule"}, http.StatusInternalServerError
+			}
+		}
+		return nil, s, http.StatusCreated
+	})
+}
+
+// GetServiceSchedules implements GET /api/ambulances/:ambulanceId/service-schedules
+func (o *implMaintenanceAPI) GetServiceSchedules(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		schedules, err := getServiceScheduleDB(c).FindDocumentsByField(ctx, "ambulance_id", ambulance.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to retrieve service schedules"}, http.StatusInternalServerError
+		}
+		return nil, schedules, http.StatusOK
+	})
+}
+
+// withServiceScheduleByID loads a ServiceSchedule and calls fn; fn may return an updated doc.
+func withServiceScheduleByID(
+	c *gin.Context,
+	fn func(*gin.Context, *ServiceSchedule) (*ServiceSchedule, interface{}, int),
+) {
+	id := c.Param("scheduleId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "scheduleId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getServiceScheduleDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		schedule, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Service schedule not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, schedule)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update service schedule"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// UpdateServiceSchedule implements PUT /api/service-schedules/:scheduleId
+func (o *implMaintenanceAPI) UpdateServiceSchedule(c *gin.Context) {
+	withServiceScheduleByID(c, func(c *gin.Context, existing *ServiceSchedule) (*ServiceSchedule, interface{}, int) {
+		var upd ServiceSchedule
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.Name != "" {
+			existing.Name = upd.Name
+		}
+		if upd.Type != "" {
+			existing.Type = upd.Type
+		}
+		existing.Mandatory = upd.Mandatory
+		if upd.IntervalKm != 0 {
+			existing.IntervalKm = upd.IntervalKm
+		}
+		if upd.IntervalDays != 0 {
+			existing.IntervalDays = upd.IntervalDays
+		}
+		if upd.LastServiceOdometer != 0 {
+			existing.LastServiceOdometer = upd.LastServiceOdometer
+		}
+		if !upd.LastServiceDate.IsZero() {
+			existing.LastServiceDate = upd.LastServiceDate
+		}
+		if problems := validateServiceSchedule(existing); len(problems) > 0 {
+			return nil, gin.H{"message": "Invalid service schedule", "errors": problems}, http.StatusUnprocessableEntity
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteServiceSchedule implements DELETE /api/service-schedules/:scheduleId
+func (o *implMaintenanceAPI) DeleteServiceSchedule(c *gin.Context) {
+	withServiceScheduleByID(c, func(c *gin.Context, s *ServiceSchedule) (*ServiceSchedule, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := getServiceScheduleDB(c).DeleteDocument(ctx, s.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete service schedule"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// GetMaintenanceDue implements GET /api/maintenance/due
+func (o *implMaintenanceAPI) GetMaintenanceDue(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	due, err := applyMaintenanceDue(ctx, getDB(c), getServiceScheduleDB(c), time.Now())
+	if err != nil {
+		log.Println("applyMaintenanceDue error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to evaluate service schedules"})
+		return
+	}
+	c.JSON(http.StatusOK, due)
+}
+
+// GetMaintenanceCostReport implements GET /api/reports/maintenance-costs
+//
+// Query parameters from and to (YYYY-MM-DD, inclusive) restrict the reported period.
+func (o *implMaintenanceAPI) GetMaintenanceCostReport(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	entries, err := getMaintenanceDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve maintenance entries"})
+		return
+	}
+	ambulances, err := getDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve ambulances"})
+		return
+	}
+	ambulancesById := map[string]Ambulance{}
+	for _, a := range ambulances {
+		ambulancesById[a.Id] = a
+	}
+
+	byDepartment := map[string]*MaintenanceCost{}
+	for _, e := range entries {
+		if !from.IsZero() && e.Timestamp.Before(from) {
+			continue
+		}
+		if !to.IsZero() && !e.Timestamp.Before(to) {
+			continue
+		}
+		ambulance := ambulancesById[e.AmbulanceId]
+		cost, ok := byDepartment[ambulance.DepartmentId]
+		if !ok {
+			cost = &MaintenanceCost{DepartmentId: ambulance.DepartmentId, Department: ambulance.Department}
+			byDepartment[ambulance.DepartmentId] = cost
+		}
+		cost.Entries++
+		cost.TotalCost += e.Cost
+	}
+
+	report := make([]MaintenanceCost, 0, len(byDepartment))
+	for _, cost := range byDepartment {
+		report = append(report, *cost)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		return report[i].Department < report[j].Department
+	})
+	c.JSON(http.StatusOK, report)
+}
+
+// parseDateRange reads the optional from and to query parameters (YYYY-MM-DD, inclusive)
+// and returns them as a half-open interval. It writes a 400 response and returns false
+// when either is malformed.
+func parseDateRange(c *gin.Context) (time.Time, time.Time, bool) {
+	var from, to time.Time
+	if v := c.Query("from"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "from must be in YYYY-MM-DD format"})
+			return from, to, false
+		}
+		from = d
+	}
+	if v := c.Query("to"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "to must be in YYYY-MM-DD format"})
+			return from, to, false
+		}
+		to = d.AddDate(0, 0, 1)
+	}
+	return from, to, true
+}
@@ -0,0 +1,123 @@
+package ambulance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// MaintenanceSuite defines the suite for maintenance scheduling tests
+type MaintenanceSuite struct {
+	suite.Suite
+	ambulanceDbMock *DbServiceMock[Ambulance]
+	scheduleDbMock  *DbServiceMock[ServiceSchedule]
+	now             time.Time
+}
+
+func TestMaintenanceSuite(t *testing.T) {
+	suite.Run(t, new(MaintenanceSuite))
+}
+
+func (suite *MaintenanceSuite) SetupTest() {
+	suite.now = time.Date(2025, 5, 21, 12, 0, 0, 0, time.UTC)
+	suite.ambulanceDbMock = &DbServiceMock[Ambulance]{}
+	suite.scheduleDbMock = &DbServiceMock[ServiceSchedule]{}
+}
+
+func (suite *MaintenanceSuite) Test_EvaluateSchedule_OverdueByDate() {
+	s := &ServiceSchedule{Id: "sch", IntervalDays: 365, LastServiceDate: suite.now.AddDate(-1, 0, -1)}
+
+	due, overdue := evaluateSchedule(s, 0, suite.now)
+
+	suite.True(overdue)
+	suite.NotNil(due.DueDate)
+	suite.Nil(due.DueOdometer)
+}
+
+func (suite *MaintenanceSuite) Test_EvaluateSchedule_OverdueByMileage() {
+	s := &ServiceSchedule{Id: "sch", IntervalKm: 30000, IntervalDays: 365, LastServiceOdometer: 150000, LastServiceDate: suite.now}
+
+	due, overdue := evaluateSchedule(s, 180000, suite.now)
+
+	suite.True(overdue)
+	suite.Equal(int32(180000), *due.DueOdometer)
+}
+
+func (suite *MaintenanceSuite) Test_EvaluateSchedule_NotYetDue() {
+	s := &ServiceSchedule{Id: "sch", IntervalKm: 30000, LastServiceOdometer: 150000}
+
+	_, overdue := evaluateSchedule(s, 179999, suite.now)
+
+	suite.False(overdue)
+}
+
+func (suite *MaintenanceSuite) Test_ApplyMaintenanceDue_TakesAmbulanceOutOfService() {
+	suite.ambulanceDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Ambulance{
+			{Id: "amb001", Name: "Ambulancia Hlavná", Status: "Available", Odometer: 100},
+			{Id: "amb002", Name: "Ambulancia Záložná", Status: "Available", Odometer: 100},
+		}, nil)
+	suite.ambulanceDbMock.
+		On("FindDocument", mock.Anything, "amb001").
+		Return(&Ambulance{Id: "amb001", Name: "Ambulancia Hlavná", Status: "Available", Odometer: 100}, nil)
+	suite.ambulanceDbMock.
+		On("UpdateDocumentIf", mock.Anything, "amb001", map[string]any{"status": "Available"}, mock.Anything).
+		Return(nil)
+	suite.scheduleDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]ServiceSchedule{
+			{Id: "inspection", AmbulanceId: "amb001", Name: "Inspection", Mandatory: true, IntervalDays: 365, LastServiceDate: suite.now.AddDate(-2, 0, 0)},
+			{Id: "tyres", AmbulanceId: "amb002", Name: "Tyres", IntervalDays: 180, LastServiceDate: suite.now.AddDate(-1, 0, 0)},
+		}, nil)
+
+	due, err := applyMaintenanceDue(context.Background(), suite.ambulanceDbMock, suite.scheduleDbMock, suite.now)
+
+	suite.NoError(err)
+	suite.Len(due, 2)
+	suite.Equal("inspection", due[0].ScheduleId)
+	suite.ambulanceDbMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "amb001", mock.Anything,
+		mock.MatchedBy(func(a *Ambulance) bool { return a.Status == AmbulanceStatusOutOfService }))
+	suite.ambulanceDbMock.AssertNotCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "amb002", mock.Anything, mock.Anything)
+}
+
+func (suite *MaintenanceSuite) Test_ApplyMaintenanceDue_KeepsEditsMadeMeanwhile() {
+	ambulanceDb := db_service.NewMemoryService[Ambulance]()
+	scheduleDb := db_service.NewMemoryService[ServiceSchedule]()
+	ctx := context.Background()
+	suite.Require().NoError(ambulanceDb.CreateDocument(ctx, "amb001", &Ambulance{Id: "amb001", Name: "Ambulancia Hlavná", Status: "Available"}))
+	suite.Require().NoError(scheduleDb.CreateDocument(ctx, "inspection", &ServiceSchedule{Id: "inspection", AmbulanceId: "amb001", Name: "Inspection", Mandatory: true, IntervalDays: 365, LastServiceDate: suite.now.AddDate(-2, 0, 0)}))
+	edited := &editingAmbulances{DbService: ambulanceDb}
+
+	_, err := applyMaintenanceDue(ctx, edited, scheduleDb, suite.now)
+
+	suite.NoError(err)
+	stored, err := ambulanceDb.FindDocument(ctx, "amb001")
+	suite.Require().NoError(err)
+	suite.Equal("Ambulancia Severná", stored.Name)
+	suite.Equal(AmbulanceStatusOutOfService, stored.Status)
+}
+
+// editingAmbulances renames the ambulances after they are listed, as an edit made while
+// the watcher evaluates the schedules would.
+type editingAmbulances struct {
+	db_service.DbService[Ambulance]
+}
+
+func (e *editingAmbulances) ListDocuments(ctx context.Context) ([]Ambulance, error) {
+	ambulances, err := e.DbService.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range ambulances {
+		a.Name = "Ambulancia Severná"
+		if err := e.DbService.UpdateDocument(ctx, a.Id, &a); err != nil {
+			return nil, err
+		}
+	}
+	return ambulances, nil
+}
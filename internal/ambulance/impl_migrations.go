@@ -0,0 +1,385 @@
+package ambulance
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// implMigrationsAPI implements the MigrationsAPI interface.
+type implMigrationsAPI struct{}
+
+// NewMigrationsAPI returns an implementation of MigrationsAPI.
+func NewMigrationsAPI() MigrationsAPI {
+	return &implMigrationsAPI{}
+}
+
+// MigrateDepartments implements POST /api/migrations/departments
+//
+// Every ambulance that still carries only a free-text department is linked to the
+// department with the same name (ignoring case and whitespace); departments missing
+// for such names are created. Running the migration again is a no-op.
+func (o *implMigrationsAPI) MigrateDepartments(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	departmentDb := getDepartmentDB(c)
+	ambulanceDb := getDB(c)
+
+	departments, err := departmentDb.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate departments"})
+		return
+	}
+	ambulances, err := ambulanceDb.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate departments"})
+		return
+	}
+
+	result := DepartmentMigrationResult{DepartmentsCreated: make([]Department, 0)}
+	for i := range ambulances {
+		a := &ambulances[i]
+		if a.DepartmentId != "" || strings.TrimSpace(a.Department) == "" {
+			continue
+		}
+
+		department := findDepartmentByName(departments, a.Department)
+		if department == nil {
+			created := Department{Id: uuid.NewString(), Name: strings.Join(strings.Fields(a.Department), " ")}
+			if err := departmentDb.CreateDocument(ctx, created.Id, &created); err != nil {
+				log.Println("CreateDocument error:", err)
+				c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate departments", "result": result})
+				return
+			}
+			departments = append(departments, created)
+			result.DepartmentsCreated = append(result.DepartmentsCreated, created)
+			department = &departments[len(departments)-1]
+		}
+
+		a.DepartmentId = department.Id
+		a.Department = department.Name
+		if err := ambulanceDb.UpdateDocument(ctx, a.Id, a); err != nil {
+			log.Println("UpdateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate departments", "result": result})
+			return
+		}
+		result.AmbulancesUpdated++
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// MigrateEncryption implements POST /api/migrations/encryption
+//
+// Patients and procedures stored in plaintext or encrypted with a retired key are
+// re-encrypted with the active key. Once it completes, retired keys can be removed from
+// the key file.
+func (o *implMigrationsAPI) MigrateEncryption(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	patientDb, patientsEncrypted := getPatientDB(c).(db_service.Reencrypter)
+	procedureDb, proceduresEncrypted := getProcedureDB(c).(db_service.Reencrypter)
+	if !patientsEncrypted || !proceduresEncrypted {
+		c.JSON(http.StatusConflict, gin.H{"message": "Encryption is not configured"})
+		return
+	}
+
+	var result EncryptionMigrationResult
+	count, err := patientDb.Reencrypt(ctx)
+	result.PatientsReencrypted = int32(count)
+	if err != nil {
+		log.Println("Reencrypt error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to re-encrypt patients", "result": result})
+		return
+	}
+	count, err = procedureDb.Reencrypt(ctx)
+	result.ProceduresReencrypted = int32(count)
+	if err != nil {
+		log.Println("Reencrypt error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to re-encrypt procedures", "result": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// MigrateFieldNames implements POST /api/migrations/field-names
+//
+// Procedures and payments stored by earlier versions under lowercase field names such
+// as "ambulanceid" get the names the API exposes, e.g. "ambulance_id", so that queries
+// by those fields find them. Services storing no renamed fields are skipped. Running the
+// migration again is a no-op.
+func (o *implMigrationsAPI) MigrateFieldNames(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	var result FieldNameMigrationResult
+	if procedureDb, ok := getProcedureDB(c).(db_service.FieldRenamer); ok {
+		count, err := procedureDb.RenameFields(ctx)
+		result.ProceduresRenamed = int32(count)
+		if err != nil {
+			log.Println("RenameFields error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to rename procedure fields", "result": result})
+			return
+		}
+	}
+	if paymentDb, ok := getPaymentDB(c).(db_service.FieldRenamer); ok {
+		count, err := paymentDb.RenameFields(ctx)
+		result.PaymentsRenamed = int32(count)
+		if err != nil {
+			log.Println("RenameFields error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to rename payment fields", "result": result})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// rewriteDocuments stores every document again in its current format and returns the
+// number of documents written.
+func rewriteDocuments[T any](ctx context.Context, db db_service.DbService[T], id func(*T) string) (int32, error) {
+	documents, err := db.ListDocuments(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := int32(0)
+	for i := range documents {
+		if err := db.UpdateDocument(ctx, id(&documents[i]), &documents[i]); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// MigrateMoney implements POST /api/migrations/money
+//
+// Prices and payment amounts stored as floating point numbers are read rounded to the
+// cent in the default currency; every procedure, payment, procedure type and price list
+// is stored again so that its amounts become Decimal128 values with a currency. Running
+// the migration again rewrites every document unchanged.
+func (o *implMigrationsAPI) MigrateMoney(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 5*time.Minute)
+	defer cancel()
+
+	var result MoneyMigrationResult
+	var err error
+	if result.ProceduresRewritten, err = rewriteDocuments(ctx, getProcedureDB(c), func(p *Procedure) string { return p.Id }); err != nil {
+		log.Println("rewriteDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate procedures", "result": result})
+		return
+	}
+	if result.PaymentsRewritten, err = rewriteDocuments(ctx, getPaymentDB(c), func(p *Payment) string { return p.Id }); err != nil {
+		log.Println("rewriteDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate payments", "result": result})
+		return
+	}
+	if result.ProcedureTypesRewritten, err = rewriteDocuments(ctx, getProcedureTypeDB(c), func(t *ProcedureType) string { return t.Id }); err != nil {
+		log.Println("rewriteDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate procedure types", "result": result})
+		return
+	}
+	if result.PriceListsRewritten, err = rewriteDocuments(ctx, getPriceListDB(c), func(l *PriceList) string { return l.Id }); err != nil {
+		log.Println("rewriteDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate price lists", "result": result})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// MigratePatients implements POST /api/migrations/patients
+//
+// Every procedure that still carries only a free-text patient is linked to the patient
+// record it names: the patient with that id or identifier, or the only patient with that
+// name (ignoring case, diacritics and whitespace). Procedures that match no patient or
+// several are reported and left unchanged. Running the migration again is a no-op.
+func (o *implMigrationsAPI) MigratePatients(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	procedureDb := getProcedureDB(c)
+
+	patients, err := getPatientDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate patients"})
+		return
+	}
+	procedures, err := procedureDb.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate patients"})
+		return
+	}
+
+	result := PatientMigrationResult{Unmatched: make([]string, 0)}
+	for i := range procedures {
+		p := &procedures[i]
+		if p.PatientId != "" || strings.TrimSpace(p.Patient) == "" {
+			continue
+		}
+
+		patient := matchProcedurePatient(patients, p.Patient)
+		if patient == nil {
+			result.Unmatched = append(result.Unmatched, p.Id)
+			continue
+		}
+
+		p.PatientId = patient.Id
+		p.Patient = patient.Name
+		if err := procedureDb.UpdateDocument(ctx, p.Id, p); err != nil {
+			log.Println("UpdateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate patients", "result": result})
+			return
+		}
+		result.ProceduresLinked++
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// MigratePayers implements POST /api/migrations/payers
+//
+// Every procedure and payment that still carries only a free-text payer is linked to the
+// payer it names: the payer with that id or code, or the only payer with that name
+// (ignoring case, diacritics and whitespace). Procedures and payments that match no payer
+// or several are reported and left unchanged. Running the migration again is a no-op.
+func (o *implMigrationsAPI) MigratePayers(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	procedureDb := getProcedureDB(c)
+	paymentDb := getPaymentDB(c)
+
+	payers, err := getPayerDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate payers"})
+		return
+	}
+	procedures, err := procedureDb.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate payers"})
+		return
+	}
+	payments, err := paymentDb.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate payers"})
+		return
+	}
+
+	result := PayerMigrationResult{UnmatchedProcedures: make([]string, 0), UnmatchedPayments: make([]string, 0)}
+	for i := range procedures {
+		p := &procedures[i]
+		if p.PayerId != "" || strings.TrimSpace(p.Payer) == "" {
+			continue
+		}
+
+		payer := matchPayer(payers, p.Payer)
+		if payer == nil {
+			result.UnmatchedProcedures = append(result.UnmatchedProcedures, p.Id)
+			continue
+		}
+
+		p.PayerId = payer.Id
+		p.Payer = payer.Name
+		if err := procedureDb.UpdateDocument(ctx, p.Id, p); err != nil {
+			log.Println("UpdateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate payers", "result": result})
+			return
+		}
+		result.ProceduresLinked++
+	}
+	for i := range payments {
+		p := &payments[i]
+		if p.PayerId != "" || strings.TrimSpace(p.Insurance) == "" {
+			continue
+		}
+
+		payer := matchPayer(payers, p.Insurance)
+		if payer == nil {
+			result.UnmatchedPayments = append(result.UnmatchedPayments, p.Id)
+			continue
+		}
+
+		p.PayerId = payer.Id
+		p.Insurance = payer.Name
+		if err := paymentDb.UpdateDocument(ctx, p.Id, p); err != nil {
+			log.Println("UpdateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate payers", "result": result})
+			return
+		}
+		result.PaymentsLinked++
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// MigrateProcedureCodes implements POST /api/migrations/procedure-codes
+//
+// Every procedure without a catalog code is assigned the code of the procedure type
+// whose name or code equals the procedure's name (ignoring case, diacritics and
+// whitespace). Procedures matching no procedure type are reported and left unchanged.
+func (o *implMigrationsAPI) MigrateProcedureCodes(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	procedureDb := getProcedureDB(c)
+
+	types, err := getProcedureTypeDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate procedure codes"})
+		return
+	}
+	procedures, err := procedureDb.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate procedure codes"})
+		return
+	}
+
+	codes := map[string]string{}
+	for _, t := range types {
+		codes[normalizeName(t.Name)] = t.Code
+		codes[normalizeName(t.Code)] = t.Code
+	}
+
+	result := ProcedureCodeMigrationResult{Unmatched: make([]string, 0)}
+	for i := range procedures {
+		p := &procedures[i]
+		if p.Code != "" {
+			continue
+		}
+
+		code, ok := codes[normalizeName(p.Name)]
+		if !ok {
+			result.Unmatched = append(result.Unmatched, p.Id)
+			continue
+		}
+
+		p.Code = code
+		if err := procedureDb.UpdateDocument(ctx, p.Id, p); err != nil {
+			log.Println("UpdateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to migrate procedure codes", "result": result})
+			return
+		}
+		result.ProceduresLinked++
+	}
+
+	c.JSON(http.StatusOK, result)
+}
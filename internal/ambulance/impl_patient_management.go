@@ -0,0 +1,448 @@
+package ambulance
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"golang.org/x/text/unicode/norm"
+)
+
+// patientNameTolerance is the number of single-character edits up to which two names
+// of patients born on the same day are reported as a possible duplicate.
+const patientNameTolerance = 2
+
+// PatientEncryptedFields are the patient details encrypted at rest. The identifier is
+// encrypted deterministically so that patients can still be looked up by it.
+var PatientEncryptedFields = []db_service.EncryptedField{
+	{Path: "identifier", Deterministic: true},
+	{Path: "name"},
+	{Path: "date_of_birth"},
+	{Path: "insurance.policy_number"},
+	{Path: "contact.phone"},
+	{Path: "contact.email"},
+	{Path: "contact.address"},
+}
+
+// implPatientAPI implements the PatientManagementAPI interface.
+type implPatientAPI struct{}
+
+// NewPatientAPI returns an implementation of PatientManagementAPI.
+func NewPatientAPI() PatientManagementAPI {
+	return &implPatientAPI{}
+}
+
+// getPatientDB extracts the DbService[Patient] from the context.
+func getPatientDB(c *gin.Context) db_service.DbService[Patient] {
+	return c.MustGet("db_service_patient").(db_service.DbService[Patient])
+}
+
+// normalizeName folds case, diacritics and whitespace so that "Peter Horváth"
+// and " peter  horvath" compare equal.
+func normalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(name) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// sortedNameParts returns the normalized name with its parts in alphabetical order,
+// so that "Horváth Peter" and "Peter Horváth" compare equal.
+func sortedNameParts(normalized string) string {
+	parts := strings.Fields(normalized)
+	sort.Strings(parts)
+	return strings.Join(parts, " ")
+}
+
+// levenshtein returns the number of single-character insertions, deletions and
+// substitutions needed to turn a into b.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return previous[len(rb)]
+}
+
+// similarPatientNames reports whether two names differ by at most patientNameTolerance
+// edits, ignoring case, diacritics and the order of the name parts.
+func similarPatientNames(a, b string) bool {
+	na, nb := normalizeName(a), normalizeName(b)
+	if levenshtein(na, nb) <= patientNameTolerance {
+		return true
+	}
+	return levenshtein(sortedNameParts(na), sortedNameParts(nb)) <= patientNameTolerance
+}
+
+// findPatientDuplicates returns the other patients born on the same day as p whose name is similar.
+func findPatientDuplicates(p *Patient, patients []Patient) []Patient {
+	duplicates := make([]Patient, 0)
+	for _, other := range patients {
+		if other.Id == p.Id || other.DateOfBirth != p.DateOfBirth {
+			continue
+		}
+		if similarPatientNames(p.Name, other.Name) {
+			duplicates = append(duplicates, other)
+		}
+	}
+	return duplicates
+}
+
+// validatePatient returns the list of problems with the patient record.
+func validatePatient(p *Patient) []string {
+	problems := make([]string, 0)
+	if strings.TrimSpace(p.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if p.DateOfBirth == "" {
+		problems = append(problems, "date_of_birth is required")
+	} else if dob, err := time.Parse(time.DateOnly, p.DateOfBirth); err != nil {
+		problems = append(problems, "date_of_birth must be in YYYY-MM-DD format")
+	} else if dob.After(time.Now()) {
+		problems = append(problems, "date_of_birth must not be in the future")
+	}
+	return problems
+}
+
+// checkPatient validates the patient against the registry. Identifiers must be unique;
+// similar patients born on the same day are rejected unless force is set.
+func checkPatient(p *Patient, patients []Patient, force bool) (interface{}, int) {
+	if problems := validatePatient(p); len(problems) > 0 {
+		return gin.H{"message": "Invalid patient", "errors": problems}, http.StatusUnprocessableEntity
+	}
+	if p.Identifier != "" {
+		for _, other := range patients {
+			if other.Id != p.Id && other.Identifier == p.Identifier {
+				return gin.H{"message": "A patient with this identifier already exists", "duplicates": []Patient{other}}, http.StatusConflict
+			}
+		}
+	}
+	if force {
+		return nil, 0
+	}
+	if duplicates := findPatientDuplicates(p, patients); len(duplicates) > 0 {
+		return gin.H{
+			"message":    "Possible duplicate patient; repeat the request with force=true to register anyway",
+			"duplicates": duplicates,
+		}, http.StatusConflict
+	}
+	return nil, 0
+}
+
+// resolveProcedurePatient fills the patient name of a procedure from the referenced
+// patient record. Procedures without patient_id keep their free-text patient. It
+// returns a validation problem when patient_id does not reference an existing patient.
+func resolveProcedurePatient(ctx context.Context, db db_service.DbService[Patient], p *Procedure) (string, error) {
+	if p.PatientId == "" {
+		return "", nil
+	}
+	patient, err := db.FindDocument(ctx, p.PatientId)
+	if err == db_service.ErrNotFound {
+		return "patient_id does not reference an existing patient", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	p.Patient = patient.Name
+	return "", nil
+}
+
+// matchProcedurePatient returns the patient a free-text patient value refers to: the
+// patient with that id or identifier, or the only patient with that name.
+func matchProcedurePatient(patients []Patient, value string) *Patient {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	for i := range patients {
+		if patients[i].Id == value || (patients[i].Identifier != "" && patients[i].Identifier == value) {
+			return &patients[i]
+		}
+	}
+	var match *Patient
+	normalized := normalizeName(value)
+	for i := range patients {
+		if normalizeName(patients[i].Name) == normalized {
+			if match != nil {
+				return nil
+			}
+			match = &patients[i]
+		}
+	}
+	return match
+}
+
+// withPatientByID loads a Patient and calls fn; fn may return an updated doc.
+func withPatientByID(
+	c *gin.Context,
+	fn func(*gin.Context, *Patient) (*Patient, interface{}, int),
+) {
+	id := c.Param("patientId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "patientId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getPatientDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		patient, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Patient not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, patient)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update patient"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// CreatePatient implements POST /api/patients
+//
+// Registering a patient similar to an existing one (same date of birth, similar name)
+// is rejected with 409 and the candidates, unless the force query parameter is true.
+func (o *implPatientAPI) CreatePatient(c *gin.Context) {
+	var p Patient
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if p.Id == "" {
+		p.Id = uuid.NewString()
+	}
+	p.Name = strings.TrimSpace(p.Name)
+
+	db := getPatientDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	patients, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create patient"})
+		return
+	}
+	if result, status := checkPatient(&p, patients, c.Query("force") == "true"); result != nil {
+		c.JSON(status, result)
+		return
+	}
+
+	if err := db.CreateDocument(ctx, p.Id, &p); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Patient already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create patient"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// GetPatientById implements GET /api/patients/:patientId
+func (o *implPatientAPI) GetPatientById(c *gin.Context) {
+	withPatientByID(c, func(_ *gin.Context, p *Patient) (*Patient, interface{}, int) {
+		return nil, p, http.StatusOK
+	})
+}
+
+// GetPatients implements GET /api/patients
+//
+// The optional identifier query parameter looks a patient up by national identifier;
+// name searches for patients whose name contains the value, ignoring case and diacritics.
+func (o *implPatientAPI) GetPatients(c *gin.Context) {
+	db := getPatientDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	var (
+		patients []Patient
+		err      error
+	)
+
+	if identifier := c.Query("identifier"); identifier != "" {
+		var found []*Patient
+		found, err = db.FindDocumentsByField(ctx, "identifier", identifier)
+		for _, p := range found {
+			patients = append(patients, *p)
+		}
+	} else {
+		patients, err = db.ListDocuments(ctx)
+	}
+
+	if err != nil {
+		log.Println("Error retrieving patients:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve patients"})
+		return
+	}
+
+	result := make([]Patient, 0, len(patients))
+	name := normalizeName(c.Query("name"))
+	for _, p := range patients {
+		if name == "" || strings.Contains(normalizeName(p.Name), name) {
+			result = append(result, p)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// UpdatePatient implements PUT /api/patients/:patientId
+//
+// Renaming a patient also renames them on their procedures.
+func (o *implPatientAPI) UpdatePatient(c *gin.Context) {
+	withPatientByID(c, func(c *gin.Context, existing *Patient) (*Patient, interface{}, int) {
+		var upd Patient
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		renamed := false
+		if upd.Name != "" && strings.TrimSpace(upd.Name) != existing.Name {
+			existing.Name = strings.TrimSpace(upd.Name)
+			renamed = true
+		}
+		if upd.Identifier != "" {
+			existing.Identifier = upd.Identifier
+		}
+		if upd.DateOfBirth != "" {
+			existing.DateOfBirth = upd.DateOfBirth
+		}
+		if upd.Insurance != (PatientInsurance{}) {
+			existing.Insurance = upd.Insurance
+		}
+		if upd.Contact != (PatientContact{}) {
+			existing.Contact = upd.Contact
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		patients, err := getPatientDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to update patient"}, http.StatusInternalServerError
+		}
+		if result, status := checkPatient(existing, patients, c.Query("force") == "true"); result != nil {
+			return nil, result, status
+		}
+
+		if renamed {
+			procedureDb := getProcedureDB(c)
+			procedures, err := procedureDb.FindDocumentsByField(ctx, "patient_id", existing.Id)
+			if err != nil {
+				log.Println("FindDocumentsByField error:", err)
+				return nil, gin.H{"message": "Failed to update patient"}, http.StatusInternalServerError
+			}
+			for _, p := range procedures {
+				p.Patient = existing.Name
+				if err := procedureDb.UpdateDocument(ctx, p.Id, p); err != nil {
+					log.Println("UpdateDocument error:", err)
+					return nil, gin.H{"message": "Failed to update patient"}, http.StatusInternalServerError
+				}
+			}
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeletePatient implements DELETE /api/patients/:patientId
+//
+// Patients that still have procedures cannot be deleted.
+func (o *implPatientAPI) DeletePatient(c *gin.Context) {
+	withPatientByID(c, func(c *gin.Context, p *Patient) (*Patient, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		procedures, err := getProcedureDB(c).FindDocumentsByField(ctx, "patient_id", p.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to delete patient"}, http.StatusInternalServerError
+		}
+		if len(procedures) > 0 {
+			return nil, gin.H{"message": "Patient still has procedures"}, http.StatusConflict
+		}
+
+		if err := getPatientDB(c).DeleteDocument(ctx, p.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete patient"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// GetPatientProcedures implements GET /api/patients/:patientId/procedures
+//
+// Procedures are returned oldest first; from and to (YYYY-MM-DD) narrow the timeline.
+func (o *implPatientAPI) GetPatientProcedures(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	patient, err := getPatientDB(c).FindDocument(ctx, c.Param("patientId"))
+	if err != nil {
+		if err == db_service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Patient not found"})
+		} else {
+			log.Println("FindDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+		}
+		return
+	}
+
+	procedures, err := getProcedureDB(c).FindDocumentsByField(ctx, "patient_id", patient.Id)
+	if err != nil {
+		log.Println("FindDocumentsByField error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve procedures"})
+		return
+	}
+
+	timeline := make([]*Procedure, 0, len(procedures))
+	for _, p := range procedures {
+		if (!from.IsZero() && p.Timestamp.Before(from)) || (!to.IsZero() && !p.Timestamp.Before(to)) {
+			continue
+		}
+		timeline = append(timeline, p)
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].Timestamp.Before(timeline[j].Timestamp)
+	})
+	c.JSON(http.StatusOK, timeline)
+}
@@ -0,0 +1,93 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// PatientSuite defines the suite for patient registry tests
+type PatientSuite struct {
+	suite.Suite
+	patientDbMock *DbServiceMock[Patient]
+	existing      []Patient
+}
+
+func TestPatientSuite(t *testing.T) {
+	suite.Run(t, new(PatientSuite))
+}
+
+func (suite *PatientSuite) SetupTest() {
+	suite.existing = []Patient{
+		{Id: "pat001", Identifier: "8501011234", Name: "Peter Horváth", DateOfBirth: "1985-01-01"},
+		{Id: "pat002", Name: "Jana Kováčová", DateOfBirth: "1990-06-15"},
+	}
+	suite.patientDbMock = &DbServiceMock[Patient]{}
+	suite.patientDbMock.
+		On("ListDocuments", mock.Anything).
+		Return(suite.existing, nil)
+}
+
+func (suite *PatientSuite) createPatient(payload string, query string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_patient", suite.patientDbMock)
+	ctx.Request = httptest.NewRequest("POST", "/api/patients"+query, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	sut := implPatientAPI{}
+	sut.CreatePatient(ctx)
+	return recorder
+}
+
+func (suite *PatientSuite) Test_FindPatientDuplicates_IgnoresDiacriticsTyposAndOrder() {
+	for _, name := range []string{"peter horvath", "Petr Horváth", "Horváth Peter"} {
+		p := &Patient{Name: name, DateOfBirth: "1985-01-01"}
+
+		duplicates := findPatientDuplicates(p, suite.existing)
+
+		suite.Len(duplicates, 1, name)
+	}
+}
+
+func (suite *PatientSuite) Test_FindPatientDuplicates_RequiresSameDateOfBirth() {
+	p := &Patient{Name: "Peter Horváth", DateOfBirth: "1985-01-02"}
+
+	suite.Empty(findPatientDuplicates(p, suite.existing))
+}
+
+func (suite *PatientSuite) Test_CreatePatient_PossibleDuplicate_ReturnsConflict() {
+	recorder := suite.createPatient(`{"name":"Petr Horvath","date_of_birth":"1985-01-01"}`, "")
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Contains(recorder.Body.String(), "pat001")
+	suite.patientDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *PatientSuite) Test_CreatePatient_Force_RegistersDuplicate() {
+	suite.patientDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	recorder := suite.createPatient(`{"name":"Petr Horvath","date_of_birth":"1985-01-01"}`, "?force=true")
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+}
+
+func (suite *PatientSuite) Test_CreatePatient_DuplicateIdentifier_ReturnsConflictEvenWithForce() {
+	recorder := suite.createPatient(`{"identifier":"8501011234","name":"Someone Else","date_of_birth":"1970-03-03"}`, "?force=true")
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+}
+
+func (suite *PatientSuite) Test_MatchProcedurePatient_ByIdentifierOrUniqueName() {
+	suite.Equal("pat001", matchProcedurePatient(suite.existing, "8501011234").Id)
+	suite.Equal("pat002", matchProcedurePatient(suite.existing, "jana kovacova").Id)
+	suite.Nil(matchProcedurePatient(suite.existing, "ID12345"))
+}
@@ -0,0 +1,471 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// Payer types.
+const (
+	PayerTypeInsurer  = "insurer"
+	PayerTypeSelf     = "self"
+	PayerTypeEmployer = "employer"
+)
+
+// implPayerAPI implements the PayerManagementAPI interface.
+type implPayerAPI struct{}
+
+// NewPayerAPI returns an implementation of PayerManagementAPI.
+func NewPayerAPI() PayerManagementAPI {
+	return &implPayerAPI{}
+}
+
+// getPayerDB extracts the DbService[Payer] from the context.
+func getPayerDB(c *gin.Context) db_service.DbService[Payer] {
+	return c.MustGet("db_service_payer").(db_service.DbService[Payer])
+}
+
+// normalizePayerCode folds case and surrounding whitespace of a payer code.
+func normalizePayerCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validatePayer checks the payer's details against the registry and normalizes the codes
+// of its coverage rules. Names and codes must be unique; coverage rules must name
+// catalog procedures at most once.
+func validatePayer(ctx context.Context, c *gin.Context, p *Payer, payers []Payer) ([]string, int, error) {
+	problems := make([]string, 0)
+	if p.Name == "" {
+		problems = append(problems, "name is required")
+	}
+	switch p.Type {
+	case PayerTypeInsurer, PayerTypeEmployer:
+	case PayerTypeSelf:
+		if len(p.Coverage) > 0 {
+			problems = append(problems, "self-paying payers cannot have coverage rules")
+		}
+	default:
+		problems = append(problems, "type must be insurer, self or employer")
+	}
+	if terms := p.ContractTerms; terms != nil {
+		from, errFrom := time.Parse(time.DateOnly, terms.ValidFrom)
+		to, errTo := time.Parse(time.DateOnly, terms.ValidTo)
+		if terms.ValidFrom != "" && errFrom != nil {
+			problems = append(problems, "contract_terms.valid_from must be in YYYY-MM-DD format")
+		}
+		if terms.ValidTo != "" && errTo != nil {
+			problems = append(problems, "contract_terms.valid_to must be in YYYY-MM-DD format")
+		}
+		if errFrom == nil && errTo == nil && to.Before(from) {
+			problems = append(problems, "contract_terms.valid_to must not be before valid_from")
+		}
+		if terms.PaymentTermsDays < 0 {
+			problems = append(problems, "contract_terms.payment_terms_days must not be negative")
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := range p.Coverage {
+		rule := &p.Coverage[i]
+		rule.Code = normalizeProcedureCode(rule.Code)
+		if rule.Percentage < 0 || rule.Percentage > 100 {
+			problems = append(problems, fmt.Sprintf("coverage[%d].percentage must be between 0 and 100", i))
+		}
+		if seen[rule.Code] {
+			problems = append(problems, fmt.Sprintf("coverage[%d] repeats the rule for code %q", i, rule.Code))
+			continue
+		}
+		seen[rule.Code] = true
+		if rule.Code == "" {
+			continue
+		}
+		if _, err := getProcedureTypeDB(c).FindDocument(ctx, rule.Code); err == db_service.ErrNotFound {
+			problems = append(problems, fmt.Sprintf("coverage[%d].code does not reference a procedure type in the catalog", i))
+		} else if err != nil {
+			return nil, 0, err
+		}
+	}
+	if len(problems) > 0 {
+		return problems, http.StatusUnprocessableEntity, nil
+	}
+
+	for _, other := range payers {
+		if other.Id == p.Id {
+			continue
+		}
+		if normalizeName(other.Name) == normalizeName(p.Name) {
+			return []string{"a payer with this name already exists"}, http.StatusConflict, nil
+		}
+		if p.Code != "" && other.Code == p.Code {
+			return []string{"a payer with this code already exists"}, http.StatusConflict, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// resolvePayer loads the payer with the given id. It returns a validation problem
+// when the id does not reference an existing payer.
+func resolvePayer(ctx context.Context, db db_service.DbService[Payer], id string) (*Payer, string, error) {
+	payer, err := db.FindDocument(ctx, id)
+	if err == db_service.ErrNotFound {
+		return nil, "payer_id does not reference an existing payer", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return payer, "", nil
+}
+
+// resolveProcedurePayer fills the payer name of a procedure from the referenced payer.
+// Procedures without payer_id keep their free-text payer.
+func resolveProcedurePayer(ctx context.Context, db db_service.DbService[Payer], p *Procedure) (string, error) {
+	if p.PayerId == "" {
+		return "", nil
+	}
+	payer, problem, err := resolvePayer(ctx, db, p.PayerId)
+	if err != nil || problem != "" {
+		return problem, err
+	}
+	p.Payer = payer.Name
+	return "", nil
+}
+
+// matchPayer returns the payer a free-text payer value refers to: the payer with that
+// id or code, or the only payer with that name.
+func matchPayer(payers []Payer, value string) *Payer {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	for i := range payers {
+		if payers[i].Id == value || (payers[i].Code != "" && payers[i].Code == normalizePayerCode(value)) {
+			return &payers[i]
+		}
+	}
+	var match *Payer
+	normalized := normalizeName(value)
+	for i := range payers {
+		if normalizeName(payers[i].Name) == normalized {
+			if match != nil {
+				return nil
+			}
+			match = &payers[i]
+		}
+	}
+	return match
+}
+
+// coverageRule returns the payer's rule for the catalog code: the rule naming the code,
+// otherwise the rule without a code, if any.
+func coverageRule(payer *Payer, code string) *CoverageRule {
+	code = normalizeProcedureCode(code)
+	var fallback *CoverageRule
+	for i := range payer.Coverage {
+		rule := &payer.Coverage[i]
+		if rule.Code == "" {
+			fallback = rule
+		} else if code != "" && rule.Code == code {
+			return rule
+		}
+	}
+	return fallback
+}
+
+// computeCoverage splits the price of the procedure into the part the payer pays and
+// the patient's co-payment. Self-paying payers cover nothing, nor do other payers
+// outside their contract period or for procedures they have no coverage rule for.
+func computeCoverage(p *Procedure, payer *Payer) CoverageSplit {
+	split := CoverageSplit{
+		ProcedureId:  p.Id,
+		PayerId:      payer.Id,
+		Payer:        payer.Name,
+		Price:        p.Price,
+		InsurerShare: money.Money{Currency: p.Price.Currency},
+		PatientShare: p.Price,
+	}
+	if payer.Type == PayerTypeSelf {
+		split.Reason = "the payer is self-paying"
+		return split
+	}
+	if terms := payer.ContractTerms; terms != nil && !p.Timestamp.IsZero() {
+		day := p.Timestamp.Format(time.DateOnly)
+		if (terms.ValidFrom != "" && day < terms.ValidFrom) || (terms.ValidTo != "" && day > terms.ValidTo) {
+			split.Reason = "the procedure is outside the contract period of the payer"
+			return split
+		}
+	}
+	rule := coverageRule(payer, p.Code)
+	if rule == nil {
+		split.Reason = "the payer does not cover the procedure"
+		return split
+	}
+
+	split.Covered = rule.Percentage > 0
+	if !split.Covered {
+		split.Reason = "the payer does not cover the procedure"
+	}
+	split.Percentage = rule.Percentage
+	split.InsurerShare = p.Price.Percent(rule.Percentage)
+	// the patient pays the rest, so that the shares always add up to the price
+	split.PatientShare, _ = p.Price.Sub(split.InsurerShare)
+	return split
+}
+
+// withPayerByID loads a Payer and calls fn; fn may return an updated doc.
+func withPayerByID(
+	c *gin.Context,
+	fn func(*gin.Context, *Payer) (*Payer, interface{}, int),
+) {
+	id := c.Param("payerId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "payerId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getPayerDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		payer, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Payer not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, payer)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// CreatePayer implements POST /api/payers
+func (o *implPayerAPI) CreatePayer(c *gin.Context) {
+	var p Payer
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if p.Id == "" {
+		p.Id = uuid.NewString()
+	}
+	p.Name = strings.TrimSpace(p.Name)
+	p.Code = normalizePayerCode(p.Code)
+	p.Type = strings.ToLower(strings.TrimSpace(p.Type))
+
+	db := getPayerDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	payers, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create payer"})
+		return
+	}
+	problems, status, err := validatePayer(ctx, c, &p, payers)
+	if err != nil {
+		log.Println("validatePayer error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create payer"})
+		return
+	}
+	if len(problems) > 0 {
+		c.JSON(status, gin.H{"message": "Invalid payer", "errors": problems})
+		return
+	}
+
+	if err := db.CreateDocument(ctx, p.Id, &p); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Payer already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create payer"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, p)
+}
+
+// GetPayerById implements GET /api/payers/:payerId
+func (o *implPayerAPI) GetPayerById(c *gin.Context) {
+	withPayerByID(c, func(_ *gin.Context, p *Payer) (*Payer, interface{}, int) {
+		return nil, p, http.StatusOK
+	})
+}
+
+// GetPayers implements GET /api/payers
+//
+// The optional type query parameter lists the payers of one type; name searches for
+// payers whose name contains the value, ignoring case and diacritics.
+func (o *implPayerAPI) GetPayers(c *gin.Context) {
+	db := getPayerDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	payers, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving payers:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve payers"})
+		return
+	}
+
+	result := make([]Payer, 0, len(payers))
+	payerType, name := strings.ToLower(c.Query("type")), normalizeName(c.Query("name"))
+	for _, p := range payers {
+		if (payerType == "" || p.Type == payerType) && (name == "" || strings.Contains(normalizeName(p.Name), name)) {
+			result = append(result, p)
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// UpdatePayer implements PUT /api/payers/:payerId
+//
+// Renaming a payer also renames it on the procedures and payments that reference it.
+// Coverage rules and contract terms are replaced when present in the request.
+func (o *implPayerAPI) UpdatePayer(c *gin.Context) {
+	withPayerByID(c, func(c *gin.Context, existing *Payer) (*Payer, interface{}, int) {
+		var upd Payer
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		renamed := false
+		if upd.Name != "" && strings.TrimSpace(upd.Name) != existing.Name {
+			existing.Name = strings.TrimSpace(upd.Name)
+			renamed = true
+		}
+		if upd.Code != "" {
+			existing.Code = normalizePayerCode(upd.Code)
+		}
+		if upd.Type != "" {
+			existing.Type = strings.ToLower(strings.TrimSpace(upd.Type))
+		}
+		if upd.ContractTerms != nil {
+			existing.ContractTerms = upd.ContractTerms
+		}
+		if upd.Coverage != nil {
+			existing.Coverage = upd.Coverage
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		payers, err := getPayerDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+		}
+		problems, status, err := validatePayer(ctx, c, existing, payers)
+		if err != nil {
+			log.Println("validatePayer error:", err)
+			return nil, gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+		}
+		if len(problems) > 0 {
+			return nil, gin.H{"message": "Invalid payer", "errors": problems}, status
+		}
+
+		if renamed {
+			procedureDb := getProcedureDB(c)
+			procedures, err := procedureDb.FindDocumentsByField(ctx, "payer_id", existing.Id)
+			if err != nil {
+				log.Println("FindDocumentsByField error:", err)
+				return nil, gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+			}
+			for _, p := range procedures {
+				p.Payer = existing.Name
+				if err := procedureDb.UpdateDocument(ctx, p.Id, p); err != nil {
+					log.Println("UpdateDocument error:", err)
+					return nil, gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+				}
+			}
+			paymentDb := getPaymentDB(c)
+			payments, err := paymentDb.FindDocumentsByField(ctx, "payer_id", existing.Id)
+			if err != nil {
+				log.Println("FindDocumentsByField error:", err)
+				return nil, gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+			}
+			for _, p := range payments {
+				p.Insurance = existing.Name
+				if err := paymentDb.UpdateDocument(ctx, p.Id, p); err != nil {
+					log.Println("UpdateDocument error:", err)
+					return nil, gin.H{"message": "Failed to update payer"}, http.StatusInternalServerError
+				}
+			}
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeletePayer implements DELETE /api/payers/:payerId
+//
+// Payers still referenced by procedures or payments cannot be deleted.
+func (o *implPayerAPI) DeletePayer(c *gin.Context) {
+	withPayerByID(c, func(c *gin.Context, p *Payer) (*Payer, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		procedures, err := getProcedureDB(c).FindDocumentsByField(ctx, "payer_id", p.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to delete payer"}, http.StatusInternalServerError
+		}
+		if len(procedures) > 0 {
+			return nil, gin.H{"message": "Payer still has procedures"}, http.StatusConflict
+		}
+		payments, err := getPaymentDB(c).FindDocumentsByField(ctx, "payer_id", p.Id)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to delete payer"}, http.StatusInternalServerError
+		}
+		if len(payments) > 0 {
+			return nil, gin.H{"message": "Payer still has payments"}, http.StatusConflict
+		}
+
+		if err := getPayerDB(c).DeleteDocument(ctx, p.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete payer"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// GetProcedureCoverage implements GET /api/procedures/:procedureId/coverage
+//
+// The procedure must be linked to a payer through payer_id.
+func (o *implPayerAPI) GetProcedureCoverage(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if p.PayerId == "" {
+			return nil, gin.H{"message": "The procedure is not linked to a payer; set its payer_id"}, http.StatusUnprocessableEntity
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		payer, problem, err := resolvePayer(ctx, getPayerDB(c), p.PayerId)
+		if err != nil {
+			log.Println("FindDocument error:", err)
+			return nil, gin.H{"message": "Failed to compute the coverage"}, http.StatusInternalServerError
+		}
+		if problem != "" {
+			return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+		return nil, computeCoverage(p, payer), http.StatusOK
+	})
+}
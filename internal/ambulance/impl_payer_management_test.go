@@ -0,0 +1,134 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// PayerSuite defines the suite for payer registry tests
+type PayerSuite struct {
+	suite.Suite
+	payerDbMock         *DbServiceMock[Payer]
+	procedureTypeDbMock *DbServiceMock[ProcedureType]
+	payer               Payer
+}
+
+func TestPayerSuite(t *testing.T) {
+	suite.Run(t, new(PayerSuite))
+}
+
+func (suite *PayerSuite) SetupTest() {
+	suite.payer = Payer{
+		Id:   "payer1",
+		Code: "XYZ",
+		Name: "Poisťovňa XYZ",
+		Type: PayerTypeInsurer,
+		ContractTerms: &PayerContractTerms{
+			ValidFrom: "2025-01-01",
+			ValidTo:   "2025-12-31",
+		},
+		Coverage: []CoverageRule{
+			{Code: "RTG-CHEST", Percentage: 80},
+			{Percentage: 50},
+		},
+	}
+	suite.payerDbMock = &DbServiceMock[Payer]{}
+	suite.payerDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Payer{suite.payer}, nil)
+	suite.payerDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+
+	suite.procedureTypeDbMock = &DbServiceMock[ProcedureType]{}
+	suite.procedureTypeDbMock.
+		On("FindDocument", mock.Anything, "RTG-CHEST").
+		Return(&ProcedureType{Code: "RTG-CHEST"}, nil)
+	suite.procedureTypeDbMock.
+		On("FindDocument", mock.Anything, mock.Anything).
+		Return((*ProcedureType)(nil), db_service.ErrNotFound)
+}
+
+func (suite *PayerSuite) createPayer(payload string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_payer", suite.payerDbMock)
+	ctx.Set("db_service_procedure_type", suite.procedureTypeDbMock)
+	ctx.Request = httptest.NewRequest("POST", "/api/payers", strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	(&implPayerAPI{}).CreatePayer(ctx)
+	return recorder
+}
+
+func (suite *PayerSuite) Test_CreatePayer_RejectsUnknownCatalogCode() {
+	recorder := suite.createPayer(`{"name": "Union", "type": "insurer", "coverage": [{"code": "nope", "percentage": 100}]}`)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.Contains(recorder.Body.String(), "coverage[0].code")
+	suite.payerDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *PayerSuite) Test_CreatePayer_DuplicateName_ReturnsConflict() {
+	recorder := suite.createPayer(`{"name": "poistovna  xyz", "type": "insurer"}`)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+}
+
+func (suite *PayerSuite) Test_CreatePayer_NormalizesCodes() {
+	recorder := suite.createPayer(`{"name": "Union", "code": "un", "type": "Insurer", "coverage": [{"code": "rtg-chest", "percentage": 100}]}`)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.payerDbMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.MatchedBy(func(p *Payer) bool {
+		return p.Code == "UN" && p.Type == PayerTypeInsurer && p.Coverage[0].Code == "RTG-CHEST"
+	}))
+}
+
+func (suite *PayerSuite) Test_ComputeCoverage_SplitsPriceByCodeRule() {
+	p := &Procedure{Id: "proc001", Code: "rtg-chest", Price: eur("120.55"), Timestamp: time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)}
+
+	split := computeCoverage(p, &suite.payer)
+
+	suite.True(split.Covered)
+	suite.Equal(80.0, split.Percentage)
+	suite.Equal(eur("96.44"), split.InsurerShare)
+	suite.Equal(eur("24.11"), split.PatientShare)
+}
+
+func (suite *PayerSuite) Test_ComputeCoverage_FallsBackToRuleWithoutCode() {
+	p := &Procedure{Id: "proc001", Code: "ECG", Price: eur("60"), Timestamp: time.Date(2025, 5, 21, 10, 0, 0, 0, time.UTC)}
+
+	split := computeCoverage(p, &suite.payer)
+
+	suite.Equal(eur("30"), split.InsurerShare)
+	suite.Equal(eur("30"), split.PatientShare)
+}
+
+func (suite *PayerSuite) Test_ComputeCoverage_OutsideContractPeriod_PatientPaysAll() {
+	p := &Procedure{Id: "proc001", Code: "RTG-CHEST", Price: eur("60"), Timestamp: time.Date(2026, 1, 2, 10, 0, 0, 0, time.UTC)}
+
+	split := computeCoverage(p, &suite.payer)
+
+	suite.False(split.Covered)
+	suite.NotEmpty(split.Reason)
+	suite.True(split.InsurerShare.IsZero())
+	suite.Equal(eur("60"), split.PatientShare)
+}
+
+func (suite *PayerSuite) Test_MatchPayer_ByCodeOrUniqueName() {
+	payers := []Payer{suite.payer, {Id: "payer2", Name: "Union"}}
+
+	suite.Equal("payer1", matchPayer(payers, "xyz").Id)
+	suite.Equal("payer1", matchPayer(payers, "poistovna xyz").Id)
+	suite.Equal("payer2", matchPayer(payers, " UNION ").Id)
+	suite.Nil(matchPayer(payers, "Insurance A"))
+}
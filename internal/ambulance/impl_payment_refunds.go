@@ -0,0 +1,143 @@
+package ambulance
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// refundableAmount returns the part of the payment not refunded yet.
+func refundableAmount(p *Payment, refunds []*Payment) (money.Money, error) {
+	refundable := p.Amount
+	for _, refund := range refunds {
+		var err error
+		// refunds carry negative amounts
+		if refundable, err = refundable.Add(refund.Amount); err != nil {
+			return money.Money{}, err
+		}
+	}
+	return refundable, nil
+}
+
+// refundableRemainder returns the part of the payment not refunded yet, from the refunded
+// total recorded on the payment or, for payments refunded before it was recorded, from
+// their refunds.
+func refundableRemainder(ctx context.Context, db db_service.DbService[Payment], p *Payment) (money.Money, error) {
+	if p.Refunded != nil {
+		return p.Amount.Sub(*p.Refunded)
+	}
+	refunds, err := db.FindDocumentsByField(ctx, "refund_of", p.Id)
+	if err != nil {
+		return money.Money{}, err
+	}
+	return refundableAmount(p, refunds)
+}
+
+// checkPaymentDeletable reports whether the payment may be deleted. Received money is a
+// financial record: settled and refunded payments and refunds must be refunded instead.
+func checkPaymentDeletable(p *Payment) (interface{}, int) {
+	if p.RefundOf != "" {
+		return gin.H{"message": "Refunds cannot be deleted"}, http.StatusConflict
+	}
+	if status := paymentStatus(p); status == PaymentStatusSettled || status == PaymentStatusRefunded {
+		return gin.H{"message": "Settled payments cannot be deleted; refund them instead"}, http.StatusConflict
+	}
+	return nil, 0
+}
+
+// RefundPayment implements POST /api/payments/:paymentId/refunds
+//
+// A refund is recorded as a linked payment with a negative amount, so the original
+// payment stays on record and the procedure balance drops by the refunded amount.
+// Without an amount the whole amount not refunded yet is refunded. A payment refunded
+// in full becomes refunded. The payment records the total refunded so far, and a refund
+// racing another refund of the same payment is rejected with 409.
+func (o *implPaymentAPI) RefundPayment(c *gin.Context) {
+	withPaymentByID(c, func(c *gin.Context, p *Payment) (*Payment, interface{}, int) {
+		var request PaymentRefund
+		if err := c.ShouldBindJSON(&request); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if strings.TrimSpace(request.Reason) == "" {
+			return nil, gin.H{"message": "reason is required"}, http.StatusUnprocessableEntity
+		}
+		if request.Amount.IsNegative() {
+			return nil, gin.H{"message": "amount must not be negative"}, http.StatusUnprocessableEntity
+		}
+		if p.RefundOf != "" {
+			return nil, gin.H{"message": "Refunds cannot be refunded"}, http.StatusConflict
+		}
+		if status := paymentStatus(p); status != PaymentStatusSettled {
+			return nil, gin.H{"message": "Only settled payments can be refunded; the payment is " + status}, http.StatusConflict
+		}
+
+		db := getPaymentDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		refundable, err := refundableRemainder(ctx, db, p)
+		if err != nil {
+			log.Println("refundableRemainder error:", err)
+			return nil, gin.H{"message": "Failed to refund payment"}, http.StatusInternalServerError
+		}
+
+		amount := request.Amount
+		if amount.IsZero() {
+			amount = refundable
+		}
+		remaining, err := refundable.Sub(amount)
+		if err != nil {
+			return nil, gin.H{"message": "amount must be in the currency of the payment, " + p.Amount.Currency}, http.StatusUnprocessableEntity
+		}
+		if remaining.IsNegative() || refundable.IsZero() {
+			return nil, gin.H{"message": "amount exceeds the amount not refunded yet", "refundable": refundable}, http.StatusConflict
+		}
+
+		// the refunded total is written only if no concurrent refund changed it since it
+		// was read, so that refunds together cannot exceed the payment
+		previous, status := p.Refunded, p.Status
+		refunded, _ := p.Amount.Sub(remaining)
+		p.Refunded = &refunded
+		if remaining.IsZero() {
+			p.Status = PaymentStatusRefunded
+		}
+		if err := db.UpdateDocumentIf(ctx, p.Id, map[string]any{"refunded": previous}, p); err != nil {
+			if err == db_service.ErrConflict {
+				return nil, gin.H{"message": "The payment was refunded concurrently; retry the refund"}, http.StatusConflict
+			}
+			log.Println("UpdateDocumentIf error:", err)
+			return nil, gin.H{"message": "Failed to refund payment"}, http.StatusInternalServerError
+		}
+
+		refund := Payment{
+			Id:          uuid.NewString(),
+			Name:        "Refund",
+			Description: p.Name,
+			ProcedureId: p.ProcedureId,
+			PayerId:     p.PayerId,
+			Insurance:   p.Insurance,
+			Amount:      amount.Neg(),
+			Status:      PaymentStatusSettled,
+			RefundOf:    p.Id,
+			Reason:      strings.TrimSpace(request.Reason),
+			Timestamp:   time.Now(),
+		}
+		if err := db.CreateDocument(ctx, refund.Id, &refund); err != nil {
+			log.Println("CreateDocument error:", err)
+			// without a transaction to roll back, the refunded total is restored
+			p.Refunded, p.Status = previous, status
+			if err := db.UpdateDocumentIf(ctx, p.Id, map[string]any{"refunded": &refunded}, p); err != nil {
+				log.Println("UpdateDocumentIf error:", err)
+			}
+			return nil, gin.H{"message": "Failed to refund payment"}, http.StatusInternalServerError
+		}
+		return nil, refund, http.StatusCreated
+	})
+}
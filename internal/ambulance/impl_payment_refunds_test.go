@@ -0,0 +1,169 @@
+package ambulance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// PaymentRefundSuite defines the suite for payment refund tests
+type PaymentRefundSuite struct {
+	suite.Suite
+	paymentDbMock *DbServiceMock[Payment]
+	paymentDb     db_service.DbService[Payment]
+	payment       Payment
+}
+
+func TestPaymentRefundSuite(t *testing.T) {
+	suite.Run(t, new(PaymentRefundSuite))
+}
+
+func (suite *PaymentRefundSuite) SetupTest() {
+	suite.payment = Payment{Id: "pay1", ProcedureId: "proc001", Amount: eur("120"), Status: PaymentStatusSettled}
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("FindDocument", mock.Anything, "pay1").
+		Return(&suite.payment, nil)
+	suite.paymentDbMock.
+		On("FindDocumentsByField", mock.Anything, "refund_of", "pay1").
+		Return([]*Payment{{Id: "ref1", RefundOf: "pay1", Amount: eur("-20"), Status: PaymentStatusSettled}}, nil)
+	suite.paymentDbMock.
+		On("CreateDocument", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil)
+	suite.paymentDbMock.
+		On("UpdateDocument", mock.Anything, "pay1", mock.Anything).
+		Return(nil)
+	suite.paymentDbMock.
+		On("UpdateDocumentIf", mock.Anything, "pay1", mock.Anything, mock.Anything).
+		Return(nil)
+	suite.paymentDb = suite.paymentDbMock
+}
+
+func (suite *PaymentRefundSuite) request(method, path, payload string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_payment", suite.paymentDb)
+	ctx.Params = []gin.Param{{Key: "paymentId", Value: "pay1"}}
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+
+	sut := implPaymentAPI{}
+	if method == "DELETE" {
+		sut.DeletePayment(ctx)
+	} else {
+		sut.RefundPayment(ctx)
+	}
+	return recorder
+}
+
+func (suite *PaymentRefundSuite) Test_RefundPayment_CreatesNegativeEntry() {
+	recorder := suite.request("POST", "/api/payments/pay1/refunds", `{"amount": "30", "reason": "Duplicate charge"}`)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.paymentDbMock.AssertCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.MatchedBy(func(p *Payment) bool {
+		return p.RefundOf == "pay1" && p.Amount.Equal(eur("-30")) && p.ProcedureId == "proc001"
+	}))
+	suite.Equal(PaymentStatusSettled, suite.payment.Status)
+}
+
+func (suite *PaymentRefundSuite) Test_RefundPayment_RemainderMarksPaymentRefunded() {
+	recorder := suite.request("POST", "/api/payments/pay1/refunds", `{"reason": "Procedure cancelled"}`)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Contains(recorder.Body.String(), `"amount":"-100.00"`)
+	suite.Equal(PaymentStatusRefunded, suite.payment.Status)
+}
+
+func (suite *PaymentRefundSuite) Test_RefundPayment_RecordsRefundedTotal() {
+	recorder := suite.request("POST", "/api/payments/pay1/refunds", `{"amount": "30", "reason": "Duplicate charge"}`)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.paymentDbMock.AssertCalled(suite.T(), "UpdateDocumentIf", mock.Anything, "pay1", map[string]any{"refunded": (*money.Money)(nil)}, mock.MatchedBy(func(p *Payment) bool {
+		return p.Refunded != nil && p.Refunded.Equal(eur("50"))
+	}))
+
+	recorder = suite.request("POST", "/api/payments/pay1/refunds", `{"amount": "70.01", "reason": "Goodwill"}`)
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.paymentDbMock.AssertNumberOfCalls(suite.T(), "FindDocumentsByField", 1)
+}
+
+// racingRefunds records a refund of 80 as a refund issued at the same time would, before
+// the first conditional update.
+type racingRefunds struct {
+	db_service.DbService[Payment]
+	raced bool
+}
+
+func (r *racingRefunds) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *Payment) error {
+	if !r.raced {
+		r.raced = true
+		payment, err := r.DbService.FindDocument(ctx, id)
+		if err != nil {
+			return err
+		}
+		refunded := eur("80")
+		payment.Refunded = &refunded
+		if err := r.DbService.UpdateDocument(ctx, id, payment); err != nil {
+			return err
+		}
+		refund := Payment{Id: "ref-racing", RefundOf: id, Amount: eur("-80"), Status: PaymentStatusSettled}
+		if err := r.DbService.CreateDocument(ctx, refund.Id, &refund); err != nil {
+			return err
+		}
+	}
+	return r.DbService.UpdateDocumentIf(ctx, id, fields, document)
+}
+
+func (suite *PaymentRefundSuite) Test_RefundPayment_RejectsRefundRacingAnother() {
+	db := db_service.NewMemoryService[Payment]()
+	suite.Require().NoError(db.CreateDocument(context.Background(), "pay1", &suite.payment))
+	suite.paymentDb = &racingRefunds{DbService: db}
+
+	recorder := suite.request("POST", "/api/payments/pay1/refunds", `{"amount": "100", "reason": "Duplicate charge"}`)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	refunds, err := db.FindDocumentsByField(context.Background(), "refund_of", "pay1")
+	suite.Require().NoError(err)
+	suite.Len(refunds, 1)
+	stored, err := db.FindDocument(context.Background(), "pay1")
+	suite.Require().NoError(err)
+	suite.True(stored.Refunded.Equal(eur("80")))
+}
+
+func (suite *PaymentRefundSuite) Test_RefundPayment_RejectsMoreThanPaid() {
+	recorder := suite.request("POST", "/api/payments/pay1/refunds", `{"amount": "100.01", "reason": "Goodwill"}`)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.paymentDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *PaymentRefundSuite) Test_RefundPayment_RequiresReason() {
+	recorder := suite.request("POST", "/api/payments/pay1/refunds", `{"amount": "10"}`)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+}
+
+func (suite *PaymentRefundSuite) Test_DeletePayment_ForbidsSettledPayments() {
+	recorder := suite.request("DELETE", "/api/payments/pay1", "")
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.paymentDbMock.AssertNotCalled(suite.T(), "DeleteDocument", mock.Anything, mock.Anything)
+}
+
+func (suite *PaymentRefundSuite) Test_ApplyPaymentSummary_DeductsRefunds() {
+	p := &Procedure{Id: "proc001", Price: eur("120")}
+
+	suite.NoError(applyPaymentSummary(p, []Payment{suite.payment, {Amount: eur("-20"), RefundOf: "pay1"}}))
+
+	suite.Equal(eur("20"), *p.Balance)
+	suite.Equal(ProcedurePartiallyPaid, p.PaymentStatus)
+}
@@ -0,0 +1,221 @@
+package ambulance
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// Payment statuses.
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusSettled    = "settled"
+	PaymentStatusFailed     = "failed"
+	PaymentStatusRefunded   = "refunded"
+)
+
+// Payment statuses of a procedure, computed from its settled payments.
+const (
+	ProcedureUnpaid        = "unpaid"
+	ProcedurePartiallyPaid = "partially_paid"
+	ProcedurePaid          = "paid"
+	ProcedureOverpaid      = "overpaid"
+)
+
+// paymentTransitions lists the statuses a payment may move to from each status.
+// Settled payments only become refunded through refunds; failed and refunded ones are final.
+var paymentTransitions = map[string][]string{
+	PaymentStatusPending:    {PaymentStatusAuthorized, PaymentStatusSettled, PaymentStatusFailed},
+	PaymentStatusAuthorized: {PaymentStatusSettled, PaymentStatusFailed},
+}
+
+// paymentStatus returns the status of p; payments without one predate statuses and were received.
+func paymentStatus(p *Payment) string {
+	if p.Status == "" {
+		return PaymentStatusSettled
+	}
+	return p.Status
+}
+
+// startPaymentLifecycle sets the initial status of a new payment, settled when none is
+// given. It returns a validation problem, if any.
+func startPaymentLifecycle(p *Payment) string {
+	p.Status = strings.ToLower(strings.TrimSpace(p.Status))
+	switch p.Status {
+	case "":
+		p.Status = PaymentStatusSettled
+	case PaymentStatusPending, PaymentStatusAuthorized, PaymentStatusSettled, PaymentStatusFailed:
+	default:
+		return "a new payment must be pending, authorized, settled or failed"
+	}
+	return ""
+}
+
+func isPaymentStatus(status string) bool {
+	switch status {
+	case PaymentStatusPending, PaymentStatusAuthorized, PaymentStatusSettled,
+		PaymentStatusFailed, PaymentStatusRefunded:
+		return true
+	}
+	return false
+}
+
+func canTransitionPayment(from, to string) bool {
+	for _, next := range paymentTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePayment checks the payment against the procedure it pays for: the procedure
+// must exist and the amount must be in the currency of its price. The insurance of a
+// payment linked to a payer is filled from the payer. It returns a validation problem,
+// if any.
+func validatePayment(ctx context.Context, c *gin.Context, p *Payment) (string, error) {
+	if p.Amount.IsNegative() {
+		return "amount must not be negative", nil
+	}
+	if p.PayerId != "" {
+		payer, problem, err := resolvePayer(ctx, getPayerDB(c), p.PayerId)
+		if err != nil || problem != "" {
+			return problem, err
+		}
+		p.Insurance = payer.Name
+	}
+	if p.ProcedureId == "" {
+		return "", nil
+	}
+	procedure, err := getProcedureDB(c).FindDocument(ctx, p.ProcedureId)
+	if err == db_service.ErrNotFound {
+		return "procedure_id does not reference an existing procedure", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	if _, err := procedure.Price.Add(p.Amount); err != nil {
+		return "amount must be in the currency of the procedure price, " + procedure.Price.Currency, nil
+	}
+	return "", nil
+}
+
+// applyPaymentSummary sets the balance and payment status of p from its payments.
+// Settled and refunded payments count, less their refunds; cancelled procedures owe nothing.
+func applyPaymentSummary(p *Procedure, payments []Payment) error {
+	owed := p.Price
+	if procedureStatus(p) == ProcedureStatusCancelled {
+		owed = money.Money{Currency: p.Price.Currency}
+	}
+	paid := money.Money{Currency: owed.Currency}
+	for i := range payments {
+		if status := paymentStatus(&payments[i]); status != PaymentStatusSettled && status != PaymentStatusRefunded {
+			continue
+		}
+		var err error
+		if paid, err = paid.Add(payments[i].Amount); err != nil {
+			return err
+		}
+	}
+	balance, err := owed.Sub(paid)
+	if err != nil {
+		return err
+	}
+
+	p.Balance = &balance
+	switch {
+	case balance.IsNegative():
+		p.PaymentStatus = ProcedureOverpaid
+	case balance.IsZero():
+		p.PaymentStatus = ProcedurePaid
+	case paid.IsZero():
+		p.PaymentStatus = ProcedureUnpaid
+	default:
+		p.PaymentStatus = ProcedurePartiallyPaid
+	}
+	return nil
+}
+
+// applyPaymentSummaries loads the payments of the procedures and sets their balances.
+func applyPaymentSummaries(ctx context.Context, c *gin.Context, procedures []Procedure) error {
+	var payments []Payment
+	if len(procedures) == 1 {
+		found, err := getPaymentDB(c).FindDocumentsByField(ctx, "procedure_id", procedures[0].Id)
+		if err != nil {
+			return err
+		}
+		for _, p := range found {
+			payments = append(payments, *p)
+		}
+	} else if len(procedures) > 1 {
+		var err error
+		if payments, err = getPaymentDB(c).ListDocuments(ctx); err != nil {
+			return err
+		}
+	}
+
+	byProcedure := map[string][]Payment{}
+	for _, p := range payments {
+		byProcedure[p.ProcedureId] = append(byProcedure[p.ProcedureId], p)
+	}
+	for i := range procedures {
+		if err := applyPaymentSummary(&procedures[i], byProcedure[procedures[i].Id]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterProceduresByPaymentStatus keeps the procedures in one of the comma-separated
+// payment statuses. It returns a validation problem for unknown statuses.
+func filterProceduresByPaymentStatus(procedures []Procedure, statuses string) ([]Procedure, string) {
+	if statuses == "" {
+		return procedures, ""
+	}
+	wanted := map[string]bool{}
+	for _, status := range strings.Split(statuses, ",") {
+		status = strings.ToLower(strings.TrimSpace(status))
+		switch status {
+		case ProcedureUnpaid, ProcedurePartiallyPaid, ProcedurePaid, ProcedureOverpaid:
+			wanted[status] = true
+		default:
+			return nil, "unknown payment status " + status
+		}
+	}
+
+	filtered := make([]Procedure, 0, len(procedures))
+	for _, p := range procedures {
+		if wanted[p.PaymentStatus] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, ""
+}
+
+// transitionPayment moves p to status, enforcing the allowed transitions.
+func transitionPayment(p *Payment, status string) (interface{}, int) {
+	to := strings.ToLower(strings.TrimSpace(status))
+	if !isPaymentStatus(to) {
+		return gin.H{"message": "status must be one of pending, authorized, settled, failed, refunded"}, http.StatusUnprocessableEntity
+	}
+	from := paymentStatus(p)
+	if to == from {
+		return nil, 0
+	}
+	if to == PaymentStatusRefunded {
+		return gin.H{"message": "Refund payments through /api/payments/{paymentId}/refunds"}, http.StatusConflict
+	}
+	if !canTransitionPayment(from, to) {
+		return gin.H{
+			"message": "Payment cannot move from " + from + " to " + to,
+			"allowed": paymentTransitions[from],
+		}, http.StatusConflict
+	}
+	p.Status = to
+	return nil, 0
+}
@@ -0,0 +1,120 @@
+package ambulance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// PaymentStatusSuite defines the suite for payment status and balance tests
+type PaymentStatusSuite struct {
+	suite.Suite
+	procedureDbMock *DbServiceMock[Procedure]
+	paymentDbMock   *DbServiceMock[Payment]
+}
+
+func TestPaymentStatusSuite(t *testing.T) {
+	suite.Run(t, new(PaymentStatusSuite))
+}
+
+func (suite *PaymentStatusSuite) SetupTest() {
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Procedure{
+			{Id: "proc001", Price: eur("120")},
+			{Id: "proc002", Price: eur("80")},
+			{Id: "proc003", Price: eur("50")},
+		}, nil)
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&Procedure{Id: "proc001", Price: eur("120")}, nil)
+
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Payment{
+			{Id: "pay1", ProcedureId: "proc001", Amount: eur("20"), Status: PaymentStatusSettled},
+			{Id: "pay2", ProcedureId: "proc001", Amount: eur("100"), Status: PaymentStatusPending},
+			{Id: "pay3", ProcedureId: "proc002", Amount: eur("80")},
+		}, nil)
+}
+
+func (suite *PaymentStatusSuite) request(method, path, payload string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	return ctx, recorder
+}
+
+func (suite *PaymentStatusSuite) Test_ApplyPaymentSummary_CountsSettledPayments() {
+	p := &Procedure{Id: "proc001", Price: eur("120")}
+
+	suite.NoError(applyPaymentSummary(p, []Payment{
+		{Amount: eur("50"), Status: PaymentStatusSettled},
+		{Amount: eur("30")},
+		{Amount: eur("40"), Status: PaymentStatusFailed},
+	}))
+
+	suite.Equal(eur("40"), *p.Balance)
+	suite.Equal(ProcedurePartiallyPaid, p.PaymentStatus)
+}
+
+func (suite *PaymentStatusSuite) Test_ApplyPaymentSummary_CancelledProcedureIsOverpaid() {
+	p := &Procedure{Id: "proc001", Price: eur("120"), Status: ProcedureStatusCancelled}
+
+	suite.NoError(applyPaymentSummary(p, []Payment{{Amount: eur("20")}}))
+
+	suite.Equal(eur("-20"), *p.Balance)
+	suite.Equal(ProcedureOverpaid, p.PaymentStatus)
+}
+
+func (suite *PaymentStatusSuite) Test_GetProcedures_FiltersByPaymentStatus() {
+	ctx, recorder := suite.request("GET", "/api/procedures?payment_status=unpaid,partially_paid", "")
+
+	(&implProcedureAPI{}).GetProcedures(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	var procedures []Procedure
+	suite.Require().NoError(json.Unmarshal(recorder.Body.Bytes(), &procedures))
+	suite.Require().Len(procedures, 2)
+	suite.Equal(ProcedurePartiallyPaid, procedures[0].PaymentStatus)
+	suite.Equal(eur("100"), *procedures[0].Balance)
+	suite.Equal("proc003", procedures[1].Id)
+	suite.Equal(ProcedureUnpaid, procedures[1].PaymentStatus)
+}
+
+func (suite *PaymentStatusSuite) Test_CreatePayment_RejectsOtherCurrency() {
+	ctx, recorder := suite.request("POST", "/api/payments",
+		`{"procedure_id": "proc001", "insurance": "VšZP", "amount": {"amount": "500", "currency": "CZK"}}`)
+
+	(&implPaymentAPI{}).CreatePayment(ctx)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.paymentDbMock.AssertNotCalled(suite.T(), "CreateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *PaymentStatusSuite) Test_UpdatePayment_RejectsDisallowedTransition() {
+	payment := Payment{Id: "pay1", ProcedureId: "proc001", Amount: money.New(2000, "EUR"), Status: PaymentStatusFailed}
+	suite.paymentDbMock.
+		On("FindDocument", mock.Anything, "pay1").
+		Return(&payment, nil)
+	ctx, recorder := suite.request("PUT", "/api/payments/pay1", `{"status": "settled"}`)
+	ctx.Params = []gin.Param{{Key: "paymentId", Value: "pay1"}}
+
+	(&implPaymentAPI{}).UpdatePayment(ctx)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.paymentDbMock.AssertNotCalled(suite.T(), "UpdateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,490 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// catalogPriceRule is the base rule id recorded when the catalog default price applied.
+const catalogPriceRule = "catalog"
+
+// implPricingAPI implements the PricingAPI interface.
+type implPricingAPI struct{}
+
+// NewPricingAPI returns an implementation of PricingAPI.
+func NewPricingAPI() PricingAPI {
+	return &implPricingAPI{}
+}
+
+// getPriceListDB extracts the DbService[PriceList] from the context.
+func getPriceListDB(c *gin.Context) db_service.DbService[PriceList] {
+	return c.MustGet("db_service_price_list").(db_service.DbService[PriceList])
+}
+
+// parseClock returns the minutes since midnight of a HH:MM time of day.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validatePriceList returns the list of problems with the price list.
+func validatePriceList(l *PriceList) []string {
+	problems := make([]string, 0)
+	if strings.TrimSpace(l.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if _, err := time.Parse(time.DateOnly, l.EffectiveFrom); err != nil {
+		problems = append(problems, "effective_from must be in YYYY-MM-DD format")
+	}
+
+	ids := map[string]bool{}
+	for i, r := range l.Rules {
+		prefix := fmt.Sprintf("rules[%d]: ", i)
+		if r.Id == "" {
+			problems = append(problems, prefix+"id is required")
+		} else if ids[r.Id] {
+			problems = append(problems, prefix+"id "+r.Id+" is not unique")
+		}
+		ids[r.Id] = true
+
+		if r.Price == nil && r.SurchargePercent == 0 {
+			problems = append(problems, prefix+"price or surcharge_percent is required")
+		}
+		if r.Price != nil && r.Price.IsNegative() {
+			problems = append(problems, prefix+"price must not be negative")
+		}
+		if (r.TimeFrom == "") != (r.TimeTo == "") {
+			problems = append(problems, prefix+"time_from and time_to must be given together")
+		}
+		for _, clock := range []string{r.TimeFrom, r.TimeTo} {
+			if _, err := parseClock(clock); clock != "" && err != nil {
+				problems = append(problems, prefix+"times must be in HH:MM format")
+			}
+		}
+		for _, day := range r.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				problems = append(problems, prefix+"unknown day "+day)
+			}
+		}
+	}
+	return problems
+}
+
+// weekdays maps day names accepted in price rules to weekdays.
+var weekdays = map[string]time.Weekday{
+	"sunday":    time.Sunday,
+	"monday":    time.Monday,
+	"tuesday":   time.Tuesday,
+	"wednesday": time.Wednesday,
+	"thursday":  time.Thursday,
+	"friday":    time.Friday,
+	"saturday":  time.Saturday,
+}
+
+// matches reports whether the rule applies to the procedure performed at the given local time.
+func (r *PriceRule) matches(p *Procedure, at time.Time) bool {
+	if r.Code != "" && normalizeProcedureCode(r.Code) != normalizeProcedureCode(p.Code) {
+		return false
+	}
+	if r.Payer != "" && !strings.EqualFold(r.Payer, p.Payer) {
+		return false
+	}
+	if r.VisitType != "" && !strings.EqualFold(r.VisitType, p.VisitType) {
+		return false
+	}
+	if len(r.Days) > 0 {
+		onDay := false
+		for _, day := range r.Days {
+			if weekdays[strings.ToLower(day)] == at.Weekday() {
+				onDay = true
+			}
+		}
+		if !onDay {
+			return false
+		}
+	}
+	if r.TimeFrom != "" {
+		from, _ := parseClock(r.TimeFrom)
+		to, _ := parseClock(r.TimeTo)
+		minute := at.Hour()*60 + at.Minute()
+		if from <= to && (minute < from || minute >= to) {
+			return false
+		}
+		if from > to && minute < from && minute >= to {
+			return false
+		}
+	}
+	return true
+}
+
+// specificity counts the conditions of the rule; the most specific matching rule sets the base price.
+func (r *PriceRule) specificity() int {
+	count := 0
+	for _, condition := range []bool{r.Code != "", r.Payer != "", r.VisitType != "", len(r.Days) > 0, r.TimeFrom != ""} {
+		if condition {
+			count++
+		}
+	}
+	return count
+}
+
+// priceListAt returns the price list in effect at the given time: the one with the
+// latest effective date not after it.
+func priceListAt(lists []PriceList, at time.Time) *PriceList {
+	var current *PriceList
+	for i := range lists {
+		from, err := time.ParseInLocation(time.DateOnly, lists[i].EffectiveFrom, time.Local)
+		if err != nil || from.After(at) {
+			continue
+		}
+		if current == nil || lists[i].EffectiveFrom > current.EffectiveFrom {
+			current = &lists[i]
+		}
+	}
+	return current
+}
+
+// computePriceQuote prices the procedure from the price list in effect at its timestamp.
+// The most specific matching rule with a price sets the base price, falling back to the
+// catalog default price of the procedure type; every matching surcharge rule then adds
+// its percentage of the base price. It returns nil when no base price applies and an
+// error when the amounts cannot be added up.
+func computePriceQuote(p *Procedure, lists []PriceList, procedureType *ProcedureType) (*PriceQuote, error) {
+	at := p.Timestamp
+	if at.IsZero() {
+		at = time.Now()
+	}
+	at = at.In(time.Local)
+
+	quote := &PriceQuote{Adjustments: make([]PriceAdjustment, 0)}
+	var base *PriceRule
+	list := priceListAt(lists, at)
+	if list != nil {
+		quote.PriceListId = list.Id
+		quote.EffectiveFrom = list.EffectiveFrom
+		for i := range list.Rules {
+			r := &list.Rules[i]
+			if r.Price != nil && r.matches(p, at) && (base == nil || r.specificity() > base.specificity()) {
+				base = r
+			}
+		}
+	}
+
+	switch {
+	case base != nil:
+		quote.BaseRuleId = base.Id
+		quote.BasePrice = *base.Price
+	case procedureType != nil:
+		quote.BaseRuleId = catalogPriceRule
+		quote.BasePrice = procedureType.DefaultPrice
+	default:
+		return nil, nil
+	}
+
+	quote.Price = quote.BasePrice
+	if list != nil {
+		for i := range list.Rules {
+			r := &list.Rules[i]
+			if r.SurchargePercent == 0 || !r.matches(p, at) {
+				continue
+			}
+			adjustment := PriceAdjustment{
+				RuleId:      r.Id,
+				Description: r.Description,
+				Percent:     r.SurchargePercent,
+				Amount:      quote.BasePrice.Percent32(r.SurchargePercent),
+			}
+			quote.Adjustments = append(quote.Adjustments, adjustment)
+			price, err := quote.Price.Add(adjustment.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("rule %v of price list %v: %w", r.Id, list.Id, err)
+			}
+			quote.Price = price
+		}
+	}
+	return quote, nil
+}
+
+// quoteProcedurePrice loads the price lists and the procedure type and prices the
+// procedure. The quote is nil when no base price applies.
+func quoteProcedurePrice(ctx context.Context, c *gin.Context, p *Procedure) (*PriceQuote, string, error) {
+	lists, err := getPriceListDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	var procedureType *ProcedureType
+	if p.Code != "" {
+		procedureType, err = getProcedureTypeDB(c).FindDocument(ctx, normalizeProcedureCode(p.Code))
+		if err == db_service.ErrNotFound {
+			return nil, "code does not reference a procedure type in the catalog", nil
+		}
+		if err != nil {
+			return nil, "", err
+		}
+	}
+	quote, err := computePriceQuote(p, lists, procedureType)
+	return quote, "", err
+}
+
+// priceProcedure sets the procedure's price from the pricing engine and records how it
+// was computed. A requested price other than the computed one is a manual override and
+// needs a reason. Procedures the engine cannot price keep the requested or their current price.
+func priceProcedure(ctx context.Context, c *gin.Context, p *Procedure, requested money.Money, reason string) (string, error) {
+	quote, problem, err := quoteProcedurePrice(ctx, c, p)
+	if err != nil || problem != "" {
+		return problem, err
+	}
+	if quote == nil {
+		p.Pricing = nil
+		p.PriceOverrideReason = ""
+		if !requested.IsZero() {
+			p.Price = requested
+		}
+		return "", nil
+	}
+
+	p.Pricing = quote
+	if requested.IsZero() || requested.Equal(quote.Price) {
+		p.Price = quote.Price
+		p.PriceOverrideReason = ""
+		return "", nil
+	}
+	if strings.TrimSpace(reason) == "" {
+		return fmt.Sprintf("price differs from the computed price %v; price_override_reason is required", quote.Price), nil
+	}
+	p.Price = requested
+	p.PriceOverrideReason = strings.TrimSpace(reason)
+	return "", nil
+}
+
+// priceListInEffect reports whether the price list has already taken effect and may
+// therefore have priced procedures.
+func priceListInEffect(l *PriceList) bool {
+	from, err := time.ParseInLocation(time.DateOnly, l.EffectiveFrom, time.Local)
+	return err == nil && !from.After(time.Now())
+}
+
+// checkPriceList validates the price list and rejects a second price list effective on the same day.
+func checkPriceList(ctx context.Context, c *gin.Context, l *PriceList) (interface{}, int, error) {
+	if problems := validatePriceList(l); len(problems) > 0 {
+		return gin.H{"message": "Invalid price list", "errors": problems}, http.StatusUnprocessableEntity, nil
+	}
+	if priceListInEffect(l) {
+		return gin.H{"message": "effective_from must be in the future; price lists in effect cannot change"}, http.StatusConflict, nil
+	}
+	lists, err := getPriceListDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, other := range lists {
+		if other.Id != l.Id && other.EffectiveFrom == l.EffectiveFrom {
+			return gin.H{"message": "Another price list takes effect on " + l.EffectiveFrom}, http.StatusConflict, nil
+		}
+	}
+	return nil, 0, nil
+}
+
+// withPriceListByID loads a PriceList and calls fn; fn may return an updated doc.
+func withPriceListByID(
+	c *gin.Context,
+	fn func(*gin.Context, *PriceList) (*PriceList, interface{}, int),
+) {
+	id := c.Param("priceListId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "priceListId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getPriceListDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		list, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Price list not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, list)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update price list"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// CreatePriceList implements POST /api/price-lists
+//
+// Price lists are versions: a new one takes effect on its effective_from date and
+// replaces the previous one from then on. Only future dates are accepted.
+func (o *implPricingAPI) CreatePriceList(c *gin.Context) {
+	var l PriceList
+	if err := c.ShouldBindJSON(&l); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if l.Id == "" {
+		l.Id = uuid.NewString()
+	}
+	if l.Rules == nil {
+		l.Rules = make([]PriceRule, 0)
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if result, status, err := checkPriceList(ctx, c, &l); err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create price list"})
+		return
+	} else if result != nil {
+		c.JSON(status, result)
+		return
+	}
+
+	if err := getPriceListDB(c).CreateDocument(ctx, l.Id, &l); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Price list already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create price list"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, l)
+}
+
+// GetPriceListById implements GET /api/price-lists/:priceListId
+func (o *implPricingAPI) GetPriceListById(c *gin.Context) {
+	withPriceListByID(c, func(_ *gin.Context, l *PriceList) (*PriceList, interface{}, int) {
+		return nil, l, http.StatusOK
+	})
+}
+
+// GetPriceLists implements GET /api/price-lists
+//
+// Price lists are returned newest first.
+func (o *implPricingAPI) GetPriceLists(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	lists, err := getPriceListDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving price lists:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve price lists"})
+		return
+	}
+
+	sort.Slice(lists, func(i, j int) bool {
+		return lists[i].EffectiveFrom > lists[j].EffectiveFrom
+	})
+	c.JSON(http.StatusOK, lists)
+}
+
+// UpdatePriceList implements PUT /api/price-lists/:priceListId
+//
+// Price lists already in effect cannot change, so that recorded prices stay explainable;
+// create a new version instead.
+func (o *implPricingAPI) UpdatePriceList(c *gin.Context) {
+	withPriceListByID(c, func(c *gin.Context, existing *PriceList) (*PriceList, interface{}, int) {
+		if priceListInEffect(existing) {
+			return nil, gin.H{"message": "Price list is in effect; create a new version instead"}, http.StatusConflict
+		}
+
+		var upd PriceList
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.Name != "" {
+			existing.Name = upd.Name
+		}
+		if upd.EffectiveFrom != "" {
+			existing.EffectiveFrom = upd.EffectiveFrom
+		}
+		if upd.Rules != nil {
+			existing.Rules = upd.Rules
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		result, status, err := checkPriceList(ctx, c, existing)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to update price list"}, http.StatusInternalServerError
+		}
+		if result != nil {
+			return nil, result, status
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeletePriceList implements DELETE /api/price-lists/:priceListId
+func (o *implPricingAPI) DeletePriceList(c *gin.Context) {
+	withPriceListByID(c, func(c *gin.Context, l *PriceList) (*PriceList, interface{}, int) {
+		if priceListInEffect(l) {
+			return nil, gin.H{"message": "Price list is in effect and cannot be deleted"}, http.StatusConflict
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := getPriceListDB(c).DeleteDocument(ctx, l.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete price list"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// QuoteProcedure implements POST /api/procedures:quote
+//
+// The procedure is priced exactly as on creation, but nothing is saved.
+func (o *implPricingAPI) QuoteProcedure(c *gin.Context) {
+	var p Procedure
+	if err := c.ShouldBindJSON(&p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	quote, problem, err := quoteProcedurePrice(ctx, c, &p)
+	if err != nil {
+		log.Println("quoteProcedurePrice error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute price"})
+		return
+	}
+	if problem != "" {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": problem})
+		return
+	}
+	if quote == nil {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "No price rule applies to the procedure and it has no catalog code"})
+		return
+	}
+	c.JSON(http.StatusOK, quote)
+}
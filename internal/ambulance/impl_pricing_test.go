@@ -0,0 +1,166 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// PricingSuite defines the suite for pricing engine tests
+type PricingSuite struct {
+	suite.Suite
+	lists     []PriceList
+	chestXRay ProcedureType
+}
+
+func TestPricingSuite(t *testing.T) {
+	suite.Run(t, new(PricingSuite))
+}
+
+// eur returns an amount in euros, e.g. eur("120.50").
+func eur(amount string) money.Money {
+	value, err := money.Parse(amount, "EUR")
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func price(amount string) *money.Money {
+	value := eur(amount)
+	return &value
+}
+
+func (suite *PricingSuite) SetupTest() {
+	suite.chestXRay = ProcedureType{Id: "RTG-CHEST", Code: "RTG-CHEST", Name: "Röntgen hrudníka", DefaultPrice: eur("100")}
+	suite.lists = []PriceList{
+		{
+			Id:            "2025",
+			Name:          "Cenník 2025",
+			EffectiveFrom: "2025-01-01",
+			Rules: []PriceRule{
+				{Id: "chest", Code: "RTG-CHEST", Price: price("110")},
+				{Id: "chest-xyz", Code: "RTG-CHEST", Payer: "poisťovňa XYZ", Price: price("105")},
+				{Id: "night", TimeFrom: "22:00", TimeTo: "06:00", SurchargePercent: 50},
+				{Id: "weekend", Days: []string{"saturday", "sunday"}, SurchargePercent: 20},
+			},
+		},
+		{
+			Id:            "2024",
+			Name:          "Cenník 2024",
+			EffectiveFrom: "2024-01-01",
+			Rules:         []PriceRule{{Id: "chest", Code: "RTG-CHEST", Price: price("90")}},
+		},
+	}
+}
+
+func (suite *PricingSuite) at(value string) time.Time {
+	t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local)
+	suite.Require().NoError(err)
+	return t
+}
+
+func (suite *PricingSuite) Test_ComputePriceQuote_MostSpecificRuleSetsBasePrice() {
+	p := &Procedure{Code: "RTG-CHEST", Payer: "Poisťovňa XYZ", Timestamp: suite.at("2025-05-21 10:00")}
+
+	quote, err := computePriceQuote(p, suite.lists, &suite.chestXRay)
+	suite.Require().NoError(err)
+
+	suite.Equal("2025", quote.PriceListId)
+	suite.Equal("chest-xyz", quote.BaseRuleId)
+	suite.Equal(eur("105"), quote.Price)
+	suite.Empty(quote.Adjustments)
+}
+
+func (suite *PricingSuite) Test_ComputePriceQuote_AppliesNightAndWeekendSurcharges() {
+	p := &Procedure{Code: "RTG-CHEST", Timestamp: suite.at("2025-05-24 23:30")}
+
+	quote, err := computePriceQuote(p, suite.lists, &suite.chestXRay)
+	suite.Require().NoError(err)
+
+	suite.Equal("chest", quote.BaseRuleId)
+	suite.Len(quote.Adjustments, 2)
+	suite.Equal(eur("187"), quote.Price)
+}
+
+func (suite *PricingSuite) Test_ComputePriceQuote_UsesPriceListInEffect() {
+	p := &Procedure{Code: "RTG-CHEST", Timestamp: suite.at("2024-06-03 10:00")}
+
+	quote, err := computePriceQuote(p, suite.lists, &suite.chestXRay)
+	suite.Require().NoError(err)
+
+	suite.Equal("2024", quote.PriceListId)
+	suite.Equal(eur("90"), quote.Price)
+}
+
+func (suite *PricingSuite) Test_ComputePriceQuote_FallsBackToCatalogPrice() {
+	p := &Procedure{Code: "RTG-CHEST", Timestamp: suite.at("2023-06-01 10:00")}
+
+	quote, err := computePriceQuote(p, suite.lists, &suite.chestXRay)
+	suite.Require().NoError(err)
+
+	suite.Equal(catalogPriceRule, quote.BaseRuleId)
+	suite.Equal(eur("100"), quote.Price)
+	quote, err = computePriceQuote(&Procedure{Timestamp: p.Timestamp}, suite.lists, nil)
+	suite.NoError(err)
+	suite.Nil(quote)
+}
+
+func (suite *PricingSuite) Test_QuoteProcedure_IsRoutedAsCustomMethod() {
+	priceListDbMock := &DbServiceMock[PriceList]{}
+	priceListDbMock.On("ListDocuments", mock.Anything).Return(suite.lists, nil)
+	procedureTypeDbMock := &DbServiceMock[ProcedureType]{}
+	procedureTypeDbMock.On("FindDocument", mock.Anything, "RTG-CHEST").Return(&suite.chestXRay, nil)
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(func(c *gin.Context) {
+		c.Set("db_service_price_list", priceListDbMock)
+		c.Set("db_service_procedure_type", procedureTypeDbMock)
+	})
+	NewRouterWithGinEngine(engine, testHandleFunctions())
+
+	recorder := httptest.NewRecorder()
+	payload := `{"code":"RTG-CHEST","timestamp":"2025-05-21T10:00:00+02:00"}`
+	engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/procedures:quote", strings.NewReader(payload)))
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), `"base_rule_id":"chest"`)
+
+	recorder = httptest.NewRecorder()
+	engine.ServeHTTP(recorder, httptest.NewRequest("POST", "/api/procedures:unknown", strings.NewReader(payload)))
+
+	suite.Equal(http.StatusNotFound, recorder.Code)
+}
+
+// testHandleFunctions returns the implementations of all APIs, as main wires them.
+func testHandleFunctions() ApiHandleFunctions {
+	return ApiHandleFunctions{
+		AmbulanceManagementAPI:   NewAmbulanceAPI(),
+		BankReconciliationAPI:    NewBankReconciliationAPI(),
+		BatchAPI:                 NewBatchAPI(),
+		ClaimsAPI:                NewClaimsAPI(),
+		ClinicalRecordsAPI:       NewClinicalRecordsAPI(),
+		CrewManagementAPI:        NewCrewAPI(),
+		DepartmentManagementAPI:  NewDepartmentAPI(),
+		InvoicesAPI:              NewInvoicesAPI(),
+		MaintenanceManagementAPI: NewMaintenanceAPI(),
+		MigrationsAPI:            NewMigrationsAPI(),
+		PatientManagementAPI:     NewPatientAPI(),
+		PaymentManagementAPI:     NewPaymentAPI(),
+		PayerManagementAPI:       NewPayerAPI(),
+		PricingAPI:               NewPricingAPI(),
+		ProcedureCatalogAPI:      NewProcedureCatalogAPI(),
+		ProcedureManagementAPI:   NewProcedureAPI(),
+		ReportsAPI:               NewReportsAPI(),
+		SchedulingAPI:            NewSchedulingAPI(),
+		ShiftManagementAPI:       NewShiftAPI(),
+	}
+}
@@ -0,0 +1,473 @@
+package ambulance
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// implProcedureCatalogAPI implements the ProcedureCatalogAPI interface.
+type implProcedureCatalogAPI struct{}
+
+// NewProcedureCatalogAPI returns an implementation of ProcedureCatalogAPI.
+func NewProcedureCatalogAPI() ProcedureCatalogAPI {
+	return &implProcedureCatalogAPI{}
+}
+
+// getProcedureTypeDB extracts the DbService[ProcedureType] from the context.
+func getProcedureTypeDB(c *gin.Context) db_service.DbService[ProcedureType] {
+	return c.MustGet("db_service_procedure_type").(db_service.DbService[ProcedureType])
+}
+
+// normalizeProcedureCode trims and upper-cases a catalog code.
+func normalizeProcedureCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// validateProcedureType returns the list of problems with the procedure type.
+func validateProcedureType(t *ProcedureType) []string {
+	problems := make([]string, 0)
+	if t.Code == "" {
+		problems = append(problems, "code is required")
+	} else if strings.ContainsAny(t.Code, " \t/:") {
+		problems = append(problems, "code must not contain whitespace, '/' or ':'")
+	}
+	if strings.TrimSpace(t.Name) == "" {
+		problems = append(problems, "name is required")
+	}
+	if t.DefaultPrice.IsNegative() {
+		problems = append(problems, "default_price must not be negative")
+	}
+	if t.DefaultDuration < 0 {
+		problems = append(problems, "default_duration must not be negative")
+	}
+	return problems
+}
+
+// applyProcedureType fills the procedure's empty name, description, price and duration
+// with the catalog defaults and checks its visit type against the allowed visit types.
+// It returns a validation problem, or an empty string.
+func applyProcedureType(p *Procedure, t *ProcedureType) string {
+	p.Code = t.Code
+	if p.Name == "" {
+		p.Name = t.Name
+	}
+	if p.Description == "" {
+		p.Description = t.Description
+	}
+	if p.Price.IsZero() {
+		p.Price = t.DefaultPrice
+	}
+	if p.Duration == 0 {
+		p.Duration = t.DefaultDuration
+	}
+
+	if len(t.VisitTypes) == 0 {
+		return ""
+	}
+	if p.VisitType == "" && len(t.VisitTypes) == 1 {
+		p.VisitType = t.VisitTypes[0]
+	}
+	for _, visitType := range t.VisitTypes {
+		if strings.EqualFold(visitType, p.VisitType) {
+			p.VisitType = visitType
+			return ""
+		}
+	}
+	return fmt.Sprintf("visit_type %q is not allowed for %s; allowed: %s", p.VisitType, t.Code, strings.Join(t.VisitTypes, ", "))
+}
+
+// resolveProcedureType applies the catalog defaults of the procedure's code. Procedures
+// without a code are left as they are. It returns a validation problem when the code is
+// not in the catalog or the visit type is not allowed.
+func resolveProcedureType(ctx context.Context, db db_service.DbService[ProcedureType], p *Procedure) (string, error) {
+	if p.Code == "" {
+		return "", nil
+	}
+	t, err := db.FindDocument(ctx, normalizeProcedureCode(p.Code))
+	if err == db_service.ErrNotFound {
+		return "code does not reference a procedure type in the catalog", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return applyProcedureType(p, t), nil
+}
+
+// parseProcedureTypesCSV reads procedure types from CSV with a header row. The code,
+// name and default_price columns are required; description, default_duration and
+// visit_types (separated by '|') are optional.
+func parseProcedureTypesCSV(r io.Reader) ([]ProcedureType, []ImportError) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, []ImportError{{Line: 1, Message: "missing header row"}}
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))] = i
+	}
+	for _, required := range []string{"code", "name", "default_price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, []ImportError{{Line: 1, Message: "missing column " + required}}
+		}
+	}
+
+	types := make([]ProcedureType, 0)
+	errors := make([]ImportError, 0)
+	lineOf := map[string]int32{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			if parseErr, ok := err.(*csv.ParseError); ok {
+				line = parseErr.Line
+			}
+			errors = append(errors, ImportError{Line: int32(line), Message: err.Error()})
+			break
+		}
+		line, _ := reader.FieldPos(0)
+		value := func(column string) string {
+			if i, ok := columns[column]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+
+		t := ProcedureType{
+			Code:        normalizeProcedureCode(value("code")),
+			Name:        value("name"),
+			Description: value("description"),
+		}
+		t.Id = t.Code
+		problems := make([]string, 0)
+		if price, err := money.Parse(value("default_price"), money.DefaultCurrency); err != nil {
+			problems = append(problems, "default_price must be a number")
+		} else {
+			t.DefaultPrice = price
+		}
+		if duration := value("default_duration"); duration != "" {
+			if minutes, err := strconv.Atoi(duration); err != nil {
+				problems = append(problems, "default_duration must be a whole number of minutes")
+			} else {
+				t.DefaultDuration = int32(minutes)
+			}
+		}
+		for _, visitType := range strings.Split(value("visit_types"), "|") {
+			if visitType = strings.TrimSpace(visitType); visitType != "" {
+				t.VisitTypes = append(t.VisitTypes, visitType)
+			}
+		}
+		problems = append(problems, validateProcedureType(&t)...)
+		if previous, ok := lineOf[t.Code]; ok && t.Code != "" {
+			problems = append(problems, fmt.Sprintf("code %s already appears on line %d", t.Code, previous))
+		}
+		lineOf[t.Code] = int32(line)
+
+		for _, problem := range problems {
+			errors = append(errors, ImportError{Line: int32(line), Message: problem})
+		}
+		types = append(types, t)
+	}
+	return types, errors
+}
+
+// summarizeByCode groups procedures by catalog code, naming each group after its
+// procedure type. Procedures without a code form a single group with an empty code.
+// Cancelled procedures are left out. Totals fail with money.ErrCurrencyMismatch when
+// procedures of one code are priced in different currencies.
+func summarizeByCode(procedures []Procedure, types []ProcedureType) ([]ProcedureCodeSummary, error) {
+	names := map[string]string{}
+	for _, t := range types {
+		names[t.Code] = t.Name
+	}
+
+	groups := map[string]*ProcedureCodeSummary{}
+	for _, p := range procedures {
+		if procedureStatus(&p) == ProcedureStatusCancelled {
+			continue
+		}
+		code := normalizeProcedureCode(p.Code)
+		group, ok := groups[code]
+		if !ok {
+			group = &ProcedureCodeSummary{Code: code, Name: names[code]}
+			if code == "" {
+				group.Name = "Uncatalogued"
+			} else if group.Name == "" {
+				group.Name = p.Name
+			}
+			groups[code] = group
+		}
+		group.Count++
+		total, err := group.Total.Add(p.Price)
+		if err != nil {
+			return nil, err
+		}
+		group.Total = total
+	}
+
+	summaries := make([]ProcedureCodeSummary, 0, len(groups))
+	for _, group := range groups {
+		summaries = append(summaries, *group)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Code < summaries[j].Code
+	})
+	return summaries, nil
+}
+
+// CreateProcedureType implements POST /api/procedure-types
+func (o *implProcedureCatalogAPI) CreateProcedureType(c *gin.Context) {
+	var t ProcedureType
+	if err := c.ShouldBindJSON(&t); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	t.Code = normalizeProcedureCode(t.Code)
+	t.Id = t.Code
+	if problems := validateProcedureType(&t); len(problems) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "Invalid procedure type", "errors": problems})
+		return
+	}
+
+	db := getProcedureTypeDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if err := db.CreateDocument(ctx, t.Id, &t); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Procedure type already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create procedure type"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, t)
+}
+
+// withProcedureTypeByCode loads a ProcedureType and calls fn; fn may return an updated doc.
+func withProcedureTypeByCode(
+	c *gin.Context,
+	fn func(*gin.Context, *ProcedureType) (*ProcedureType, interface{}, int),
+) {
+	code := normalizeProcedureCode(c.Param("code"))
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "code is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getProcedureTypeDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		t, err := db.FindDocument(ctx, code)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Procedure type not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, t)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, code, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update procedure type"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// GetProcedureType implements GET /api/procedure-types/:code
+func (o *implProcedureCatalogAPI) GetProcedureType(c *gin.Context) {
+	withProcedureTypeByCode(c, func(_ *gin.Context, t *ProcedureType) (*ProcedureType, interface{}, int) {
+		return nil, t, http.StatusOK
+	})
+}
+
+// GetProcedureTypes implements GET /api/procedure-types
+func (o *implProcedureCatalogAPI) GetProcedureTypes(c *gin.Context) {
+	db := getProcedureTypeDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	types, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("Error retrieving procedure types:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve procedure types"})
+		return
+	}
+
+	sort.Slice(types, func(i, j int) bool {
+		return types[i].Code < types[j].Code
+	})
+	c.JSON(http.StatusOK, types)
+}
+
+// UpdateProcedureType implements PUT /api/procedure-types/:code
+//
+// Changing the defaults does not change existing procedures.
+func (o *implProcedureCatalogAPI) UpdateProcedureType(c *gin.Context) {
+	withProcedureTypeByCode(c, func(c *gin.Context, existing *ProcedureType) (*ProcedureType, interface{}, int) {
+		var upd ProcedureType
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.Name != "" {
+			existing.Name = upd.Name
+		}
+		if upd.Description != "" {
+			existing.Description = upd.Description
+		}
+		if !upd.DefaultPrice.IsZero() {
+			existing.DefaultPrice = upd.DefaultPrice
+		}
+		if upd.DefaultDuration != 0 {
+			existing.DefaultDuration = upd.DefaultDuration
+		}
+		if upd.VisitTypes != nil {
+			existing.VisitTypes = upd.VisitTypes
+		}
+		if problems := validateProcedureType(existing); len(problems) > 0 {
+			return nil, gin.H{"message": "Invalid procedure type", "errors": problems}, http.StatusUnprocessableEntity
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteProcedureType implements DELETE /api/procedure-types/:code
+//
+// Procedure types still referenced by procedures cannot be deleted.
+func (o *implProcedureCatalogAPI) DeleteProcedureType(c *gin.Context) {
+	withProcedureTypeByCode(c, func(c *gin.Context, t *ProcedureType) (*ProcedureType, interface{}, int) {
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		procedures, err := getProcedureDB(c).FindDocumentsByField(ctx, "code", t.Code)
+		if err != nil {
+			log.Println("FindDocumentsByField error:", err)
+			return nil, gin.H{"message": "Failed to delete procedure type"}, http.StatusInternalServerError
+		}
+		if len(procedures) > 0 {
+			return nil, gin.H{"message": "Procedure type is used by procedures"}, http.StatusConflict
+		}
+
+		if err := getProcedureTypeDB(c).DeleteDocument(ctx, t.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete procedure type"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// ImportProcedureTypes implements POST /api/procedure-types/import
+//
+// The request body is CSV as read by parseProcedureTypesCSV. Procedure types with a
+// code already in the catalog are replaced. When any line is invalid nothing is imported.
+func (o *implProcedureCatalogAPI) ImportProcedureTypes(c *gin.Context) {
+	types, errors := parseProcedureTypesCSV(c.Request.Body)
+	result := ProcedureTypeImportResult{Errors: errors}
+	if len(errors) > 0 {
+		c.JSON(http.StatusUnprocessableEntity, result)
+		return
+	}
+
+	db := getProcedureTypeDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 60*time.Second)
+	defer cancel()
+
+	existing, err := db.ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import procedure types"})
+		return
+	}
+	known := map[string]bool{}
+	for _, t := range existing {
+		known[t.Id] = true
+	}
+
+	for i := range types {
+		t := &types[i]
+		if known[t.Id] {
+			err = db.UpdateDocument(ctx, t.Id, t)
+			result.Updated++
+		} else {
+			err = db.CreateDocument(ctx, t.Id, t)
+			result.Created++
+		}
+		if err != nil {
+			log.Println("Import procedure type error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to import procedure types", "result": result})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// GetProcedureReport implements GET /api/reports/procedures
+//
+// Procedures are counted and their prices summed per catalog code, optionally limited
+// to an ambulance (ambulance_id) and a date range (from, to).
+func (o *implProcedureCatalogAPI) GetProcedureReport(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	procedures, err := getProcedureDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute procedure report"})
+		return
+	}
+	types, err := getProcedureTypeDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute procedure report"})
+		return
+	}
+
+	ambulanceID := c.Query("ambulance_id")
+	selected := make([]Procedure, 0, len(procedures))
+	for _, p := range procedures {
+		if ambulanceID != "" && p.AmbulanceId != ambulanceID {
+			continue
+		}
+		if (!from.IsZero() && p.Timestamp.Before(from)) || (!to.IsZero() && !p.Timestamp.Before(to)) {
+			continue
+		}
+		selected = append(selected, p)
+	}
+	summaries, err := summarizeByCode(selected, types)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "Failed to compute procedure report", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, summaries)
+}
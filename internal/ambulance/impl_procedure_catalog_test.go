@@ -0,0 +1,80 @@
+package ambulance
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ProcedureCatalogSuite defines the suite for procedure catalog tests
+type ProcedureCatalogSuite struct {
+	suite.Suite
+	chestXRay ProcedureType
+}
+
+func TestProcedureCatalogSuite(t *testing.T) {
+	suite.Run(t, new(ProcedureCatalogSuite))
+}
+
+func (suite *ProcedureCatalogSuite) SetupTest() {
+	suite.chestXRay = ProcedureType{
+		Id:              "RTG-CHEST",
+		Code:            "RTG-CHEST",
+		Name:            "Röntgen hrudníka",
+		Description:     "Routine chest X-ray",
+		DefaultPrice:    eur("120.50"),
+		DefaultDuration: 15,
+		VisitTypes:      []string{"emergency", "follow-up"},
+	}
+}
+
+func (suite *ProcedureCatalogSuite) Test_ApplyProcedureType_FillsDefaults() {
+	p := &Procedure{Code: "RTG-CHEST", VisitType: "Emergency"}
+
+	suite.Empty(applyProcedureType(p, &suite.chestXRay))
+	suite.Equal("Röntgen hrudníka", p.Name)
+	suite.Equal(eur("120.50"), p.Price)
+	suite.Equal(int32(15), p.Duration)
+	suite.Equal("emergency", p.VisitType)
+}
+
+func (suite *ProcedureCatalogSuite) Test_ApplyProcedureType_KeepsExplicitPrice() {
+	p := &Procedure{Code: "RTG-CHEST", VisitType: "follow-up", Price: eur("99")}
+
+	suite.Empty(applyProcedureType(p, &suite.chestXRay))
+	suite.Equal(eur("99"), p.Price)
+}
+
+func (suite *ProcedureCatalogSuite) Test_ApplyProcedureType_RejectsVisitType() {
+	p := &Procedure{Code: "RTG-CHEST", VisitType: "konzultácia"}
+
+	suite.Contains(applyProcedureType(p, &suite.chestXRay), "not allowed")
+}
+
+func (suite *ProcedureCatalogSuite) Test_ParseProcedureTypesCSV_ReadsRows() {
+	csv := "code,name,default_price,default_duration,visit_types\n" +
+		"rtg-chest,Röntgen hrudníka,120.50,15,emergency|follow-up\n" +
+		"CONS,Konzultácia,30,,\n"
+
+	types, errors := parseProcedureTypesCSV(strings.NewReader(csv))
+
+	suite.Empty(errors)
+	suite.Len(types, 2)
+	suite.Equal("RTG-CHEST", types[0].Id)
+	suite.Equal([]string{"emergency", "follow-up"}, types[0].VisitTypes)
+	suite.Nil(types[1].VisitTypes)
+}
+
+func (suite *ProcedureCatalogSuite) Test_ParseProcedureTypesCSV_ReportsLines() {
+	csv := "code,name,default_price\n" +
+		"CONS,Konzultácia,abc\n" +
+		"CONS,Konzultácia,30\n"
+
+	_, errors := parseProcedureTypesCSV(strings.NewReader(csv))
+
+	suite.Equal([]ImportError{
+		{Line: 2, Message: "default_price must be a number"},
+		{Line: 3, Message: "code CONS already appears on line 2"},
+	}, errors)
+}
@@ -0,0 +1,167 @@
+package ambulance
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Procedure lifecycle statuses.
+const (
+	ProcedureStatusScheduled  = "scheduled"
+	ProcedureStatusInProgress = "in_progress"
+	ProcedureStatusCompleted  = "completed"
+	ProcedureStatusCancelled  = "cancelled"
+	ProcedureStatusBilled     = "billed"
+)
+
+// UserRoleHeader carries the role of the user making the request.
+const UserRoleHeader = "X-User-Role"
+
+// RoleBilling is the role allowed to bill procedures and to change billed ones.
+const RoleBilling = "billing"
+
+// procedureTransitions lists the statuses a procedure may move to from each status.
+// Billing may return a billed procedure to completed to correct it.
+var procedureTransitions = map[string][]string{
+	ProcedureStatusScheduled:  {ProcedureStatusInProgress, ProcedureStatusCompleted, ProcedureStatusCancelled},
+	ProcedureStatusInProgress: {ProcedureStatusCompleted, ProcedureStatusCancelled},
+	ProcedureStatusCompleted:  {ProcedureStatusBilled},
+	ProcedureStatusBilled:     {ProcedureStatusCompleted},
+}
+
+// procedureStatus returns the status of p; procedures without one predate statuses and were performed.
+func procedureStatus(p *Procedure) string {
+	if p.Status == "" {
+		return ProcedureStatusCompleted
+	}
+	return p.Status
+}
+
+func isProcedureStatus(status string) bool {
+	switch status {
+	case ProcedureStatusScheduled, ProcedureStatusInProgress, ProcedureStatusCompleted,
+		ProcedureStatusCancelled, ProcedureStatusBilled:
+		return true
+	}
+	return false
+}
+
+func canTransition(from, to string) bool {
+	for _, next := range procedureTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// userRole returns the role sent in the X-User-Role header.
+func userRole(c *gin.Context) string {
+	return strings.ToLower(strings.TrimSpace(c.GetHeader(UserRoleHeader)))
+}
+
+// recordProcedureStatus moves p to status and appends the change to its history.
+// Changes to scheduled also record the appointment time, so reschedules stay traceable.
+func recordProcedureStatus(p *Procedure, status, role, note string, at time.Time) {
+	p.Status = status
+	change := ProcedureStatusChange{
+		Status:    status,
+		ChangedAt: at,
+		ChangedBy: role,
+		Note:      note,
+	}
+	if status == ProcedureStatusScheduled {
+		scheduledFor := p.Timestamp
+		change.ScheduledFor = &scheduledFor
+	}
+	p.StatusHistory = append(p.StatusHistory, change)
+}
+
+// startProcedureLifecycle sets the initial status of a new procedure. Without a status it is
+// scheduled when it lies in the future and completed otherwise. It returns a validation problem, if any.
+func startProcedureLifecycle(p *Procedure, role string, now time.Time) string {
+	status := strings.ToLower(strings.TrimSpace(p.Status))
+	switch status {
+	case "":
+		status = ProcedureStatusCompleted
+		if p.Timestamp.After(now) {
+			status = ProcedureStatusScheduled
+		}
+	case ProcedureStatusScheduled, ProcedureStatusInProgress, ProcedureStatusCompleted:
+	default:
+		return "a new procedure must be scheduled, in_progress or completed"
+	}
+	p.StatusHistory = nil
+	recordProcedureStatus(p, status, role, "", now)
+	return ""
+}
+
+// checkProcedureEditable reports whether the caller may change p; billed procedures
+// may only be changed by billing.
+func checkProcedureEditable(c *gin.Context, p *Procedure) (interface{}, int) {
+	if procedureStatus(p) == ProcedureStatusBilled && userRole(c) != RoleBilling {
+		return gin.H{"message": "Billed procedures can only be changed by billing"}, http.StatusForbidden
+	}
+	return nil, 0
+}
+
+// transitionProcedure applies change to p, enforcing the allowed transitions.
+func transitionProcedure(p *Procedure, change *ProcedureStatusChange, role string, now time.Time) (interface{}, int) {
+	to := strings.ToLower(strings.TrimSpace(change.Status))
+	if !isProcedureStatus(to) {
+		return gin.H{"message": "status must be one of scheduled, in_progress, completed, cancelled, billed"}, http.StatusUnprocessableEntity
+	}
+	from := procedureStatus(p)
+	if (from == ProcedureStatusBilled || to == ProcedureStatusBilled) && role != RoleBilling {
+		return gin.H{"message": "Only billing can bill procedures or change billed ones"}, http.StatusForbidden
+	}
+	if !canTransition(from, to) {
+		return gin.H{
+			"message": "Procedure cannot move from " + from + " to " + to,
+			"allowed": procedureTransitions[from],
+		}, http.StatusConflict
+	}
+	recordProcedureStatus(p, to, role, change.Note, now)
+	return nil, 0
+}
+
+// filterProceduresByStatus keeps the procedures in one of the comma-separated statuses.
+// It returns a validation problem for unknown statuses.
+func filterProceduresByStatus(procedures []Procedure, statuses string) ([]Procedure, string) {
+	if statuses == "" {
+		return procedures, ""
+	}
+	wanted := map[string]bool{}
+	for _, status := range strings.Split(statuses, ",") {
+		status = strings.ToLower(strings.TrimSpace(status))
+		if !isProcedureStatus(status) {
+			return nil, "unknown status " + status
+		}
+		wanted[status] = true
+	}
+
+	filtered := make([]Procedure, 0, len(procedures))
+	for _, p := range procedures {
+		if wanted[procedureStatus(&p)] {
+			filtered = append(filtered, p)
+		}
+	}
+	return filtered, ""
+}
+
+// ChangeProcedureStatus implements POST /api/procedures/:procedureId/status
+func (o *implProcedureAPI) ChangeProcedureStatus(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		var change ProcedureStatusChange
+		if err := c.ShouldBindJSON(&change); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if result, status := transitionProcedure(p, &change, userRole(c), time.Now()); result != nil {
+			return nil, result, status
+		}
+		return p, p, http.StatusOK
+	})
+}
@@ -0,0 +1,111 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// ProcedureLifecycleSuite defines the suite for procedure status tests
+type ProcedureLifecycleSuite struct {
+	suite.Suite
+	procedureDbMock *DbServiceMock[Procedure]
+	procedure       Procedure
+}
+
+func TestProcedureLifecycleSuite(t *testing.T) {
+	suite.Run(t, new(ProcedureLifecycleSuite))
+}
+
+func (suite *ProcedureLifecycleSuite) SetupTest() {
+	suite.procedure = Procedure{Id: "proc001", Name: "Röntgen hrudníka", Price: eur("110"), Status: ProcedureStatusCompleted}
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("FindDocument", mock.Anything, "proc001").
+		Return(&suite.procedure, nil)
+	suite.procedureDbMock.
+		On("UpdateDocument", mock.Anything, "proc001", mock.Anything).
+		Return(nil)
+}
+
+func (suite *ProcedureLifecycleSuite) request(method, path, payload, role string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Params = []gin.Param{{Key: "procedureId", Value: "proc001"}}
+	ctx.Request = httptest.NewRequest(method, path, strings.NewReader(payload))
+	ctx.Request.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		ctx.Request.Header.Set(UserRoleHeader, role)
+	}
+	return ctx, recorder
+}
+
+func (suite *ProcedureLifecycleSuite) Test_StartProcedureLifecycle_DefaultsFromTimestamp() {
+	now := time.Now()
+	future := &Procedure{Timestamp: now.Add(time.Hour)}
+	past := &Procedure{Timestamp: now.Add(-time.Hour)}
+
+	suite.Empty(startProcedureLifecycle(future, "", now))
+	suite.Empty(startProcedureLifecycle(past, "", now))
+
+	suite.Equal(ProcedureStatusScheduled, future.Status)
+	suite.Equal(ProcedureStatusCompleted, past.Status)
+	suite.Len(past.StatusHistory, 1)
+	suite.NotEmpty(startProcedureLifecycle(&Procedure{Status: ProcedureStatusBilled}, RoleBilling, now))
+}
+
+func (suite *ProcedureLifecycleSuite) Test_TransitionProcedure_OnlyCompletedCanBeBilled() {
+	scheduled := &Procedure{Status: ProcedureStatusScheduled}
+
+	_, status := transitionProcedure(scheduled, &ProcedureStatusChange{Status: ProcedureStatusBilled}, RoleBilling, time.Now())
+
+	suite.Equal(http.StatusConflict, status)
+	suite.Equal(ProcedureStatusScheduled, scheduled.Status)
+}
+
+func (suite *ProcedureLifecycleSuite) Test_ChangeProcedureStatus_BillingRequiresRole() {
+	ctx, recorder := suite.request("POST", "/api/procedures/proc001/status", `{"status":"billed"}`, "")
+	(&implProcedureAPI{}).ChangeProcedureStatus(ctx)
+	suite.Equal(http.StatusForbidden, recorder.Code)
+
+	ctx, recorder = suite.request("POST", "/api/procedures/proc001/status", `{"status":"billed","note":"invoice 2025/001"}`, RoleBilling)
+	(&implProcedureAPI{}).ChangeProcedureStatus(ctx)
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.procedureDbMock.AssertCalled(suite.T(), "UpdateDocument", mock.Anything, "proc001",
+		mock.MatchedBy(func(p *Procedure) bool {
+			return p.Status == ProcedureStatusBilled && p.StatusHistory[len(p.StatusHistory)-1].ChangedBy == RoleBilling
+		}))
+}
+
+func (suite *ProcedureLifecycleSuite) Test_UpdateProcedure_BilledProcedureIsReadOnly() {
+	suite.procedure.Status = ProcedureStatusBilled
+
+	ctx, recorder := suite.request("PUT", "/api/procedures/proc001", `{"payer":"poisťovňa XYZ"}`, "")
+	(&implProcedureAPI{}).UpdateProcedure(ctx)
+
+	suite.Equal(http.StatusForbidden, recorder.Code)
+	suite.procedureDbMock.AssertNotCalled(suite.T(), "UpdateDocument", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func (suite *ProcedureLifecycleSuite) Test_FilterProceduresByStatus_TreatsLegacyAsCompleted() {
+	procedures := []Procedure{
+		{Id: "legacy"},
+		{Id: "planned", Status: ProcedureStatusScheduled},
+		{Id: "cancelled", Status: ProcedureStatusCancelled},
+	}
+
+	filtered, problem := filterProceduresByStatus(procedures, "completed, scheduled")
+
+	suite.Empty(problem)
+	suite.Len(filtered, 2)
+	_, problem = filterProceduresByStatus(procedures, "done")
+	suite.NotEmpty(problem)
+}
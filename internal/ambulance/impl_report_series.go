@@ -0,0 +1,369 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Groupings of the revenue and volume reports.
+const (
+	ReportByDay        = "day"
+	ReportByWeek       = "week"
+	ReportByMonth      = "month"
+	ReportByAmbulance  = "ambulance"
+	ReportByDepartment = "department"
+	ReportByVisitType  = "visit_type"
+	ReportByPayer      = "payer"
+)
+
+// DefaultReportDays is the length of the report range when ?from= is not given.
+const DefaultReportDays = 30
+
+// MaxReportPeriods limits the length of a time series, so that a wide range grouped by
+// day does not produce an unbounded response.
+const MaxReportPeriods = 1000
+
+// reportGroups assigns procedures, and payments through their procedures, to the rows of
+// a revenue or volume report.
+type reportGroups struct {
+	by string
+	// first and last day of the range, both included
+	from, to time.Time
+
+	ambulances   map[string]string
+	departmentOf map[string]string
+	departments  map[string]string
+	payers       map[string]string
+}
+
+func isReportGrouping(by string) bool {
+	switch by {
+	case ReportByDay, ReportByWeek, ReportByMonth, ReportByAmbulance, ReportByDepartment, ReportByVisitType, ReportByPayer:
+		return true
+	}
+	return false
+}
+
+func (g *reportGroups) timeSeries() bool {
+	return g.by == ReportByDay || g.by == ReportByWeek || g.by == ReportByMonth
+}
+
+// reportPeriod returns the first day of the period holding t, in UTC. Weeks start on Monday.
+func reportPeriod(t time.Time, by string) time.Time {
+	y, m, d := t.UTC().Date()
+	switch by {
+	case ReportByWeek:
+		day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	case ReportByMonth:
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func nextReportPeriod(period time.Time, by string) time.Time {
+	switch by {
+	case ReportByWeek:
+		return period.AddDate(0, 0, 7)
+	case ReportByMonth:
+		return period.AddDate(0, 1, 0)
+	}
+	return period.AddDate(0, 0, 1)
+}
+
+// reportPeriodLabel names a period: 2025-06-02 for days, 2025-W23 for ISO weeks and
+// 2025-06 for months.
+func reportPeriodLabel(period time.Time, by string) string {
+	switch by {
+	case ReportByWeek:
+		year, week := period.ISOWeek()
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case ReportByMonth:
+		return period.Format("2006-01")
+	}
+	return period.Format(time.DateOnly)
+}
+
+// periods returns the first days of the periods overlapping the range, or nil when there
+// are more than MaxReportPeriods of them.
+func (g *reportGroups) periods() []time.Time {
+	periods := make([]time.Time, 0)
+	for period := reportPeriod(g.from, g.by); !period.After(g.to); period = nextReportPeriod(period, g.by) {
+		if len(periods) == MaxReportPeriods {
+			return nil
+		}
+		periods = append(periods, period)
+	}
+	return periods
+}
+
+// group returns the row of something happening at the given time on procedure p, and
+// false when the time is out of the range. Payers in the registry are named as registered;
+// other payers are grouped by name.
+func (g *reportGroups) group(p *Procedure, at time.Time) (string, string, bool) {
+	if at.Before(g.from) || !at.Before(g.to.AddDate(0, 0, 1)) {
+		return "", "", false
+	}
+	switch g.by {
+	case ReportByAmbulance:
+		return p.AmbulanceId, g.ambulances[p.AmbulanceId], true
+	case ReportByDepartment:
+		id := g.departmentOf[p.AmbulanceId]
+		return id, g.departments[id], true
+	case ReportByVisitType:
+		visitType := strings.ToLower(p.VisitType)
+		return visitType, visitType, true
+	case ReportByPayer:
+		if p.PayerId == "" {
+			return p.Payer, p.Payer, true
+		}
+		if name, ok := g.payers[p.PayerId]; ok {
+			return p.PayerId, name, true
+		}
+		return p.PayerId, p.Payer, true
+	}
+	period := reportPeriod(at, g.by)
+	return period.Format(time.DateOnly), reportPeriodLabel(period, g.by), true
+}
+
+// less orders rows chronologically in time series and by label otherwise.
+func (g *reportGroups) less(keyI, labelI, keyJ, labelJ string) bool {
+	if !g.timeSeries() && labelI != labelJ {
+		return labelI < labelJ
+	}
+	return keyI < keyJ
+}
+
+// loadReportGroups reads ?group_by= (day by default) and the ?from= and ?to= dates of the
+// range (the last DefaultReportDays days by default), and loads the names the grouping needs.
+func loadReportGroups(ctx context.Context, c *gin.Context) (*reportGroups, interface{}, int) {
+	g := &reportGroups{by: c.DefaultQuery("group_by", ReportByDay)}
+	if !isReportGrouping(g.by) {
+		return nil, gin.H{"message": "group_by must be one of day, week, month, ambulance, department, visit_type, payer"}, http.StatusBadRequest
+	}
+	g.to = time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.Query("to"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, gin.H{"message": "to must be in YYYY-MM-DD format"}, http.StatusBadRequest
+		}
+		g.to = date
+	}
+	g.from = g.to.AddDate(0, 0, 1-DefaultReportDays)
+	if value := c.Query("from"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return nil, gin.H{"message": "from must be in YYYY-MM-DD format"}, http.StatusBadRequest
+		}
+		g.from = date
+	}
+	if g.from.After(g.to) {
+		return nil, gin.H{"message": "from must not be after to"}, http.StatusBadRequest
+	}
+	if g.timeSeries() && g.periods() == nil {
+		return nil, gin.H{"message": fmt.Sprintf("The range holds more than %d periods; group by a longer period", MaxReportPeriods)}, http.StatusBadRequest
+	}
+
+	switch g.by {
+	case ReportByAmbulance, ReportByDepartment:
+		ambulances, err := getDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to compute report"}, http.StatusInternalServerError
+		}
+		g.ambulances, g.departmentOf = map[string]string{}, map[string]string{}
+		for _, a := range ambulances {
+			g.ambulances[a.Id] = a.Name
+			g.departmentOf[a.Id] = a.DepartmentId
+		}
+	case ReportByPayer:
+		payers, err := getPayerDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to compute report"}, http.StatusInternalServerError
+		}
+		g.payers = map[string]string{}
+		for _, payer := range payers {
+			g.payers[payer.Id] = payer.Name
+		}
+	}
+	if g.by == ReportByDepartment {
+		departments, err := getDepartmentDB(c).ListDocuments(ctx)
+		if err != nil {
+			log.Println("ListDocuments error:", err)
+			return nil, gin.H{"message": "Failed to compute report"}, http.StatusInternalServerError
+		}
+		g.departments = map[string]string{}
+		for _, d := range departments {
+			g.departments[d.Id] = d.Name
+		}
+	}
+	return g, nil, http.StatusOK
+}
+
+// summarizeRevenue totals the prices of completed and billed procedures performed in each
+// group, and the settled payments less refunds received in it. Payments are placed in time
+// by their own timestamp and in the other groupings by their procedure. Totals fail with
+// money.ErrCurrencyMismatch when a group mixes currencies.
+func summarizeRevenue(g *reportGroups, procedures []Procedure, payments []Payment) ([]RevenueRow, error) {
+	rows := map[string]*RevenueRow{}
+	row := func(key, label string) *RevenueRow {
+		if _, ok := rows[key]; !ok {
+			rows[key] = &RevenueRow{Key: key, Label: label}
+		}
+		return rows[key]
+	}
+	if g.timeSeries() {
+		for _, period := range g.periods() {
+			row(period.Format(time.DateOnly), reportPeriodLabel(period, g.by))
+		}
+	}
+
+	byId := map[string]*Procedure{}
+	for i := range procedures {
+		p := &procedures[i]
+		byId[p.Id] = p
+		if status := procedureStatus(p); status != ProcedureStatusCompleted && status != ProcedureStatusBilled {
+			continue
+		}
+		key, label, ok := g.group(p, p.Timestamp)
+		if !ok {
+			continue
+		}
+		r := row(key, label)
+		billed, err := r.Billed.Add(p.Price)
+		if err != nil {
+			return nil, err
+		}
+		r.Billed = billed
+		r.Procedures++
+	}
+	for i := range payments {
+		payment := &payments[i]
+		if status := paymentStatus(payment); status != PaymentStatusSettled && status != PaymentStatusRefunded {
+			continue
+		}
+		p, found := byId[payment.ProcedureId]
+		if !found {
+			p = &Procedure{}
+		}
+		key, label, ok := g.group(p, payment.Timestamp)
+		if !ok {
+			continue
+		}
+		r := row(key, label)
+		collected, err := r.Collected.Add(payment.Amount)
+		if err != nil {
+			return nil, err
+		}
+		r.Collected = collected
+	}
+
+	result := make([]RevenueRow, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return g.less(result[i].Key, result[i].Label, result[j].Key, result[j].Label)
+	})
+	return result, nil
+}
+
+// summarizeVolume counts the procedures of each group by status.
+func summarizeVolume(g *reportGroups, procedures []Procedure) []VolumeRow {
+	rows := map[string]*VolumeRow{}
+	row := func(key, label string) *VolumeRow {
+		if _, ok := rows[key]; !ok {
+			rows[key] = &VolumeRow{Key: key, Label: label}
+		}
+		return rows[key]
+	}
+	if g.timeSeries() {
+		for _, period := range g.periods() {
+			row(period.Format(time.DateOnly), reportPeriodLabel(period, g.by))
+		}
+	}
+
+	for i := range procedures {
+		p := &procedures[i]
+		key, label, ok := g.group(p, p.Timestamp)
+		if !ok {
+			continue
+		}
+		r := row(key, label)
+		r.Procedures++
+		switch procedureStatus(p) {
+		case ProcedureStatusCompleted, ProcedureStatusBilled:
+			r.Completed++
+		case ProcedureStatusCancelled:
+			r.Cancelled++
+		default:
+			r.Scheduled++
+		}
+	}
+
+	result := make([]VolumeRow, 0, len(rows))
+	for _, r := range rows {
+		result = append(result, *r)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return g.less(result[i].Key, result[i].Label, result[j].Key, result[j].Label)
+	})
+	return result
+}
+
+// GetRevenueReport implements GET /api/reports/revenue
+func (o *implReportsAPI) GetRevenueReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	g, result, status := loadReportGroups(ctx, c)
+	if g == nil {
+		c.JSON(status, result)
+		return
+	}
+	procedures, err := getProcedureDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute report"})
+		return
+	}
+	payments, err := getPaymentDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute report"})
+		return
+	}
+	rows, err := summarizeRevenue(g, procedures, payments)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "Failed to compute report", "error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
+
+// GetVolumeReport implements GET /api/reports/volume
+func (o *implReportsAPI) GetVolumeReport(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	g, result, status := loadReportGroups(ctx, c)
+	if g == nil {
+		c.JSON(status, result)
+		return
+	}
+	procedures, err := getProcedureDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute report"})
+		return
+	}
+	c.JSON(http.StatusOK, summarizeVolume(g, procedures))
+}
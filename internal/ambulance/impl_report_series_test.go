@@ -0,0 +1,111 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// ReportSeriesSuite defines the suite for revenue and volume report tests
+type ReportSeriesSuite struct {
+	suite.Suite
+	procedureDbMock *DbServiceMock[Procedure]
+	paymentDbMock   *DbServiceMock[Payment]
+	payerDbMock     *DbServiceMock[Payer]
+	procedures      []Procedure
+	payments        []Payment
+}
+
+func TestReportSeriesSuite(t *testing.T) {
+	suite.Run(t, new(ReportSeriesSuite))
+}
+
+func (suite *ReportSeriesSuite) SetupTest() {
+	suite.procedures = []Procedure{
+		{Id: "proc001", PayerId: "payer1", Payer: "xyz", VisitType: "Emergency", Price: eur("120"), Timestamp: time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc002", Payer: "Ján Novák", VisitType: "follow-up", Price: eur("60"), Status: ProcedureStatusBilled, Timestamp: time.Date(2025, 6, 4, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc003", Payer: "Ján Novák", VisitType: "follow-up", Price: eur("60"), Status: ProcedureStatusScheduled, Timestamp: time.Date(2025, 6, 10, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc004", Payer: "Ján Novák", VisitType: "emergency", Price: eur("80"), Status: ProcedureStatusCancelled, Timestamp: time.Date(2025, 6, 11, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc005", Payer: "Ján Novák", VisitType: "emergency", Price: eur("80"), Timestamp: time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	suite.payments = []Payment{
+		{Id: "pay1", ProcedureId: "proc001", Amount: eur("120"), Status: PaymentStatusSettled, Timestamp: time.Date(2025, 6, 12, 9, 0, 0, 0, time.UTC)},
+		{Id: "pay2", ProcedureId: "proc001", Amount: eur("-20"), Status: PaymentStatusRefunded, Timestamp: time.Date(2025, 6, 13, 9, 0, 0, 0, time.UTC)},
+		{Id: "pay3", ProcedureId: "proc002", Amount: eur("60"), Status: PaymentStatusPending, Timestamp: time.Date(2025, 6, 5, 9, 0, 0, 0, time.UTC)},
+	}
+
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("ListDocuments", mock.Anything).
+		Return(suite.procedures, nil)
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("ListDocuments", mock.Anything).
+		Return(suite.payments, nil)
+	suite.payerDbMock = &DbServiceMock[Payer]{}
+	suite.payerDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Payer{{Id: "payer1", Name: "Poisťovňa XYZ"}}, nil)
+}
+
+func (suite *ReportSeriesSuite) request(path string) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_procedure", suite.procedureDbMock)
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Set("db_service_payer", suite.payerDbMock)
+	ctx.Request = httptest.NewRequest("GET", path, nil)
+	return ctx, recorder
+}
+
+func (suite *ReportSeriesSuite) Test_SummarizeRevenue_WeeklySeriesFillsEmptyWeeks() {
+	g := &reportGroups{by: ReportByWeek, from: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), to: time.Date(2025, 6, 20, 0, 0, 0, 0, time.UTC)}
+
+	rows, err := summarizeRevenue(g, suite.procedures, suite.payments)
+
+	suite.Require().NoError(err)
+	suite.Equal([]RevenueRow{
+		{Key: "2025-05-26", Label: "2025-W22"},
+		{Key: "2025-06-02", Label: "2025-W23", Procedures: 2, Billed: eur("180")},
+		{Key: "2025-06-09", Label: "2025-W24", Collected: eur("100")},
+		{Key: "2025-06-16", Label: "2025-W25"},
+	}, rows)
+}
+
+func (suite *ReportSeriesSuite) Test_SummarizeVolume_ByVisitType() {
+	g := &reportGroups{by: ReportByVisitType, from: time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC), to: time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)}
+
+	rows := summarizeVolume(g, suite.procedures)
+
+	suite.Equal([]VolumeRow{
+		{Key: "emergency", Label: "emergency", Procedures: 2, Completed: 1, Cancelled: 1},
+		{Key: "follow-up", Label: "follow-up", Procedures: 2, Completed: 1, Scheduled: 1},
+	}, rows)
+}
+
+func (suite *ReportSeriesSuite) Test_GetRevenueReport_ByPayer() {
+	ctx, recorder := suite.request("/api/reports/revenue?group_by=payer&from=2025-06-01&to=2025-06-30")
+
+	(&implReportsAPI{}).GetRevenueReport(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[
+		{"key":"Ján Novák","label":"Ján Novák","procedures":1,"billed":{"amount":"60.00","currency":"EUR"},"collected":{"amount":"0.00","currency":"EUR"}},
+		{"key":"payer1","label":"Poisťovňa XYZ","procedures":1,"billed":{"amount":"120.00","currency":"EUR"},"collected":{"amount":"100.00","currency":"EUR"}}
+	]`, recorder.Body.String())
+}
+
+func (suite *ReportSeriesSuite) Test_GetVolumeReport_RejectsTooLongDailySeries() {
+	ctx, recorder := suite.request("/api/reports/volume?group_by=day&from=2020-01-01&to=2025-06-30")
+
+	(&implReportsAPI{}).GetVolumeReport(ctx)
+
+	suite.Equal(http.StatusBadRequest, recorder.Code)
+	suite.procedureDbMock.AssertNotCalled(suite.T(), "ListDocuments", mock.Anything)
+}
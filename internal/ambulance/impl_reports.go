@@ -0,0 +1,323 @@
+package ambulance
+
+import (
+	"context"
+	"encoding/csv"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MIMECSV is the content type of reports and lists exported as CSV.
+const MIMECSV = "text/csv"
+
+// implReportsAPI implements the ReportsAPI interface.
+type implReportsAPI struct{}
+
+// NewReportsAPI returns an implementation of ReportsAPI.
+func NewReportsAPI() ReportsAPI {
+	return &implReportsAPI{}
+}
+
+// agingBalance is the outstanding balance of the procedures of one payer and department
+// in one age bucket, as computed by the aggregation pipeline or computeAgingBalances.
+type agingBalance struct {
+	PayerId      string      `bson:"payer_id"`
+	Payer        string      `bson:"payer"`
+	DepartmentId string      `bson:"department_id"`
+	Bucket       string      `bson:"bucket"`
+	Procedures   int32       `bson:"procedures"`
+	Amount       money.Money `bson:"amount"`
+}
+
+// agingDays returns the number of calendar days, in UTC, from one time to another.
+func agingDays(from, to time.Time) int {
+	day := func(t time.Time) time.Time {
+		y, m, d := t.UTC().Date()
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	}
+	return int(day(to).Sub(day(from)).Hours() / 24)
+}
+
+// agingPipeline is the aggregation over the procedure collection computing the same
+// balances as computeAgingBalances, as of the given day. It joins the payment and
+// ambulance collections, so it relies on their names in the service configuration.
+func agingPipeline(asOf time.Time) bson.A {
+	branches := bson.A{}
+	for _, bucket := range agingBuckets {
+		branches = append(branches, bson.M{"case": bson.M{"$lte": bson.A{"$age", bucket.maxDays}}, "then": bucket.name})
+	}
+	counted := bson.A{"", PaymentStatusSettled, PaymentStatusRefunded}
+
+	return bson.A{
+		bson.M{"$match": bson.M{
+			"status":    bson.M{"$in": bson.A{nil, "", ProcedureStatusCompleted, ProcedureStatusBilled}},
+			"timestamp": bson.M{"$not": bson.M{"$gte": asOf.AddDate(0, 0, 1)}},
+		}},
+		bson.M{"$lookup": bson.M{"from": "payment", "localField": "id", "foreignField": "procedure_id", "as": "payments"}},
+		bson.M{"$lookup": bson.M{"from": "ambulance", "localField": "ambulance_id", "foreignField": "id", "as": "ambulance"}},
+		bson.M{"$set": bson.M{
+			"currency": bson.M{"$ifNull": bson.A{"$price.currency", money.DefaultCurrency}},
+			"balance": bson.M{"$subtract": bson.A{
+				bson.M{"$toDecimal": bson.M{"$ifNull": bson.A{"$price.amount", "$price"}}},
+				bson.M{"$sum": bson.M{"$map": bson.M{
+					"input": bson.M{"$filter": bson.M{
+						"input": "$payments",
+						"as":    "p",
+						"cond":  bson.M{"$in": bson.A{bson.M{"$ifNull": bson.A{"$$p.status", ""}}, counted}},
+					}},
+					"as": "p",
+					"in": bson.M{"$toDecimal": bson.M{"$ifNull": bson.A{"$$p.amount.amount", "$$p.amount"}}},
+				}}},
+			}},
+			"age": bson.M{"$dateDiff": bson.M{
+				"startDate": bson.M{"$ifNull": bson.A{"$timestamp", time.Time{}}},
+				"endDate":   asOf,
+				"unit":      "day",
+			}},
+		}},
+		bson.M{"$match": bson.M{"balance": bson.M{"$gt": 0}}},
+		bson.M{"$group": bson.M{
+			"_id": bson.M{
+				"payer_id":      "$payer_id",
+				"payer":         "$payer",
+				"department_id": bson.M{"$first": "$ambulance.department_id"},
+				"currency":      "$currency",
+				"bucket":        bson.M{"$switch": bson.M{"branches": branches, "default": "90+"}},
+			},
+			"procedures": bson.M{"$sum": 1},
+			"amount":     bson.M{"$sum": "$balance"},
+		}},
+		bson.M{"$project": bson.M{
+			"_id":           0,
+			"payer_id":      "$_id.payer_id",
+			"payer":         "$_id.payer",
+			"department_id": "$_id.department_id",
+			"bucket":        "$_id.bucket",
+			"procedures":    1,
+			"amount":        bson.M{"amount": "$amount", "currency": "$_id.currency"},
+		}},
+	}
+}
+
+// computeAgingBalances computes the outstanding balances in memory for services that
+// cannot run agingPipeline. Completed and billed procedures performed by the end of
+// asOf owe their price less settled payments; their age counts from the procedure day.
+func computeAgingBalances(procedures []Procedure, payments []Payment, ambulances []Ambulance, asOf time.Time) ([]agingBalance, error) {
+	departmentOf := map[string]string{}
+	for _, a := range ambulances {
+		departmentOf[a.Id] = a.DepartmentId
+	}
+	byProcedure := map[string][]Payment{}
+	for _, p := range payments {
+		byProcedure[p.ProcedureId] = append(byProcedure[p.ProcedureId], p)
+	}
+
+	cutoff := asOf.AddDate(0, 0, 1)
+	balances := map[[5]string]*agingBalance{}
+	for i := range procedures {
+		p := &procedures[i]
+		if status := procedureStatus(p); status != ProcedureStatusCompleted && status != ProcedureStatusBilled {
+			continue
+		}
+		if !p.Timestamp.Before(cutoff) {
+			continue
+		}
+		if err := applyPaymentSummary(p, byProcedure[p.Id]); err != nil {
+			return nil, err
+		}
+		if p.Balance.IsZero() || p.Balance.IsNegative() {
+			continue
+		}
+
+		bucket := agingBucket(agingDays(p.Timestamp, asOf))
+		key := [5]string{p.PayerId, p.Payer, departmentOf[p.AmbulanceId], bucket, p.Balance.Currency}
+		balance, ok := balances[key]
+		if !ok {
+			balance = &agingBalance{PayerId: p.PayerId, Payer: p.Payer, DepartmentId: key[2], Bucket: bucket}
+			balances[key] = balance
+		}
+		amount, err := balance.Amount.Add(*p.Balance)
+		if err != nil {
+			return nil, err
+		}
+		balance.Amount = amount
+		balance.Procedures++
+	}
+
+	result := make([]agingBalance, 0, len(balances))
+	for _, balance := range balances {
+		result = append(result, *balance)
+	}
+	return result, nil
+}
+
+// summarizeAging names the payers and departments of the balances and orders them by
+// payer, department and age. Payers in the registry are named as registered. Balances of
+// one payer, department and bucket in different currencies fail with money.ErrCurrencyMismatch.
+func summarizeAging(balances []agingBalance, payers []Payer, departments []Department) ([]AgingRow, error) {
+	payerNames := map[string]string{}
+	for _, payer := range payers {
+		payerNames[payer.Id] = payer.Name
+	}
+	departmentNames := map[string]string{}
+	for _, d := range departments {
+		departmentNames[d.Id] = d.Name
+	}
+
+	rows := map[[4]string]*AgingRow{}
+	for _, balance := range balances {
+		payer := balance.Payer
+		if name, ok := payerNames[balance.PayerId]; ok {
+			payer = name
+		}
+		key := [4]string{balance.PayerId, payer, balance.DepartmentId, balance.Bucket}
+		row, ok := rows[key]
+		if !ok {
+			row = &AgingRow{
+				PayerId:      balance.PayerId,
+				Payer:        payer,
+				DepartmentId: balance.DepartmentId,
+				Department:   departmentNames[balance.DepartmentId],
+				Bucket:       balance.Bucket,
+			}
+			rows[key] = row
+		}
+		amount, err := row.Amount.Add(balance.Amount)
+		if err != nil {
+			return nil, err
+		}
+		row.Amount = amount
+		row.Procedures += balance.Procedures
+	}
+
+	order := map[string]int{"90+": len(agingBuckets)}
+	for i, bucket := range agingBuckets {
+		order[bucket.name] = i
+	}
+	result := make([]AgingRow, 0, len(rows))
+	for _, row := range rows {
+		result = append(result, *row)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Payer != result[j].Payer {
+			return result[i].Payer < result[j].Payer
+		}
+		if result[i].Department != result[j].Department {
+			return result[i].Department < result[j].Department
+		}
+		return order[result[i].Bucket] < order[result[j].Bucket]
+	})
+	return result, nil
+}
+
+// loadAgingBalances runs agingPipeline when the procedure service can aggregate, and
+// computes the balances in memory otherwise.
+func loadAgingBalances(ctx context.Context, c *gin.Context, asOf time.Time) ([]agingBalance, error) {
+	if aggregator, ok := getProcedureDB(c).(db_service.Aggregator); ok {
+		balances := make([]agingBalance, 0)
+		err := aggregator.Aggregate(ctx, agingPipeline(asOf), &balances)
+		if err != db_service.ErrNotAggregatable {
+			return balances, err
+		}
+	}
+
+	procedures, err := getProcedureDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payments, err := getPaymentDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ambulances, err := getDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return computeAgingBalances(procedures, payments, ambulances, asOf)
+}
+
+// writeAgingCSV writes the aging rows as CSV with a header row.
+func writeAgingCSV(w io.Writer, rows []AgingRow) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{"payer_id", "payer", "department_id", "department", "bucket", "procedures", "amount", "currency"})
+	for _, row := range rows {
+		writer.Write([]string{
+			row.PayerId, row.Payer, row.DepartmentId, row.Department, row.Bucket,
+			strconv.Itoa(int(row.Procedures)), row.Amount.Decimal(), row.Amount.Currency,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// GetAgingReport implements GET /api/reports/aging
+//
+// Outstanding procedure balances are grouped by payer, department and days since the
+// procedure as of ?as_of= (default today), as JSON or CSV.
+func (o *implReportsAPI) GetAgingReport(c *gin.Context) {
+	format := negotiateFormat(c, gin.MIMEJSON, MIMECSV)
+	if format == "" {
+		c.JSON(http.StatusNotAcceptable, gin.H{"message": "The report is available as application/json and text/csv"})
+		return
+	}
+	asOf := time.Now().UTC().Truncate(24 * time.Hour)
+	if value := c.Query("as_of"); value != "" {
+		date, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "as_of must be in YYYY-MM-DD format"})
+			return
+		}
+		asOf = date
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 30*time.Second)
+	defer cancel()
+
+	balances, err := loadAgingBalances(ctx, c, asOf)
+	if err == money.ErrCurrencyMismatch {
+		c.JSON(http.StatusConflict, gin.H{"message": "Failed to compute aging", "error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println("loadAgingBalances error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute aging"})
+		return
+	}
+	payers, err := getPayerDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute aging"})
+		return
+	}
+	departments, err := getDepartmentDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to compute aging"})
+		return
+	}
+	rows, err := summarizeAging(balances, payers, departments)
+	if err != nil {
+		c.JSON(http.StatusConflict, gin.H{"message": "Failed to compute aging", "error": err.Error()})
+		return
+	}
+
+	if format == MIMECSV {
+		c.Header("Content-Disposition", `attachment; filename="aging-`+asOf.Format(time.DateOnly)+`.csv"`)
+		c.Status(http.StatusOK)
+		c.Header("Content-Type", MIMECSV+"; charset=utf-8")
+		if err := writeAgingCSV(c.Writer, rows); err != nil {
+			log.Println("writeAgingCSV error:", err)
+		}
+		return
+	}
+	c.JSON(http.StatusOK, rows)
+}
@@ -0,0 +1,151 @@
+package ambulance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// aggregatingDbServiceMock is a DbServiceMock that can also run aggregation pipelines.
+type aggregatingDbServiceMock[DocType interface{}] struct {
+	DbServiceMock[DocType]
+}
+
+func (m *aggregatingDbServiceMock[DocType]) Aggregate(ctx context.Context, pipeline any, results any) error {
+	args := m.Called(ctx, pipeline, results)
+	return args.Error(0)
+}
+
+// ReportsSuite defines the suite for report tests
+type ReportsSuite struct {
+	suite.Suite
+	procedureDbMock  *DbServiceMock[Procedure]
+	paymentDbMock    *DbServiceMock[Payment]
+	ambulanceDbMock  *DbServiceMock[Ambulance]
+	payerDbMock      *DbServiceMock[Payer]
+	departmentDbMock *DbServiceMock[Department]
+	asOf             time.Time
+}
+
+func TestReportsSuite(t *testing.T) {
+	suite.Run(t, new(ReportsSuite))
+}
+
+func (suite *ReportsSuite) SetupTest() {
+	suite.asOf = time.Date(2025, 6, 30, 0, 0, 0, 0, time.UTC)
+	procedures := []Procedure{
+		{Id: "proc001", AmbulanceId: "amb1", PayerId: "payer1", Payer: "xyz", Price: eur("120"), Timestamp: time.Date(2025, 6, 20, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc002", AmbulanceId: "amb1", PayerId: "payer1", Payer: "xyz", Price: eur("60"), Status: ProcedureStatusBilled, Timestamp: time.Date(2025, 5, 10, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc003", AmbulanceId: "amb1", Payer: "Ján Novák", Price: eur("40"), Timestamp: time.Date(2025, 1, 2, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc004", AmbulanceId: "amb2", Payer: "Ján Novák", Price: eur("80"), Timestamp: time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc005", AmbulanceId: "amb1", Payer: "Ján Novák", Price: eur("80"), Status: ProcedureStatusCancelled, Timestamp: time.Date(2025, 6, 1, 10, 0, 0, 0, time.UTC)},
+		{Id: "proc006", AmbulanceId: "amb1", Payer: "Ján Novák", Price: eur("80"), Timestamp: time.Date(2025, 7, 1, 10, 0, 0, 0, time.UTC)},
+	}
+	payments := []Payment{
+		{Id: "pay1", ProcedureId: "proc001", Amount: eur("20"), Status: PaymentStatusSettled},
+		{Id: "pay2", ProcedureId: "proc001", Amount: eur("100"), Status: PaymentStatusPending},
+		{Id: "pay3", ProcedureId: "proc004", Amount: eur("80")},
+	}
+
+	suite.procedureDbMock = &DbServiceMock[Procedure]{}
+	suite.procedureDbMock.
+		On("ListDocuments", mock.Anything).
+		Return(procedures, nil)
+	suite.paymentDbMock = &DbServiceMock[Payment]{}
+	suite.paymentDbMock.
+		On("ListDocuments", mock.Anything).
+		Return(payments, nil)
+	suite.ambulanceDbMock = &DbServiceMock[Ambulance]{}
+	suite.ambulanceDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Ambulance{{Id: "amb1", DepartmentId: "dep1"}, {Id: "amb2", DepartmentId: "dep2"}}, nil)
+	suite.payerDbMock = &DbServiceMock[Payer]{}
+	suite.payerDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Payer{{Id: "payer1", Name: "Poisťovňa XYZ"}}, nil)
+	suite.departmentDbMock = &DbServiceMock[Department]{}
+	suite.departmentDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Department{{Id: "dep1", Name: "Radiology"}, {Id: "dep2", Name: "Surgery"}}, nil)
+}
+
+func (suite *ReportsSuite) request(path string, procedureDb db_service.DbService[Procedure]) (*gin.Context, *httptest.ResponseRecorder) {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_procedure", procedureDb)
+	ctx.Set("db_service_payment", suite.paymentDbMock)
+	ctx.Set("db_service_ambulance", suite.ambulanceDbMock)
+	ctx.Set("db_service_payer", suite.payerDbMock)
+	ctx.Set("db_service_department", suite.departmentDbMock)
+	ctx.Request = httptest.NewRequest("GET", path, nil)
+	return ctx, recorder
+}
+
+func (suite *ReportsSuite) Test_ComputeAgingBalances_BucketsOutstandingProcedures() {
+	procedures, _ := suite.procedureDbMock.ListDocuments(context.Background())
+	payments, _ := suite.paymentDbMock.ListDocuments(context.Background())
+	ambulances, _ := suite.ambulanceDbMock.ListDocuments(context.Background())
+
+	balances, err := computeAgingBalances(procedures, payments, ambulances, suite.asOf)
+	suite.Require().NoError(err)
+	payers, _ := suite.payerDbMock.ListDocuments(context.Background())
+	departments, _ := suite.departmentDbMock.ListDocuments(context.Background())
+	rows, err := summarizeAging(balances, payers, departments)
+
+	suite.Require().NoError(err)
+	suite.Equal([]AgingRow{
+		{Payer: "Ján Novák", DepartmentId: "dep1", Department: "Radiology", Bucket: "90+", Procedures: 1, Amount: eur("40")},
+		{PayerId: "payer1", Payer: "Poisťovňa XYZ", DepartmentId: "dep1", Department: "Radiology", Bucket: "0-30", Procedures: 1, Amount: eur("100")},
+		{PayerId: "payer1", Payer: "Poisťovňa XYZ", DepartmentId: "dep1", Department: "Radiology", Bucket: "31-60", Procedures: 1, Amount: eur("60")},
+	}, rows)
+}
+
+func (suite *ReportsSuite) Test_GetAgingReport_UsesAggregationPipeline() {
+	aggregator := &aggregatingDbServiceMock[Procedure]{}
+	aggregator.
+		On("Aggregate", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]agingBalance) = []agingBalance{
+				{PayerId: "payer1", DepartmentId: "dep2", Bucket: "61-90", Procedures: 2, Amount: eur("70")},
+			}
+		}).
+		Return(nil)
+	ctx, recorder := suite.request("/api/reports/aging?as_of=2025-06-30", aggregator)
+
+	(&implReportsAPI{}).GetAgingReport(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.JSONEq(`[{"payer_id":"payer1","payer":"Poisťovňa XYZ","department_id":"dep2","department":"Surgery","bucket":"61-90","procedures":2,"amount":{"amount":"70.00","currency":"EUR"}}]`, recorder.Body.String())
+	aggregator.AssertNotCalled(suite.T(), "ListDocuments", mock.Anything)
+}
+
+func (suite *ReportsSuite) Test_GetAgingReport_ExportsCSV() {
+	ctx, recorder := suite.request("/api/reports/aging?as_of=2025-06-30&format=csv", suite.procedureDbMock)
+
+	(&implReportsAPI{}).GetAgingReport(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Header().Get("Content-Type"), MIMECSV)
+	suite.Contains(recorder.Header().Get("Content-Disposition"), "aging-2025-06-30.csv")
+	suite.Equal("payer_id,payer,department_id,department,bucket,procedures,amount,currency\n"+
+		",Ján Novák,dep1,Radiology,90+,1,40.00,EUR\n"+
+		"payer1,Poisťovňa XYZ,dep1,Radiology,0-30,1,100.00,EUR\n"+
+		"payer1,Poisťovňa XYZ,dep1,Radiology,31-60,1,60.00,EUR\n", recorder.Body.String())
+}
+
+func (suite *ReportsSuite) Test_GetAgingReport_RejectsUnsupportedAccept() {
+	ctx, recorder := suite.request("/api/reports/aging", suite.procedureDbMock)
+	ctx.Request.Header.Set("Accept", "application/pdf")
+
+	(&implReportsAPI{}).GetAgingReport(ctx)
+
+	suite.Equal(http.StatusNotAcceptable, recorder.Code)
+}
@@ -0,0 +1,350 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+// slotStep is the granularity at which appointment slots start.
+const slotStep = 15 * time.Minute
+
+// defaultAppointmentDuration is the length in minutes of procedures without a duration.
+const defaultAppointmentDuration = 30
+
+// implSchedulingAPI implements the SchedulingAPI interface.
+type implSchedulingAPI struct{}
+
+// NewSchedulingAPI returns an implementation of SchedulingAPI.
+func NewSchedulingAPI() SchedulingAPI {
+	return &implSchedulingAPI{}
+}
+
+// validateWorkingHours returns the list of problems with the working hours.
+func validateWorkingHours(hours []WorkingHours) []string {
+	problems := make([]string, 0)
+	for i, h := range hours {
+		prefix := fmt.Sprintf("working_hours[%d]: ", i)
+		from, errFrom := parseClock(h.From)
+		to, errTo := parseClock(h.To)
+		if errFrom != nil || errTo != nil {
+			problems = append(problems, prefix+"from and to must be in HH:MM format")
+		} else if from >= to {
+			problems = append(problems, prefix+"from must be before to")
+		}
+		for _, day := range h.Days {
+			if _, ok := weekdays[strings.ToLower(day)]; !ok {
+				problems = append(problems, prefix+"unknown day "+day)
+			}
+		}
+	}
+	return problems
+}
+
+// effectiveWorkingHours returns the ambulance's working hours, or those of the nearest
+// department up the hierarchy that has any.
+func effectiveWorkingHours(ctx context.Context, c *gin.Context, ambulance *Ambulance) ([]WorkingHours, error) {
+	if len(ambulance.WorkingHours) > 0 || ambulance.DepartmentId == "" {
+		return ambulance.WorkingHours, nil
+	}
+	departments, err := getDepartmentDB(c).ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	byId := map[string]*Department{}
+	for i := range departments {
+		byId[departments[i].Id] = &departments[i]
+	}
+	visited := map[string]bool{}
+	for id := ambulance.DepartmentId; id != "" && !visited[id]; {
+		visited[id] = true
+		d, ok := byId[id]
+		if !ok {
+			break
+		}
+		if len(d.WorkingHours) > 0 {
+			return d.WorkingHours, nil
+		}
+		id = d.ParentId
+	}
+	return nil, nil
+}
+
+// workingWindows returns the working intervals on the local day starting at midnight day.
+func workingWindows(hours []WorkingHours, day time.Time) []AppointmentSlot {
+	windows := make([]AppointmentSlot, 0)
+	for _, h := range hours {
+		if len(h.Days) > 0 {
+			onDay := false
+			for _, d := range h.Days {
+				if weekdays[strings.ToLower(d)] == day.Weekday() {
+					onDay = true
+				}
+			}
+			if !onDay {
+				continue
+			}
+		}
+		from, _ := parseClock(h.From)
+		to, _ := parseClock(h.To)
+		windows = append(windows, AppointmentSlot{
+			Start: day.Add(time.Duration(from) * time.Minute),
+			End:   day.Add(time.Duration(to) * time.Minute),
+		})
+	}
+	return windows
+}
+
+// procedureInterval returns the time the procedure occupies its ambulance.
+func procedureInterval(p *Procedure) AppointmentSlot {
+	duration := p.Duration
+	if duration <= 0 {
+		duration = defaultAppointmentDuration
+	}
+	return AppointmentSlot{Start: p.Timestamp, End: p.Timestamp.Add(time.Duration(duration) * time.Minute)}
+}
+
+func (s AppointmentSlot) overlaps(other AppointmentSlot) bool {
+	return s.Start.Before(other.End) && other.Start.Before(s.End)
+}
+
+// bookedIntervals returns the intervals occupied by the procedures other than exceptId,
+// leaving out cancelled ones.
+func bookedIntervals(procedures []*Procedure, exceptId string) map[string]AppointmentSlot {
+	booked := map[string]AppointmentSlot{}
+	for _, p := range procedures {
+		if p.Id == exceptId || procedureStatus(p) == ProcedureStatusCancelled {
+			continue
+		}
+		booked[p.Id] = procedureInterval(p)
+	}
+	return booked
+}
+
+// freeSlots lists the slots of the given duration within the working windows that start
+// after now and overlap no booked procedure.
+func freeSlots(windows []AppointmentSlot, booked map[string]AppointmentSlot, duration time.Duration, now time.Time) []AppointmentSlot {
+	slots := make([]AppointmentSlot, 0)
+	for _, window := range windows {
+		for start := window.Start; !start.Add(duration).After(window.End); start = start.Add(slotStep) {
+			slot := AppointmentSlot{Start: start, End: start.Add(duration)}
+			if start.Before(now) {
+				continue
+			}
+			free := true
+			for _, interval := range booked {
+				if slot.overlaps(interval) {
+					free = false
+					break
+				}
+			}
+			if free {
+				slots = append(slots, slot)
+			}
+		}
+	}
+	return slots
+}
+
+// checkAppointment validates that p fits into the working hours and overlaps no booked procedure.
+func checkAppointment(p *Procedure, hours []WorkingHours, booked map[string]AppointmentSlot) (interface{}, int) {
+	slot := procedureInterval(p)
+	start := slot.Start.In(time.Local)
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+
+	inHours := false
+	for _, window := range workingWindows(hours, day) {
+		if !slot.Start.Before(window.Start) && !slot.End.After(window.End) {
+			inHours = true
+		}
+	}
+	if !inHours {
+		return gin.H{"message": "The appointment is outside the ambulance's working hours"}, http.StatusUnprocessableEntity
+	}
+
+	conflicts := make([]string, 0)
+	for id, interval := range booked {
+		if slot.overlaps(interval) {
+			conflicts = append(conflicts, id)
+		}
+	}
+	if len(conflicts) > 0 {
+		return gin.H{"message": "The appointment overlaps booked procedures", "conflicts": conflicts}, http.StatusConflict
+	}
+	return nil, 0
+}
+
+// loadSchedule returns the working hours of the ambulance and the intervals booked on it.
+func loadSchedule(ctx context.Context, c *gin.Context, ambulance *Ambulance, exceptId string) ([]WorkingHours, map[string]AppointmentSlot, error) {
+	hours, err := effectiveWorkingHours(ctx, c, ambulance)
+	if err != nil {
+		return nil, nil, err
+	}
+	procedures, err := getProcedureDB(c).FindDocumentsByField(ctx, "ambulance_id", ambulance.Id)
+	if err != nil {
+		return nil, nil, err
+	}
+	return hours, bookedIntervals(procedures, exceptId), nil
+}
+
+// GetAmbulanceSlots implements GET /api/ambulances/:ambulanceId/slots
+//
+// The slot length is ?duration= in minutes, the catalog duration of ?code=, or 30 minutes.
+func (o *implSchedulingAPI) GetAmbulanceSlots(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		day, err := time.ParseInLocation(time.DateOnly, c.Query("date"), time.Local)
+		if err != nil {
+			return nil, gin.H{"message": "date must be in YYYY-MM-DD format"}, http.StatusBadRequest
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		duration := int32(defaultAppointmentDuration)
+		if value := c.Query("duration"); value != "" {
+			minutes, err := strconv.Atoi(value)
+			if err != nil || minutes <= 0 {
+				return nil, gin.H{"message": "duration must be a positive number of minutes"}, http.StatusBadRequest
+			}
+			duration = int32(minutes)
+		} else if code := c.Query("code"); code != "" {
+			procedureType, err := getProcedureTypeDB(c).FindDocument(ctx, normalizeProcedureCode(code))
+			if err == db_service.ErrNotFound {
+				return nil, gin.H{"message": "code does not reference a procedure type in the catalog"}, http.StatusUnprocessableEntity
+			} else if err != nil {
+				log.Println("FindDocument error:", err)
+				return nil, gin.H{"message": "Failed to compute slots"}, http.StatusInternalServerError
+			}
+			if procedureType.DefaultDuration > 0 {
+				duration = procedureType.DefaultDuration
+			}
+		}
+
+		hours, booked, err := loadSchedule(ctx, c, ambulance, "")
+		if err != nil {
+			log.Println("loadSchedule error:", err)
+			return nil, gin.H{"message": "Failed to compute slots"}, http.StatusInternalServerError
+		}
+		slots := freeSlots(workingWindows(hours, day), booked, time.Duration(duration)*time.Minute, time.Now())
+		return nil, slots, http.StatusOK
+	})
+}
+
+// BookAppointment implements POST /api/ambulances/:ambulanceId/appointments
+//
+// The booking creates a scheduled procedure on the ambulance.
+func (o *implSchedulingAPI) BookAppointment(c *gin.Context) {
+	withAmbulanceByID(c, func(c *gin.Context, ambulance *Ambulance) (*Ambulance, interface{}, int) {
+		var p Procedure
+		if err := c.ShouldBindJSON(&p); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		now := time.Now()
+		if !p.Timestamp.After(now) {
+			return nil, gin.H{"message": "timestamp must be in the future"}, http.StatusUnprocessableEntity
+		}
+		if p.Id == "" {
+			p.Id = uuid.NewString()
+		}
+		p.AmbulanceId = ambulance.Id
+		p.Status = ProcedureStatusScheduled
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if problem, err := prepareProcedure(ctx, c, &p); err != nil {
+			log.Println("prepareProcedure error:", err)
+			return nil, gin.H{"message": "Failed to book appointment"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+		if p.Duration <= 0 {
+			p.Duration = defaultAppointmentDuration
+		}
+
+		hours, booked, err := loadSchedule(ctx, c, ambulance, p.Id)
+		if err != nil {
+			log.Println("loadSchedule error:", err)
+			return nil, gin.H{"message": "Failed to book appointment"}, http.StatusInternalServerError
+		}
+		if result, status := checkAppointment(&p, hours, booked); result != nil {
+			return nil, result, status
+		}
+		startProcedureLifecycle(&p, userRole(c), now)
+
+		if err := getProcedureDB(c).CreateDocument(ctx, p.Id, &p); err != nil {
+			if err == db_service.ErrConflict {
+				return nil, gin.H{"message": "Procedure already exists"}, http.StatusConflict
+			}
+			log.Println("CreateDocument error:", err)
+			return nil, gin.H{"message": "Failed to book appointment"}, http.StatusInternalServerError
+		}
+		return nil, p, http.StatusCreated
+	})
+}
+
+// RescheduleAppointment implements POST /api/procedures/:procedureId/reschedule
+//
+// The new time is recorded in the status history, so earlier appointment times stay visible.
+func (o *implSchedulingAPI) RescheduleAppointment(c *gin.Context) {
+	withProcedureByID(c, func(c *gin.Context, p *Procedure) (*Procedure, interface{}, int) {
+		if procedureStatus(p) != ProcedureStatusScheduled {
+			return nil, gin.H{"message": "Only scheduled procedures can be rescheduled"}, http.StatusConflict
+		}
+		var req AppointmentReschedule
+		if err := c.ShouldBindJSON(&req); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		now := time.Now()
+		if !req.Timestamp.After(now) {
+			return nil, gin.H{"message": "timestamp must be in the future"}, http.StatusUnprocessableEntity
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if req.AmbulanceId != "" {
+			p.AmbulanceId = req.AmbulanceId
+		}
+		ambulance, err := getDB(c).FindDocument(ctx, p.AmbulanceId)
+		if err == db_service.ErrNotFound {
+			return nil, gin.H{"message": "ambulance_id does not reference an existing ambulance"}, http.StatusUnprocessableEntity
+		} else if err != nil {
+			log.Println("FindDocument error:", err)
+			return nil, gin.H{"message": "Failed to reschedule appointment"}, http.StatusInternalServerError
+		}
+		p.Timestamp = req.Timestamp
+
+		hours, booked, err := loadSchedule(ctx, c, ambulance, p.Id)
+		if err != nil {
+			log.Println("loadSchedule error:", err)
+			return nil, gin.H{"message": "Failed to reschedule appointment"}, http.StatusInternalServerError
+		}
+		if result, status := checkAppointment(p, hours, booked); result != nil {
+			return nil, result, status
+		}
+
+		// the new time may fall under other price rules; a manual override is kept
+		requested := money.Money{}
+		if p.PriceOverrideReason != "" {
+			requested = p.Price
+		}
+		if problem, err := priceProcedure(ctx, c, p, requested, p.PriceOverrideReason); err != nil {
+			log.Println("priceProcedure error:", err)
+			return nil, gin.H{"message": "Failed to reschedule appointment"}, http.StatusInternalServerError
+		} else if problem != "" {
+			return nil, gin.H{"message": problem}, http.StatusUnprocessableEntity
+		}
+		recordProcedureStatus(p, ProcedureStatusScheduled, userRole(c), req.Note, now)
+		return p, p, http.StatusOK
+	})
+}
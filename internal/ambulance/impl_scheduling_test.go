@@ -0,0 +1,98 @@
+package ambulance
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+)
+
+// SchedulingSuite defines the suite for appointment scheduling tests
+type SchedulingSuite struct {
+	suite.Suite
+	day    time.Time
+	hours  []WorkingHours
+	booked []*Procedure
+}
+
+func TestSchedulingSuite(t *testing.T) {
+	suite.Run(t, new(SchedulingSuite))
+}
+
+func (suite *SchedulingSuite) SetupTest() {
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	suite.day = time.Date(tomorrow.Year(), tomorrow.Month(), tomorrow.Day(), 0, 0, 0, 0, time.Local)
+	suite.hours = []WorkingHours{{From: "08:00", To: "10:00"}}
+	suite.booked = []*Procedure{
+		{Id: "proc001", Timestamp: suite.day.Add(8*time.Hour + 30*time.Minute), Duration: 45, Status: ProcedureStatusScheduled},
+		{Id: "proc002", Timestamp: suite.day.Add(9 * time.Hour), Duration: 60, Status: ProcedureStatusCancelled},
+	}
+}
+
+func (suite *SchedulingSuite) Test_FreeSlots_SkipBookedProcedures() {
+	windows := workingWindows(suite.hours, suite.day)
+
+	slots := freeSlots(windows, bookedIntervals(suite.booked, ""), 30*time.Minute, time.Now())
+
+	starts := make([]string, 0, len(slots))
+	for _, slot := range slots {
+		starts = append(starts, slot.Start.Format("15:04"))
+	}
+	suite.Equal([]string{"08:00", "09:15", "09:30"}, starts)
+}
+
+func (suite *SchedulingSuite) Test_WorkingWindows_RespectDays() {
+	hours := []WorkingHours{{Days: []string{suite.day.Weekday().String()}, From: "08:00", To: "12:00"}}
+
+	suite.Len(workingWindows(hours, suite.day), 1)
+	suite.Empty(workingWindows(hours, suite.day.AddDate(0, 0, 1)))
+}
+
+func (suite *SchedulingSuite) Test_CheckAppointment_RejectsOverlapAndOutsideHours() {
+	overlapping := &Procedure{Id: "new", Timestamp: suite.day.Add(8*time.Hour + 15*time.Minute), Duration: 30}
+	late := &Procedure{Id: "new", Timestamp: suite.day.Add(9*time.Hour + 45*time.Minute), Duration: 30}
+
+	_, status := checkAppointment(overlapping, suite.hours, bookedIntervals(suite.booked, "new"))
+	suite.Equal(http.StatusConflict, status)
+	_, status = checkAppointment(late, suite.hours, bookedIntervals(suite.booked, "new"))
+	suite.Equal(http.StatusUnprocessableEntity, status)
+
+	// rescheduling a procedure does not conflict with its own booking
+	_, status = checkAppointment(suite.booked[0], suite.hours, bookedIntervals(suite.booked, "proc001"))
+	suite.Zero(status)
+}
+
+func (suite *SchedulingSuite) Test_GetAmbulanceSlots_UsesDepartmentHours() {
+	ambulanceDbMock := &DbServiceMock[Ambulance]{}
+	ambulanceDbMock.
+		On("FindDocument", mock.Anything, "amb001").
+		Return(&Ambulance{Id: "amb001", DepartmentId: "dep-child"}, nil)
+	departmentDbMock := &DbServiceMock[Department]{}
+	departmentDbMock.
+		On("ListDocuments", mock.Anything).
+		Return([]Department{{Id: "dep-child", ParentId: "dep-root"}, {Id: "dep-root", WorkingHours: suite.hours}}, nil)
+	procedureDbMock := &DbServiceMock[Procedure]{}
+	procedureDbMock.
+		On("FindDocumentsByField", mock.Anything, "ambulance_id", "amb001").
+		Return(suite.booked, nil)
+
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_ambulance", ambulanceDbMock)
+	ctx.Set("db_service_department", departmentDbMock)
+	ctx.Set("db_service_procedure", procedureDbMock)
+	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "amb001"}}
+	ctx.Request = httptest.NewRequest("GET", "/api/ambulances/amb001/slots?date="+suite.day.Format(time.DateOnly)+"&duration=45", strings.NewReader(""))
+
+	(&implSchedulingAPI{}).GetAmbulanceSlots(ctx)
+
+	suite.Equal(http.StatusOK, recorder.Code)
+	suite.Contains(recorder.Body.String(), suite.day.Add(9*time.Hour+15*time.Minute).Format(time.RFC3339))
+	suite.NotContains(recorder.Body.String(), suite.day.Add(8*time.Hour).Format(time.RFC3339))
+}
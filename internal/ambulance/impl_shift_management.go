@@ -0,0 +1,596 @@
+package ambulance
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// implShiftAPI implements the ShiftManagementAPI interface.
+type implShiftAPI struct{}
+
+// NewShiftAPI returns an implementation of ShiftManagementAPI.
+func NewShiftAPI() ShiftManagementAPI {
+	return &implShiftAPI{}
+}
+
+// getShiftDB extracts the DbService[Shift] from the context.
+func getShiftDB(c *gin.Context) db_service.DbService[Shift] {
+	return c.MustGet("db_service_shift").(db_service.DbService[Shift])
+}
+
+// overlaps reports whether the shift overlaps the half-open window [start, end).
+func (s *Shift) overlaps(start, end time.Time) bool {
+	return s.Start.Before(end) && start.Before(s.End)
+}
+
+// covers reports whether the shift is in progress at the given time.
+func (s *Shift) covers(at time.Time) bool {
+	return !s.Start.After(at) && s.End.After(at)
+}
+
+// validateShift returns the problems found in a shift record.
+func validateShift(s *Shift) []string {
+	var problems []string
+	if s.AmbulanceId == "" {
+		problems = append(problems, "ambulance_id is required")
+	}
+	if !s.End.After(s.Start) {
+		problems = append(problems, "end must be after start")
+	}
+	seen := map[string]bool{}
+	for i, m := range s.Crew {
+		prefix := "crew[" + strconv.Itoa(i) + "]"
+		if m.CrewMemberId == "" {
+			problems = append(problems, prefix+".crew_member_id is required")
+		} else if seen[m.CrewMemberId] {
+			problems = append(problems, prefix+".crew_member_id is listed twice")
+		}
+		seen[m.CrewMemberId] = true
+		if !isCrewRole(m.Role) {
+			problems = append(problems, prefix+".role must be one of driver, paramedic, doctor, nurse")
+		}
+	}
+	return problems
+}
+
+// crewBooking is a time window in which a crew member works on an ambulance, either in a
+// shift or, with an empty shiftId, through a crew assignment of the ambulance.
+type crewBooking struct {
+	shiftId      string
+	ambulanceId  string
+	crewMemberId string
+	start        time.Time
+	end          time.Time
+}
+
+// crewBookings returns the bookings of the crew of the shifts and of the crew
+// assignments of the ambulances.
+func crewBookings(shifts []Shift, ambulances []Ambulance) []crewBooking {
+	var bookings []crewBooking
+	for _, s := range shifts {
+		for _, m := range s.Crew {
+			bookings = append(bookings, crewBooking{shiftId: s.Id, ambulanceId: s.AmbulanceId, crewMemberId: m.CrewMemberId, start: s.Start, end: s.End})
+		}
+	}
+	for _, a := range ambulances {
+		for _, m := range a.Crew {
+			bookings = append(bookings, crewBooking{ambulanceId: a.Id, crewMemberId: m.CrewMemberId, start: m.ShiftStart, end: m.ShiftEnd})
+		}
+	}
+	return bookings
+}
+
+// findCrewConflicts returns the bookings that double-book the crew member working on the
+// ambulance in [start, end), as part of the shift with shiftId or, when it is empty, of a
+// crew assignment. Shifts conflict with every other overlapping shift of the crew
+// member; crew assignments and shifts of the same ambulance only duplicate each other.
+func findCrewConflicts(bookings []crewBooking, shiftId, ambulanceId, crewMemberId string, start, end time.Time) []ShiftConflict {
+	var conflicts []ShiftConflict
+	for _, b := range bookings {
+		if b.crewMemberId != crewMemberId || !b.start.Before(end) || !start.Before(b.end) {
+			continue
+		}
+		if b.shiftId != "" && b.shiftId == shiftId {
+			continue
+		}
+		bothShifts := b.shiftId != "" && shiftId != ""
+		if b.ambulanceId == ambulanceId && !bothShifts {
+			continue
+		}
+		if b.shiftId != "" {
+			conflicts = append(conflicts, ShiftConflict{ShiftId: b.shiftId, CrewMemberId: crewMemberId})
+		} else {
+			conflicts = append(conflicts, ShiftConflict{AmbulanceId: b.ambulanceId, CrewMemberId: crewMemberId})
+		}
+	}
+	return conflicts
+}
+
+// findShiftConflicts returns the existing shifts that double-book the ambulance or a crew
+// member of s, and the crew assignments of other ambulances that double-book its crew.
+func findShiftConflicts(s *Shift, existing []Shift, ambulances []Ambulance) []ShiftConflict {
+	conflicts := make([]ShiftConflict, 0)
+	for _, other := range existing {
+		if other.Id != s.Id && other.AmbulanceId == s.AmbulanceId && other.overlaps(s.Start, s.End) {
+			conflicts = append(conflicts, ShiftConflict{ShiftId: other.Id, AmbulanceId: other.AmbulanceId})
+		}
+	}
+	bookings := crewBookings(existing, ambulances)
+	for _, m := range s.Crew {
+		conflicts = append(conflicts, findCrewConflicts(bookings, s.Id, s.AmbulanceId, m.CrewMemberId, s.Start, s.End)...)
+	}
+	return conflicts
+}
+
+// findAssignmentConflicts returns the shifts and the crew assignments of other ambulances
+// that double-book the crew assigned to the ambulance.
+func findAssignmentConflicts(ambulanceId string, crew []CrewAssignment, shifts []Shift, ambulances []Ambulance) []ShiftConflict {
+	bookings := crewBookings(shifts, ambulances)
+	conflicts := make([]ShiftConflict, 0)
+	for _, a := range crew {
+		conflicts = append(conflicts, findCrewConflicts(bookings, "", ambulanceId, a.CrewMemberId, a.ShiftStart, a.ShiftEnd)...)
+	}
+	return conflicts
+}
+
+// checkShift validates s against the referenced ambulance and crew and against the existing shifts.
+// It returns a response body and status when the shift must be rejected, or nil when it is acceptable.
+func checkShift(ctx context.Context, c *gin.Context, s *Shift) (interface{}, int) {
+	if problems := validateShift(s); len(problems) > 0 {
+		return gin.H{"message": "Invalid shift", "errors": problems}, http.StatusUnprocessableEntity
+	}
+
+	var problems []string
+	if _, err := getDB(c).FindDocument(ctx, s.AmbulanceId); err != nil {
+		if err != db_service.ErrNotFound {
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+		problems = append(problems, "ambulance_id does not reference an existing ambulance")
+	}
+	crewDb := getCrewDB(c)
+	for i, m := range s.Crew {
+		if _, err := crewDb.FindDocument(ctx, m.CrewMemberId); err != nil {
+			if err != db_service.ErrNotFound {
+				log.Println("FindDocument error:", err)
+				return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+			}
+			problems = append(problems, "crew["+strconv.Itoa(i)+"].crew_member_id does not reference an existing crew member")
+		}
+	}
+	if len(problems) > 0 {
+		return gin.H{"message": "Invalid shift", "errors": problems}, http.StatusUnprocessableEntity
+	}
+
+	existing, err := getShiftDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+	}
+	// the crew assignments of the ambulances can only double-book a shift with crew
+	var ambulances []Ambulance
+	if len(s.Crew) > 0 {
+		if ambulances, err = getDB(c).ListDocuments(ctx); err != nil {
+			log.Println("ListDocuments error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+	}
+	if conflicts := findShiftConflicts(s, existing, ambulances); len(conflicts) > 0 {
+		return gin.H{"message": "Shift conflicts with existing shifts", "conflicts": conflicts}, http.StatusConflict
+	}
+	return nil, 0
+}
+
+// shiftCrewOnDuty returns the crew of the ambulance's shifts in progress at the given time
+// expressed as crew assignments.
+func shiftCrewOnDuty(ctx context.Context, db db_service.DbService[Shift], ambulanceId string, at time.Time) ([]CrewAssignment, error) {
+	shifts, err := db.FindDocumentsByField(ctx, "ambulance_id", ambulanceId)
+	if err != nil {
+		return nil, err
+	}
+	var crew []CrewAssignment
+	for _, s := range shifts {
+		if !s.covers(at) {
+			continue
+		}
+		for _, m := range s.Crew {
+			crew = append(crew, CrewAssignment{
+				CrewMemberId: m.CrewMemberId,
+				Role:         m.Role,
+				ShiftStart:   s.Start,
+				ShiftEnd:     s.End,
+			})
+		}
+	}
+	return crew, nil
+}
+
+// crewOnDuty returns the crew working the ambulance at the given time, combining
+// the ambulance's own crew assignments with the crew of its scheduled shifts.
+func crewOnDuty(ctx context.Context, c *gin.Context, ambulance *Ambulance, at time.Time) ([]CrewAssignment, error) {
+	crew := activeCrew(ambulance, at)
+	scheduled, err := shiftCrewOnDuty(ctx, getShiftDB(c), ambulance.Id, at)
+	if err != nil {
+		return nil, err
+	}
+	return append(crew, scheduled...), nil
+}
+
+// onDutyAmbulances returns the ids of the ambulances with a shift in progress or a crew
+// assignment covering the given time.
+func onDutyAmbulances(ctx context.Context, db db_service.DbService[Shift], ambulances []Ambulance, at time.Time) (map[string]bool, error) {
+	shifts, err := db.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	onDuty := map[string]bool{}
+	for _, s := range shifts {
+		if s.covers(at) {
+			onDuty[s.AmbulanceId] = true
+		}
+	}
+	for i := range ambulances {
+		if len(activeCrew(&ambulances[i], at)) > 0 {
+			onDuty[ambulances[i].Id] = true
+		}
+	}
+	return onDuty, nil
+}
+
+// withShiftByID loads a Shift and calls fn; fn may return an updated doc.
+func withShiftByID(
+	c *gin.Context,
+	fn func(*gin.Context, *Shift) (*Shift, interface{}, int),
+) {
+	id := c.Param("shiftId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "shiftId is required"})
+		return
+	}
+
+	respondInTransaction(c, func() (interface{}, int) {
+		db := getShiftDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		shift, err := db.FindDocument(ctx, id)
+		if err != nil {
+			if err == db_service.ErrNotFound {
+				return gin.H{"message": "Shift not found"}, http.StatusNotFound
+			}
+			log.Println("FindDocument error:", err)
+			return gin.H{"message": "Internal error"}, http.StatusInternalServerError
+		}
+
+		updated, result, status := fn(c, shift)
+		if updated != nil {
+			if err := db.UpdateDocument(ctx, id, updated); err != nil {
+				log.Println("UpdateDocument error:", err)
+				return gin.H{"message": "Failed to update shift"}, http.StatusInternalServerError
+			}
+		}
+		return result, status
+	})
+}
+
+// CreateShift implements POST /api/shifts
+func (o *implShiftAPI) CreateShift(c *gin.Context) {
+	var s Shift
+	if err := c.ShouldBindJSON(&s); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+		return
+	}
+	if s.Id == "" {
+		s.Id = uuid.NewString()
+	}
+
+	db := getShiftDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	if result, status := checkShift(ctx, c, &s); result != nil {
+		c.JSON(status, result)
+		return
+	}
+
+	if err := db.CreateDocument(ctx, s.Id, &s); err != nil {
+		switch err {
+		case db_service.ErrConflict:
+			c.JSON(http.StatusConflict, gin.H{"message": "Shift already exists"})
+		default:
+			log.Println("CreateDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to create shift"})
+		}
+		return
+	}
+	c.JSON(http.StatusCreated, s)
+}
+
+// GetShiftById implements GET /api/shifts/:shiftId
+func (o *implShiftAPI) GetShiftById(c *gin.Context) {
+	withShiftByID(c, func(_ *gin.Context, s *Shift) (*Shift, interface{}, int) {
+		return nil, s, http.StatusOK
+	})
+}
+
+// GetShifts implements GET /api/shifts
+//
+// Query parameters:
+//   - ambulance_id: only shifts of this ambulance
+//   - crew_member_id: only shifts worked by this crew member
+func (o *implShiftAPI) GetShifts(c *gin.Context) {
+	db := getShiftDB(c)
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	ambulanceID := c.Query("ambulance_id")
+	crewMemberID := c.Query("crew_member_id")
+
+	var (
+		shifts any
+		err    error
+	)
+
+	switch {
+	case ambulanceID != "":
+		shifts, err = db.FindDocumentsByField(ctx, "ambulance_id", ambulanceID)
+	case crewMemberID != "":
+		shifts, err = db.FindDocumentsByField(ctx, "crew.crew_member_id", crewMemberID)
+	default:
+		shifts, err = db.ListDocuments(ctx)
+	}
+
+	if err != nil {
+		log.Println("Error retrieving shifts:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve shifts"})
+		return
+	}
+
+	c.JSON(http.StatusOK, shifts)
+}
+
+// UpdateShift implements PUT /api/shifts/:shiftId
+func (o *implShiftAPI) UpdateShift(c *gin.Context) {
+	withShiftByID(c, func(c *gin.Context, existing *Shift) (*Shift, interface{}, int) {
+		var upd Shift
+		if err := c.ShouldBindJSON(&upd); err != nil {
+			return nil, gin.H{"message": "Invalid request", "error": err.Error()}, http.StatusBadRequest
+		}
+		if upd.AmbulanceId != "" {
+			existing.AmbulanceId = upd.AmbulanceId
+		}
+		if !upd.Start.IsZero() {
+			existing.Start = upd.Start
+		}
+		if !upd.End.IsZero() {
+			existing.End = upd.End
+		}
+		if upd.Crew != nil {
+			existing.Crew = upd.Crew
+		}
+		if upd.Notes != "" {
+			existing.Notes = upd.Notes
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if result, status := checkShift(ctx, c, existing); result != nil {
+			return nil, result, status
+		}
+		return existing, existing, http.StatusOK
+	})
+}
+
+// DeleteShift implements DELETE /api/shifts/:shiftId
+func (o *implShiftAPI) DeleteShift(c *gin.Context) {
+	withShiftByID(c, func(_ *gin.Context, s *Shift) (*Shift, interface{}, int) {
+		db := getShiftDB(c)
+		ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+		defer cancel()
+
+		if err := db.DeleteDocument(ctx, s.Id); err != nil {
+			log.Println("DeleteDocument error:", err)
+			return nil, gin.H{"message": "Failed to delete shift"}, http.StatusInternalServerError
+		}
+		return nil, nil, http.StatusNoContent
+	})
+}
+
+// GetRoster implements GET /api/roster
+//
+// Returns every shift overlapping the requested day (query parameter date, YYYY-MM-DD,
+// defaults to today) together with ambulance and crew names.
+func (o *implShiftAPI) GetRoster(c *gin.Context) {
+	day := time.Now()
+	if v := c.Query("date"); v != "" {
+		d, err := time.ParseInLocation(time.DateOnly, v, time.Local)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"message": "date must be in YYYY-MM-DD format"})
+			return
+		}
+		day = d
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, day.Location())
+	end := start.AddDate(0, 0, 1)
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	shifts, err := getShiftDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve shifts"})
+		return
+	}
+	ambulances, err := getDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve ambulances"})
+		return
+	}
+	members, err := getCrewDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve crew members"})
+		return
+	}
+
+	ambulanceNames := map[string]string{}
+	for _, a := range ambulances {
+		ambulanceNames[a.Id] = a.Name
+	}
+	memberNames := map[string]string{}
+	for _, m := range members {
+		memberNames[m.Id] = m.Name
+	}
+
+	roster := make([]RosterEntry, 0)
+	for _, s := range shifts {
+		if !s.overlaps(start, end) {
+			continue
+		}
+		entry := RosterEntry{Shift: s, AmbulanceName: ambulanceNames[s.AmbulanceId], Crew: make([]RosterCrewMember, 0, len(s.Crew))}
+		for _, m := range s.Crew {
+			entry.Crew = append(entry.Crew, RosterCrewMember{CrewMemberId: m.CrewMemberId, Name: memberNames[m.CrewMemberId], Role: m.Role})
+		}
+		roster = append(roster, entry)
+	}
+	sort.Slice(roster, func(i, j int) bool {
+		if !roster[i].Shift.Start.Equal(roster[j].Shift.Start) {
+			return roster[i].Shift.Start.Before(roster[j].Shift.Start)
+		}
+		return roster[i].AmbulanceName < roster[j].AmbulanceName
+	})
+
+	c.JSON(http.StatusOK, roster)
+}
+
+// GetCrewMemberCalendar implements GET /api/crew/:crewMemberId/calendar
+//
+// Renders all shifts and crew assignments of the crew member as an iCalendar (RFC 5545) feed.
+func (o *implShiftAPI) GetCrewMemberCalendar(c *gin.Context) {
+	id := c.Param("crewMemberId")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "crewMemberId is required"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), 10*time.Second)
+	defer cancel()
+
+	member, err := getCrewDB(c).FindDocument(ctx, id)
+	if err != nil {
+		if err == db_service.ErrNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Crew member not found"})
+		} else {
+			log.Println("FindDocument error:", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+		}
+		return
+	}
+
+	shifts, err := getShiftDB(c).FindDocumentsByField(ctx, "crew.crew_member_id", member.Id)
+	if err != nil {
+		log.Println("FindDocumentsByField error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve shifts"})
+		return
+	}
+	ambulances, err := getDB(c).ListDocuments(ctx)
+	if err != nil {
+		log.Println("ListDocuments error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Failed to retrieve ambulances"})
+		return
+	}
+	ambulanceNames := map[string]string{}
+	for _, a := range ambulances {
+		ambulanceNames[a.Id] = a.Name
+		for _, assignment := range a.Crew {
+			if assignment.CrewMemberId == member.Id {
+				shifts = append(shifts, assignmentShift(&a, &assignment))
+			}
+		}
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+member.Id+`.ics"`)
+	c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(renderCalendar(member, shifts, ambulanceNames, time.Now())))
+}
+
+// assignmentShift returns a crew assignment of the ambulance as a shift with an id of its
+// own, so that it is exported like the scheduled shifts.
+func assignmentShift(a *Ambulance, assignment *CrewAssignment) *Shift {
+	return &Shift{
+		Id:          a.Id + "-" + assignment.CrewMemberId + "-" + assignment.ShiftStart.UTC().Format("20060102T150405Z"),
+		AmbulanceId: a.Id,
+		Start:       assignment.ShiftStart,
+		End:         assignment.ShiftEnd,
+		Crew:        []ShiftCrewMember{{CrewMemberId: assignment.CrewMemberId, Role: assignment.Role}},
+	}
+}
+
+// renderCalendar renders the shifts of a crew member as an iCalendar document.
+func renderCalendar(m *CrewMember, shifts []*Shift, ambulanceNames map[string]string, stamp time.Time) string {
+	const layout = "20060102T150405Z"
+	var b strings.Builder
+	// content lines are folded after 75 octets, never within a UTF-8 sequence; the
+	// space starting a continuation line counts towards its length
+	line := func(s string) {
+		for limit := 75; len(s) > limit; limit = 74 {
+			cut := limit
+			for !utf8.RuneStart(s[cut]) {
+				cut--
+			}
+			b.WriteString(s[:cut])
+			b.WriteString("\r\n ")
+			s = s[cut:]
+		}
+		b.WriteString(s)
+		b.WriteString("\r\n")
+	}
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//wac-project//Hospital Management API//EN")
+	line("CALSCALE:GREGORIAN")
+	line("X-WR-CALNAME:" + escapeCalendarText("Shifts of "+m.Name))
+	for _, s := range shifts {
+		role := ""
+		for _, cm := range s.Crew {
+			if cm.CrewMemberId == m.Id {
+				role = cm.Role
+			}
+		}
+		ambulanceName := ambulanceNames[s.AmbulanceId]
+		if ambulanceName == "" {
+			ambulanceName = s.AmbulanceId
+		}
+		line("BEGIN:VEVENT")
+		line("UID:" + s.Id + "@wac-api")
+		line("DTSTAMP:" + stamp.UTC().Format(layout))
+		line("DTSTART:" + s.Start.UTC().Format(layout))
+		line("DTEND:" + s.End.UTC().Format(layout))
+		line("SUMMARY:" + escapeCalendarText(fmt.Sprintf("%s shift – %s", role, ambulanceName)))
+		if s.Notes != "" {
+			line("DESCRIPTION:" + escapeCalendarText(s.Notes))
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return b.String()
+}
+
+// escapeCalendarText escapes a TEXT value as required by RFC 5545.
+func escapeCalendarText(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
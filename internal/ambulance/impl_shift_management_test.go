@@ -0,0 +1,138 @@
+package ambulance
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// ShiftSuite defines the suite for shift scheduling tests
+type ShiftSuite struct {
+	suite.Suite
+	day      time.Time
+	existing []Shift
+}
+
+func TestShiftSuite(t *testing.T) {
+	suite.Run(t, new(ShiftSuite))
+}
+
+func (suite *ShiftSuite) SetupTest() {
+	suite.day = time.Date(2025, 5, 21, 0, 0, 0, 0, time.UTC)
+	suite.existing = []Shift{
+		{
+			Id:          "day-shift",
+			AmbulanceId: "amb001",
+			Start:       suite.day.Add(6 * time.Hour),
+			End:         suite.day.Add(18 * time.Hour),
+			Crew: []ShiftCrewMember{
+				{CrewMemberId: "crew001", Role: CrewRoleDriver},
+				{CrewMemberId: "crew002", Role: CrewRoleParamedic},
+			},
+		},
+	}
+}
+
+func (suite *ShiftSuite) Test_FindShiftConflicts_DetectsDoubleBookedAmbulance() {
+	s := &Shift{Id: "new", AmbulanceId: "amb001", Start: suite.day.Add(17 * time.Hour), End: suite.day.Add(30 * time.Hour)}
+
+	conflicts := findShiftConflicts(s, suite.existing, nil)
+
+	suite.Equal([]ShiftConflict{{ShiftId: "day-shift", AmbulanceId: "amb001"}}, conflicts)
+}
+
+func (suite *ShiftSuite) Test_FindShiftConflicts_DetectsDoubleBookedCrewMember() {
+	s := &Shift{
+		Id:          "new",
+		AmbulanceId: "amb002",
+		Start:       suite.day.Add(8 * time.Hour),
+		End:         suite.day.Add(12 * time.Hour),
+		Crew:        []ShiftCrewMember{{CrewMemberId: "crew002", Role: CrewRoleParamedic}},
+	}
+
+	conflicts := findShiftConflicts(s, suite.existing, nil)
+
+	suite.Equal([]ShiftConflict{{ShiftId: "day-shift", CrewMemberId: "crew002"}}, conflicts)
+}
+
+func (suite *ShiftSuite) Test_FindShiftConflicts_AllowsBackToBackShifts() {
+	s := &Shift{
+		Id:          "night-shift",
+		AmbulanceId: "amb001",
+		Start:       suite.day.Add(18 * time.Hour),
+		End:         suite.day.Add(30 * time.Hour),
+		Crew:        []ShiftCrewMember{{CrewMemberId: "crew001", Role: CrewRoleDriver}},
+	}
+
+	suite.Empty(findShiftConflicts(s, suite.existing, nil))
+}
+
+func (suite *ShiftSuite) Test_FindShiftConflicts_IgnoresTheShiftItself() {
+	s := suite.existing[0]
+	s.End = s.End.Add(time.Hour)
+
+	suite.Empty(findShiftConflicts(&s, suite.existing, nil))
+}
+
+func (suite *ShiftSuite) Test_FindShiftConflicts_DetectsCrewAssignmentOfOtherAmbulance() {
+	s := &Shift{
+		Id:          "new",
+		AmbulanceId: "amb002",
+		Start:       suite.day.Add(20 * time.Hour),
+		End:         suite.day.Add(30 * time.Hour),
+		Crew:        []ShiftCrewMember{{CrewMemberId: "crew003", Role: CrewRoleDriver}},
+	}
+	ambulances := []Ambulance{
+		{Id: "amb002", Crew: []CrewAssignment{{CrewMemberId: "crew003", Role: CrewRoleDriver, ShiftStart: suite.day.Add(20 * time.Hour), ShiftEnd: suite.day.Add(30 * time.Hour)}}},
+		{Id: "amb003", Crew: []CrewAssignment{{CrewMemberId: "crew003", Role: CrewRoleDriver, ShiftStart: suite.day.Add(29 * time.Hour), ShiftEnd: suite.day.Add(40 * time.Hour)}}},
+	}
+
+	conflicts := findShiftConflicts(s, suite.existing, ambulances)
+
+	suite.Equal([]ShiftConflict{{AmbulanceId: "amb003", CrewMemberId: "crew003"}}, conflicts)
+}
+
+func (suite *ShiftSuite) Test_FindAssignmentConflicts_DetectsShiftOfOtherAmbulance() {
+	crew := []CrewAssignment{
+		{CrewMemberId: "crew001", Role: CrewRoleDriver, ShiftStart: suite.day.Add(6 * time.Hour), ShiftEnd: suite.day.Add(18 * time.Hour)},
+		{CrewMemberId: "crew002", Role: CrewRoleParamedic, ShiftStart: suite.day.Add(17 * time.Hour), ShiftEnd: suite.day.Add(20 * time.Hour)},
+	}
+
+	suite.Empty(findAssignmentConflicts("amb001", crew, suite.existing, nil))
+	suite.Equal([]ShiftConflict{
+		{ShiftId: "day-shift", CrewMemberId: "crew001"},
+		{ShiftId: "day-shift", CrewMemberId: "crew002"},
+	}, findAssignmentConflicts("amb002", crew, suite.existing, []Ambulance{{Id: "amb002", Crew: crew}}))
+}
+
+func (suite *ShiftSuite) Test_RenderCalendar_FoldsLongLines() {
+	member := &CrewMember{Id: "crew001", Name: "Ján Novák", Role: CrewRoleDriver}
+	shift := suite.existing[0]
+	shift.Notes = strings.Repeat("Prevzatie vozidla a kontrola výbavy. ", 5)
+
+	ics := renderCalendar(member, []*Shift{&shift}, nil, suite.day)
+
+	for _, line := range strings.Split(strings.TrimSuffix(ics, "\r\n"), "\r\n") {
+		suite.LessOrEqual(len(line), 75)
+		suite.True(utf8.ValidString(line))
+	}
+	unfolded := strings.ReplaceAll(ics, "\r\n ", "")
+	suite.Contains(unfolded, "DESCRIPTION:"+strings.Repeat("Prevzatie vozidla a kontrola výbavy. ", 5)+"\r\n")
+}
+
+func (suite *ShiftSuite) Test_RenderCalendar_ProducesEventPerShift() {
+	member := &CrewMember{Id: "crew001", Name: "Ján Novák", Role: CrewRoleDriver}
+	shifts := []*Shift{&suite.existing[0]}
+
+	ics := renderCalendar(member, shifts, map[string]string{"amb001": "Ambulancia Hlavná"}, suite.day)
+
+	suite.True(strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n"))
+	suite.Contains(ics, "UID:day-shift@wac-api\r\n")
+	suite.Contains(ics, "DTSTART:20250521T060000Z\r\n")
+	suite.Contains(ics, "DTEND:20250521T180000Z\r\n")
+	suite.Contains(ics, "SUMMARY:driver shift – Ambulancia Hlavná\r\n")
+	suite.True(strings.HasSuffix(ics, "END:VCALENDAR\r\n"))
+}
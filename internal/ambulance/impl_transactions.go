@@ -0,0 +1,116 @@
+package ambulance
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// errResponseFailed rolls back the transaction of a handler that responds with an error.
+var errResponseFailed = errors.New("the response is an error")
+
+// commitHooks holds the functions to call once the outermost transaction of a request
+// has been committed, and the request context from before the transaction.
+type commitHooks struct {
+	hooks   []func()
+	outside context.Context
+}
+
+type commitHooksKey struct{}
+
+// getTransactor returns the transactor of the DbServices, nil when none is injected.
+func getTransactor(c *gin.Context) db_service.Transactor {
+	if transactor, ok := c.Get("db_transactor"); ok {
+		return transactor.(db_service.Transactor)
+	}
+	return nil
+}
+
+// requireTransaction calls fn in a transaction, so that its writes to several collections
+// apply together or not at all. While fn runs, the request context carries the
+// transaction, and the contexts handlers derive from it take part. The transaction is
+// rolled back when fn returns an error. It returns db_service.ErrNoTransactions without
+// calling fn when the database does not run transactions.
+func requireTransaction(c *gin.Context, fn func() error) error {
+	transactor := getTransactor(c)
+	if transactor == nil {
+		return db_service.ErrNoTransactions
+	}
+	request := c.Request
+	ctx := request.Context()
+	hooks, joined := ctx.Value(commitHooksKey{}).(*commitHooks)
+	if !joined {
+		hooks = &commitHooks{outside: ctx}
+		ctx = context.WithValue(ctx, commitHooksKey{}, hooks)
+	}
+	err := transactor.RunInTransaction(ctx, func(txCtx context.Context) error {
+		if !joined {
+			// a transaction run again starts over
+			hooks.hooks = nil
+		}
+		c.Request = request.WithContext(txCtx)
+		defer func() { c.Request = request }()
+		return fn()
+	})
+	if err == nil && !joined {
+		for _, hook := range hooks.hooks {
+			hook()
+		}
+	}
+	return err
+}
+
+// runInTransaction calls fn in a transaction as requireTransaction does. When the
+// database does not run transactions, fn runs as it is.
+func runInTransaction(c *gin.Context, fn func() error) error {
+	err := requireTransaction(c, fn)
+	if err == db_service.ErrNoTransactions {
+		return fn()
+	}
+	return err
+}
+
+// afterCommit calls fn once the transaction the request runs in has been committed, and
+// never if it is rolled back. Outside of a transaction fn is called at once. It is meant
+// for effects outside of the database, such as removing blobs.
+func afterCommit(c *gin.Context, fn func()) {
+	if hooks, ok := c.Request.Context().Value(commitHooksKey{}).(*commitHooks); ok {
+		hooks.hooks = append(hooks.hooks, fn)
+		return
+	}
+	fn()
+}
+
+// outsideTransaction returns the request context without the transaction the request
+// runs in. It is meant for stores keeping their own connection to the database, such as
+// the GridFS blob store, which refuse the session of another client.
+func outsideTransaction(c *gin.Context) context.Context {
+	if hooks, ok := c.Request.Context().Value(commitHooksKey{}).(*commitHooks); ok {
+		return hooks.outside
+	}
+	return c.Request.Context()
+}
+
+// respondInTransaction calls fn in a transaction and responds with its result. The
+// transaction is rolled back when the status is an error.
+func respondInTransaction(c *gin.Context, fn func() (interface{}, int)) {
+	var result interface{}
+	var status int
+	err := runInTransaction(c, func() error {
+		result, status = fn()
+		if status >= http.StatusBadRequest {
+			return errResponseFailed
+		}
+		return nil
+	})
+	if err != nil && err != errResponseFailed {
+		log.Println("transaction error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+		return
+	}
+	c.JSON(status, result)
+}
@@ -0,0 +1,142 @@
+package ambulance
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// TransactionSuite defines the suite for tests of handlers writing in transactions
+type TransactionSuite struct {
+	suite.Suite
+	ambulanceDb   db_service.DbService[Ambulance]
+	maintenanceDb db_service.DbService[MaintenanceEntry]
+	scheduleDb    db_service.DbService[ServiceSchedule]
+	transactor    *db_service.MemoryTransactor
+}
+
+func TestTransactionSuite(t *testing.T) {
+	suite.Run(t, new(TransactionSuite))
+}
+
+func (suite *TransactionSuite) SetupTest() {
+	suite.ambulanceDb = db_service.NewMemoryService[Ambulance]()
+	suite.maintenanceDb = db_service.NewMemoryService[MaintenanceEntry]()
+	suite.scheduleDb = db_service.NewMemoryService[ServiceSchedule]()
+	suite.transactor = db_service.NewMemoryTransactor()
+
+	ctx := context.Background()
+	suite.Require().NoError(suite.ambulanceDb.CreateDocument(ctx, "amb1", &Ambulance{Id: "amb1", Odometer: 1000}))
+	suite.Require().NoError(suite.scheduleDb.CreateDocument(ctx, "sch1", &ServiceSchedule{Id: "sch1", AmbulanceId: "amb1", IntervalKm: 30000, LastServiceOdometer: 500}))
+	suite.Require().NoError(suite.maintenanceDb.CreateDocument(ctx, "ent1", &MaintenanceEntry{Id: "ent1", AmbulanceId: "amb1", Type: "oil"}))
+}
+
+func (suite *TransactionSuite) createEntry(body string) *httptest.ResponseRecorder {
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(recorder)
+	ctx.Set("db_service_ambulance", suite.ambulanceDb)
+	ctx.Set("db_service_maintenance", suite.maintenanceDb)
+	ctx.Set("db_service_service_schedule", suite.scheduleDb)
+	ctx.Set("db_transactor", suite.transactor)
+	ctx.Params = []gin.Param{{Key: "ambulanceId", Value: "amb1"}}
+	ctx.Request = httptest.NewRequest("POST", "/api/ambulances/amb1/maintenance", strings.NewReader(body))
+
+	(&implMaintenanceAPI{}).CreateMaintenanceEntry(ctx)
+	return recorder
+}
+
+func (suite *TransactionSuite) Test_CreateMaintenanceEntry_RollsBackScheduleOnConflict() {
+	recorder := suite.createEntry(`{"id":"ent1","type":"oil","odometer":2000,"schedule_id":"sch1"}`)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	schedule, err := suite.scheduleDb.FindDocument(context.Background(), "sch1")
+	suite.Require().NoError(err)
+	suite.Equal(int32(500), schedule.LastServiceOdometer)
+	suite.True(schedule.LastServiceDate.IsZero())
+}
+
+func (suite *TransactionSuite) Test_CreateMaintenanceEntry_CommitsAllWrites() {
+	recorder := suite.createEntry(`{"id":"ent2","type":"oil","odometer":2000,"schedule_id":"sch1","timestamp":"2025-06-02T10:00:00Z"}`)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	schedule, err := suite.scheduleDb.FindDocument(context.Background(), "sch1")
+	suite.Require().NoError(err)
+	suite.Equal(int32(2000), schedule.LastServiceOdometer)
+	suite.Equal(time.Date(2025, 6, 2, 10, 0, 0, 0, time.UTC), schedule.LastServiceDate.UTC())
+	ambulance, err := suite.ambulanceDb.FindDocument(context.Background(), "amb1")
+	suite.Require().NoError(err)
+	suite.Equal(int32(2000), ambulance.Odometer)
+	_, err = suite.maintenanceDb.FindDocument(context.Background(), "ent2")
+	suite.NoError(err)
+}
+
+// standaloneTransactor stands for a database that does not run transactions.
+type standaloneTransactor struct{}
+
+func (standaloneTransactor) RunInTransaction(context.Context, func(context.Context) error) error {
+	return db_service.ErrNoTransactions
+}
+
+func (suite *TransactionSuite) transactionContext(transactor db_service.Transactor) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	ctx.Set("db_transactor", transactor)
+	ctx.Request = httptest.NewRequest("POST", "/", nil)
+	return ctx
+}
+
+func (suite *TransactionSuite) Test_AfterCommit_RunsOnceTheOutermostTransactionCommits() {
+	c := suite.transactionContext(suite.transactor)
+	var calls []string
+
+	err := runInTransaction(c, func() error {
+		afterCommit(c, func() { calls = append(calls, "outer") })
+		err := runInTransaction(c, func() error {
+			afterCommit(c, func() { calls = append(calls, "inner") })
+			return nil
+		})
+		suite.Empty(calls)
+		return err
+	})
+
+	suite.NoError(err)
+	suite.Equal([]string{"outer", "inner"}, calls)
+}
+
+func (suite *TransactionSuite) Test_AfterCommit_SkippedOnRollback() {
+	c := suite.transactionContext(suite.transactor)
+	called := false
+
+	err := runInTransaction(c, func() error {
+		afterCommit(c, func() { called = true })
+		return errResponseFailed
+	})
+
+	suite.Equal(errResponseFailed, err)
+	suite.False(called)
+}
+
+func (suite *TransactionSuite) Test_RunInTransaction_WithoutTransactions_RunsDirectly() {
+	c := suite.transactionContext(standaloneTransactor{})
+	called := false
+
+	err := runInTransaction(c, func() error {
+		afterCommit(c, func() { called = true })
+		suite.True(called)
+		return nil
+	})
+
+	suite.NoError(err)
+	suite.Equal(db_service.ErrNoTransactions, requireTransaction(c, func() error {
+		suite.Fail("fn must not run without transactions")
+		return nil
+	}))
+}
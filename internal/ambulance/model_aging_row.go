@@ -0,0 +1,39 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type AgingRow struct {
+
+	// Identifier of the payer owing the amount, for payers in the registry.
+	PayerId string `json:"payer_id,omitempty"`
+
+	// Name of the payer owing the amount.
+	Payer string `json:"payer"`
+
+	// Identifier of the department of the ambulance that performed the procedures.
+	DepartmentId string `json:"department_id,omitempty"`
+
+	// Name of the department.
+	Department string `json:"department"`
+
+	// Age bucket in days since the procedure (0-30, 31-60, 61-90, 90+).
+	Bucket string `json:"bucket"`
+
+	// Number of procedures with an outstanding balance in the bucket.
+	Procedures int32 `json:"procedures"`
+
+	// Outstanding amount: the prices less settled payments.
+	Amount money.Money `json:"amount"`
+}
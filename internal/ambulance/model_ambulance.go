@@ -21,7 +21,10 @@ type Ambulance struct {
 	// Location or base of the ambulance.
 	Location string `json:"location"`
 
-	// Department the ambulance belongs to.
+	// Identifier of the department the ambulance belongs to.
+	DepartmentId string `json:"department_id,omitempty"`
+
+	// Name of the department the ambulance belongs to.
 	Department string `json:"department"`
 
 	// Capacity of the ambulance (number of patients it can serve).
@@ -29,4 +32,13 @@ type Ambulance struct {
 
 	// Current status of the ambulance (e.g., Available, Occupied).
 	Status string `json:"status"`
+
+	// Odometer reading in kilometres.
+	Odometer int32 `json:"odometer,omitempty"`
+
+	// Crew assigned to the ambulance per shift.
+	Crew []CrewAssignment `json:"crew,omitempty"`
+
+	// Hours the ambulance takes appointments; the department's hours apply when empty.
+	WorkingHours []WorkingHours `json:"working_hours,omitempty"`
 }
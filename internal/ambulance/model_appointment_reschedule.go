@@ -0,0 +1,27 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type AppointmentReschedule struct {
+
+	// New date and time of the appointment (ISO 8601).
+	Timestamp time.Time `json:"timestamp"`
+
+	// Identifier of the ambulance to move the appointment to; the current one when empty.
+	AmbulanceId string `json:"ambulance_id,omitempty"`
+
+	// Reason for rescheduling.
+	Note string `json:"note,omitempty"`
+}
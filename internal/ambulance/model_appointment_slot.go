@@ -0,0 +1,24 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type AppointmentSlot struct {
+
+	// Start of the free slot (ISO 8601).
+	Start time.Time `json:"start"`
+
+	// End of the free slot (ISO 8601).
+	End time.Time `json:"end"`
+}
@@ -0,0 +1,42 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type Attachment struct {
+
+	// Unique identifier of the attachment.
+	Id string `json:"id"`
+
+	// Original file name of the attachment.
+	FileName string `json:"file_name"`
+
+	// Media type detected from the content (e.g., image/png, application/pdf).
+	ContentType string `json:"content_type"`
+
+	// Size of the content in bytes.
+	Size int64 `json:"size"`
+
+	// Hex encoded SHA-256 checksum of the content.
+	Sha256 string `json:"sha256"`
+
+	// Description of the attachment.
+	Description string `json:"description,omitempty"`
+
+	// Date and time of the upload (ISO 8601).
+	UploadedAt time.Time `json:"uploaded_at"`
+
+	// Role of the user who uploaded the attachment, from the X-User-Role header.
+	UploadedBy string `json:"uploaded_by,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type AttachmentChecksum struct {
+
+	// Identifier of the attachment.
+	AttachmentId string `json:"attachment_id"`
+
+	// Checksum recorded at upload.
+	Expected string `json:"expected"`
+
+	// Checksum of the stored content.
+	Actual string `json:"actual"`
+
+	// Whether the stored content matches the checksum recorded at upload.
+	Valid bool `json:"valid"`
+}
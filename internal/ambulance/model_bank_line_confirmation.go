@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type BankLineConfirmation struct {
+
+	// Identifier of the procedure the line pays; one of the candidates when both fields are omitted.
+	ProcedureId string `json:"procedure_id,omitempty"`
+
+	// Identifier of the invoice the line pays.
+	InvoiceId string `json:"invoice_id,omitempty"`
+}
@@ -0,0 +1,17 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type BankLineDismissal struct {
+
+	// Why the line is not booked as a payment.
+	Reason string `json:"reason"`
+}
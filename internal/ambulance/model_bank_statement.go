@@ -0,0 +1,36 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type BankStatement struct {
+
+	// Unique identifier of the statement, the SHA-256 checksum of the imported file.
+	Id string `json:"id"`
+
+	// Format of the imported file (csv or camt053).
+	Format string `json:"format"`
+
+	// Account the statement belongs to, as given in the file.
+	Account string `json:"account,omitempty"`
+
+	// Identifier the bank gave the statement.
+	Reference string `json:"reference,omitempty"`
+
+	// Entries of the statement, in file order.
+	Lines []BankStatementLine `json:"lines"`
+
+	// Date and time the statement was imported.
+	ImportedAt time.Time `json:"imported_at"`
+}
@@ -0,0 +1,68 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type BankStatementLine struct {
+
+	// Identifier of the statement the line belongs to.
+	StatementId string `json:"statement_id"`
+
+	// Position of the line in the statement, starting at 1.
+	Line int32 `json:"line"`
+
+	// Identifier the bank gave the entry; entries already imported with another statement are duplicates.
+	BankReference string `json:"bank_reference,omitempty"`
+
+	// Date the entry was booked (YYYY-MM-DD).
+	BookingDate string `json:"booking_date"`
+
+	// Amount of the entry; incoming payments are positive.
+	Amount money.Money `json:"amount"`
+
+	// Payment reference given by the payer, such as an invoice number.
+	Reference string `json:"reference,omitempty"`
+
+	// Name of the other party.
+	Counterparty string `json:"counterparty,omitempty"`
+
+	// Account of the other party.
+	CounterpartyAccount string `json:"counterparty_account,omitempty"`
+
+	// Free-text remittance information.
+	Description string `json:"description,omitempty"`
+
+	// Reconciliation status of the line (matched, ambiguous, unmatched, confirmed, dismissed, duplicate).
+	Status string `json:"status"`
+
+	// Open procedures and invoices the line may pay, best matches only.
+	Candidates []ReconciliationCandidate `json:"candidates,omitempty"`
+
+	// Identifier of the procedure the confirmed line paid.
+	ProcedureId string `json:"procedure_id,omitempty"`
+
+	// Identifier of the invoice the confirmed line paid.
+	InvoiceId string `json:"invoice_id,omitempty"`
+
+	// Identifier of the payment created on confirmation.
+	PaymentId string `json:"payment_id,omitempty"`
+
+	// Why the line was dismissed or needs review.
+	Note string `json:"note,omitempty"`
+
+	// Date and time the line was confirmed or dismissed.
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"encoding/json"
+)
+
+type BatchOperation struct {
+
+	// Label of the operation that later operations refer to as ${label.field}.
+	Id string `json:"id,omitempty"`
+
+	// HTTP method of the request.
+	Method string `json:"method"`
+
+	// Path of the request, starting with /api/, with an optional query.
+	Path string `json:"path"`
+
+	// JSON body of the request.
+	Body json.RawMessage `json:"body,omitempty"`
+}
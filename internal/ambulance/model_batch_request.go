@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type BatchRequest struct {
+
+	// Whether the operations apply together or not at all.
+	Transactional bool `json:"transactional,omitempty"`
+
+	// Operations to execute, in order.
+	Operations []BatchOperation `json:"operations"`
+}
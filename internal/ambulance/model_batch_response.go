@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type BatchResponse struct {
+
+	// Whether the operations were applied together.
+	Transactional bool `json:"transactional"`
+
+	// Whether an operation of a transactional batch failed and none was applied.
+	RolledBack bool `json:"rolled_back,omitempty"`
+
+	// Result of every operation, in the order of the request.
+	Results []BatchResult `json:"results"`
+}
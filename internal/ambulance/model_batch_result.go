@@ -0,0 +1,27 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"encoding/json"
+)
+
+type BatchResult struct {
+
+	// Label of the operation.
+	Id string `json:"id,omitempty"`
+
+	// HTTP status of the response.
+	Status int32 `json:"status"`
+
+	// Body of the response; text responses are given as a JSON string.
+	Body json.RawMessage `json:"body,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type Certification struct {
+
+	// Name of the certification.
+	Name string `json:"name"`
+
+	// Crew role the certification qualifies for (driver, paramedic, doctor, nurse).
+	Role string `json:"role"`
+
+	// Date and time when the certification was issued (ISO 8601).
+	IssuedAt time.Time `json:"issued_at,omitempty"`
+
+	// Date and time when the certification expires (ISO 8601).
+	ExpiresAt time.Time `json:"expires_at"`
+}
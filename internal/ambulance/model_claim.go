@@ -0,0 +1,50 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type Claim struct {
+
+	// Unique identifier of the claim.
+	Id string `json:"id"`
+
+	// Insurer the claim is sent to; every procedure of the claim has it as payer.
+	Insurer string `json:"insurer"`
+
+	// Status of the claim (draft, submitted, accepted, partially_accepted, rejected, paid).
+	Status string `json:"status,omitempty"`
+
+	// Claimed procedures.
+	Lines []ClaimLine `json:"lines"`
+
+	// Total claimed amount; set by the server.
+	Total money.Money `json:"total"`
+
+	// Total amount accepted by the insurer; set by the server on the decision.
+	AcceptedTotal *money.Money `json:"accepted_total,omitempty"`
+
+	// Date and time the claim was created (ISO 8601).
+	CreatedAt time.Time `json:"created_at,omitempty"`
+
+	// Date and time the claim was submitted to the insurer (ISO 8601).
+	SubmittedAt *time.Time `json:"submitted_at,omitempty"`
+
+	// Date and time the insurer decided the claim (ISO 8601).
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+
+	// Date and time the insurer paid the claim (ISO 8601).
+	PaidAt *time.Time `json:"paid_at,omitempty"`
+}
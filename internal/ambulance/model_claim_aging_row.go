@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type ClaimAgingRow struct {
+
+	// Insurer of the claims.
+	Insurer string `json:"insurer"`
+
+	// Age bucket in days since submission (0-30, 31-60, 61-90, 90+).
+	Bucket string `json:"bucket"`
+
+	// Number of unpaid claims in the bucket.
+	Claims int32 `json:"claims"`
+
+	// Amount outstanding: the accepted amount of decided claims, the claimed amount otherwise.
+	Amount money.Money `json:"amount"`
+}
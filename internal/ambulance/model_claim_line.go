@@ -0,0 +1,36 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type ClaimLine struct {
+
+	// Identifier of the claimed procedure.
+	ProcedureId string `json:"procedure_id"`
+
+	// Claimed amount: the procedure price; set by the server.
+	Amount money.Money `json:"amount"`
+
+	// Decision on the line (pending, accepted, rejected); set by the server.
+	Status string `json:"status,omitempty"`
+
+	// Amount accepted by the insurer.
+	AcceptedAmount *money.Money `json:"accepted_amount,omitempty"`
+
+	// Reason the insurer gave for rejecting the line.
+	RejectionReason string `json:"rejection_reason,omitempty"`
+
+	// Identifier of the payment created for the accepted amount.
+	PaymentId string `json:"payment_id,omitempty"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type ClaimLineDecision struct {
+
+	// Identifier of the claimed procedure.
+	ProcedureId string `json:"procedure_id"`
+
+	// Whether the insurer accepted the line.
+	Accepted bool `json:"accepted"`
+
+	// Amount accepted; the claimed amount when omitted.
+	AcceptedAmount *money.Money `json:"accepted_amount,omitempty"`
+
+	// Reason for rejecting the line; required for rejected lines.
+	RejectionReason string `json:"rejection_reason,omitempty"`
+}
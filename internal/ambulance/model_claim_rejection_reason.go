@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ClaimRejectionReason struct {
+
+	// Reason given by the insurer.
+	Reason string `json:"reason"`
+
+	// Number of lines rejected for the reason.
+	Count int32 `json:"count"`
+}
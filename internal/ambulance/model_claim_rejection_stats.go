@@ -0,0 +1,29 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ClaimRejectionStats struct {
+
+	// Insurer deciding the claims.
+	Insurer string `json:"insurer"`
+
+	// Number of decided claim lines.
+	LinesDecided int32 `json:"lines_decided"`
+
+	// Number of rejected claim lines.
+	LinesRejected int32 `json:"lines_rejected"`
+
+	// Share of decided lines that were rejected, between 0 and 1.
+	RejectionRate float64 `json:"rejection_rate"`
+
+	// Rejection reasons, most frequent first.
+	Reasons []ClaimRejectionReason `json:"reasons"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type ClinicalNote struct {
+
+	// Unique identifier of the note.
+	Id string `json:"id"`
+
+	// Free-text content of the note.
+	Text string `json:"text"`
+
+	// Author of the note.
+	Author string `json:"author,omitempty"`
+
+	// Date and time the note was written (ISO 8601); set by the server.
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
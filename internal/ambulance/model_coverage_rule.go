@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type CoverageRule struct {
+
+	// Catalog code of the covered procedure type; the rule covers every procedure without a rule of its own when empty.
+	Code string `json:"code,omitempty"`
+
+	// Share of the procedure price the payer pays, in per cent.
+	Percentage float64 `json:"percentage"`
+}
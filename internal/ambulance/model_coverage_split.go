@@ -0,0 +1,45 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type CoverageSplit struct {
+
+	// Identifier of the procedure.
+	ProcedureId string `json:"procedure_id"`
+
+	// Identifier of the payer of the procedure.
+	PayerId string `json:"payer_id"`
+
+	// Name of the payer of the procedure.
+	Payer string `json:"payer"`
+
+	// Whether the payer covers the procedure.
+	Covered bool `json:"covered"`
+
+	// Reason the procedure is not covered.
+	Reason string `json:"reason,omitempty"`
+
+	// Share of the price the payer pays, in per cent.
+	Percentage float64 `json:"percentage"`
+
+	// Price of the procedure.
+	Price money.Money `json:"price"`
+
+	// Part of the price paid by the payer.
+	InsurerShare money.Money `json:"insurer_share"`
+
+	// Part of the price paid by the patient.
+	PatientShare money.Money `json:"patient_share"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type CrewAssignment struct {
+
+	// Identifier of the assigned crew member.
+	CrewMemberId string `json:"crew_member_id"`
+
+	// Role of the crew member on this shift (driver, paramedic, doctor, nurse).
+	Role string `json:"role"`
+
+	// Start of the shift (ISO 8601).
+	ShiftStart time.Time `json:"shift_start"`
+
+	// End of the shift (ISO 8601).
+	ShiftEnd time.Time `json:"shift_end"`
+}
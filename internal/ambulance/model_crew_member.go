@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type CrewMember struct {
+
+	// Unique identifier of the crew member.
+	Id string `json:"id"`
+
+	// Full name of the crew member.
+	Name string `json:"name"`
+
+	// Primary role of the crew member (driver, paramedic, doctor, nurse).
+	Role string `json:"role"`
+
+	// Certifications held by the crew member.
+	Certifications []Certification `json:"certifications,omitempty"`
+}
@@ -0,0 +1,29 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type Department struct {
+
+	// Unique identifier of the department.
+	Id string `json:"id"`
+
+	// Name of the department.
+	Name string `json:"name"`
+
+	// Identifier of the parent department, empty for top-level departments.
+	ParentId string `json:"parent_id,omitempty"`
+
+	// Cost centre the department is accounted under.
+	CostCentre string `json:"cost_centre,omitempty"`
+
+	// Hours the department's ambulances take appointments; the parent department's hours apply when empty.
+	WorkingHours []WorkingHours `json:"working_hours,omitempty"`
+}
@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type DepartmentMigrationResult struct {
+
+	// Number of ambulances linked to a department.
+	AmbulancesUpdated int32 `json:"ambulances_updated"`
+
+	// Departments created from free-text values.
+	DepartmentsCreated []Department `json:"departments_created"`
+}
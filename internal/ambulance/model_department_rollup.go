@@ -0,0 +1,39 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type DepartmentRollup struct {
+
+	// Identifier of the department.
+	DepartmentId string `json:"department_id"`
+
+	// Name of the department.
+	Name string `json:"name"`
+
+	// Cost centre of the department.
+	CostCentre string `json:"cost_centre,omitempty"`
+
+	// Identifiers of the department and all its sub-departments included in the totals.
+	IncludedDepartments []string `json:"included_departments"`
+
+	// Number of ambulances in the department and its sub-departments.
+	Ambulances int32 `json:"ambulances"`
+
+	// Number of procedures performed by those ambulances.
+	Procedures int32 `json:"procedures"`
+
+	// Total price of those procedures.
+	Revenue money.Money `json:"revenue"`
+}
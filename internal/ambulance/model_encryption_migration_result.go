@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type EncryptionMigrationResult struct {
+
+	// Number of patients re-encrypted with the active key.
+	PatientsReencrypted int32 `json:"patients_reencrypted"`
+
+	// Number of procedures re-encrypted with the active key.
+	ProceduresReencrypted int32 `json:"procedures_reencrypted"`
+}
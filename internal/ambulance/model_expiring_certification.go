@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ExpiringCertification struct {
+
+	// Identifier of the crew member holding the certification.
+	CrewMemberId string `json:"crew_member_id"`
+
+	// Name of the crew member holding the certification.
+	CrewMemberName string `json:"crew_member_name"`
+
+	// The expiring certification.
+	Certification Certification `json:"certification"`
+
+	// Whether the certification has already expired.
+	Expired bool `json:"expired"`
+}
@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type FieldNameMigrationResult struct {
+
+	// Number of procedures whose fields were renamed.
+	ProceduresRenamed int32 `json:"procedures_renamed"`
+
+	// Number of payments whose fields were renamed.
+	PaymentsRenamed int32 `json:"payments_renamed"`
+}
@@ -10,7 +10,14 @@
 
 package ambulance
 
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
 type GetAmbulanceSummary200Response struct {
+	AmbulanceId string `json:"ambulance_id,omitempty"`
+
+	TotalCost money.Money `json:"totalCost,omitempty"`
 
-	TotalCost float32 `json:"totalCost,omitempty"`
+	ByCode []ProcedureCodeSummary `json:"by_code,omitempty"`
 }
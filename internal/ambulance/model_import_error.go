@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ImportError struct {
+
+	// Line of the imported file the error refers to, starting at 1.
+	Line int32 `json:"line"`
+
+	// Description of the problem.
+	Message string `json:"message"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ImportLine struct {
+
+	// Line of the imported file holding the record, starting at 1.
+	Line int32 `json:"line"`
+
+	// Identifier of the record, generated when the record has none.
+	Id string `json:"id,omitempty"`
+
+	// Outcome of the record: imported, valid (checked but not stored) or failed.
+	Status string `json:"status"`
+
+	// Problems of a failed record.
+	Errors []string `json:"errors,omitempty"`
+}
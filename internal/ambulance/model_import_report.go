@@ -0,0 +1,32 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ImportReport struct {
+
+	// Whether the records were only validated and nothing was stored.
+	DryRun bool `json:"dry_run"`
+
+	// Whether nothing was to be stored unless every record could be.
+	AllOrNothing bool `json:"all_or_nothing"`
+
+	// Number of records read.
+	Total int32 `json:"total"`
+
+	// Number of records stored.
+	Imported int32 `json:"imported"`
+
+	// Number of records that failed validation or could not be stored.
+	Failed int32 `json:"failed"`
+
+	// Outcome of every record, in the order of the file.
+	Lines []ImportLine `json:"lines"`
+}
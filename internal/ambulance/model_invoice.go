@@ -0,0 +1,80 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type Invoice struct {
+
+	// Unique identifier of the invoice.
+	Id string `json:"id"`
+
+	// Invoice number, assigned without gaps per year when the invoice is issued (e.g., 2025-000042).
+	Number string `json:"number,omitempty"`
+
+	// Year of the invoice number.
+	Year int32 `json:"year,omitempty"`
+
+	// Position of the invoice in the numbering of its year.
+	Sequence int32 `json:"sequence,omitempty"`
+
+	// Status of the invoice (draft, issued, partially_paid, paid, cancelled).
+	Status string `json:"status"`
+
+	// Identifier of the payer billed; the invoice is addressed to the payer's name.
+	PayerId string `json:"payer_id,omitempty"`
+
+	// Name of the party billed.
+	BillTo string `json:"bill_to"`
+
+	// Postal address of the party billed.
+	BillToAddress string `json:"bill_to_address,omitempty"`
+
+	// Invoiced procedures.
+	Lines []InvoiceLine `json:"lines"`
+
+	// Total of the lines before tax.
+	Subtotal money.Money `json:"subtotal"`
+
+	// Total tax of the lines.
+	TaxTotal money.Money `json:"tax_total"`
+
+	// Total of the lines including tax.
+	Total money.Money `json:"total"`
+
+	// Part of the total covered by allocated payments.
+	Paid money.Money `json:"paid"`
+
+	// Part of the total still to be paid.
+	Balance money.Money `json:"balance"`
+
+	// Payments allocated against the invoice, oldest first.
+	Allocations []InvoiceAllocation `json:"allocations,omitempty"`
+
+	// Date the invoice was issued (YYYY-MM-DD).
+	IssueDate string `json:"issue_date,omitempty"`
+
+	// Date the invoice is due (YYYY-MM-DD); by default the payer's payment terms after the issue date.
+	DueDate string `json:"due_date,omitempty"`
+
+	// Notes printed on the invoice.
+	Notes string `json:"notes,omitempty"`
+
+	// Reason the invoice was cancelled.
+	CancellationReason string `json:"cancellation_reason,omitempty"`
+
+	// Date and time the invoice was created.
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,29 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type InvoiceAllocation struct {
+
+	// Identifier of the allocated payment.
+	PaymentId string `json:"payment_id"`
+
+	// Part of the payment allocated against the invoice; as much as the payment and the invoice allow when omitted.
+	Amount money.Money `json:"amount"`
+
+	// Date and time the payment was allocated.
+	AllocatedAt time.Time `json:"allocated_at"`
+}
@@ -0,0 +1,36 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type InvoiceLine struct {
+
+	// Identifier of the invoiced procedure.
+	ProcedureId string `json:"procedure_id"`
+
+	// Description of the line, taken from the procedure.
+	Description string `json:"description,omitempty"`
+
+	// Tax rate of the line in per cent; the configured default rate when omitted.
+	TaxRate *float64 `json:"tax_rate,omitempty"`
+
+	// Price of the procedure before tax.
+	Net money.Money `json:"net"`
+
+	// Tax of the line.
+	Tax money.Money `json:"tax"`
+
+	// Price of the procedure including tax.
+	Total money.Money `json:"total"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type MaintenanceCost struct {
+
+	// Identifier of the department the ambulances belong to.
+	DepartmentId string `json:"department_id"`
+
+	// Name of the department the ambulances belong to.
+	Department string `json:"department"`
+
+	// Number of maintenance log entries.
+	Entries int32 `json:"entries"`
+
+	// Total maintenance cost.
+	TotalCost float32 `json:"total_cost"`
+}
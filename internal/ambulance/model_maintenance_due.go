@@ -0,0 +1,42 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type MaintenanceDue struct {
+
+	// Identifier of the overdue service schedule.
+	ScheduleId string `json:"schedule_id"`
+
+	// Name of the overdue service.
+	ScheduleName string `json:"schedule_name"`
+
+	// Whether the overdue service is a mandatory inspection.
+	Mandatory bool `json:"mandatory"`
+
+	// Identifier of the ambulance.
+	AmbulanceId string `json:"ambulance_id"`
+
+	// Name of the ambulance.
+	AmbulanceName string `json:"ambulance_name"`
+
+	// Date the service was due, when date based (ISO 8601).
+	DueDate *time.Time `json:"due_date,omitempty"`
+
+	// Odometer reading at which the service was due, when mileage based.
+	DueOdometer *int32 `json:"due_odometer,omitempty"`
+
+	// Current odometer reading of the ambulance.
+	Odometer int32 `json:"odometer"`
+}
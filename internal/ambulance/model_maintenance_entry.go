@@ -0,0 +1,45 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type MaintenanceEntry struct {
+
+	// Unique identifier of the maintenance log entry.
+	Id string `json:"id"`
+
+	// Identifier of the serviced ambulance.
+	AmbulanceId string `json:"ambulance_id"`
+
+	// Type of maintenance (e.g., inspection, service, repair, tyres).
+	Type string `json:"type"`
+
+	// Identifier of the service schedule fulfilled by this entry, if any.
+	ScheduleId string `json:"schedule_id,omitempty"`
+
+	// Odometer reading in kilometres at the time of maintenance.
+	Odometer int32 `json:"odometer"`
+
+	// Cost of the maintenance.
+	Cost float32 `json:"cost"`
+
+	// Parts replaced or used.
+	Parts []string `json:"parts,omitempty"`
+
+	// Free-text notes describing the work done and why.
+	Notes string `json:"notes,omitempty"`
+
+	// Date and time of the maintenance (ISO 8601).
+	Timestamp time.Time `json:"timestamp,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type MoneyMigrationResult struct {
+
+	// Number of procedures stored again, including those whose prices were already exact.
+	ProceduresRewritten int32 `json:"procedures_rewritten"`
+
+	// Number of payments stored again, including those whose amounts were already exact.
+	PaymentsRewritten int32 `json:"payments_rewritten"`
+
+	// Number of procedure types stored again, including those whose default prices were already exact.
+	ProcedureTypesRewritten int32 `json:"procedure_types_rewritten"`
+
+	// Number of price lists stored again, including those whose rule prices were already exact.
+	PriceListsRewritten int32 `json:"price_lists_rewritten"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type Patient struct {
+
+	// Unique identifier of the patient record.
+	Id string `json:"id"`
+
+	// National identifier of the patient (e.g., birth number).
+	Identifier string `json:"identifier,omitempty"`
+
+	// Full name of the patient.
+	Name string `json:"name"`
+
+	// Date of birth of the patient (YYYY-MM-DD).
+	DateOfBirth string `json:"date_of_birth"`
+
+	Insurance PatientInsurance `json:"insurance,omitempty"`
+
+	Contact PatientContact `json:"contact,omitempty"`
+}
@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type PatientContact struct {
+
+	// Phone number of the patient.
+	Phone string `json:"phone,omitempty"`
+
+	// E-mail address of the patient.
+	Email string `json:"email,omitempty"`
+
+	// Postal address of the patient.
+	Address string `json:"address,omitempty"`
+}
@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type PatientInsurance struct {
+
+	// Health insurance company the patient is affiliated with.
+	Insurer string `json:"insurer,omitempty"`
+
+	// Insurance number of the patient.
+	PolicyNumber string `json:"policy_number,omitempty"`
+}
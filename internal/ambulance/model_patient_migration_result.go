@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type PatientMigrationResult struct {
+
+	// Number of procedures linked to a patient record.
+	ProceduresLinked int32 `json:"procedures_linked"`
+
+	// Identifiers of procedures whose patient could not be matched to exactly one patient record.
+	Unmatched []string `json:"unmatched"`
+}
@@ -0,0 +1,31 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type Payer struct {
+
+	// Unique identifier of the payer.
+	Id string `json:"id"`
+
+	// Short code of the payer, such as the insurer code used on claims.
+	Code string `json:"code,omitempty"`
+
+	// Name of the payer.
+	Name string `json:"name"`
+
+	// Type of the payer (insurer, self, employer).
+	Type string `json:"type"`
+
+	ContractTerms *PayerContractTerms `json:"contract_terms,omitempty"`
+
+	// Catalog procedures the payer covers and the share of their price it pays.
+	Coverage []CoverageRule `json:"coverage,omitempty"`
+}
@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type PayerContractTerms struct {
+
+	// Number of the contract with the payer.
+	ContractNumber string `json:"contract_number,omitempty"`
+
+	// First day the contract applies (YYYY-MM-DD); procedures before it are not covered.
+	ValidFrom string `json:"valid_from,omitempty"`
+
+	// Last day the contract applies (YYYY-MM-DD); procedures after it are not covered.
+	ValidTo string `json:"valid_to,omitempty"`
+
+	// Number of days the payer has to pay an invoice or claim.
+	PaymentTermsDays int32 `json:"payment_terms_days,omitempty"`
+}
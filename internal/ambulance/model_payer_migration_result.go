@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type PayerMigrationResult struct {
+
+	// Number of procedures linked to a payer.
+	ProceduresLinked int32 `json:"procedures_linked"`
+
+	// Number of payments linked to a payer.
+	PaymentsLinked int32 `json:"payments_linked"`
+
+	// Identifiers of procedures whose payer could not be matched to exactly one payer.
+	UnmatchedProcedures []string `json:"unmatched_procedures"`
+
+	// Identifiers of payments whose insurance could not be matched to exactly one payer.
+	UnmatchedPayments []string `json:"unmatched_payments"`
+}
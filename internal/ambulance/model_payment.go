@@ -12,6 +12,8 @@ package ambulance
 
 import (
 	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
 )
 
 type Payment struct {
@@ -28,11 +30,30 @@ type Payment struct {
 	// Identifier of the related procedure.
 	ProcedureId string `json:"procedure_id"`
 
-	// Insurance or payer for the procedure.
+	// Identifier of the payer making the payment.
+	PayerId string `json:"payer_id,omitempty"`
+
+	// Insurance or payer for the procedure. Filled from the payer registry; older payments may hold a free-text payer.
 	Insurance string `json:"insurance"`
 
 	// Payment amount.
-	Amount float32 `json:"amount"`
+	Amount money.Money `json:"amount"`
+
+	// Status of the payment (pending, authorized, settled, failed, refunded).
+	// Payments recorded before statuses were introduced have none and count as settled.
+	Status string `json:"status,omitempty"`
+
+	// Total amount refunded so far. Payments refunded before it was recorded have none.
+	Refunded *money.Money `json:"refunded,omitempty"`
+
+	// Identifier of the payment this entry refunds; refunds carry a negative amount.
+	RefundOf string `json:"refund_of,omitempty"`
+
+	// Reason for the refund.
+	Reason string `json:"reason,omitempty"`
+
+	// Identifier of the insurance claim whose acceptance created the payment.
+	ClaimId string `json:"claim_id,omitempty"`
 
 	// Date and time when the payment was made (ISO 8601).
 	Timestamp time.Time `json:"timestamp,omitempty"`
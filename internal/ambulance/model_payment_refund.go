@@ -0,0 +1,24 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type PaymentRefund struct {
+
+	// Amount to refund; the whole amount not yet refunded when omitted.
+	Amount money.Money `json:"amount"`
+
+	// Reason for the refund.
+	Reason string `json:"reason"`
+}
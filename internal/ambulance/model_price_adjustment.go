@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type PriceAdjustment struct {
+
+	// Identifier of the rule that produced the adjustment.
+	RuleId string `json:"rule_id"`
+
+	// Description of the rule.
+	Description string `json:"description,omitempty"`
+
+	// Surcharge in percent of the base price.
+	Percent float32 `json:"percent"`
+
+	// Amount added to the base price.
+	Amount money.Money `json:"amount"`
+}
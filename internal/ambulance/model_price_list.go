@@ -0,0 +1,26 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type PriceList struct {
+
+	// Unique identifier of the price list.
+	Id string `json:"id"`
+
+	// Name of the price list.
+	Name string `json:"name"`
+
+	// Date from which the price list applies (YYYY-MM-DD); it applies until the next price list takes effect.
+	EffectiveFrom string `json:"effective_from"`
+
+	// Pricing rules of the price list.
+	Rules []PriceRule `json:"rules"`
+}
@@ -0,0 +1,36 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type PriceQuote struct {
+
+	// Identifier of the price list the price was computed from; empty when only the catalog price applied.
+	PriceListId string `json:"price_list_id,omitempty"`
+
+	// Date from which the price list applies.
+	EffectiveFrom string `json:"effective_from,omitempty"`
+
+	// Identifier of the rule that set the base price, or "catalog" for the catalog default price.
+	BaseRuleId string `json:"base_rule_id"`
+
+	// Base price before surcharges.
+	BasePrice money.Money `json:"base_price"`
+
+	// Surcharges and discounts applied to the base price.
+	Adjustments []PriceAdjustment `json:"adjustments"`
+
+	// Computed price.
+	Price money.Money `json:"price"`
+}
@@ -0,0 +1,48 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type PriceRule struct {
+
+	// Identifier of the rule, unique within its price list.
+	Id string `json:"id"`
+
+	// Description of the rule.
+	Description string `json:"description,omitempty"`
+
+	// Catalog code the rule applies to; any procedure when empty.
+	Code string `json:"code,omitempty"`
+
+	// Payer the rule applies to; any payer when empty.
+	Payer string `json:"payer,omitempty"`
+
+	// Visit type the rule applies to; any visit type when empty.
+	VisitType string `json:"visit_type,omitempty"`
+
+	// Days of the week the rule applies on (e.g., saturday); every day when empty.
+	Days []string `json:"days,omitempty"`
+
+	// Start of the time of day the rule applies at (HH:MM); the whole day when empty.
+	TimeFrom string `json:"time_from,omitempty"`
+
+	// End of the time of day the rule applies at (HH:MM), exclusive; may be before time_from to span midnight.
+	TimeTo string `json:"time_to,omitempty"`
+
+	// Base price set by the rule.
+	Price *money.Money `json:"price,omitempty"`
+
+	// Surcharge added by the rule, in percent of the base price; negative for discounts.
+	SurchargePercent float32 `json:"surcharge_percent,omitempty"`
+}
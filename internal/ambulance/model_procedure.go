@@ -12,6 +12,8 @@ package ambulance
 
 import (
 	"time"
+
+	"github.com/wac-project/wac-api/internal/money"
 )
 
 type Procedure struct {
@@ -19,27 +21,64 @@ type Procedure struct {
 	// Unique identifier of the procedure.
 	Id string `json:"id"`
 
+	// Catalog code of the procedure type.
+	Code string `json:"code,omitempty"`
+
 	// Name of the procedure.
 	Name string `json:"name"`
 
 	// Description of the procedure.
 	Description string `json:"description"`
 
-	// Name or identifier of the patient.
+	// Identifier of the patient record the procedure was performed on.
+	PatientId string `json:"patient_id,omitempty"`
+
+	// Name of the patient. Filled from the patient record; older procedures may hold a free-text name or identifier.
 	Patient string `json:"patient"`
 
 	// Type of visit (e.g., emergency, follow-up).
 	VisitType string `json:"visit_type"`
 
 	// Price of the procedure.
-	Price float32 `json:"price"`
+	Price money.Money `json:"price"`
+
+	// How the pricing engine computed the price.
+	Pricing *PriceQuote `json:"pricing,omitempty"`
+
+	// Reason the price differs from the computed price; required for manual overrides.
+	PriceOverrideReason string `json:"price_override_reason,omitempty"`
+
+	// Price still to be paid: the price less settled payments, negative when overpaid. Computed, not stored.
+	Balance *money.Money `json:"balance,omitempty" bson:"-"`
+
+	// Payment status computed from settled payments (unpaid, partially_paid, paid, overpaid). Computed, not stored.
+	PaymentStatus string `json:"payment_status,omitempty" bson:"-"`
 
-	// Payer for the procedure.
+	// Identifier of the payer of the procedure.
+	PayerId string `json:"payer_id,omitempty"`
+
+	// Name of the payer. Filled from the payer registry; older procedures may hold a free-text payer.
 	Payer string `json:"payer"`
 
 	// Identifier of the ambulance associated with the procedure.
 	AmbulanceId string `json:"ambulance_id"`
 
+	// Duration of the procedure in minutes.
+	Duration int32 `json:"duration,omitempty"`
+
 	// Date and time of the procedure (ISO 8601).
 	Timestamp time.Time `json:"timestamp,omitempty"`
+
+	// Lifecycle status of the procedure (scheduled, in_progress, completed, cancelled, billed).
+	// Procedures recorded before statuses were introduced have none and count as completed.
+	Status string `json:"status,omitempty"`
+
+	// Status changes of the procedure, oldest first.
+	StatusHistory []ProcedureStatusChange `json:"status_history,omitempty"`
+
+	// Clinical notes on the procedure, oldest first.
+	Notes []ClinicalNote `json:"notes,omitempty"`
+
+	// Files attached to the procedure, such as X-ray images and PDF reports.
+	Attachments []Attachment `json:"attachments,omitempty"`
 }
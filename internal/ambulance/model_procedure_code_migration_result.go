@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ProcedureCodeMigrationResult struct {
+
+	// Number of procedures assigned a catalog code.
+	ProceduresLinked int32 `json:"procedures_linked"`
+
+	// Identifiers of procedures whose name matches no procedure type in the catalog.
+	Unmatched []string `json:"unmatched"`
+}
@@ -0,0 +1,30 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type ProcedureCodeSummary struct {
+
+	// Catalog code of the procedures, empty for procedures without a code.
+	Code string `json:"code"`
+
+	// Name of the procedure type in the catalog.
+	Name string `json:"name"`
+
+	// Number of procedures.
+	Count int32 `json:"count"`
+
+	// Total price of the procedures.
+	Total money.Money `json:"total"`
+}
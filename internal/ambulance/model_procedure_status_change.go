@@ -0,0 +1,33 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type ProcedureStatusChange struct {
+
+	// Status the procedure moved to (scheduled, in_progress, completed, cancelled, billed).
+	Status string `json:"status"`
+
+	// Date and time of the change (ISO 8601); set by the server.
+	ChangedAt time.Time `json:"changed_at,omitempty"`
+
+	// Appointment time the procedure was scheduled for, on changes to scheduled.
+	ScheduledFor *time.Time `json:"scheduled_for,omitempty"`
+
+	// Role of the user who made the change, from the X-User-Role header.
+	ChangedBy string `json:"changed_by,omitempty"`
+
+	// Reason or remark for the change.
+	Note string `json:"note,omitempty"`
+}
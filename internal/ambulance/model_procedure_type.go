@@ -0,0 +1,39 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type ProcedureType struct {
+
+	// Unique identifier of the procedure type; equal to its code.
+	Id string `json:"id"`
+
+	// Catalog code of the procedure type.
+	Code string `json:"code"`
+
+	// Name of the procedure type.
+	Name string `json:"name"`
+
+	// Description of the procedure type.
+	Description string `json:"description,omitempty"`
+
+	// Standard price of the procedure.
+	DefaultPrice money.Money `json:"default_price"`
+
+	// Standard duration of the procedure in minutes.
+	DefaultDuration int32 `json:"default_duration,omitempty"`
+
+	// Visit types the procedure may be performed in; any visit type when empty.
+	VisitTypes []string `json:"visit_types,omitempty"`
+}
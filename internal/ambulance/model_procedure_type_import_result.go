@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ProcedureTypeImportResult struct {
+
+	// Number of procedure types added to the catalog.
+	Created int32 `json:"created"`
+
+	// Number of existing procedure types replaced.
+	Updated int32 `json:"updated"`
+
+	// Problems found in the file; nothing is imported when there are any.
+	Errors []ImportError `json:"errors"`
+}
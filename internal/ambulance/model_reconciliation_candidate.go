@@ -0,0 +1,33 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type ReconciliationCandidate struct {
+
+	// Identifier of the open procedure, for procedures not invoiced.
+	ProcedureId string `json:"procedure_id,omitempty"`
+
+	// Identifier of the open invoice.
+	InvoiceId string `json:"invoice_id,omitempty"`
+
+	// Identifier of the payer of the procedure or invoice.
+	PayerId string `json:"payer_id,omitempty"`
+
+	// Amount still owed.
+	Balance money.Money `json:"balance"`
+
+	// What matched the bank line: reference, amount and/or payer.
+	Reasons []string `json:"reasons"`
+}
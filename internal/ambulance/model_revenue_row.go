@@ -0,0 +1,34 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"github.com/wac-project/wac-api/internal/money"
+)
+
+type RevenueRow struct {
+
+	// Group of the row: the first day of the period for day, week and month grouping, the
+	// identifier of the ambulance, department or payer, or the visit type otherwise.
+	Key string `json:"key"`
+
+	// Readable name of the group, such as 2025-W23 or the name of the department.
+	Label string `json:"label"`
+
+	// Number of completed and billed procedures performed in the group.
+	Procedures int32 `json:"procedures"`
+
+	// Prices of the completed and billed procedures performed in the group.
+	Billed money.Money `json:"billed"`
+
+	// Settled payments less refunds received in the group.
+	Collected money.Money `json:"collected"`
+}
@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type RosterCrewMember struct {
+
+	// Identifier of the crew member.
+	CrewMemberId string `json:"crew_member_id"`
+
+	// Name of the crew member.
+	Name string `json:"name"`
+
+	// Role of the crew member on the shift.
+	Role string `json:"role"`
+}
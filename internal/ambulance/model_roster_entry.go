@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type RosterEntry struct {
+
+	// The scheduled shift.
+	Shift Shift `json:"shift"`
+
+	// Name of the ambulance on duty.
+	AmbulanceName string `json:"ambulance_name"`
+
+	// Crew members working the shift, with their names.
+	Crew []RosterCrewMember `json:"crew"`
+}
@@ -0,0 +1,45 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type ServiceSchedule struct {
+
+	// Unique identifier of the service schedule.
+	Id string `json:"id"`
+
+	// Identifier of the ambulance the schedule applies to.
+	AmbulanceId string `json:"ambulance_id"`
+
+	// Name of the recurring service (e.g., Annual technical inspection).
+	Name string `json:"name"`
+
+	// Type of maintenance logged when the service is performed.
+	Type string `json:"type"`
+
+	// Whether the service is a mandatory inspection; overdue mandatory inspections take the ambulance out of service.
+	Mandatory bool `json:"mandatory"`
+
+	// Service interval in kilometres (0 when not mileage based).
+	IntervalKm int32 `json:"interval_km,omitempty"`
+
+	// Service interval in days (0 when not date based).
+	IntervalDays int32 `json:"interval_days,omitempty"`
+
+	// Odometer reading at the last service.
+	LastServiceOdometer int32 `json:"last_service_odometer"`
+
+	// Date and time of the last service (ISO 8601).
+	LastServiceDate time.Time `json:"last_service_date"`
+}
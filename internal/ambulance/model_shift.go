@@ -0,0 +1,36 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+import (
+	"time"
+)
+
+type Shift struct {
+
+	// Unique identifier of the shift.
+	Id string `json:"id"`
+
+	// Identifier of the ambulance on duty during the shift.
+	AmbulanceId string `json:"ambulance_id"`
+
+	// Start of the shift (ISO 8601).
+	Start time.Time `json:"start"`
+
+	// End of the shift (ISO 8601).
+	End time.Time `json:"end"`
+
+	// Crew members working the shift.
+	Crew []ShiftCrewMember `json:"crew"`
+
+	// Free-text notes for the shift.
+	Notes string `json:"notes,omitempty"`
+}
@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ShiftConflict struct {
+
+	// Identifier of the conflicting shift; empty when a crew assignment of an ambulance conflicts.
+	ShiftId string `json:"shift_id,omitempty"`
+
+	// Identifier of the double-booked ambulance, or of the ambulance whose crew assignment conflicts.
+	AmbulanceId string `json:"ambulance_id,omitempty"`
+
+	// Identifier of the double-booked crew member, if any.
+	CrewMemberId string `json:"crew_member_id,omitempty"`
+}
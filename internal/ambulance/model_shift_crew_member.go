@@ -0,0 +1,20 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type ShiftCrewMember struct {
+
+	// Identifier of the crew member.
+	CrewMemberId string `json:"crew_member_id"`
+
+	// Role of the crew member on the shift (driver, paramedic, doctor, nurse).
+	Role string `json:"role"`
+}
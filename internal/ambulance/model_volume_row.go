@@ -0,0 +1,33 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type VolumeRow struct {
+
+	// Group of the row: the first day of the period for day, week and month grouping, the
+	// identifier of the ambulance, department or payer, or the visit type otherwise.
+	Key string `json:"key"`
+
+	// Readable name of the group, such as 2025-W23 or the name of the department.
+	Label string `json:"label"`
+
+	// Number of procedures in the group.
+	Procedures int32 `json:"procedures"`
+
+	// Number of completed and billed procedures.
+	Completed int32 `json:"completed"`
+
+	// Number of scheduled and in-progress procedures.
+	Scheduled int32 `json:"scheduled"`
+
+	// Number of cancelled procedures.
+	Cancelled int32 `json:"cancelled"`
+}
@@ -0,0 +1,23 @@
+/*
+ * Hospital Management API
+ *
+ * API for managing hospital ambulances, procedures, and payments.
+ *
+ * API version: 1.0.0
+ * Contact: xkokavecs@stuba.sk
+ * Generated by: OpenAPI Generator (https://openapi-generator.tech)
+ */
+
+package ambulance
+
+type WorkingHours struct {
+
+	// Days of the week the hours apply on; every day when empty.
+	Days []string `json:"days,omitempty"`
+
+	// Start of the working time of day (HH:MM).
+	From string `json:"from"`
+
+	// End of the working time of day (HH:MM), exclusive.
+	To string `json:"to"`
+}
@@ -12,6 +12,7 @@ package ambulance
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -35,10 +36,26 @@ func NewRouter(handleFunctions ApiHandleFunctions) *gin.Engine {
 
 // NewRouter add routes to existing gin engine.
 func NewRouterWithGinEngine(router *gin.Engine, handleFunctions ApiHandleFunctions) *gin.Engine {
+	type resource struct{ method, pattern string }
+	customMethods := map[resource]map[string]gin.HandlerFunc{}
+	// the operations of a batch run through the routes of the router itself
+	router.Use(func(c *gin.Context) {
+		c.Set(batchRouterKey, router)
+	})
 	for _, route := range getRoutes(handleFunctions) {
 		if route.HandlerFunc == nil {
 			route.HandlerFunc = DefaultHandleFunc
 		}
+		// gin reads the ":quote" of "/api/procedures:quote" as a path parameter, so custom
+		// methods of a resource share one route that dispatches on the parameter
+		if i := strings.LastIndex(route.Pattern, ":"); i > 0 && route.Pattern[i-1] != '/' {
+			key := resource{route.Method, route.Pattern[:i] + ":action"}
+			if customMethods[key] == nil {
+				customMethods[key] = map[string]gin.HandlerFunc{}
+			}
+			customMethods[key][route.Pattern[i:]] = route.HandlerFunc
+			continue
+		}
 		switch route.Method {
 		case http.MethodGet:
 			router.GET(route.Pattern, route.HandlerFunc)
@@ -52,10 +69,25 @@ func NewRouterWithGinEngine(router *gin.Engine, handleFunctions ApiHandleFunctio
 			router.DELETE(route.Pattern, route.HandlerFunc)
 		}
 	}
+	for key, handlers := range customMethods {
+		router.Handle(key.method, key.pattern, dispatchCustomMethod(handlers))
+	}
 
 	return router
 }
 
+// dispatchCustomMethod returns a handler calling the custom method named by the action path parameter.
+func dispatchCustomMethod(handlers map[string]gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		handler, ok := handlers[c.Param("action")]
+		if !ok {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+			return
+		}
+		handler(c)
+	}
+}
+
 // Default handler for not yet implemented routes
 func DefaultHandleFunc(c *gin.Context) {
 	c.String(http.StatusNotImplemented, "501 not implemented")
@@ -65,14 +97,52 @@ type ApiHandleFunctions struct {
 
 	// Routes for the AmbulanceManagementAPI part of the API
 	AmbulanceManagementAPI AmbulanceManagementAPI
+	// Routes for the BankReconciliationAPI part of the API
+	BankReconciliationAPI BankReconciliationAPI
+	// Routes for the BatchAPI part of the API
+	BatchAPI BatchAPI
+	// Routes for the ClaimsAPI part of the API
+	ClaimsAPI ClaimsAPI
+	// Routes for the ClinicalRecordsAPI part of the API
+	ClinicalRecordsAPI ClinicalRecordsAPI
+	// Routes for the CrewManagementAPI part of the API
+	CrewManagementAPI CrewManagementAPI
+	// Routes for the DepartmentManagementAPI part of the API
+	DepartmentManagementAPI DepartmentManagementAPI
+	// Routes for the InvoicesAPI part of the API
+	InvoicesAPI InvoicesAPI
+	// Routes for the MaintenanceManagementAPI part of the API
+	MaintenanceManagementAPI MaintenanceManagementAPI
+	// Routes for the MigrationsAPI part of the API
+	MigrationsAPI MigrationsAPI
+	// Routes for the PatientManagementAPI part of the API
+	PatientManagementAPI PatientManagementAPI
 	// Routes for the PaymentManagementAPI part of the API
 	PaymentManagementAPI PaymentManagementAPI
+	// Routes for the PayerManagementAPI part of the API
+	PayerManagementAPI PayerManagementAPI
+	// Routes for the PricingAPI part of the API
+	PricingAPI PricingAPI
+	// Routes for the ProcedureCatalogAPI part of the API
+	ProcedureCatalogAPI ProcedureCatalogAPI
 	// Routes for the ProcedureManagementAPI part of the API
 	ProcedureManagementAPI ProcedureManagementAPI
+	// Routes for the ReportsAPI part of the API
+	ReportsAPI ReportsAPI
+	// Routes for the SchedulingAPI part of the API
+	SchedulingAPI SchedulingAPI
+	// Routes for the ShiftManagementAPI part of the API
+	ShiftManagementAPI ShiftManagementAPI
 }
 
 func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 	return []Route{
+		{
+			"AssignAmbulanceCrew",
+			http.MethodPut,
+			"/api/ambulances/:ambulanceId/crew",
+			handleFunctions.AmbulanceManagementAPI.AssignAmbulanceCrew,
+		},
 		{
 			"CreateAmbulance",
 			http.MethodPost,
@@ -109,12 +179,432 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/api/ambulances/:ambulanceId/procedures",
 			handleFunctions.AmbulanceManagementAPI.GetProceduresByAmbulance,
 		},
+		{
+			"ImportAmbulances",
+			http.MethodPost,
+			"/api/ambulances:import",
+			handleFunctions.AmbulanceManagementAPI.ImportAmbulances,
+		},
 		{
 			"UpdateAmbulance",
 			http.MethodPut,
 			"/api/ambulances/:ambulanceId",
 			handleFunctions.AmbulanceManagementAPI.UpdateAmbulance,
 		},
+		{
+			"ConfirmBankStatement",
+			http.MethodPost,
+			"/api/bank-statements/:statementId/confirmation",
+			handleFunctions.BankReconciliationAPI.ConfirmBankStatement,
+		},
+		{
+			"ConfirmBankStatementLine",
+			http.MethodPost,
+			"/api/bank-statements/:statementId/lines/:lineNumber/confirmation",
+			handleFunctions.BankReconciliationAPI.ConfirmBankStatementLine,
+		},
+		{
+			"DismissBankStatementLine",
+			http.MethodPost,
+			"/api/bank-statements/:statementId/lines/:lineNumber/dismissal",
+			handleFunctions.BankReconciliationAPI.DismissBankStatementLine,
+		},
+		{
+			"GetBankStatementById",
+			http.MethodGet,
+			"/api/bank-statements/:statementId",
+			handleFunctions.BankReconciliationAPI.GetBankStatementById,
+		},
+		{
+			"GetBankStatements",
+			http.MethodGet,
+			"/api/bank-statements",
+			handleFunctions.BankReconciliationAPI.GetBankStatements,
+		},
+		{
+			"GetReconciliationQueue",
+			http.MethodGet,
+			"/api/reconciliation/queue",
+			handleFunctions.BankReconciliationAPI.GetReconciliationQueue,
+		},
+		{
+			"ImportBankStatement",
+			http.MethodPost,
+			"/api/bank-statements",
+			handleFunctions.BankReconciliationAPI.ImportBankStatement,
+		},
+		{
+			"ExecuteBatch",
+			http.MethodPost,
+			"/api/batch",
+			handleFunctions.BatchAPI.ExecuteBatch,
+		},
+		{
+			"CreateClaim",
+			http.MethodPost,
+			"/api/claims",
+			handleFunctions.ClaimsAPI.CreateClaim,
+		},
+		{
+			"DecideClaim",
+			http.MethodPost,
+			"/api/claims/:claimId/decision",
+			handleFunctions.ClaimsAPI.DecideClaim,
+		},
+		{
+			"DeleteClaim",
+			http.MethodDelete,
+			"/api/claims/:claimId",
+			handleFunctions.ClaimsAPI.DeleteClaim,
+		},
+		{
+			"GetClaimAgingReport",
+			http.MethodGet,
+			"/api/reports/claims/aging",
+			handleFunctions.ClaimsAPI.GetClaimAgingReport,
+		},
+		{
+			"GetClaimById",
+			http.MethodGet,
+			"/api/claims/:claimId",
+			handleFunctions.ClaimsAPI.GetClaimById,
+		},
+		{
+			"GetClaimRejectionReport",
+			http.MethodGet,
+			"/api/reports/claims/rejections",
+			handleFunctions.ClaimsAPI.GetClaimRejectionReport,
+		},
+		{
+			"GetClaims",
+			http.MethodGet,
+			"/api/claims",
+			handleFunctions.ClaimsAPI.GetClaims,
+		},
+		{
+			"SettleClaim",
+			http.MethodPost,
+			"/api/claims/:claimId/settlement",
+			handleFunctions.ClaimsAPI.SettleClaim,
+		},
+		{
+			"SubmitClaim",
+			http.MethodPost,
+			"/api/claims/:claimId/submission",
+			handleFunctions.ClaimsAPI.SubmitClaim,
+		},
+		{
+			"UpdateClaim",
+			http.MethodPut,
+			"/api/claims/:claimId",
+			handleFunctions.ClaimsAPI.UpdateClaim,
+		},
+		{
+			"CreateProcedureNote",
+			http.MethodPost,
+			"/api/procedures/:procedureId/notes",
+			handleFunctions.ClinicalRecordsAPI.CreateProcedureNote,
+		},
+		{
+			"DeleteAttachment",
+			http.MethodDelete,
+			"/api/procedures/:procedureId/attachments/:attachmentId",
+			handleFunctions.ClinicalRecordsAPI.DeleteAttachment,
+		},
+		{
+			"DeleteProcedureNote",
+			http.MethodDelete,
+			"/api/procedures/:procedureId/notes/:noteId",
+			handleFunctions.ClinicalRecordsAPI.DeleteProcedureNote,
+		},
+		{
+			"DownloadAttachment",
+			http.MethodGet,
+			"/api/procedures/:procedureId/attachments/:attachmentId",
+			handleFunctions.ClinicalRecordsAPI.DownloadAttachment,
+		},
+		{
+			"GetAttachments",
+			http.MethodGet,
+			"/api/procedures/:procedureId/attachments",
+			handleFunctions.ClinicalRecordsAPI.GetAttachments,
+		},
+		{
+			"GetProcedureNotes",
+			http.MethodGet,
+			"/api/procedures/:procedureId/notes",
+			handleFunctions.ClinicalRecordsAPI.GetProcedureNotes,
+		},
+		{
+			"UploadAttachment",
+			http.MethodPost,
+			"/api/procedures/:procedureId/attachments",
+			handleFunctions.ClinicalRecordsAPI.UploadAttachment,
+		},
+		{
+			"VerifyAttachment",
+			http.MethodGet,
+			"/api/procedures/:procedureId/attachments/:attachmentId/checksum",
+			handleFunctions.ClinicalRecordsAPI.VerifyAttachment,
+		},
+		{
+			"CreateCrewMember",
+			http.MethodPost,
+			"/api/crew",
+			handleFunctions.CrewManagementAPI.CreateCrewMember,
+		},
+		{
+			"DeleteCrewMember",
+			http.MethodDelete,
+			"/api/crew/:crewMemberId",
+			handleFunctions.CrewManagementAPI.DeleteCrewMember,
+		},
+		{
+			"GetCrewMemberById",
+			http.MethodGet,
+			"/api/crew/:crewMemberId",
+			handleFunctions.CrewManagementAPI.GetCrewMemberById,
+		},
+		{
+			"GetCrewMembers",
+			http.MethodGet,
+			"/api/crew",
+			handleFunctions.CrewManagementAPI.GetCrewMembers,
+		},
+		{
+			"GetExpiringCertifications",
+			http.MethodGet,
+			"/api/certifications/expiring",
+			handleFunctions.CrewManagementAPI.GetExpiringCertifications,
+		},
+		{
+			"UpdateCrewMember",
+			http.MethodPut,
+			"/api/crew/:crewMemberId",
+			handleFunctions.CrewManagementAPI.UpdateCrewMember,
+		},
+		{
+			"CreateDepartment",
+			http.MethodPost,
+			"/api/departments",
+			handleFunctions.DepartmentManagementAPI.CreateDepartment,
+		},
+		{
+			"DeleteDepartment",
+			http.MethodDelete,
+			"/api/departments/:departmentId",
+			handleFunctions.DepartmentManagementAPI.DeleteDepartment,
+		},
+		{
+			"GetDepartmentById",
+			http.MethodGet,
+			"/api/departments/:departmentId",
+			handleFunctions.DepartmentManagementAPI.GetDepartmentById,
+		},
+		{
+			"GetDepartmentReport",
+			http.MethodGet,
+			"/api/reports/departments",
+			handleFunctions.DepartmentManagementAPI.GetDepartmentReport,
+		},
+		{
+			"GetDepartmentRollup",
+			http.MethodGet,
+			"/api/departments/:departmentId/rollup",
+			handleFunctions.DepartmentManagementAPI.GetDepartmentRollup,
+		},
+		{
+			"GetDepartments",
+			http.MethodGet,
+			"/api/departments",
+			handleFunctions.DepartmentManagementAPI.GetDepartments,
+		},
+		{
+			"UpdateDepartment",
+			http.MethodPut,
+			"/api/departments/:departmentId",
+			handleFunctions.DepartmentManagementAPI.UpdateDepartment,
+		},
+		{
+			"AllocatePayment",
+			http.MethodPost,
+			"/api/invoices/:invoiceId/allocations",
+			handleFunctions.InvoicesAPI.AllocatePayment,
+		},
+		{
+			"CancelInvoice",
+			http.MethodPost,
+			"/api/invoices/:invoiceId/cancellation",
+			handleFunctions.InvoicesAPI.CancelInvoice,
+		},
+		{
+			"CreateInvoice",
+			http.MethodPost,
+			"/api/invoices",
+			handleFunctions.InvoicesAPI.CreateInvoice,
+		},
+		{
+			"DeleteInvoice",
+			http.MethodDelete,
+			"/api/invoices/:invoiceId",
+			handleFunctions.InvoicesAPI.DeleteInvoice,
+		},
+		{
+			"GetInvoiceById",
+			http.MethodGet,
+			"/api/invoices/:invoiceId",
+			handleFunctions.InvoicesAPI.GetInvoiceById,
+		},
+		{
+			"GetInvoices",
+			http.MethodGet,
+			"/api/invoices",
+			handleFunctions.InvoicesAPI.GetInvoices,
+		},
+		{
+			"IssueInvoice",
+			http.MethodPost,
+			"/api/invoices/:invoiceId/issuance",
+			handleFunctions.InvoicesAPI.IssueInvoice,
+		},
+		{
+			"UpdateInvoice",
+			http.MethodPut,
+			"/api/invoices/:invoiceId",
+			handleFunctions.InvoicesAPI.UpdateInvoice,
+		},
+		{
+			"CreateMaintenanceEntry",
+			http.MethodPost,
+			"/api/ambulances/:ambulanceId/maintenance",
+			handleFunctions.MaintenanceManagementAPI.CreateMaintenanceEntry,
+		},
+		{
+			"CreateServiceSchedule",
+			http.MethodPost,
+			"/api/ambulances/:ambulanceId/service-schedules",
+			handleFunctions.MaintenanceManagementAPI.CreateServiceSchedule,
+		},
+		{
+			"DeleteMaintenanceEntry",
+			http.MethodDelete,
+			"/api/maintenance/:entryId",
+			handleFunctions.MaintenanceManagementAPI.DeleteMaintenanceEntry,
+		},
+		{
+			"DeleteServiceSchedule",
+			http.MethodDelete,
+			"/api/service-schedules/:scheduleId",
+			handleFunctions.MaintenanceManagementAPI.DeleteServiceSchedule,
+		},
+		{
+			"GetMaintenanceCostReport",
+			http.MethodGet,
+			"/api/reports/maintenance-costs",
+			handleFunctions.MaintenanceManagementAPI.GetMaintenanceCostReport,
+		},
+		{
+			"GetMaintenanceDue",
+			http.MethodGet,
+			"/api/maintenance/due",
+			handleFunctions.MaintenanceManagementAPI.GetMaintenanceDue,
+		},
+		{
+			"GetMaintenanceEntries",
+			http.MethodGet,
+			"/api/ambulances/:ambulanceId/maintenance",
+			handleFunctions.MaintenanceManagementAPI.GetMaintenanceEntries,
+		},
+		{
+			"GetServiceSchedules",
+			http.MethodGet,
+			"/api/ambulances/:ambulanceId/service-schedules",
+			handleFunctions.MaintenanceManagementAPI.GetServiceSchedules,
+		},
+		{
+			"UpdateServiceSchedule",
+			http.MethodPut,
+			"/api/service-schedules/:scheduleId",
+			handleFunctions.MaintenanceManagementAPI.UpdateServiceSchedule,
+		},
+		{
+			"MigrateDepartments",
+			http.MethodPost,
+			"/api/migrations/departments",
+			handleFunctions.MigrationsAPI.MigrateDepartments,
+		},
+		{
+			"MigrateEncryption",
+			http.MethodPost,
+			"/api/migrations/encryption",
+			handleFunctions.MigrationsAPI.MigrateEncryption,
+		},
+		{
+			"MigrateFieldNames",
+			http.MethodPost,
+			"/api/migrations/field-names",
+			handleFunctions.MigrationsAPI.MigrateFieldNames,
+		},
+		{
+			"MigrateMoney",
+			http.MethodPost,
+			"/api/migrations/money",
+			handleFunctions.MigrationsAPI.MigrateMoney,
+		},
+		{
+			"MigrateProcedureCodes",
+			http.MethodPost,
+			"/api/migrations/procedure-codes",
+			handleFunctions.MigrationsAPI.MigrateProcedureCodes,
+		},
+		{
+			"MigratePatients",
+			http.MethodPost,
+			"/api/migrations/patients",
+			handleFunctions.MigrationsAPI.MigratePatients,
+		},
+		{
+			"MigratePayers",
+			http.MethodPost,
+			"/api/migrations/payers",
+			handleFunctions.MigrationsAPI.MigratePayers,
+		},
+		{
+			"CreatePatient",
+			http.MethodPost,
+			"/api/patients",
+			handleFunctions.PatientManagementAPI.CreatePatient,
+		},
+		{
+			"DeletePatient",
+			http.MethodDelete,
+			"/api/patients/:patientId",
+			handleFunctions.PatientManagementAPI.DeletePatient,
+		},
+		{
+			"GetPatientById",
+			http.MethodGet,
+			"/api/patients/:patientId",
+			handleFunctions.PatientManagementAPI.GetPatientById,
+		},
+		{
+			"GetPatientProcedures",
+			http.MethodGet,
+			"/api/patients/:patientId/procedures",
+			handleFunctions.PatientManagementAPI.GetPatientProcedures,
+		},
+		{
+			"GetPatients",
+			http.MethodGet,
+			"/api/patients",
+			handleFunctions.PatientManagementAPI.GetPatients,
+		},
+		{
+			"UpdatePatient",
+			http.MethodPut,
+			"/api/patients/:patientId",
+			handleFunctions.PatientManagementAPI.UpdatePatient,
+		},
 		{
 			"CreatePayment",
 			http.MethodPost,
@@ -139,12 +629,144 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/api/payments",
 			handleFunctions.PaymentManagementAPI.GetPayments,
 		},
+		{
+			"ImportPayments",
+			http.MethodPost,
+			"/api/payments:import",
+			handleFunctions.PaymentManagementAPI.ImportPayments,
+		},
+		{
+			"RefundPayment",
+			http.MethodPost,
+			"/api/payments/:paymentId/refunds",
+			handleFunctions.PaymentManagementAPI.RefundPayment,
+		},
 		{
 			"UpdatePayment",
 			http.MethodPut,
 			"/api/payments/:paymentId",
 			handleFunctions.PaymentManagementAPI.UpdatePayment,
 		},
+		{
+			"CreatePayer",
+			http.MethodPost,
+			"/api/payers",
+			handleFunctions.PayerManagementAPI.CreatePayer,
+		},
+		{
+			"DeletePayer",
+			http.MethodDelete,
+			"/api/payers/:payerId",
+			handleFunctions.PayerManagementAPI.DeletePayer,
+		},
+		{
+			"GetPayerById",
+			http.MethodGet,
+			"/api/payers/:payerId",
+			handleFunctions.PayerManagementAPI.GetPayerById,
+		},
+		{
+			"GetPayers",
+			http.MethodGet,
+			"/api/payers",
+			handleFunctions.PayerManagementAPI.GetPayers,
+		},
+		{
+			"GetProcedureCoverage",
+			http.MethodGet,
+			"/api/procedures/:procedureId/coverage",
+			handleFunctions.PayerManagementAPI.GetProcedureCoverage,
+		},
+		{
+			"UpdatePayer",
+			http.MethodPut,
+			"/api/payers/:payerId",
+			handleFunctions.PayerManagementAPI.UpdatePayer,
+		},
+		{
+			"CreatePriceList",
+			http.MethodPost,
+			"/api/price-lists",
+			handleFunctions.PricingAPI.CreatePriceList,
+		},
+		{
+			"DeletePriceList",
+			http.MethodDelete,
+			"/api/price-lists/:priceListId",
+			handleFunctions.PricingAPI.DeletePriceList,
+		},
+		{
+			"GetPriceListById",
+			http.MethodGet,
+			"/api/price-lists/:priceListId",
+			handleFunctions.PricingAPI.GetPriceListById,
+		},
+		{
+			"GetPriceLists",
+			http.MethodGet,
+			"/api/price-lists",
+			handleFunctions.PricingAPI.GetPriceLists,
+		},
+		{
+			"QuoteProcedure",
+			http.MethodPost,
+			"/api/procedures:quote",
+			handleFunctions.PricingAPI.QuoteProcedure,
+		},
+		{
+			"UpdatePriceList",
+			http.MethodPut,
+			"/api/price-lists/:priceListId",
+			handleFunctions.PricingAPI.UpdatePriceList,
+		},
+		{
+			"CreateProcedureType",
+			http.MethodPost,
+			"/api/procedure-types",
+			handleFunctions.ProcedureCatalogAPI.CreateProcedureType,
+		},
+		{
+			"DeleteProcedureType",
+			http.MethodDelete,
+			"/api/procedure-types/:code",
+			handleFunctions.ProcedureCatalogAPI.DeleteProcedureType,
+		},
+		{
+			"GetProcedureReport",
+			http.MethodGet,
+			"/api/reports/procedures",
+			handleFunctions.ProcedureCatalogAPI.GetProcedureReport,
+		},
+		{
+			"GetProcedureType",
+			http.MethodGet,
+			"/api/procedure-types/:code",
+			handleFunctions.ProcedureCatalogAPI.GetProcedureType,
+		},
+		{
+			"GetProcedureTypes",
+			http.MethodGet,
+			"/api/procedure-types",
+			handleFunctions.ProcedureCatalogAPI.GetProcedureTypes,
+		},
+		{
+			"ImportProcedureTypes",
+			http.MethodPost,
+			"/api/procedure-types/import",
+			handleFunctions.ProcedureCatalogAPI.ImportProcedureTypes,
+		},
+		{
+			"UpdateProcedureType",
+			http.MethodPut,
+			"/api/procedure-types/:code",
+			handleFunctions.ProcedureCatalogAPI.UpdateProcedureType,
+		},
+		{
+			"ChangeProcedureStatus",
+			http.MethodPost,
+			"/api/procedures/:procedureId/status",
+			handleFunctions.ProcedureManagementAPI.ChangeProcedureStatus,
+		},
 		{
 			"CreateProcedure",
 			http.MethodPost,
@@ -169,11 +791,95 @@ func getRoutes(handleFunctions ApiHandleFunctions) []Route {
 			"/api/procedures",
 			handleFunctions.ProcedureManagementAPI.GetProcedures,
 		},
+		{
+			"ImportProcedures",
+			http.MethodPost,
+			"/api/procedures:import",
+			handleFunctions.ProcedureManagementAPI.ImportProcedures,
+		},
 		{
 			"UpdateProcedure",
 			http.MethodPut,
 			"/api/procedures/:procedureId",
 			handleFunctions.ProcedureManagementAPI.UpdateProcedure,
 		},
+		{
+			"GetAgingReport",
+			http.MethodGet,
+			"/api/reports/aging",
+			handleFunctions.ReportsAPI.GetAgingReport,
+		},
+		{
+			"GetRevenueReport",
+			http.MethodGet,
+			"/api/reports/revenue",
+			handleFunctions.ReportsAPI.GetRevenueReport,
+		},
+		{
+			"GetVolumeReport",
+			http.MethodGet,
+			"/api/reports/volume",
+			handleFunctions.ReportsAPI.GetVolumeReport,
+		},
+		{
+			"BookAppointment",
+			http.MethodPost,
+			"/api/ambulances/:ambulanceId/appointments",
+			handleFunctions.SchedulingAPI.BookAppointment,
+		},
+		{
+			"GetAmbulanceSlots",
+			http.MethodGet,
+			"/api/ambulances/:ambulanceId/slots",
+			handleFunctions.SchedulingAPI.GetAmbulanceSlots,
+		},
+		{
+			"RescheduleAppointment",
+			http.MethodPost,
+			"/api/procedures/:procedureId/reschedule",
+			handleFunctions.SchedulingAPI.RescheduleAppointment,
+		},
+		{
+			"CreateShift",
+			http.MethodPost,
+			"/api/shifts",
+			handleFunctions.ShiftManagementAPI.CreateShift,
+		},
+		{
+			"DeleteShift",
+			http.MethodDelete,
+			"/api/shifts/:shiftId",
+			handleFunctions.ShiftManagementAPI.DeleteShift,
+		},
+		{
+			"GetCrewMemberCalendar",
+			http.MethodGet,
+			"/api/crew/:crewMemberId/calendar",
+			handleFunctions.ShiftManagementAPI.GetCrewMemberCalendar,
+		},
+		{
+			"GetRoster",
+			http.MethodGet,
+			"/api/roster",
+			handleFunctions.ShiftManagementAPI.GetRoster,
+		},
+		{
+			"GetShiftById",
+			http.MethodGet,
+			"/api/shifts/:shiftId",
+			handleFunctions.ShiftManagementAPI.GetShiftById,
+		},
+		{
+			"GetShifts",
+			http.MethodGet,
+			"/api/shifts",
+			handleFunctions.ShiftManagementAPI.GetShifts,
+		},
+		{
+			"UpdateShift",
+			http.MethodPut,
+			"/api/shifts/:shiftId",
+			handleFunctions.ShiftManagementAPI.UpdateShift,
+		},
 	}
 }
@@ -0,0 +1,28 @@
+package blob_store
+
+import (
+	"context"
+	"fmt"
+	"io"
+)
+
+// BlobStore keeps binary content, such as procedure attachments, under string keys.
+type BlobStore interface {
+	// Put stores the content read from r under key, replacing any previous content,
+	// and returns the number of bytes stored.
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns the content stored under key.
+	Open(ctx context.Context, key string) (Blob, error)
+	// Delete removes the content stored under key.
+	Delete(ctx context.Context, key string) error
+	Disconnect(ctx context.Context) error
+}
+
+// Blob is stored content that can be read from any offset, e.g. to serve range requests.
+type Blob interface {
+	io.ReadSeekCloser
+	Size() int64
+}
+
+var ErrNotFound = fmt.Errorf("blob not found")
+var ErrInvalidKey = fmt.Errorf("invalid blob key")
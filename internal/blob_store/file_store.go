@@ -0,0 +1,112 @@
+package blob_store
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileStore keeps every blob in a file below the root directory; keys are slash
+// separated relative paths.
+type fileStore struct {
+	root string
+}
+
+type fileBlob struct {
+	*os.File
+	size int64
+}
+
+func (b *fileBlob) Size() int64 {
+	return b.size
+}
+
+// NewFileStore returns a BlobStore keeping blobs on the local filesystem below root.
+func NewFileStore(root string) (BlobStore, error) {
+	if err := os.MkdirAll(root, 0o750); err != nil {
+		return nil, err
+	}
+	return &fileStore{root: root}, nil
+}
+
+// path maps key to a file below the root, rejecting keys that would escape it.
+func (s *fileStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return "", ErrInvalidKey
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return "", ErrInvalidKey
+		}
+	}
+	return filepath.Join(s.root, filepath.FromSlash(key)), nil
+}
+
+func (s *fileStore) Put(_ context.Context, key string, r io.Reader) (int64, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return 0, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return 0, err
+	}
+
+	// write to a temporary file first so that readers never see partial content
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+
+	size, err := io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return 0, err
+	}
+	return size, nil
+}
+
+func (s *fileStore) Open(_ context.Context, key string) (Blob, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &fileBlob{File: file, size: info.Size()}, nil
+}
+
+func (s *fileStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *fileStore) Disconnect(_ context.Context) error {
+	return nil
+}
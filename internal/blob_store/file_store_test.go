@@ -0,0 +1,61 @@
+package blob_store
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// FileStoreSuite defines the suite for local filesystem blob store tests
+type FileStoreSuite struct {
+	suite.Suite
+	store BlobStore
+}
+
+func TestFileStoreSuite(t *testing.T) {
+	suite.Run(t, new(FileStoreSuite))
+}
+
+func (suite *FileStoreSuite) SetupTest() {
+	store, err := NewFileStore(suite.T().TempDir())
+	suite.Require().NoError(err)
+	suite.store = store
+}
+
+func (suite *FileStoreSuite) Test_PutOpen_ReadsFromAnyOffset() {
+	size, err := suite.store.Put(context.Background(), "procedures/proc001/att001", strings.NewReader("chest x-ray"))
+	suite.NoError(err)
+	suite.Equal(int64(11), size)
+
+	blob, err := suite.store.Open(context.Background(), "procedures/proc001/att001")
+	suite.Require().NoError(err)
+	defer blob.Close()
+
+	suite.Equal(int64(11), blob.Size())
+	_, err = blob.Seek(6, io.SeekStart)
+	suite.NoError(err)
+	rest, err := io.ReadAll(blob)
+	suite.NoError(err)
+	suite.Equal("x-ray", string(rest))
+}
+
+func (suite *FileStoreSuite) Test_Delete_RemovesBlob() {
+	_, err := suite.store.Put(context.Background(), "att001", strings.NewReader("note"))
+	suite.Require().NoError(err)
+
+	suite.NoError(suite.store.Delete(context.Background(), "att001"))
+
+	_, err = suite.store.Open(context.Background(), "att001")
+	suite.Equal(ErrNotFound, err)
+	suite.Equal(ErrNotFound, suite.store.Delete(context.Background(), "att001"))
+}
+
+func (suite *FileStoreSuite) Test_Put_RejectsKeysOutsideRoot() {
+	for _, key := range []string{"../escape", "/etc/passwd", "a//b", "a/./b", ""} {
+		_, err := suite.store.Put(context.Background(), key, strings.NewReader("x"))
+		suite.Equal(ErrInvalidKey, err, key)
+	}
+}
@@ -0,0 +1,234 @@
+package blob_store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type GridFSConfig struct {
+	ServerHost string
+	ServerPort int
+	UserName   string
+	Password   string
+	DbName     string
+	Bucket     string
+	Timeout    time.Duration
+}
+
+// gridfsStore keeps blobs in a MongoDB GridFS bucket, using the key as the file id.
+type gridfsStore struct {
+	GridFSConfig
+	client     *mongo.Client
+	clientLock sync.Mutex
+}
+
+// NewGridFSStore returns a BlobStore keeping blobs in MongoDB GridFS. Unset configuration
+// falls back to the same AMBULANCE_API_MONGODB_* environment variables as the DbService.
+// The store connects its own client, so its calls must not be given a context carrying
+// a session of the DbService client.
+func NewGridFSStore(config GridFSConfig) BlobStore {
+	enviro := func(name string, defaultValue string) string {
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		return defaultValue
+	}
+
+	s := &gridfsStore{GridFSConfig: config}
+	if s.ServerHost == "" {
+		s.ServerHost = enviro("AMBULANCE_API_MONGODB_HOST", "localhost")
+	}
+	if s.ServerPort == 0 {
+		port := enviro("AMBULANCE_API_MONGODB_PORT", "27017")
+		if port, err := strconv.Atoi(port); err == nil {
+			s.ServerPort = port
+		} else {
+			log.Printf("Invalid port value: %v", port)
+			s.ServerPort = 27017
+		}
+	}
+	if s.UserName == "" {
+		s.UserName = enviro("AMBULANCE_API_MONGODB_USERNAME", "")
+	}
+	if s.Password == "" {
+		s.Password = enviro("AMBULANCE_API_MONGODB_PASSWORD", "")
+	}
+	if s.DbName == "" {
+		s.DbName = enviro("AMBULANCE_API_MONGODB_DATABASE", "xdudakm-wac-ambulance-wl")
+	}
+	if s.Bucket == "" {
+		s.Bucket = "fs"
+	}
+	if s.Timeout == 0 {
+		s.Timeout = 10 * time.Second
+	}
+	return s
+}
+
+func (s *gridfsStore) bucket(ctx context.Context) (*gridfs.Bucket, error) {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+
+	if s.client == nil {
+		ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+
+		uri := fmt.Sprintf("mongodb://%v:%v", s.ServerHost, s.ServerPort)
+		if len(s.UserName) != 0 {
+			uri = fmt.Sprintf("mongodb://%v:%v@%v:%v", s.UserName, s.Password, s.ServerHost, s.ServerPort)
+		}
+		client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetConnectTimeout(10*time.Second))
+		if err != nil {
+			return nil, err
+		}
+		s.client = client
+	}
+	return gridfs.NewBucket(s.client.Database(s.DbName), options.GridFSBucket().SetName(s.Bucket))
+}
+
+// countingReader counts the bytes read through it.
+type countingReader struct {
+	io.Reader
+	count int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.count += int64(n)
+	return n, err
+}
+
+func (s *gridfsStore) Put(ctx context.Context, key string, r io.Reader) (int64, error) {
+	if key == "" {
+		return 0, ErrInvalidKey
+	}
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if err := bucket.DeleteContext(ctx, key); err != nil && !errors.Is(err, gridfs.ErrFileNotFound) {
+		return 0, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := bucket.SetWriteDeadline(deadline); err != nil {
+			return 0, err
+		}
+	}
+	counter := &countingReader{Reader: r}
+	if err := bucket.UploadFromStreamWithID(key, key, counter); err != nil {
+		return 0, err
+	}
+	return counter.count, nil
+}
+
+func (s *gridfsStore) Open(ctx context.Context, key string) (Blob, error) {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return nil, err
+	}
+	stream, err := bucket.OpenDownloadStream(key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &gridfsBlob{bucket: bucket, key: key, stream: stream, size: stream.GetFile().Length}, nil
+}
+
+func (s *gridfsStore) Delete(ctx context.Context, key string) error {
+	bucket, err := s.bucket(ctx)
+	if err != nil {
+		return err
+	}
+	err = bucket.DeleteContext(ctx, key)
+	if errors.Is(err, gridfs.ErrFileNotFound) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (s *gridfsStore) Disconnect(ctx context.Context) error {
+	s.clientLock.Lock()
+	defer s.clientLock.Unlock()
+
+	if s.client == nil {
+		return nil
+	}
+	defer func() { s.client = nil }()
+	return s.client.Disconnect(ctx)
+}
+
+// gridfsBlob makes a GridFS download stream seekable: seeking only moves the position,
+// and the next read reopens the stream there when it is elsewhere.
+type gridfsBlob struct {
+	bucket   *gridfs.Bucket
+	key      string
+	stream   *gridfs.DownloadStream
+	streamAt int64
+	position int64
+	size     int64
+}
+
+func (b *gridfsBlob) Size() int64 {
+	return b.size
+}
+
+func (b *gridfsBlob) Read(p []byte) (int, error) {
+	if b.position >= b.size {
+		return 0, io.EOF
+	}
+	if b.stream == nil || b.streamAt != b.position {
+		if b.stream != nil {
+			b.stream.Close()
+		}
+		stream, err := b.bucket.OpenDownloadStream(b.key)
+		if err != nil {
+			return 0, err
+		}
+		if _, err := stream.Skip(b.position); err != nil {
+			stream.Close()
+			return 0, err
+		}
+		b.stream, b.streamAt = stream, b.position
+	}
+	n, err := b.stream.Read(p)
+	b.position += int64(n)
+	b.streamAt = b.position
+	return n, err
+}
+
+func (b *gridfsBlob) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.position
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, fmt.Errorf("invalid whence %d", whence)
+	}
+	if offset < 0 {
+		return 0, fmt.Errorf("negative position %d", offset)
+	}
+	b.position = offset
+	return offset, nil
+}
+
+func (b *gridfsBlob) Close() error {
+	if b.stream == nil {
+		return nil
+	}
+	return b.stream.Close()
+}
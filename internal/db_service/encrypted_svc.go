@@ -0,0 +1,411 @@
+package db_service
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// encryptedPrefix marks field values encrypted by this package. Values without it are
+// legacy plaintext and are returned unchanged until they are re-encrypted.
+const encryptedPrefix = "enc:v1:"
+
+var ErrNotQueryable = fmt.Errorf("field is encrypted non-deterministically and cannot be queried")
+
+// EncryptedField designates a string field, by its dotted json path (e.g. "contact.phone"),
+// to be encrypted at rest. Deterministic fields encrypt equal values to equal ciphertexts
+// under the same key, so they can still be queried by equality.
+type EncryptedField struct {
+	Path          string
+	Deterministic bool
+}
+
+// Reencrypter is implemented by services that can re-encrypt their stored documents
+// with the active key, e.g. after a key rotation or to encrypt legacy plaintext.
+type Reencrypter interface {
+	Reencrypt(ctx context.Context) (int, error)
+}
+
+// KeyRing holds the AES-256 keys by id; new values are encrypted with the active key,
+// while all keys remain available for decryption.
+type KeyRing struct {
+	active string
+	keys   map[string][]byte
+}
+
+// keyFile is the on-disk format of a key ring:
+//
+//	{"active": "2025-05", "keys": {"2025-01": "<base64>", "2025-05": "<base64>"}}
+type keyFile struct {
+	Active string            `json:"active"`
+	Keys   map[string]string `json:"keys"`
+}
+
+// LoadKeyRing reads a key ring from a local JSON key file. Every key must be 32 bytes
+// of base64 encoded random data, and the active key must be present.
+func LoadKeyRing(path string) (*KeyRing, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var file keyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid key file: %w", err)
+	}
+
+	ring := &KeyRing{active: file.Active, keys: map[string][]byte{}}
+	for id, encoded := range file.Keys {
+		if id == "" || strings.Contains(id, ":") {
+			return nil, fmt.Errorf("invalid key id %q", id)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q: %w", id, err)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+		ring.keys[id] = key
+	}
+	if _, ok := ring.keys[ring.active]; !ok {
+		return nil, fmt.Errorf("active key %q is not in the key file", ring.active)
+	}
+	return ring, nil
+}
+
+// encrypt encrypts value with the key id. Deterministic encryption derives the nonce
+// from the field path and value instead of drawing it at random.
+func (k *KeyRing) encrypt(id string, path string, value string, deterministic bool) (string, error) {
+	key := k.keys[id]
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	mode := "r"
+	if deterministic {
+		mode = "d"
+		nonceKey := hmac.New(sha256.New, key)
+		nonceKey.Write([]byte("deterministic-nonce"))
+		mac := hmac.New(sha256.New, nonceKey.Sum(nil))
+		mac.Write([]byte(path))
+		mac.Write([]byte{0})
+		mac.Write([]byte(value))
+		copy(nonce, mac.Sum(nil))
+	} else if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, []byte(value), []byte(path))
+	return encryptedPrefix + id + ":" + mode + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// decrypt returns the plaintext of an encrypted value and the id of the key it was
+// encrypted with. Values that are not encrypted are returned as they are.
+func (k *KeyRing) decrypt(path string, value string) (string, string, error) {
+	if !strings.HasPrefix(value, encryptedPrefix) {
+		return value, "", nil
+	}
+	parts := strings.SplitN(strings.TrimPrefix(value, encryptedPrefix), ":", 3)
+	if len(parts) != 3 {
+		return "", "", fmt.Errorf("malformed encrypted value in %s", path)
+	}
+	key, ok := k.keys[parts[0]]
+	if !ok {
+		return "", "", fmt.Errorf("unknown key %q for %s", parts[0], path)
+	}
+	sealed, err := base64.StdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", "", fmt.Errorf("malformed encrypted value in %s: %w", path, err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", "", fmt.Errorf("malformed encrypted value in %s", path)
+	}
+	plain, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], []byte(path))
+	if err != nil {
+		return "", "", fmt.Errorf("cannot decrypt %s: %w", path, err)
+	}
+	return string(plain), parts[0], nil
+}
+
+type encryptedSvc[DocType interface{}] struct {
+	DbService[DocType]
+	keys   *KeyRing
+	fields []EncryptedField
+}
+
+// NewEncryptedService wraps a DbService so that the designated fields are encrypted with
+// AES-GCM before documents are stored and decrypted after they are loaded. Callers keep
+// working with plaintext documents.
+func NewEncryptedService[DocType interface{}](inner DbService[DocType], keys *KeyRing, fields ...EncryptedField) (DbService[DocType], error) {
+	var zero DocType
+	for _, field := range fields {
+		if _, err := fieldByPath(reflect.ValueOf(&zero).Elem(), field.Path); err != nil {
+			return nil, err
+		}
+	}
+	return &encryptedSvc[DocType]{DbService: inner, keys: keys, fields: fields}, nil
+}
+
+// fieldByPath returns the string field of v at the dotted json path.
+func fieldByPath(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return reflect.Value{}, nil
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("encrypted field %s: %s is not an object", path, name)
+		}
+		found := false
+		for i := 0; i < v.NumField(); i++ {
+			tag := strings.Split(v.Type().Field(i).Tag.Get("json"), ",")[0]
+			if tag == name {
+				v = v.Field(i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return reflect.Value{}, fmt.Errorf("encrypted field %s: no field %s", path, name)
+		}
+	}
+	if v.Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("encrypted field %s is not a string", path)
+	}
+	return v, nil
+}
+
+func (m *encryptedSvc[DocType]) field(path string) *EncryptedField {
+	for i := range m.fields {
+		if m.fields[i].Path == path {
+			return &m.fields[i]
+		}
+	}
+	return nil
+}
+
+// encryptDocument returns a copy of document with the designated fields encrypted
+// with the active key. The caller's document is left in plaintext.
+func (m *encryptedSvc[DocType]) encryptDocument(document *DocType) (*DocType, error) {
+	encrypted := *document
+	for _, field := range m.fields {
+		v, err := fieldByPath(reflect.ValueOf(&encrypted).Elem(), field.Path)
+		if err != nil {
+			return nil, err
+		}
+		if !v.IsValid() || v.String() == "" {
+			continue
+		}
+		value, err := m.keys.encrypt(m.keys.active, field.Path, v.String(), field.Deterministic)
+		if err != nil {
+			return nil, err
+		}
+		v.SetString(value)
+	}
+	return &encrypted, nil
+}
+
+// decryptDocument decrypts the designated fields in place and reports whether any of
+// them was stored in plaintext or under a key other than the active one.
+func (m *encryptedSvc[DocType]) decryptDocument(document *DocType) (bool, error) {
+	stale := false
+	for _, field := range m.fields {
+		v, err := fieldByPath(reflect.ValueOf(document).Elem(), field.Path)
+		if err != nil {
+			return false, err
+		}
+		if !v.IsValid() || v.String() == "" {
+			continue
+		}
+		value, keyId, err := m.keys.decrypt(field.Path, v.String())
+		if err != nil {
+			return false, err
+		}
+		if keyId != m.keys.active {
+			stale = true
+		}
+		v.SetString(value)
+	}
+	return stale, nil
+}
+
+func (m *encryptedSvc[DocType]) CreateDocument(ctx context.Context, id string, document *DocType) error {
+	encrypted, err := m.encryptDocument(document)
+	if err != nil {
+		return err
+	}
+	return m.DbService.CreateDocument(ctx, id, encrypted)
+}
+
+func (m *encryptedSvc[DocType]) CreateDocuments(ctx context.Context, ids []string, documents []*DocType) ([]error, error) {
+	encrypted := make([]*DocType, len(documents))
+	for i, document := range documents {
+		var err error
+		if encrypted[i], err = m.encryptDocument(document); err != nil {
+			return nil, err
+		}
+	}
+	return m.DbService.CreateDocuments(ctx, ids, encrypted)
+}
+
+func (m *encryptedSvc[DocType]) FindDocument(ctx context.Context, id string) (*DocType, error) {
+	document, err := m.DbService.FindDocument(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.decryptDocument(document); err != nil {
+		return nil, err
+	}
+	return document, nil
+}
+
+func (m *encryptedSvc[DocType]) ListDocuments(ctx context.Context) ([]DocType, error) {
+	documents, err := m.DbService.ListDocuments(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for i := range documents {
+		if _, err := m.decryptDocument(&documents[i]); err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+func (m *encryptedSvc[DocType]) UpdateDocument(ctx context.Context, id string, document *DocType) error {
+	encrypted, err := m.encryptDocument(document)
+	if err != nil {
+		return err
+	}
+	return m.DbService.UpdateDocument(ctx, id, encrypted)
+}
+
+// UpdateDocumentIf cannot compare encrypted fields, whose stored values are ciphertext.
+func (m *encryptedSvc[DocType]) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *DocType) error {
+	for name := range fields {
+		if m.field(name) != nil {
+			return fmt.Errorf("field %v is encrypted and cannot be compared", name)
+		}
+	}
+	encrypted, err := m.encryptDocument(document)
+	if err != nil {
+		return err
+	}
+	return m.DbService.UpdateDocumentIf(ctx, id, fields, encrypted)
+}
+
+// FindDocumentsByField queries deterministic fields by their ciphertext under every key
+// of the ring, so documents not yet re-encrypted after a rotation are found as well.
+func (m *encryptedSvc[DocType]) FindDocumentsByField(ctx context.Context, fieldName string, value any) ([]*DocType, error) {
+	field := m.field(fieldName)
+	plain, isString := value.(string)
+	if field == nil || !isString || plain == "" {
+		return m.decryptAll(m.DbService.FindDocumentsByField(ctx, fieldName, value))
+	}
+	if !field.Deterministic {
+		return nil, ErrNotQueryable
+	}
+
+	// legacy plaintext values match as they are
+	results, err := m.DbService.FindDocumentsByField(ctx, fieldName, plain)
+	if err != nil {
+		return nil, err
+	}
+	for id := range m.keys.keys {
+		encrypted, err := m.keys.encrypt(id, fieldName, plain, true)
+		if err != nil {
+			return nil, err
+		}
+		found, err := m.DbService.FindDocumentsByField(ctx, fieldName, encrypted)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, found...)
+	}
+	return m.decryptAll(results, nil)
+}
+
+func (m *encryptedSvc[DocType]) decryptAll(documents []*DocType, err error) ([]*DocType, error) {
+	if err != nil {
+		return nil, err
+	}
+	for _, document := range documents {
+		if _, err := m.decryptDocument(document); err != nil {
+			return nil, err
+		}
+	}
+	return documents, nil
+}
+
+// Reencrypt rewrites every document holding plaintext or values encrypted with a key
+// other than the active one, and returns the number of documents rewritten. Once it
+// completes, retired keys can be removed from the key file.
+func (m *encryptedSvc[DocType]) Reencrypt(ctx context.Context) (int, error) {
+	documents, err := m.DbService.ListDocuments(ctx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for i := range documents {
+		stale, err := m.decryptDocument(&documents[i])
+		if err != nil {
+			return count, err
+		}
+		if !stale {
+			continue
+		}
+		id, err := fieldByPath(reflect.ValueOf(&documents[i]).Elem(), "id")
+		if err != nil {
+			return count, err
+		}
+		if err := m.UpdateDocument(ctx, id.String(), &documents[i]); err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// Aggregate runs the pipeline on the inner service. Encrypted fields reach the pipeline
+// as ciphertext, so pipelines must not depend on them.
+func (m *encryptedSvc[DocType]) Aggregate(ctx context.Context, pipeline any, results any) error {
+	aggregator, ok := m.DbService.(Aggregator)
+	if !ok {
+		return ErrNotAggregatable
+	}
+	return aggregator.Aggregate(ctx, pipeline, results)
+}
+
+// RenameFields renames the fields of the inner service; field names are not encrypted.
+func (m *encryptedSvc[DocType]) RenameFields(ctx context.Context) (int64, error) {
+	renamer, ok := m.DbService.(FieldRenamer)
+	if !ok {
+		return 0, nil
+	}
+	return renamer.RenameFields(ctx)
+}
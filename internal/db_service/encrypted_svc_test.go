@@ -0,0 +1,181 @@
+package db_service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type testContact struct {
+	Phone string `json:"phone,omitempty"`
+}
+
+type testDocument struct {
+	Id         string      `json:"id"`
+	Identifier string      `json:"identifier"`
+	Name       string      `json:"name"`
+	Contact    testContact `json:"contact"`
+}
+
+// memorySvc is a DbService keeping documents in a map, as stored by the wrapped service.
+type memorySvc struct {
+	DbService[testDocument]
+	documents map[string]testDocument
+}
+
+func (m *memorySvc) CreateDocument(_ context.Context, id string, document *testDocument) error {
+	m.documents[id] = *document
+	return nil
+}
+
+func (m *memorySvc) CreateDocuments(_ context.Context, ids []string, documents []*testDocument) ([]error, error) {
+	errs := make([]error, len(documents))
+	for i, document := range documents {
+		if _, ok := m.documents[ids[i]]; ok {
+			errs[i] = ErrConflict
+			continue
+		}
+		m.documents[ids[i]] = *document
+	}
+	return errs, nil
+}
+
+func (m *memorySvc) FindDocument(_ context.Context, id string) (*testDocument, error) {
+	document, ok := m.documents[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &document, nil
+}
+
+func (m *memorySvc) ListDocuments(_ context.Context) ([]testDocument, error) {
+	documents := make([]testDocument, 0)
+	for _, document := range m.documents {
+		documents = append(documents, document)
+	}
+	return documents, nil
+}
+
+func (m *memorySvc) UpdateDocument(_ context.Context, id string, document *testDocument) error {
+	m.documents[id] = *document
+	return nil
+}
+
+func (m *memorySvc) FindDocumentsByField(_ context.Context, fieldName string, value any) ([]*testDocument, error) {
+	results := make([]*testDocument, 0)
+	for _, document := range m.documents {
+		if fieldName == "identifier" && document.Identifier == value {
+			document := document
+			results = append(results, &document)
+		}
+	}
+	return results, nil
+}
+
+// EncryptedSuite defines the suite for field-level encryption tests
+type EncryptedSuite struct {
+	suite.Suite
+	dir    string
+	stored *memorySvc
+}
+
+func TestEncryptedSuite(t *testing.T) {
+	suite.Run(t, new(EncryptedSuite))
+}
+
+func (suite *EncryptedSuite) SetupTest() {
+	suite.dir = suite.T().TempDir()
+	suite.stored = &memorySvc{documents: map[string]testDocument{}}
+}
+
+func (suite *EncryptedSuite) keyRing(active string, ids ...string) *KeyRing {
+	file := keyFile{Active: active, Keys: map[string]string{}}
+	for i, id := range ids {
+		file.Keys[id] = base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune('a'+i)), 32)))
+	}
+	data, _ := json.Marshal(file)
+	path := filepath.Join(suite.dir, "keys.json")
+	suite.Require().NoError(os.WriteFile(path, data, 0600))
+
+	ring, err := LoadKeyRing(path)
+	suite.Require().NoError(err)
+	return ring
+}
+
+func (suite *EncryptedSuite) service(keys *KeyRing) DbService[testDocument] {
+	svc, err := NewEncryptedService[testDocument](suite.stored, keys,
+		EncryptedField{Path: "identifier", Deterministic: true},
+		EncryptedField{Path: "name"},
+		EncryptedField{Path: "contact.phone"},
+	)
+	suite.Require().NoError(err)
+	return svc
+}
+
+func (suite *EncryptedSuite) Test_CreateDocument_StoresCiphertextAndReadsPlaintext() {
+	svc := suite.service(suite.keyRing("k1", "k1"))
+	document := &testDocument{Id: "p1", Identifier: "8501011234", Name: "Peter Horváth", Contact: testContact{Phone: "+421900"}}
+
+	suite.NoError(svc.CreateDocument(context.Background(), "p1", document))
+
+	stored := suite.stored.documents["p1"]
+	suite.True(strings.HasPrefix(stored.Name, "enc:v1:k1:r:"))
+	suite.True(strings.HasPrefix(stored.Contact.Phone, "enc:v1:k1:r:"))
+	suite.Equal("Peter Horváth", document.Name)
+
+	loaded, err := svc.FindDocument(context.Background(), "p1")
+	suite.NoError(err)
+	suite.Equal(*document, *loaded)
+}
+
+func (suite *EncryptedSuite) Test_CreateDocuments_StoresCiphertext() {
+	svc := suite.service(suite.keyRing("k1", "k1"))
+	suite.stored.documents["p0"] = testDocument{Id: "p0"}
+	documents := []*testDocument{{Id: "p0", Name: "Ján Novák"}, {Id: "p1", Name: "Peter Horváth"}}
+
+	errs, err := svc.CreateDocuments(context.Background(), []string{"p0", "p1"}, documents)
+
+	suite.NoError(err)
+	suite.Equal([]error{ErrConflict, nil}, errs)
+	suite.True(strings.HasPrefix(suite.stored.documents["p1"].Name, "enc:v1:k1:r:"))
+	suite.Equal("Peter Horváth", documents[1].Name)
+}
+
+func (suite *EncryptedSuite) Test_FindDocumentsByField_MatchesDeterministicFieldAcrossKeys() {
+	keys := suite.keyRing("k1", "k1")
+	suite.NoError(suite.service(keys).CreateDocument(context.Background(), "p1", &testDocument{Id: "p1", Identifier: "8501011234"}))
+
+	rotated := suite.service(suite.keyRing("k2", "k1", "k2"))
+	suite.NoError(rotated.CreateDocument(context.Background(), "p2", &testDocument{Id: "p2", Identifier: "9001011234"}))
+
+	found, err := rotated.FindDocumentsByField(context.Background(), "identifier", "8501011234")
+	suite.NoError(err)
+	suite.Len(found, 1)
+	suite.Equal("p1", found[0].Id)
+
+	_, err = rotated.FindDocumentsByField(context.Background(), "name", "Peter Horváth")
+	suite.Equal(ErrNotQueryable, err)
+}
+
+func (suite *EncryptedSuite) Test_Reencrypt_RewritesPlaintextAndRetiredKeys() {
+	suite.stored.documents["legacy"] = testDocument{Id: "legacy", Name: "Jana Kováčová"}
+	suite.NoError(suite.service(suite.keyRing("k1", "k1")).CreateDocument(context.Background(), "old", &testDocument{Id: "old", Name: "Peter Horváth"}))
+
+	rotated := suite.service(suite.keyRing("k2", "k1", "k2"))
+	count, err := rotated.(Reencrypter).Reencrypt(context.Background())
+
+	suite.NoError(err)
+	suite.Equal(2, count)
+	for _, document := range suite.stored.documents {
+		suite.True(strings.HasPrefix(document.Name, "enc:v1:k2:"))
+	}
+	count, err = rotated.(Reencrypter).Reencrypt(context.Background())
+	suite.NoError(err)
+	suite.Equal(0, count)
+}
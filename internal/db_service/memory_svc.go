@@ -0,0 +1,239 @@
+package db_service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// MemoryTransactor runs transactions over the services of NewMemoryService, undoing
+// their writes when the function of a transaction fails. Transactions run one at a time. It is meant for
+// tests of code using transactions.
+type MemoryTransactor struct {
+	lock sync.Mutex
+}
+
+// memoryTx is the undo log of a transaction of a MemoryTransactor.
+type memoryTx struct {
+	undo []func()
+}
+
+type memoryTxKey struct{}
+
+// NewMemoryTransactor returns a transactor over the memory services.
+func NewMemoryTransactor() *MemoryTransactor {
+	return &MemoryTransactor{}
+}
+
+func (t *MemoryTransactor) RunInTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if _, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		return fn(ctx)
+	}
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	tx := &memoryTx{}
+	err := fn(context.WithValue(ctx, memoryTxKey{}, tx))
+	if err != nil {
+		for i := len(tx.undo) - 1; i >= 0; i-- {
+			tx.undo[i]()
+		}
+	}
+	return err
+}
+
+// memoryService keeps documents as their JSON in memory, in the order they were created.
+type memoryService[DocType interface{}] struct {
+	lock      sync.Mutex
+	documents map[string][]byte
+	ids       []string
+}
+
+// NewMemoryService returns a DbService keeping documents in memory. Its writes take part
+// in the transactions of a MemoryTransactor. It is meant for tests.
+func NewMemoryService[DocType interface{}]() DbService[DocType] {
+	return &memoryService[DocType]{documents: map[string][]byte{}}
+}
+
+// set stores or, given nil, removes the document with the id. Within a transaction the
+// previous state is logged so that it can be restored.
+func (m *memoryService[DocType]) set(ctx context.Context, id string, data []byte) {
+	previous := m.documents[id]
+	if tx, ok := ctx.Value(memoryTxKey{}).(*memoryTx); ok {
+		tx.undo = append(tx.undo, func() {
+			m.lock.Lock()
+			defer m.lock.Unlock()
+			m.put(id, previous)
+		})
+	}
+	m.put(id, data)
+}
+
+func (m *memoryService[DocType]) put(id string, data []byte) {
+	_, existed := m.documents[id]
+	switch {
+	case data == nil && existed:
+		delete(m.documents, id)
+		for i := range m.ids {
+			if m.ids[i] == id {
+				m.ids = append(m.ids[:i], m.ids[i+1:]...)
+				break
+			}
+		}
+	case data != nil:
+		if !existed {
+			m.ids = append(m.ids, id)
+		}
+		m.documents[id] = data
+	}
+}
+
+func (m *memoryService[DocType]) decode(data []byte) (*DocType, error) {
+	var document DocType
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+func (m *memoryService[DocType]) CreateDocument(ctx context.Context, id string, document *DocType) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.documents[id]; ok {
+		return ErrConflict
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	m.set(ctx, id, data)
+	return nil
+}
+
+func (m *memoryService[DocType]) CreateDocuments(ctx context.Context, ids []string, documents []*DocType) ([]error, error) {
+	errs := make([]error, len(documents))
+	for i, document := range documents {
+		errs[i] = m.CreateDocument(ctx, ids[i], document)
+	}
+	return errs, nil
+}
+
+func (m *memoryService[DocType]) FindDocument(_ context.Context, id string) (*DocType, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	data, ok := m.documents[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return m.decode(data)
+}
+
+func (m *memoryService[DocType]) ListDocuments(_ context.Context) ([]DocType, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	results := make([]DocType, 0, len(m.ids))
+	for _, id := range m.ids {
+		document, err := m.decode(m.documents[id])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, *document)
+	}
+	return results, nil
+}
+
+func (m *memoryService[DocType]) UpdateDocument(ctx context.Context, id string, document *DocType) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.documents[id]; !ok {
+		return ErrNotFound
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	m.set(ctx, id, data)
+	return nil
+}
+
+func (m *memoryService[DocType]) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *DocType) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	stored, ok := m.documents[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for name, value := range fields {
+		matches, err := fieldMatches(stored, name, value)
+		if err != nil {
+			return err
+		}
+		if !matches {
+			return ErrConflict
+		}
+	}
+	data, err := json.Marshal(document)
+	if err != nil {
+		return err
+	}
+	m.set(ctx, id, data)
+	return nil
+}
+
+func (m *memoryService[DocType]) DeleteDocument(ctx context.Context, id string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.documents[id]; !ok {
+		return ErrNotFound
+	}
+	m.set(ctx, id, nil)
+	return nil
+}
+
+func (m *memoryService[DocType]) Disconnect(_ context.Context) error {
+	return nil
+}
+
+// fieldMatches reports whether the field of the document, a dotted path of JSON field
+// names, has the JSON of the value.
+func fieldMatches(data []byte, fieldName string, value any) (bool, error) {
+	want, err := json.Marshal(value)
+	if err != nil {
+		return false, err
+	}
+	var field any
+	if err := json.Unmarshal(data, &field); err != nil {
+		return false, err
+	}
+	for _, name := range strings.Split(fieldName, ".") {
+		object, _ := field.(map[string]any)
+		field = object[name]
+	}
+	got, _ := json.Marshal(field)
+	return bytes.Equal(got, want), nil
+}
+
+// FindDocumentsByField returns the documents whose field, a dotted path of JSON field
+// names, has the JSON of the value.
+func (m *memoryService[DocType]) FindDocumentsByField(_ context.Context, fieldName string, value any) ([]*DocType, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	var results []*DocType
+	for _, id := range m.ids {
+		matches, err := fieldMatches(m.documents[id], fieldName, value)
+		if err != nil {
+			return nil, err
+		}
+		if !matches {
+			continue
+		}
+		document, err := m.decode(m.documents[id])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, document)
+	}
+	return results, nil
+}
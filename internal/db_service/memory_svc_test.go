@@ -0,0 +1,81 @@
+package db_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// MemoryServiceSuite defines the suite for in-memory service and transaction tests
+type MemoryServiceSuite struct {
+	suite.Suite
+	documents  DbService[testDocument]
+	transactor *MemoryTransactor
+}
+
+func TestMemoryServiceSuite(t *testing.T) {
+	suite.Run(t, new(MemoryServiceSuite))
+}
+
+func (suite *MemoryServiceSuite) SetupTest() {
+	suite.documents = NewMemoryService[testDocument]()
+	suite.transactor = NewMemoryTransactor()
+	suite.Require().NoError(suite.documents.CreateDocument(context.Background(), "doc1", &testDocument{Id: "doc1", Name: "Jana", Contact: testContact{Phone: "0900"}}))
+}
+
+func (suite *MemoryServiceSuite) Test_Service_StoresCopies() {
+	ctx := context.Background()
+	document, err := suite.documents.FindDocument(ctx, "doc1")
+	suite.Require().NoError(err)
+	document.Name = "changed"
+
+	stored, _ := suite.documents.FindDocument(ctx, "doc1")
+	suite.Equal("Jana", stored.Name)
+	suite.ErrorIs(suite.documents.CreateDocument(ctx, "doc1", document), ErrConflict)
+	suite.ErrorIs(suite.documents.UpdateDocument(ctx, "doc2", document), ErrNotFound)
+
+	found, err := suite.documents.FindDocumentsByField(ctx, "contact.phone", "0900")
+	suite.Require().NoError(err)
+	suite.Len(found, 1)
+}
+
+func (suite *MemoryServiceSuite) Test_UpdateDocumentIf_ReplacesOnlyUnchangedDocument() {
+	ctx := context.Background()
+	unchanged := map[string]any{"name": "Jana", "contact.phone": "0900"}
+
+	suite.NoError(suite.documents.UpdateDocumentIf(ctx, "doc1", unchanged, &testDocument{Id: "doc1", Name: "Eva"}))
+	suite.ErrorIs(suite.documents.UpdateDocumentIf(ctx, "doc1", unchanged, &testDocument{Id: "doc1", Name: "Zuzana"}), ErrConflict)
+	suite.ErrorIs(suite.documents.UpdateDocumentIf(ctx, "doc2", unchanged, &testDocument{Id: "doc2"}), ErrNotFound)
+
+	stored, _ := suite.documents.FindDocument(ctx, "doc1")
+	suite.Equal("Eva", stored.Name)
+}
+
+func (suite *MemoryServiceSuite) Test_RunInTransaction_RollsBackOnError() {
+	failure := errors.New("failure")
+	err := suite.transactor.RunInTransaction(context.Background(), func(txCtx context.Context) error {
+		suite.Require().NoError(suite.documents.CreateDocument(txCtx, "doc2", &testDocument{Id: "doc2"}))
+		suite.Require().NoError(suite.documents.UpdateDocument(txCtx, "doc1", &testDocument{Id: "doc1", Name: "Eva"}))
+		// a nested transaction joins the outer one
+		return suite.transactor.RunInTransaction(txCtx, func(txCtx context.Context) error {
+			suite.Require().NoError(suite.documents.DeleteDocument(txCtx, "doc1"))
+			return failure
+		})
+	})
+
+	suite.ErrorIs(err, failure)
+	documents, _ := suite.documents.ListDocuments(context.Background())
+	suite.Equal([]testDocument{{Id: "doc1", Name: "Jana", Contact: testContact{Phone: "0900"}}}, documents)
+}
+
+func (suite *MemoryServiceSuite) Test_RunInTransaction_KeepsWritesOnSuccess() {
+	err := suite.transactor.RunInTransaction(context.Background(), func(txCtx context.Context) error {
+		return suite.documents.DeleteDocument(txCtx, "doc1")
+	})
+
+	suite.NoError(err)
+	_, err = suite.documents.FindDocument(context.Background(), "doc1")
+	suite.ErrorIs(err, ErrNotFound)
+}
@@ -2,6 +2,7 @@ package db_service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -17,16 +18,66 @@ import (
 
 type DbService[DocType interface{}] interface {
 	CreateDocument(ctx context.Context, id string, document *DocType) error
+	// CreateDocuments stores many documents under their ids at once. It returns an error
+	// per document, nil for the stored ones and ErrConflict for ids already taken, and
+	// fails as a whole only when the write cannot be carried out.
+	CreateDocuments(ctx context.Context, ids []string, documents []*DocType) ([]error, error)
 	FindDocument(ctx context.Context, id string) (*DocType, error)
 	ListDocuments(ctx context.Context) ([]DocType, error) // ← new
 	UpdateDocument(ctx context.Context, id string, document *DocType) error
+	// UpdateDocumentIf replaces the document only while the stored one still has the
+	// given values of the fields, named as for FindDocumentsByField. It returns
+	// ErrConflict when a value differs, so that concurrent writers cannot both succeed.
+	UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *DocType) error
 	DeleteDocument(ctx context.Context, id string) error
 	Disconnect(ctx context.Context) error
 	FindDocumentsByField(ctx context.Context, fieldName string, value any) ([]*DocType, error)
 }
 
+// Aggregator is implemented by services that can run aggregation pipelines in the
+// database. Callers fall back to computing in memory from ListDocuments otherwise.
+type Aggregator interface {
+	// Aggregate runs the pipeline on the collection and decodes the output documents
+	// into results, a pointer to a slice.
+	Aggregate(ctx context.Context, pipeline any, results any) error
+}
+
+// FieldRenamer is implemented by services that can rename fields stored under the names
+// of earlier versions.
+type FieldRenamer interface {
+	// RenameFields gives the stored documents the current field names and returns the
+	// number of documents renamed.
+	RenameFields(ctx context.Context) (int64, error)
+}
+
 var ErrNotFound = fmt.Errorf("document not found")
 var ErrConflict = fmt.Errorf("conflict: document already exists")
+var ErrNotAggregatable = fmt.Errorf("service cannot run aggregation pipelines")
+var ErrNoTransactions = fmt.Errorf("the database does not run transactions")
+
+// Transactor runs functions in multi-document transactions.
+type Transactor interface {
+	// RunInTransaction calls fn with a context carrying a transaction. The operations of
+	// the services of the transactor given that context take part in the transaction,
+	// which is committed when fn returns nil and aborted otherwise. Called with a context
+	// already carrying a transaction, it runs fn in that transaction. When the database
+	// cannot run transactions it returns ErrNoTransactions without calling fn. For a
+	// context marked with RetryOnWriteConflict, fn may be called more than once.
+	RunInTransaction(ctx context.Context, fn func(txCtx context.Context) error) error
+}
+
+// transactionAttempts is how often RunInTransaction runs fn for a context marked with
+// RetryOnWriteConflict.
+const transactionAttempts = 5
+
+type retryKey struct{}
+
+// RetryOnWriteConflict marks ctx so that a transaction started with it is run again when
+// it is aborted because a concurrent transaction wrote the same documents. The function
+// run in the transaction must then be safe to call more than once.
+func RetryOnWriteConflict(ctx context.Context) context.Context {
+	return context.WithValue(ctx, retryKey{}, true)
+}
 
 type MongoServiceConfig struct {
 	ServerHost string
@@ -36,15 +87,34 @@ type MongoServiceConfig struct {
 	DbName     string
 	Collection string
 	Timeout    time.Duration
+	// Client, when set, is the connection the service shares with the services of other
+	// collections, so that their operations can take part in one transaction.
+	Client *MongoClient
+	// RenamedFields maps names fields were stored under by earlier versions to their
+	// current names. Documents still using the old names are updated by RenameFields.
+	RenamedFields map[string]string
 }
 
-type mongoSvc[DocType interface{}] struct {
+// MongoClient is a connection to MongoDB, made on first use.
+type MongoClient struct {
 	MongoServiceConfig
 	client     atomic.Pointer[mongo.Client]
 	clientLock sync.Mutex
+	// transactions caches whether the server runs transactions: 0 unknown, 1 yes, 2 no
+	transactions atomic.Int32
 }
 
-func NewMongoService[DocType interface{}](config MongoServiceConfig) DbService[DocType] {
+type mongoSvc[DocType interface{}] struct {
+	MongoServiceConfig
+	connection *MongoClient
+	// sharedConnection is set when the connection belongs to the caller, which disconnects it
+	sharedConnection bool
+	prepared         atomic.Bool
+	prepareLock      sync.Mutex
+}
+
+// withDefaults fills the unset fields of the config from the environment.
+func (config MongoServiceConfig) withDefaults() MongoServiceConfig {
 	enviro := func(name string, defaultValue string) string {
 		if value, ok := os.LookupEnv(name); ok {
 			return value
@@ -52,48 +122,67 @@ func NewMongoService[DocType interface{}](config MongoServiceConfig) DbService[D
 		return defaultValue
 	}
 
-	svc := &mongoSvc[DocType]{}
-	svc.MongoServiceConfig = config
-
-	if svc.ServerHost == "" {
-		svc.ServerHost = enviro("AMBULANCE_API_MONGODB_HOST", "localhost")
+	if config.ServerHost == "" {
+		config.ServerHost = enviro("AMBULANCE_API_MONGODB_HOST", "localhost")
 	}
 
-	if svc.ServerPort == 0 {
+	if config.ServerPort == 0 {
 		port := enviro("AMBULANCE_API_MONGODB_PORT", "27017")
 		if port, err := strconv.Atoi(port); err == nil {
-			svc.ServerPort = port
+			config.ServerPort = port
 		} else {
 			log.Printf("Invalid port value: %v", port)
-			svc.ServerPort = 27017
+			config.ServerPort = 27017
 		}
 	}
 
-	if svc.UserName == "" {
-		svc.UserName = enviro("AMBULANCE_API_MONGODB_USERNAME", "")
+	if config.UserName == "" {
+		config.UserName = enviro("AMBULANCE_API_MONGODB_USERNAME", "")
 	}
 
-	if svc.Password == "" {
-		svc.Password = enviro("AMBULANCE_API_MONGODB_PASSWORD", "")
+	if config.Password == "" {
+		config.Password = enviro("AMBULANCE_API_MONGODB_PASSWORD", "")
 	}
 
-	if svc.DbName == "" {
-		svc.DbName = enviro("AMBULANCE_API_MONGODB_DATABASE", "xdudakm-wac-ambulance-wl")
+	if config.DbName == "" {
+		config.DbName = enviro("AMBULANCE_API_MONGODB_DATABASE", "xdudakm-wac-ambulance-wl")
 	}
 
-	if svc.Collection == "" {
-		svc.Collection = enviro("AMBULANCE_API_MONGODB_COLLECTION", "ambulance")
+	if config.Collection == "" {
+		config.Collection = enviro("AMBULANCE_API_MONGODB_COLLECTION", "ambulance")
 	}
 
-	if svc.Timeout == 0 {
+	if config.Timeout == 0 {
 		seconds := enviro("AMBULANCE_API_MONGODB_TIMEOUT_SECONDS", "10")
 		if seconds, err := strconv.Atoi(seconds); err == nil {
-			svc.Timeout = time.Duration(seconds) * time.Second
+			config.Timeout = time.Duration(seconds) * time.Second
 		} else {
 			log.Printf("Invalid timeout value: %v", seconds)
-			svc.Timeout = 10 * time.Second
+			config.Timeout = 10 * time.Second
 		}
 	}
+	return config
+}
+
+// NewMongoClient returns a connection to be shared by the services given it in their
+// config. The connection settings of the services are taken from it.
+func NewMongoClient(config MongoServiceConfig) *MongoClient {
+	client := &MongoClient{}
+	client.MongoServiceConfig = config.withDefaults()
+	return client
+}
+
+func NewMongoService[DocType interface{}](config MongoServiceConfig) DbService[DocType] {
+	svc := &mongoSvc[DocType]{}
+	if config.Client != nil {
+		config.ServerHost, config.ServerPort = config.Client.ServerHost, config.Client.ServerPort
+		config.UserName, config.Password = config.Client.UserName, config.Client.Password
+		svc.connection, svc.sharedConnection = config.Client, true
+	}
+	svc.MongoServiceConfig = config.withDefaults()
+	if svc.connection == nil {
+		svc.connection = &MongoClient{MongoServiceConfig: svc.MongoServiceConfig}
+	}
 
 	log.Printf(
 		"MongoDB config: //%v@%v:%v/%v/%v",
@@ -106,7 +195,7 @@ func NewMongoService[DocType interface{}](config MongoServiceConfig) DbService[D
 	return svc
 }
 
-func (m *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, error) {
+func (m *MongoClient) connect(ctx context.Context) (*mongo.Client, error) {
 	client := m.client.Load()
 	if client != nil {
 		return client, nil
@@ -124,13 +213,21 @@ func (m *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, error)
 	defer contextCancel()
 
 	var uri = fmt.Sprintf("mongodb://%v:%v", m.ServerHost, m.ServerPort)
-	log.Printf("Using URI: " + uri)
+	log.Printf("Using URI: %v", uri)
 
 	if len(m.UserName) != 0 {
 		uri = fmt.Sprintf("mongodb://%v:%v@%v:%v", m.UserName, m.Password, m.ServerHost, m.ServerPort)
 	}
 
-	if client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri).SetConnectTimeout(10*time.Second)); err != nil {
+	// documents are stored under their json field names so that field queries
+	// such as "ambulance_id" match what the API exposes; documents stored under the
+	// default lowercase names are migrated through FieldRenamer
+	clientOptions := options.Client().
+		ApplyURI(uri).
+		SetConnectTimeout(10 * time.Second).
+		SetBSONOptions(&options.BSONOptions{UseJSONStructTags: true})
+
+	if client, err := mongo.Connect(ctx, clientOptions); err != nil {
 		return nil, err
 	} else {
 		m.client.Store(client)
@@ -138,7 +235,7 @@ func (m *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, error)
 	}
 }
 
-func (m *mongoSvc[DocType]) Disconnect(ctx context.Context) error {
+func (m *MongoClient) Disconnect(ctx context.Context) error {
 	client := m.client.Load()
 
 	if client != nil {
@@ -156,6 +253,159 @@ func (m *mongoSvc[DocType]) Disconnect(ctx context.Context) error {
 	return nil
 }
 
+// supportsTransactions asks the server once whether it runs transactions, which replica
+// sets and sharded clusters do and standalone servers do not.
+func (m *MongoClient) supportsTransactions(ctx context.Context, client *mongo.Client) (bool, error) {
+	if known := m.transactions.Load(); known != 0 {
+		return known == 1, nil
+	}
+	var hello struct {
+		SetName string `bson:"setName"`
+		Msg     string `bson:"msg"`
+	}
+	if err := client.Database("admin").RunCommand(ctx, bson.D{{Key: "hello", Value: 1}}).Decode(&hello); err != nil {
+		return false, err
+	}
+	supported := hello.SetName != "" || hello.Msg == "isdbgrid"
+	if supported {
+		m.transactions.Store(1)
+	} else {
+		log.Printf("MongoDB runs standalone, writes to several collections run without transactions")
+		m.transactions.Store(2)
+	}
+	return supported, nil
+}
+
+// txState tracks a transaction run by RunInTransaction.
+type txState struct {
+	// transient is set when an operation failed with an error that aborted the
+	// transaction but lets it succeed when run again, such as a write conflict
+	transient bool
+}
+
+type txStateKey struct{}
+
+// transientTransactionError labels the errors of the server after which a transaction
+// can be run again.
+const transientTransactionError = "TransientTransactionError"
+
+// isTransient reports whether err aborted a transaction that can be run again.
+func isTransient(err error) bool {
+	var labeled mongo.LabeledError
+	return errors.As(err, &labeled) && labeled.HasErrorLabel(transientTransactionError)
+}
+
+// noteTransient records in the transaction of ctx that err aborted it transiently. The
+// callers of a service may only log the error, so it is noted where it occurs. It
+// returns err.
+func noteTransient(ctx context.Context, err error) error {
+	if state, ok := ctx.Value(txStateKey{}).(*txState); ok && isTransient(err) {
+		state.transient = true
+	}
+	return err
+}
+
+// retryTransient calls run, again while it fails transiently, at most attempts times.
+func retryTransient(attempts int, run func(state *txState) error) error {
+	for attempt := 1; ; attempt++ {
+		state := &txState{}
+		err := run(state)
+		if err == nil || attempt >= attempts || !(state.transient || isTransient(err)) {
+			return err
+		}
+		log.Printf("Running transaction again after a write conflict: %v", err)
+	}
+}
+
+// RunInTransaction runs fn in a session with a transaction. Transactions need MongoDB
+// to run as a replica set or sharded cluster. fn is run again on a transient error, such
+// as a write conflict with another transaction, only for a context marked with
+// RetryOnWriteConflict; otherwise the error is returned to the caller.
+func (m *MongoClient) RunInTransaction(ctx context.Context, fn func(txCtx context.Context) error) error {
+	if mongo.SessionFromContext(ctx) != nil {
+		return fn(ctx)
+	}
+	client, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	if supported, err := m.supportsTransactions(ctx, client); err != nil {
+		return err
+	} else if !supported {
+		return ErrNoTransactions
+	}
+
+	attempts := 1
+	if retry, _ := ctx.Value(retryKey{}).(bool); retry {
+		attempts = transactionAttempts
+	}
+	return retryTransient(attempts, func(state *txState) error {
+		session, err := client.StartSession()
+		if err != nil {
+			return err
+		}
+		defer session.EndSession(context.Background())
+
+		return mongo.WithSession(ctx, session, func(sessionCtx mongo.SessionContext) error {
+			if err := session.StartTransaction(); err != nil {
+				return err
+			}
+			if err := fn(context.WithValue(sessionCtx, txStateKey{}, state)); err != nil {
+				if abortErr := session.AbortTransaction(context.Background()); abortErr != nil {
+					log.Printf("Cannot abort transaction: %v", abortErr)
+				}
+				return err
+			}
+			return session.CommitTransaction(sessionCtx)
+		})
+	})
+}
+
+// connect returns the client, preparing the collection first.
+func (m *mongoSvc[DocType]) connect(ctx context.Context) (*mongo.Client, error) {
+	client, err := m.connection.connect(ctx)
+	if err != nil || m.prepared.Load() {
+		return client, err
+	}
+
+	m.prepareLock.Lock()
+	defer m.prepareLock.Unlock()
+	if m.prepared.Load() {
+		return client, nil
+	}
+	if err := m.prepare(client); err != nil {
+		return nil, err
+	}
+	m.prepared.Store(true)
+	return client, nil
+}
+
+// prepare makes the ids of the documents of the collection unique. It runs outside of
+// any transaction of the caller, once per service.
+func (m *mongoSvc[DocType]) prepare(client *mongo.Client) error {
+	ctx, contextCancel := context.WithTimeout(context.Background(), m.Timeout)
+	defer contextCancel()
+
+	collection := client.Database(m.DbName).Collection(m.Collection)
+	_, err := collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if mongo.IsDuplicateKeyError(err) {
+		// the service works on, without the database keeping concurrent writers apart
+		log.Printf("Cannot make ids of %v unique, some are stored twice: %v", m.Collection, err)
+		return nil
+	}
+	return err
+}
+
+func (m *mongoSvc[DocType]) Disconnect(ctx context.Context) error {
+	if m.sharedConnection {
+		return nil
+	}
+	return m.connection.Disconnect(ctx)
+}
+
 func (m *mongoSvc[DocType]) CreateDocument(ctx context.Context, id string, document *DocType) error {
 	ctx, contextCancel := context.WithTimeout(ctx, m.Timeout)
 	defer contextCancel()
@@ -175,7 +425,72 @@ func (m *mongoSvc[DocType]) CreateDocument(ctx context.Context, id string, docum
 	}
 
 	_, err = collection.InsertOne(ctx, document)
-	return err
+	if mongo.IsDuplicateKeyError(err) {
+		// created concurrently since the check
+		return ErrConflict
+	}
+	return noteTransient(ctx, err)
+}
+
+// CreateDocuments checks which ids are taken with one query and inserts the other
+// documents with one unordered BulkWrite, so that a failing document does not stop the rest.
+func (m *mongoSvc[DocType]) CreateDocuments(ctx context.Context, ids []string, documents []*DocType) ([]error, error) {
+	ctx, contextCancel := context.WithTimeout(ctx, m.Timeout)
+	defer contextCancel()
+	client, err := m.connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	collection := client.Database(m.DbName).Collection(m.Collection)
+
+	cursor, err := collection.Find(ctx, bson.D{{Key: "id", Value: bson.D{{Key: "$in", Value: ids}}}},
+		options.Find().SetProjection(bson.D{{Key: "id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	var stored []struct {
+		Id string `bson:"id"`
+	}
+	if err := cursor.All(ctx, &stored); err != nil {
+		return nil, err
+	}
+	taken := map[string]bool{}
+	for _, document := range stored {
+		taken[document.Id] = true
+	}
+
+	errs := make([]error, len(documents))
+	models := make([]mongo.WriteModel, 0, len(documents))
+	positions := make([]int, 0, len(documents))
+	for i, document := range documents {
+		if taken[ids[i]] {
+			errs[i] = ErrConflict
+			continue
+		}
+		taken[ids[i]] = true
+		models = append(models, mongo.NewInsertOneModel().SetDocument(document))
+		positions = append(positions, i)
+	}
+	if len(models) == 0 {
+		return errs, nil
+	}
+
+	_, err = collection.BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	noteTransient(ctx, err)
+	if bulkErr, ok := err.(mongo.BulkWriteException); ok && bulkErr.WriteConcernError == nil {
+		for _, writeErr := range bulkErr.WriteErrors {
+			if mongo.IsDuplicateKeyError(writeErr) {
+				errs[positions[writeErr.Index]] = ErrConflict
+			} else {
+				errs[positions[writeErr.Index]] = writeErr
+			}
+		}
+		return errs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
 }
 
 func (m *mongoSvc[DocType]) FindDocument(ctx context.Context, id string) (*DocType, error) {
@@ -220,7 +535,36 @@ func (m *mongoSvc[DocType]) UpdateDocument(ctx context.Context, id string, docum
 		return result.Err()
 	}
 	_, err = collection.ReplaceOne(ctx, bson.D{{Key: "id", Value: id}}, document)
-	return err
+	return noteTransient(ctx, err)
+}
+
+func (m *mongoSvc[DocType]) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *DocType) error {
+	ctx, contextCancel := context.WithTimeout(ctx, m.Timeout)
+	defer contextCancel()
+	client, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	collection := client.Database(m.DbName).Collection(m.Collection)
+	filter := bson.D{{Key: "id", Value: id}}
+	for name, value := range fields {
+		filter = append(filter, bson.E{Key: name, Value: value})
+	}
+	result, err := collection.ReplaceOne(ctx, filter, document)
+	if err != nil {
+		return noteTransient(ctx, err)
+	}
+	if result.MatchedCount > 0 {
+		return nil
+	}
+	switch err := collection.FindOne(ctx, bson.D{{Key: "id", Value: id}}).Err(); err {
+	case nil:
+		return ErrConflict
+	case mongo.ErrNoDocuments:
+		return ErrNotFound
+	default:
+		return err
+	}
 }
 
 func (m *mongoSvc[DocType]) DeleteDocument(ctx context.Context, id string) error {
@@ -241,7 +585,7 @@ func (m *mongoSvc[DocType]) DeleteDocument(ctx context.Context, id string) error
 		return result.Err()
 	}
 	_, err = collection.DeleteOne(ctx, bson.D{{Key: "id", Value: id}})
-	return err
+	return noteTransient(ctx, err)
 }
 
 func (m *mongoSvc[DocType]) ListDocuments(ctx context.Context) ([]DocType, error) {
@@ -308,3 +652,54 @@ func (m *mongoSvc[DocType]) FindDocumentsByField(ctx context.Context, fieldName
 
 	return results, nil
 }
+
+func (m *mongoSvc[DocType]) Aggregate(ctx context.Context, pipeline any, results any) error {
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	client, err := m.connect(ctx)
+	if err != nil {
+		return err
+	}
+	coll := client.Database(m.DbName).Collection(m.Collection)
+
+	cursor, err := coll.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+	return cursor.All(ctx, results)
+}
+
+// RenameFields renames the RenamedFields of the documents still stored under their old
+// names and returns the number of documents renamed. Safe to run repeatedly.
+func (m *mongoSvc[DocType]) RenameFields(ctx context.Context) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, m.Timeout)
+	defer cancel()
+
+	client, err := m.connect(ctx)
+	if err != nil {
+		return 0, err
+	}
+	coll := client.Database(m.DbName).Collection(m.Collection)
+
+	filter := bson.A{}
+	rename := bson.D{}
+	for oldName, newName := range m.RenamedFields {
+		filter = append(filter, bson.D{{Key: oldName, Value: bson.D{{Key: "$exists", Value: true}}}})
+		rename = append(rename, bson.E{Key: oldName, Value: newName})
+	}
+	if len(rename) == 0 {
+		return 0, nil
+	}
+	result, err := coll.UpdateMany(ctx,
+		bson.D{{Key: "$or", Value: filter}},
+		bson.D{{Key: "$rename", Value: rename}})
+	if err != nil {
+		return 0, err
+	}
+	if result.ModifiedCount > 0 {
+		log.Printf("Renamed fields of %v documents of %v", result.ModifiedCount, m.Collection)
+	}
+	return result.ModifiedCount, nil
+}
@@ -0,0 +1,65 @@
+package db_service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoTransactionSuite defines the suite for tests of running Mongo transactions again
+type MongoTransactionSuite struct {
+	suite.Suite
+}
+
+func TestMongoTransactionSuite(t *testing.T) {
+	suite.Run(t, new(MongoTransactionSuite))
+}
+
+func (suite *MongoTransactionSuite) Test_NoteTransient_MarksTransactionOfWriteConflict() {
+	state := &txState{}
+	ctx := context.WithValue(context.Background(), txStateKey{}, state)
+
+	noteTransient(ctx, errors.New("connection lost"))
+	suite.False(state.transient)
+
+	conflict := mongo.CommandError{Code: 112, Name: "WriteConflict", Labels: []string{transientTransactionError}}
+	suite.Equal(conflict, noteTransient(ctx, conflict))
+	suite.True(state.transient)
+}
+
+func (suite *MongoTransactionSuite) Test_RetryTransient_RunsAgainAfterWriteConflict() {
+	calls := 0
+	err := retryTransient(transactionAttempts, func(state *txState) error {
+		calls++
+		if calls == 1 {
+			// the handler only reports that it failed
+			state.transient = true
+			return errors.New("failed to issue invoice")
+		}
+		return nil
+	})
+
+	suite.NoError(err)
+	suite.Equal(2, calls)
+}
+
+func (suite *MongoTransactionSuite) Test_RetryTransient_ReturnsOtherErrorsAndGivesUp() {
+	failure := errors.New("invalid document")
+	calls := 0
+	suite.Equal(failure, retryTransient(transactionAttempts, func(*txState) error {
+		calls++
+		return failure
+	}))
+	suite.Equal(1, calls)
+
+	conflict := mongo.CommandError{Labels: []string{transientTransactionError}}
+	calls = 0
+	suite.Equal(conflict, retryTransient(3, func(*txState) error {
+		calls++
+		return conflict
+	}))
+	suite.Equal(3, calls)
+}
@@ -0,0 +1,175 @@
+package idempotency
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// Header carries the client-chosen key of a request that may be retried.
+const Header = "Idempotency-Key"
+
+// ReplayedHeader is set on responses replayed from an earlier request.
+const ReplayedHeader = "Idempotent-Replayed"
+
+// DefaultTTL is how long responses are kept for replay unless configured otherwise.
+const DefaultTTL = 24 * time.Hour
+
+// lockTimeout is how long a request may hold a key before another one takes it over,
+// in case the first never completed.
+const lockTimeout = time.Minute
+
+// Record is the stored outcome of a request made with an idempotency key.
+type Record struct {
+
+	// The idempotency key.
+	Id string `json:"id"`
+
+	// Hash of the method, path and body of the request.
+	Fingerprint string `json:"fingerprint"`
+
+	// Whether the response was stored; false while the first request is in progress.
+	Completed bool `json:"completed"`
+
+	// Status code of the response.
+	Status int `json:"status,omitempty"`
+
+	// Content type of the response.
+	ContentType string `json:"content_type,omitempty"`
+
+	// Body of the response.
+	Body []byte `json:"body,omitempty"`
+
+	// Date and time the key was first used.
+	CreatedAt time.Time `json:"created_at"`
+
+	// Date and time after which the key may be used again.
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// recorder passes the response through while keeping a copy of its body.
+type recorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.body.Write(data)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *recorder) WriteString(s string) (int, error) {
+	r.body.WriteString(s)
+	return r.ResponseWriter.WriteString(s)
+}
+
+// fingerprint hashes what identifies a request, so that a key reused for a different
+// request can be told apart from a retry.
+func fingerprint(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	io.WriteString(hash, c.Request.Method+" "+c.Request.URL.Path+"\n")
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// lockedBy returns the fields identifying the request holding the key of the record, for
+// replacing the record only while that request still holds it.
+func lockedBy(record *Record) map[string]any {
+	return map[string]any{"created_at": record.CreatedAt, "completed": record.Completed}
+}
+
+// Middleware makes POST requests carrying an Idempotency-Key header safe to retry. The
+// first response is stored for ttl; a retry with the same key and body gets the same
+// status and body without running the handler again, while a key reused for a different
+// request is rejected with 422. Server errors are not stored, so they can be retried.
+func Middleware(store db_service.DbService[Record], ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(Header)
+		if c.Request.Method != http.MethodPost || key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": Header + " must not be longer than 255 characters"})
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"message": "Invalid request", "error": err.Error()})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		requestHash := fingerprint(c, body)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		now := time.Now()
+		record := Record{Id: key, Fingerprint: requestHash, CreatedAt: now, ExpiresAt: now.Add(ttl)}
+		existing, err := store.FindDocument(ctx, key)
+		switch {
+		case err == db_service.ErrNotFound:
+			// ids are unique, so of concurrent first requests all but one get ErrConflict
+			err = store.CreateDocument(ctx, key, &record)
+		case err != nil:
+		case now.After(existing.ExpiresAt) || (!existing.Completed && now.Sub(existing.CreatedAt) > lockTimeout):
+			// the key expired or its request was abandoned; of concurrent requests taking it
+			// over only the first finds the record as it was
+			err = store.UpdateDocumentIf(ctx, key, lockedBy(existing), &record)
+		case existing.Fingerprint != requestHash:
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"message": Header + " was already used for a different request"})
+			return
+		case !existing.Completed:
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": "A request with this " + Header + " is still in progress"})
+			return
+		default:
+			c.Header(ReplayedHeader, "true")
+			c.Data(existing.Status, existing.ContentType, existing.Body)
+			c.Abort()
+			return
+		}
+		if err == db_service.ErrConflict || err == db_service.ErrNotFound {
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{"message": "A request with this " + Header + " is still in progress"})
+			return
+		}
+		if err != nil {
+			log.Println("idempotency store error:", err)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Internal error"})
+			return
+		}
+
+		writer := &recorder{ResponseWriter: c.Writer}
+		c.Writer = writer
+		c.Next()
+
+		// the handler may have taken a while
+		ctx, cancel = context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			if err := store.DeleteDocument(ctx, key); err != nil {
+				log.Println("idempotency store error:", err)
+			}
+			return
+		}
+		lock := lockedBy(&record)
+		record.Completed = true
+		record.Status = c.Writer.Status()
+		record.ContentType = c.Writer.Header().Get("Content-Type")
+		record.Body = writer.body.Bytes()
+		if err := store.UpdateDocumentIf(ctx, key, lock, &record); err == db_service.ErrConflict {
+			log.Printf("idempotency key %v was taken over before its request completed", key)
+		} else if err != nil {
+			log.Println("idempotency store error:", err)
+		}
+	}
+}
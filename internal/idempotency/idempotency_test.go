@@ -0,0 +1,150 @@
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/suite"
+	"github.com/wac-project/wac-api/internal/db_service"
+)
+
+// racingStore runs a competing request once, just before the next write of the key.
+type racingStore struct {
+	db_service.DbService[Record]
+	race func()
+}
+
+func (m *racingStore) runRace() {
+	if race := m.race; race != nil {
+		m.race = nil
+		race()
+	}
+}
+
+func (m *racingStore) CreateDocument(ctx context.Context, id string, document *Record) error {
+	m.runRace()
+	return m.DbService.CreateDocument(ctx, id, document)
+}
+
+func (m *racingStore) UpdateDocumentIf(ctx context.Context, id string, fields map[string]any, document *Record) error {
+	m.runRace()
+	return m.DbService.UpdateDocumentIf(ctx, id, fields, document)
+}
+
+// IdempotencySuite defines the suite for idempotency key tests
+type IdempotencySuite struct {
+	suite.Suite
+	store  *racingStore
+	router *gin.Engine
+	calls  int
+	status int
+}
+
+func TestIdempotencySuite(t *testing.T) {
+	suite.Run(t, new(IdempotencySuite))
+}
+
+func (suite *IdempotencySuite) SetupTest() {
+	gin.SetMode(gin.TestMode)
+	suite.store = &racingStore{DbService: db_service.NewMemoryService[Record]()}
+	suite.calls = 0
+	suite.status = http.StatusCreated
+	suite.router = gin.New()
+	suite.router.Use(Middleware(suite.store, time.Hour))
+	suite.router.POST("/api/payments", func(c *gin.Context) {
+		suite.calls++
+		c.JSON(suite.status, gin.H{"id": "pay", "call": suite.calls})
+	})
+}
+
+func (suite *IdempotencySuite) post(key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/payments", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(Header, key)
+	}
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func (suite *IdempotencySuite) Test_Replay_ReturnsFirstResponse() {
+	first := suite.post("key-1", `{"amount": "120.50"}`)
+	replay := suite.post("key-1", `{"amount": "120.50"}`)
+
+	suite.Equal(1, suite.calls)
+	suite.Equal(http.StatusCreated, replay.Code)
+	suite.Equal(first.Body.String(), replay.Body.String())
+	suite.Equal("true", replay.Header().Get(ReplayedHeader))
+	suite.Equal("application/json; charset=utf-8", replay.Header().Get("Content-Type"))
+}
+
+func (suite *IdempotencySuite) Test_ReusedKeyWithDifferentBody_Returns422() {
+	suite.post("key-1", `{"amount": "120.50"}`)
+
+	recorder := suite.post("key-1", `{"amount": "99.00"}`)
+
+	suite.Equal(http.StatusUnprocessableEntity, recorder.Code)
+	suite.Equal(1, suite.calls)
+}
+
+func (suite *IdempotencySuite) Test_ExpiredKey_RunsRequestAgain() {
+	suite.post("key-1", `{}`)
+	record, err := suite.store.FindDocument(context.Background(), "key-1")
+	suite.Require().NoError(err)
+	record.ExpiresAt = time.Now().Add(-time.Second)
+	suite.Require().NoError(suite.store.UpdateDocument(context.Background(), "key-1", record))
+
+	suite.post("key-1", `{}`)
+
+	suite.Equal(2, suite.calls)
+}
+
+func (suite *IdempotencySuite) Test_ConcurrentFirstRequests_RunOnce() {
+	suite.store.race = func() { suite.post("key-1", `{}`) }
+
+	recorder := suite.post("key-1", `{}`)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Equal(1, suite.calls)
+}
+
+func (suite *IdempotencySuite) Test_AbandonedKey_IsTakenOverOnce() {
+	abandoned := time.Now().Add(-2 * lockTimeout)
+	suite.Require().NoError(suite.store.CreateDocument(context.Background(), "key-1",
+		&Record{Id: "key-1", CreatedAt: abandoned, ExpiresAt: abandoned.Add(time.Hour)}))
+	suite.store.race = func() { suite.post("key-1", `{}`) }
+
+	recorder := suite.post("key-1", `{}`)
+
+	suite.Equal(http.StatusConflict, recorder.Code)
+	suite.Equal(1, suite.calls)
+	replay := suite.post("key-1", `{}`)
+	suite.Equal(http.StatusCreated, replay.Code)
+	suite.Equal("true", replay.Header().Get(ReplayedHeader))
+}
+
+func (suite *IdempotencySuite) Test_ServerError_IsNotStored() {
+	suite.status = http.StatusInternalServerError
+	suite.post("key-1", `{}`)
+	suite.status = http.StatusCreated
+
+	recorder := suite.post("key-1", `{}`)
+
+	suite.Equal(http.StatusCreated, recorder.Code)
+	suite.Equal(2, suite.calls)
+}
+
+func (suite *IdempotencySuite) Test_WithoutKey_EveryRequestRuns() {
+	suite.post("", `{}`)
+	suite.post("", `{}`)
+
+	suite.Equal(2, suite.calls)
+	records, _ := suite.store.ListDocuments(context.Background())
+	suite.Empty(records)
+}
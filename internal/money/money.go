@@ -0,0 +1,337 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DefaultCurrency is assumed for amounts given without a currency, including amounts
+// stored before currencies were recorded.
+const DefaultCurrency = "EUR"
+
+var ErrCurrencyMismatch = fmt.Errorf("amounts in different currencies cannot be combined")
+
+// Money is an exact amount of money: a whole number of minor units (e.g. cents) of an
+// ISO 4217 currency. The zero value is zero in the default currency.
+//
+// In JSON it is written as {"amount": "120.50", "currency": "EUR"}; a bare number or
+// string amount is read in the default currency. In MongoDB the amount is stored as a
+// Decimal128; legacy floating point amounts are read in the default currency.
+type Money struct {
+	Minor    int64
+	Currency string
+}
+
+// exponents lists the ISO 4217 currencies whose minor unit is not a hundredth.
+var exponents = map[string]int{
+	"BHD": 3, "IQD": 3, "JOD": 3, "KWD": 3, "LYD": 3, "OMR": 3, "TND": 3,
+	"BIF": 0, "CLP": 0, "DJF": 0, "GNF": 0, "ISK": 0, "JPY": 0, "KMF": 0, "KRW": 0,
+	"PYG": 0, "RWF": 0, "UGX": 0, "VND": 0, "VUV": 0, "XAF": 0, "XOF": 0, "XPF": 0,
+}
+
+// New returns minor units of the currency, e.g. New(12050, "EUR") is 120.50 EUR.
+func New(minor int64, currency string) Money {
+	return Money{Minor: minor, Currency: currency}
+}
+
+// Exponent returns the number of decimal places of the currency's minor unit.
+func Exponent(currency string) int {
+	if exponent, ok := exponents[currency]; ok {
+		return exponent
+	}
+	return 2
+}
+
+// ValidCurrency reports whether code looks like an ISO 4217 currency code.
+func ValidCurrency(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse reads an amount such as "120.50" or "120.50 EUR"; amounts without a currency
+// are in the given default currency. Amounts finer than the minor unit are rejected.
+func Parse(value string, currency string) (Money, error) {
+	fields := strings.Fields(value)
+	switch {
+	case len(fields) == 2:
+		currency = strings.ToUpper(fields[1])
+	case len(fields) != 1:
+		return Money{}, fmt.Errorf("invalid amount %q", value)
+	}
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	if !ValidCurrency(currency) {
+		return Money{}, fmt.Errorf("invalid currency %q", currency)
+	}
+	minor, err := parseMinor(fields[0], Exponent(currency))
+	if err != nil {
+		return Money{}, err
+	}
+	return Money{Minor: minor, Currency: currency}, nil
+}
+
+// parseMinor converts a decimal amount to minor units with the given exponent.
+func parseMinor(amount string, exponent int) (int64, error) {
+	rat, ok := new(big.Rat).SetString(amount)
+	if !ok || strings.ContainsAny(amount, "/eE") {
+		return 0, fmt.Errorf("invalid amount %q", amount)
+	}
+	rat.Mul(rat, new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(exponent)), nil)))
+	if !rat.IsInt() {
+		return 0, fmt.Errorf("amount %q has more than %d decimal places", amount, exponent)
+	}
+	if !rat.Num().IsInt64() {
+		return 0, fmt.Errorf("amount %q is out of range", amount)
+	}
+	return rat.Num().Int64(), nil
+}
+
+// FromFloat converts a floating point amount, as stored before amounts were exact,
+// rounding to the nearest minor unit.
+func FromFloat(value float64, currency string) Money {
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+	return Money{Minor: int64(math.Round(value * math.Pow10(Exponent(currency)))), Currency: currency}
+}
+
+// currency returns the currency of m, the default one for the zero value.
+func (m Money) currency() string {
+	if m.Currency == "" {
+		return DefaultCurrency
+	}
+	return m.Currency
+}
+
+// Decimal returns the amount without the currency, e.g. "120.50".
+func (m Money) Decimal() string {
+	exponent := Exponent(m.currency())
+	sign, minor := "", m.Minor
+	if minor < 0 {
+		sign, minor = "-", -minor
+	}
+	digits := strconv.FormatInt(minor, 10)
+	if exponent == 0 {
+		return sign + digits
+	}
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String returns the amount with its currency, e.g. "120.50 EUR".
+func (m Money) String() string {
+	return m.Decimal() + " " + m.currency()
+}
+
+func (m Money) IsZero() bool {
+	return m.Minor == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Minor < 0
+}
+
+// Equal reports whether m and o are the same amount; zero amounts are equal in any currency.
+func (m Money) Equal(o Money) bool {
+	return m.Minor == o.Minor && (m.Minor == 0 || m.currency() == o.currency())
+}
+
+// Add returns m + o. A zero amount adopts the currency of the other one.
+func (m Money) Add(o Money) (Money, error) {
+	switch {
+	case m.Minor == 0 && m.Currency == "":
+		return o, nil
+	case o.Minor == 0 && o.Currency == "":
+		return m, nil
+	case m.currency() != o.currency():
+		return Money{}, ErrCurrencyMismatch
+	}
+	return Money{Minor: m.Minor + o.Minor, Currency: m.currency()}, nil
+}
+
+// Sub returns m - o.
+func (m Money) Sub(o Money) (Money, error) {
+	return m.Add(o.Neg())
+}
+
+// Neg returns -m.
+func (m Money) Neg() Money {
+	return Money{Minor: -m.Minor, Currency: m.Currency}
+}
+
+// Percent returns percent per cent of m, rounded half away from zero to the minor unit.
+// The percentage is taken as the shortest decimal that reads back as percent, so that
+// 2.3 is exactly 23/10 per cent, and the product is computed on whole numbers.
+func (m Money) Percent(percent float64) Money {
+	return m.percent(strconv.FormatFloat(percent, 'f', -1, 64))
+}
+
+// Percent32 is Percent for percentages held as float32.
+func (m Money) Percent32(percent float32) Money {
+	return m.percent(strconv.FormatFloat(float64(percent), 'f', -1, 32))
+}
+
+func (m Money) percent(decimal string) Money {
+	rate, ok := new(big.Rat).SetString(decimal)
+	if !ok {
+		// NaN or infinity
+		return Money{Currency: m.currency()}
+	}
+	numerator := new(big.Int).Mul(big.NewInt(m.Minor), rate.Num())
+	denominator := new(big.Int).Mul(big.NewInt(100), rate.Denom())
+	quotient, remainder := new(big.Int).QuoRem(numerator, denominator, new(big.Int))
+	if remainder.Lsh(remainder.Abs(remainder), 1).Cmp(denominator) >= 0 {
+		quotient.Add(quotient, big.NewInt(int64(numerator.Sign())))
+	}
+	return Money{Minor: quotient.Int64(), Currency: m.currency()}
+}
+
+// Sum totals the amounts, which must all be in one currency.
+func Sum(amounts ...Money) (Money, error) {
+	total := Money{}
+	for _, amount := range amounts {
+		var err error
+		if total, err = total.Add(amount); err != nil {
+			return Money{}, err
+		}
+	}
+	return total, nil
+}
+
+type jsonMoney struct {
+	Amount   json.Number `json:"amount"`
+	Currency string      `json:"currency"`
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Amount   string `json:"amount"`
+		Currency string `json:"currency"`
+	}{m.Decimal(), m.currency()})
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	switch {
+	case bytes.Equal(data, []byte("null")):
+		return nil
+	case len(data) > 0 && data[0] == '{':
+		var value jsonMoney
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&value); err != nil {
+			return err
+		}
+		if value.Amount == "" {
+			return fmt.Errorf("amount is required")
+		}
+		parsed, err := Parse(value.Amount.String(), strings.ToUpper(value.Currency))
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	case len(data) > 0 && data[0] == '"':
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+		parsed, err := Parse(value, DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	default:
+		parsed, err := Parse(string(data), DefaultCurrency)
+		if err != nil {
+			return err
+		}
+		*m = parsed
+		return nil
+	}
+}
+
+func (m Money) MarshalBSONValue() (bsontype.Type, []byte, error) {
+	amount, err := primitive.ParseDecimal128(m.Decimal())
+	if err != nil {
+		return 0, nil, err
+	}
+	return bson.MarshalValue(bson.D{{Key: "amount", Value: amount}, {Key: "currency", Value: m.currency()}})
+}
+
+func (m *Money) UnmarshalBSONValue(t bsontype.Type, data []byte) error {
+	value := bson.RawValue{Type: t, Value: data}
+	if t != bson.TypeEmbeddedDocument {
+		return m.fromBSONAmount(value, DefaultCurrency)
+	}
+	document := value.Document()
+	currency := DefaultCurrency
+	if c, ok := document.Lookup("currency").StringValueOK(); ok && c != "" {
+		currency = c
+	}
+	return m.fromBSONAmount(document.Lookup("amount"), currency)
+}
+
+// fromBSONAmount reads a Decimal128 amount, or a legacy numeric one, in the currency.
+func (m *Money) fromBSONAmount(value bson.RawValue, currency string) error {
+	switch value.Type {
+	case bson.TypeNull, bson.TypeUndefined, 0:
+		*m = Money{}
+	case bson.TypeDouble:
+		*m = FromFloat(value.Double(), currency)
+	case bson.TypeInt32, bson.TypeInt64:
+		*m = FromFloat(float64(value.AsInt64()), currency)
+	case bson.TypeDecimal128:
+		return m.fromDecimal128(value.Decimal128(), currency)
+	default:
+		return fmt.Errorf("cannot decode %v into Money", value.Type)
+	}
+	return nil
+}
+
+func (m *Money) fromDecimal128(d primitive.Decimal128, currency string) error {
+	coefficient, exp, err := d.BigInt()
+	if err != nil {
+		return err
+	}
+	rat := new(big.Rat).SetInt(coefficient)
+	scale := exp + Exponent(currency)
+	power := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(scale))), nil))
+	if scale >= 0 {
+		rat.Mul(rat, power)
+	} else {
+		rat.Quo(rat, power)
+	}
+	if !rat.IsInt() || !rat.Num().IsInt64() {
+		return fmt.Errorf("amount %v is not a whole number of minor units of %v", d, currency)
+	}
+	*m = Money{Minor: rat.Num().Int64(), Currency: currency}
+	return nil
+}
+
+func abs(value int) int {
+	if value < 0 {
+		return -value
+	}
+	return value
+}
@@ -0,0 +1,86 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// MoneySuite defines the suite for money tests
+type MoneySuite struct {
+	suite.Suite
+}
+
+func TestMoneySuite(t *testing.T) {
+	suite.Run(t, new(MoneySuite))
+}
+
+type document struct {
+	Price Money `bson:"price"`
+}
+
+func (suite *MoneySuite) Test_Parse_IsExact() {
+	price, err := Parse("0.10", "")
+	suite.NoError(err)
+
+	total, err := Sum(price, price, price)
+
+	suite.NoError(err)
+	suite.Equal(New(30, "EUR"), total)
+	suite.Equal("0.30 EUR", total.String())
+	_, err = Parse("0.105", "EUR")
+	suite.Error(err)
+}
+
+func (suite *MoneySuite) Test_Parse_UsesCurrencyMinorUnit() {
+	yen, err := Parse("1200 JPY", "")
+	suite.NoError(err)
+	dinar, err := Parse("1.250", "KWD")
+	suite.NoError(err)
+
+	suite.Equal(int64(1200), yen.Minor)
+	suite.Equal(int64(1250), dinar.Minor)
+	suite.Equal("1.250", dinar.Decimal())
+	suite.Equal("-0.05", New(-5, "EUR").Decimal())
+}
+
+func (suite *MoneySuite) Test_Add_RejectsCurrencyMismatch() {
+	_, err := New(100, "EUR").Add(New(100, "CZK"))
+
+	suite.Equal(ErrCurrencyMismatch, err)
+}
+
+func (suite *MoneySuite) Test_Percent_RoundsExactProductHalfAwayFromZero() {
+	suite.Equal(New(35, "EUR"), New(1500, "EUR").Percent(2.3))
+	suite.Equal(New(299, "EUR"), New(1500, "EUR").Percent32(19.9))
+	suite.Equal(New(-35, "EUR"), New(-1500, "EUR").Percent(2.3))
+	suite.Equal(New(2, "JPY"), New(15, "JPY").Percent(10))
+	suite.Equal(New(0, "EUR"), New(1500, "EUR").Percent(0.01))
+}
+
+func (suite *MoneySuite) Test_JSON_AcceptsLegacyNumbersAndWritesStrings() {
+	var values []Money
+	suite.NoError(json.Unmarshal([]byte(`[120.5, "99.99", {"amount": "10", "currency": "czk"}]`), &values))
+
+	suite.Equal([]Money{New(12050, "EUR"), New(9999, "EUR"), New(1000, "CZK")}, values)
+	data, err := json.Marshal(values[0])
+	suite.NoError(err)
+	suite.JSONEq(`{"amount": "120.50", "currency": "EUR"}`, string(data))
+}
+
+func (suite *MoneySuite) Test_BSON_StoresDecimal128AndReadsLegacyFloats() {
+	data, err := bson.Marshal(document{Price: New(12050, "EUR")})
+	suite.NoError(err)
+	stored := bson.Raw(data).Lookup("price", "amount")
+	suite.Equal(bson.TypeDecimal128, stored.Type)
+
+	var decoded document
+	suite.NoError(bson.Unmarshal(data, &decoded))
+	suite.Equal(New(12050, "EUR"), decoded.Price)
+
+	legacy, _ := bson.Marshal(bson.M{"price": float64(float32(0.1))})
+	suite.NoError(bson.Unmarshal(legacy, &decoded))
+	suite.Equal(New(10, "EUR"), decoded.Price)
+}
@@ -0,0 +1,176 @@
+// Package pdf writes simple PDF documents: A4 pages of text in the standard Helvetica
+// fonts and straight lines. It needs no external tools or embedded fonts, which is
+// enough for documents such as invoices.
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/unicode/norm"
+)
+
+// A4 page size in points.
+const (
+	PageWidth  = 595.28
+	PageHeight = 841.89
+)
+
+// helveticaWidths and helveticaBoldWidths are the advance widths of the printable ASCII
+// characters (space to tilde) in thousandths of the font size.
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var helveticaBoldWidths = [95]int{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// Document is a PDF document being built page by page.
+type Document struct {
+	pages []*Page
+}
+
+// Page is a page of a Document. Coordinates are in points from the bottom-left corner.
+type Page struct {
+	content bytes.Buffer
+}
+
+// New returns an empty document.
+func New() *Document {
+	return &Document{}
+}
+
+// AddPage appends an empty A4 page to the document.
+func (d *Document) AddPage() *Page {
+	page := &Page{}
+	d.pages = append(d.pages, page)
+	return page
+}
+
+// baseRune returns r without diacritics, so that "ť" can be written as "t" where the
+// font encoding has no glyph for it.
+func baseRune(r rune) rune {
+	if r < 0x80 {
+		return r
+	}
+	return []rune(norm.NFD.String(string(r)))[0]
+}
+
+// encode converts text to the WinAnsi encoding of the standard fonts. Characters the
+// encoding lacks are written without their diacritics, or as '?'.
+func encode(text string) []byte {
+	encoded := make([]byte, 0, len(text))
+	for _, r := range text {
+		if b, ok := charmap.Windows1252.EncodeRune(r); ok {
+			encoded = append(encoded, b)
+		} else if b, ok := charmap.Windows1252.EncodeRune(baseRune(r)); ok {
+			encoded = append(encoded, b)
+		} else {
+			encoded = append(encoded, '?')
+		}
+	}
+	return encoded
+}
+
+// TextWidth returns the width of text in points when set in the given size.
+func TextWidth(text string, size float64, bold bool) float64 {
+	widths := &helveticaWidths
+	if bold {
+		widths = &helveticaBoldWidths
+	}
+	total := 0
+	for _, r := range text {
+		if r = baseRune(r); r >= ' ' && r <= '~' {
+			total += widths[r-' ']
+		} else {
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// Text writes text with its baseline starting at (x, y).
+func (p *Page) Text(x, y, size float64, bold bool, text string) {
+	font := "F1"
+	if bold {
+		font = "F2"
+	}
+	fmt.Fprintf(&p.content, "BT /%s %s Tf %s %s Td (", font, number(size), number(x), number(y))
+	for _, b := range encode(text) {
+		if b == '(' || b == ')' || b == '\\' {
+			p.content.WriteByte('\\')
+		}
+		p.content.WriteByte(b)
+	}
+	p.content.WriteString(") Tj ET\n")
+}
+
+// TextRight writes text with its baseline ending at (x, y).
+func (p *Page) TextRight(x, y, size float64, bold bool, text string) {
+	p.Text(x-TextWidth(text, size, bold), y, size, bold, text)
+}
+
+// Line draws a thin line from (x1, y1) to (x2, y2).
+func (p *Page) Line(x1, y1, x2, y2 float64) {
+	fmt.Fprintf(&p.content, "0.5 w %s %s m %s %s l S\n", number(x1), number(y1), number(x2), number(y2))
+}
+
+// number formats a coordinate with at most two decimals.
+func number(value float64) string {
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
+// WriteTo writes the document in PDF format.
+func (d *Document) WriteTo(w io.Writer) (int64, error) {
+	if len(d.pages) == 0 {
+		d.AddPage()
+	}
+
+	// objects 1 and 2 are the catalog and the page tree, 3 and 4 the fonts; every
+	// page takes two more objects, the page and its content stream
+	var out bytes.Buffer
+	offsets := make([]int, 0, 4+2*len(d.pages))
+	object := func(body string) {
+		offsets = append(offsets, out.Len())
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, page := range d.pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			number(PageWidth), number(PageHeight), 6+2*i))
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.content.Len(), page.content.String()))
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return out.WriteTo(w)
+}
@@ -0,0 +1,59 @@
+package pdf
+
+import (
+	"bytes"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// PdfSuite defines the suite for PDF writer tests
+type PdfSuite struct {
+	suite.Suite
+}
+
+func TestPdfSuite(t *testing.T) {
+	suite.Run(t, new(PdfSuite))
+}
+
+func (suite *PdfSuite) Test_WriteTo_CrossReferencesEveryObject() {
+	doc := New()
+	doc.AddPage().Text(50, 800, 12, true, "Invoice 2025-000001")
+	doc.AddPage().Line(50, 700, 545, 700)
+
+	var out bytes.Buffer
+	_, err := doc.WriteTo(&out)
+
+	suite.Require().NoError(err)
+	data := out.Bytes()
+	suite.True(bytes.HasPrefix(data, []byte("%PDF-1.4\n")))
+	suite.True(bytes.HasSuffix(data, []byte("%%EOF\n")))
+
+	start := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(data)
+	suite.Require().NotNil(start)
+	xref, _ := strconv.Atoi(string(start[1]))
+	suite.True(bytes.HasPrefix(data[xref:], []byte("xref\n0 9\n")))
+
+	// every entry points at the start of its object
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(data[xref:], -1)
+	suite.Len(entries, 8)
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		suite.True(bytes.HasPrefix(data[offset:], []byte(strconv.Itoa(i+1)+" 0 obj\n")), "object %d", i+1)
+	}
+}
+
+func (suite *PdfSuite) Test_Text_EncodesWinAnsiAndEscapes() {
+	page := New().AddPage()
+
+	page.Text(0, 0, 10, false, "Horváth (poisťovňa) 50\\50")
+
+	suite.Equal("BT /F1 10 Tf 0 0 Td (Horv\xe1th \\(poistovna\\) 50\\\\50) Tj ET\n", page.content.String())
+}
+
+func (suite *PdfSuite) Test_TextWidth_UsesFontMetrics() {
+	suite.InDelta(5.56*3, TextWidth("100", 10, false), 0.001)
+	suite.Greater(TextWidth("Total", 10, true), TextWidth("Total", 10, false))
+}
@@ -0,0 +1,236 @@
+// Package xlsx streams single-sheet Office Open XML spreadsheets. Rows are written to the
+// underlying writer as they come, so large sheets are not held in memory. Cells hold
+// strings, numbers, amounts and dates; number and date styles are the built-in ones that
+// spreadsheet applications display in the reader's locale.
+package xlsx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MaxSheetNameLength is the longest sheet name spreadsheet applications accept.
+const MaxSheetNameLength = 31
+
+// ErrClosed is returned when rows are written after Close.
+var ErrClosed = errors.New("xlsx: writer is closed")
+
+// Cell styles, as indexes into cellXfs of styles.xml.
+const (
+	styleDefault = iota
+	styleHeader
+	styleAmount
+	styleDate
+	styleDateTime
+)
+
+// Cell is a cell of a row. The zero Cell is empty.
+type Cell struct {
+	value  string
+	number bool
+	style  int
+}
+
+// String returns a text cell.
+func String(value string) Cell {
+	return Cell{value: value}
+}
+
+// Header returns a bold text cell.
+func Header(value string) Cell {
+	return Cell{value: value, style: styleHeader}
+}
+
+// Number returns a numeric cell from a decimal such as 42 or -4.5.
+func Number(decimal string) Cell {
+	return Cell{value: decimal, number: true}
+}
+
+// Amount returns a numeric cell from a decimal, displayed with two decimal places and
+// thousands separators.
+func Amount(decimal string) Cell {
+	return Cell{value: decimal, number: true, style: styleAmount}
+}
+
+// Date returns a date cell for the calendar day of t in its location.
+func Date(t time.Time) Cell {
+	return Cell{value: strconv.Itoa(serialDay(t)), number: true, style: styleDate}
+}
+
+// DateTime returns a date and time cell for the wall clock of t in its location.
+func DateTime(t time.Time) Cell {
+	seconds := t.Hour()*3600 + t.Minute()*60 + t.Second()
+	serial := float64(serialDay(t)) + float64(seconds)/86400
+	return Cell{value: strconv.FormatFloat(serial, 'f', -1, 64), number: true, style: styleDateTime}
+}
+
+// serialDay returns the spreadsheet serial number of the day of t: days since 30 December 1899.
+func serialDay(t time.Time) int {
+	y, m, d := t.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	return int(day.Sub(time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)).Hours() / 24)
+}
+
+// Writer writes a spreadsheet with one sheet.
+type Writer struct {
+	archive *zip.Writer
+	sheet   *bufio.Writer
+	rows    int
+	closed  bool
+}
+
+// NewWriter writes the parts of the spreadsheet preceding the rows to w and returns a
+// Writer for the rows of the sheet with the given name. The name is shortened and
+// stripped of characters not allowed in sheet names.
+func NewWriter(w io.Writer, sheetName string) (*Writer, error) {
+	archive := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", contentTypes},
+		{"_rels/.rels", packageRels},
+		{"xl/workbook.xml", fmt.Sprintf(workbook, escape(cleanSheetName(sheetName)))},
+		{"xl/_rels/workbook.xml.rels", workbookRels},
+		{"xl/styles.xml", styles},
+	}
+	for _, part := range parts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	f, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	sheet := bufio.NewWriter(f)
+	sheet.WriteString(xml.Header)
+	sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	return &Writer{archive: archive, sheet: sheet}, nil
+}
+
+// WriteRow appends a row to the sheet.
+func (w *Writer) WriteRow(cells ...Cell) error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.rows++
+	fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows)
+	for i, cell := range cells {
+		if cell.value == "" {
+			continue
+		}
+		ref := columnName(i) + strconv.Itoa(w.rows)
+		style := ""
+		if cell.style != styleDefault {
+			style = fmt.Sprintf(` s="%d"`, cell.style)
+		}
+		if cell.number {
+			fmt.Fprintf(w.sheet, `<c r="%s"%s><v>%s</v></c>`, ref, style, cell.value)
+		} else {
+			fmt.Fprintf(w.sheet, `<c r="%s"%s t="inlineStr"><is><t xml:space="preserve">%s</t></is></c>`, ref, style, escape(cell.value))
+		}
+	}
+	_, err := w.sheet.WriteString("</row>")
+	return err
+}
+
+// Flush writes the buffered rows to the underlying writer.
+func (w *Writer) Flush() error {
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.archive.Flush()
+}
+
+// Close finishes the sheet and the archive. It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.sheet.WriteString("</sheetData></worksheet>")
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// columnName returns the letters of the zero-based column: A, B, ..., Z, AA, ...
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
+
+func cleanSheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return -1
+		}
+		return r
+	}, name)
+	if runes := []rune(name); len(runes) > MaxSheetNameLength {
+		name = string(runes[:MaxSheetNameLength])
+	}
+	if strings.TrimSpace(name) == "" {
+		return "Sheet1"
+	}
+	return name
+}
+
+func escape(value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	return b.String()
+}
+
+const contentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const packageRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const workbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" ` +
+	`xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets><sheet name="%s" sheetId="1" r:id="rId1"/></sheets></workbook>`
+
+const workbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+	`<Relationship Id="rId2" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+// styles holds the cell styles in the order of the style constants. Number formats 4
+// (#,##0.00), 14 (short date) and 22 (short date and time) are built in and follow the
+// locale of the spreadsheet application.
+const styles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="5">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="4" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
@@ -0,0 +1,56 @@
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// XlsxSuite defines the suite for spreadsheet writer tests
+type XlsxSuite struct {
+	suite.Suite
+}
+
+func TestXlsxSuite(t *testing.T) {
+	suite.Run(t, new(XlsxSuite))
+}
+
+func (suite *XlsxSuite) readPart(data []byte, name string) string {
+	archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	suite.Require().NoError(err)
+	f, err := archive.Open(name)
+	suite.Require().NoError(err)
+	content, err := io.ReadAll(f)
+	suite.Require().NoError(err)
+	return string(content)
+}
+
+func (suite *XlsxSuite) Test_Writer_WritesWellFormedSheet() {
+	var out bytes.Buffer
+	w, err := NewWriter(&out, "Procedures: 2025/06")
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(w.WriteRow(Header("name"), Header("price"), Header("timestamp")))
+	suite.Require().NoError(w.WriteRow(String("Röntgen hrudníka <RTG>"), Amount("120.50"), DateTime(time.Date(2025, 6, 2, 18, 0, 0, 0, time.UTC))))
+	suite.Require().NoError(w.Close())
+	suite.ErrorIs(w.WriteRow(String("late")), ErrClosed)
+
+	sheet := suite.readPart(out.Bytes(), "xl/worksheets/sheet1.xml")
+	suite.NoError(xml.Unmarshal([]byte(sheet), new(struct{})))
+	suite.Contains(sheet, `<c r="A2" t="inlineStr"><is><t xml:space="preserve">Röntgen hrudníka &lt;RTG&gt;</t></is></c>`)
+	suite.Contains(sheet, `<c r="B2" s="2"><v>120.50</v></c>`)
+	suite.Contains(sheet, `<c r="C2" s="4"><v>45810.75</v></c>`)
+	suite.Contains(suite.readPart(out.Bytes(), "xl/workbook.xml"), `name="Procedures 202506"`)
+}
+
+func (suite *XlsxSuite) Test_ColumnName() {
+	suite.Equal("A", columnName(0))
+	suite.Equal("Z", columnName(25))
+	suite.Equal("AA", columnName(26))
+	suite.Equal("BA", columnName(52))
+}